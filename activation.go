@@ -0,0 +1,332 @@
+package cel2sql
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ConvertWithActivation partially evaluates a CEL AST against the given variable bindings and
+// converts the residual expression like Convert. Bound variables are inlined as literals,
+// sub-expressions that depend only on them are evaluated, and &&, ||, ! and ?: branches that
+// become constant are folded away, so that the SQL only references the unbound variables, such
+// as table columns. This suits row filters that depend on the request context, e.g.
+// `users.org_id == request.org_id || request.is_admin`.
+func ConvertWithActivation(ast *cel.Ast, vars map[string]any, opts ...ConvertOption) (string, error) {
+	checkedExpr, err := cel.AstToCheckedExpr(ast)
+	if err != nil {
+		return "", err
+	}
+	con := &converter{}
+	for _, opt := range opts {
+		opt(&con.opts)
+	}
+	if err := con.checkDepth(checkedExpr.Expr); err != nil {
+		return "", err
+	}
+
+	pe, err := newPartialEvaluator(checkedExpr, vars)
+	if err != nil {
+		return "", err
+	}
+	residual, err := pe.fold(proto.Clone(checkedExpr.Expr).(*exprpb.Expr), nil)
+	if err != nil {
+		return "", err
+	}
+	return Convert(cel.CheckedExprToAst(&exprpb.CheckedExpr{
+		Expr:         residual,
+		TypeMap:      pe.typeMap,
+		ReferenceMap: checkedExpr.GetReferenceMap(),
+		SourceInfo:   checkedExpr.GetSourceInfo(),
+	}), opts...)
+}
+
+// partialEvaluator folds the variables of an activation into an expression
+type partialEvaluator struct {
+	vars    map[string]any
+	env     *cel.Env
+	typeMap map[int64]*exprpb.Type // copy of the checked type map, updated for folded nodes
+	maxID   int64
+}
+
+func newPartialEvaluator(checkedExpr *exprpb.CheckedExpr, vars map[string]any) (*partialEvaluator, error) {
+	envOpts := make([]cel.EnvOption, 0, len(vars))
+	for name := range vars {
+		envOpts = append(envOpts, cel.Variable(name, cel.DynType))
+	}
+	env, err := cel.NewEnv(envOpts...)
+	if err != nil {
+		return nil, err
+	}
+	pe := &partialEvaluator{
+		vars:    vars,
+		env:     env,
+		typeMap: maps.Clone(checkedExpr.GetTypeMap()),
+	}
+	if pe.typeMap == nil {
+		pe.typeMap = make(map[int64]*exprpb.Type)
+	}
+	stack := []*exprpb.Expr{checkedExpr.GetExpr()}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		pe.maxID = max(pe.maxID, node.GetId())
+		stack = append(stack, childExprs(node)...)
+	}
+	return pe, nil
+}
+
+// fold returns expr with the bound variables inlined and constant branches folded. scope holds
+// the comprehension variables visible at expr, which shadow bound variables.
+func (pe *partialEvaluator) fold(expr *exprpb.Expr, scope map[string]bool) (*exprpb.Expr, error) {
+	if pe.isGround(expr, scope) {
+		if val, ok := pe.eval(expr); ok {
+			if lit, ok := pe.literal(val, expr.GetId()); ok {
+				return lit, nil
+			}
+		}
+	}
+	if ident := expr.GetIdentExpr(); ident != nil {
+		if value, bound := pe.vars[ident.GetName()]; bound && !scope[ident.GetName()] {
+			return nil, fmt.Errorf("cannot inline variable %s: unsupported value of type %T", ident.GetName(), value)
+		}
+		return expr, nil
+	}
+	if err := pe.foldChildren(expr, scope); err != nil {
+		return nil, err
+	}
+	return pe.simplify(expr), nil
+}
+
+// foldChildren folds the sub-expressions of expr in place
+func (pe *partialEvaluator) foldChildren(expr *exprpb.Expr, scope map[string]bool) error {
+	var err error
+	switch expr.GetExprKind().(type) {
+	case *exprpb.Expr_SelectExpr:
+		sel := expr.GetSelectExpr()
+		sel.Operand, err = pe.fold(sel.GetOperand(), scope)
+	case *exprpb.Expr_CallExpr:
+		call := expr.GetCallExpr()
+		if call.GetTarget() != nil {
+			if call.Target, err = pe.fold(call.GetTarget(), scope); err != nil {
+				return err
+			}
+		}
+		for i, arg := range call.GetArgs() {
+			if call.Args[i], err = pe.fold(arg, scope); err != nil {
+				return err
+			}
+		}
+	case *exprpb.Expr_ListExpr:
+		list := expr.GetListExpr()
+		for i, elem := range list.GetElements() {
+			if list.Elements[i], err = pe.fold(elem, scope); err != nil {
+				return err
+			}
+		}
+	case *exprpb.Expr_StructExpr:
+		for _, entry := range expr.GetStructExpr().GetEntries() {
+			if key := entry.GetMapKey(); key != nil {
+				if key, err = pe.fold(key, scope); err != nil {
+					return err
+				}
+				entry.KeyKind = &exprpb.Expr_CreateStruct_Entry_MapKey{MapKey: key}
+			}
+			if entry.Value, err = pe.fold(entry.GetValue(), scope); err != nil {
+				return err
+			}
+		}
+	case *exprpb.Expr_ComprehensionExpr:
+		comp := expr.GetComprehensionExpr()
+		if comp.IterRange, err = pe.fold(comp.GetIterRange(), scope); err != nil {
+			return err
+		}
+		if comp.AccuInit, err = pe.fold(comp.GetAccuInit(), scope); err != nil {
+			return err
+		}
+		inner := maps.Clone(scope)
+		if inner == nil {
+			inner = make(map[string]bool)
+		}
+		inner[comp.GetIterVar()] = true
+		inner[comp.GetIterVar2()] = true
+		inner[comp.GetAccuVar()] = true
+		if comp.LoopCondition, err = pe.fold(comp.GetLoopCondition(), inner); err != nil {
+			return err
+		}
+		// The loop step itself is not simplified, as the comprehension patterns depend on it
+		if err := pe.foldChildren(comp.GetLoopStep(), inner); err != nil {
+			return err
+		}
+		comp.Result, err = pe.fold(comp.GetResult(), inner)
+	}
+	return err
+}
+
+// simplify folds a logical operator or conditional whose operands were folded to constants
+func (pe *partialEvaluator) simplify(expr *exprpb.Expr) *exprpb.Expr {
+	call := expr.GetCallExpr()
+	args := call.GetArgs()
+	switch call.GetFunction() {
+	case operators.LogicalAnd, operators.LogicalOr:
+		// true absorbs ||, false absorbs &&, and the other value is the identity
+		absorbing := call.GetFunction() == operators.LogicalOr
+		var rest []*exprpb.Expr
+		for _, arg := range args {
+			value, ok := boolConstant(arg)
+			if !ok {
+				rest = append(rest, arg)
+			} else if value == absorbing {
+				return arg
+			}
+		}
+		switch len(rest) {
+		case 0:
+			return args[0]
+		case 1:
+			return rest[0]
+		}
+	case operators.LogicalNot:
+		if value, ok := boolConstant(args[0]); ok {
+			lit, _ := pe.literal(types.Bool(!value), expr.GetId())
+			return lit
+		}
+	case operators.Conditional:
+		if value, ok := boolConstant(args[0]); ok {
+			if value {
+				return args[1]
+			}
+			return args[2]
+		}
+	}
+	return expr
+}
+
+// isGround checks if expr references at least one bound variable and no unbound ones
+func (pe *partialEvaluator) isGround(expr *exprpb.Expr, scope map[string]bool) bool {
+	ground, bound := true, false
+	var walk func(e *exprpb.Expr, local map[string]bool)
+	walk = func(e *exprpb.Expr, local map[string]bool) {
+		if e == nil || !ground {
+			return
+		}
+		if ident := e.GetIdentExpr(); ident != nil {
+			name := ident.GetName()
+			switch _, ok := pe.vars[name]; {
+			case local[name]:
+			case ok && !scope[name]:
+				bound = true
+			default:
+				ground = false
+			}
+			return
+		}
+		if comp := e.GetComprehensionExpr(); comp != nil {
+			walk(comp.GetIterRange(), local)
+			walk(comp.GetAccuInit(), local)
+			inner := maps.Clone(local)
+			inner[comp.GetIterVar()] = true
+			inner[comp.GetIterVar2()] = true
+			inner[comp.GetAccuVar()] = true
+			walk(comp.GetLoopCondition(), inner)
+			walk(comp.GetLoopStep(), inner)
+			walk(comp.GetResult(), inner)
+			return
+		}
+		for _, child := range childExprs(e) {
+			walk(child, local)
+		}
+	}
+	walk(expr, map[string]bool{})
+	return ground && bound
+}
+
+// eval evaluates a ground expression against the bound variables
+func (pe *partialEvaluator) eval(expr *exprpb.Expr) (ref.Val, bool) {
+	prg, err := pe.env.Program(cel.ParsedExprToAst(&exprpb.ParsedExpr{Expr: expr}))
+	if err != nil {
+		return nil, false
+	}
+	val, _, err := prg.Eval(pe.vars)
+	if err != nil || types.IsUnknownOrError(val) {
+		return nil, false
+	}
+	return val, true
+}
+
+// literal returns val as a literal expression with the given ID, recording its type. Only
+// primitive values, null and lists of them can be represented.
+func (pe *partialEvaluator) literal(val ref.Val, id int64) (*exprpb.Expr, bool) {
+	var constant *exprpb.Constant
+	var typ *exprpb.Type
+	switch v := val.(type) {
+	case types.Bool:
+		constant = &exprpb.Constant{ConstantKind: &exprpb.Constant_BoolValue{BoolValue: bool(v)}}
+		typ = primitiveType(exprpb.Type_BOOL)
+	case types.Bytes:
+		constant = &exprpb.Constant{ConstantKind: &exprpb.Constant_BytesValue{BytesValue: []byte(v)}}
+		typ = primitiveType(exprpb.Type_BYTES)
+	case types.Double:
+		constant = &exprpb.Constant{ConstantKind: &exprpb.Constant_DoubleValue{DoubleValue: float64(v)}}
+		typ = primitiveType(exprpb.Type_DOUBLE)
+	case types.Int:
+		constant = &exprpb.Constant{ConstantKind: &exprpb.Constant_Int64Value{Int64Value: int64(v)}}
+		typ = primitiveType(exprpb.Type_INT64)
+	case types.String:
+		constant = &exprpb.Constant{ConstantKind: &exprpb.Constant_StringValue{StringValue: string(v)}}
+		typ = primitiveType(exprpb.Type_STRING)
+	case types.Uint:
+		constant = &exprpb.Constant{ConstantKind: &exprpb.Constant_Uint64Value{Uint64Value: uint64(v)}}
+		typ = primitiveType(exprpb.Type_UINT64)
+	case types.Null:
+		constant = &exprpb.Constant{ConstantKind: &exprpb.Constant_NullValue{}}
+		typ = &exprpb.Type{TypeKind: &exprpb.Type_Null{Null: structpb.NullValue_NULL_VALUE}}
+	case traits.Lister:
+		var elems []*exprpb.Expr
+		var elemType *exprpb.Type
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			pe.maxID++
+			elem, ok := pe.literal(it.Next(), pe.maxID)
+			if !ok {
+				return nil, false
+			}
+			switch elemTyp := pe.typeMap[elem.GetId()]; {
+			case elemType == nil:
+				elemType = elemTyp
+			case !proto.Equal(elemType, elemTyp):
+				elemType = &exprpb.Type{TypeKind: &exprpb.Type_Dyn{Dyn: &emptypb.Empty{}}}
+			}
+			elems = append(elems, elem)
+		}
+		if elemType == nil {
+			elemType = &exprpb.Type{TypeKind: &exprpb.Type_Dyn{Dyn: &emptypb.Empty{}}}
+		}
+		pe.typeMap[id] = &exprpb.Type{TypeKind: &exprpb.Type_ListType_{ListType: &exprpb.Type_ListType{ElemType: elemType}}}
+		return &exprpb.Expr{Id: id, ExprKind: &exprpb.Expr_ListExpr{ListExpr: &exprpb.Expr_CreateList{Elements: elems}}}, true
+	default:
+		return nil, false
+	}
+	pe.typeMap[id] = typ
+	return &exprpb.Expr{Id: id, ExprKind: &exprpb.Expr_ConstExpr{ConstExpr: constant}}, true
+}
+
+// boolConstant returns the value of a boolean literal
+func boolConstant(expr *exprpb.Expr) (bool, bool) {
+	if !isBoolLiteral(expr) {
+		return false, false
+	}
+	return expr.GetConstExpr().GetBoolValue(), true
+}
+
+func primitiveType(primitive exprpb.Type_PrimitiveType) *exprpb.Type {
+	return &exprpb.Type{TypeKind: &exprpb.Type_Primitive{Primitive: primitive}}
+}
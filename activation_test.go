@@ -0,0 +1,114 @@
+package cel2sql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertWithActivation(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("org_id", cel.IntType),
+		cel.Variable("owner", cel.StringType),
+		cel.Variable("tags", cel.ListType(cel.StringType)),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("now", cel.TimestampType),
+	)
+	require.NoError(t, err)
+
+	request := map[string]any{
+		"org_id":   42,
+		"user":     "alice",
+		"is_admin": false,
+		"roles":    []string{"editor", "viewer"},
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		vars    map[string]any
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "inline_field",
+			source: `org_id == request.org_id`,
+			vars:   map[string]any{"request": request},
+			want:   "org_id = 42",
+		},
+		{
+			name:   "fold_false_branch",
+			source: `request.is_admin || (org_id == request.org_id && owner == request.user)`,
+			vars:   map[string]any{"request": request},
+			want:   "org_id = 42 AND owner = 'alice'",
+		},
+		{
+			name:   "fold_true_branch",
+			source: `!request.is_admin && org_id == 1 || request.user == "alice"`,
+			vars:   map[string]any{"request": request},
+			want:   "TRUE",
+		},
+		{
+			name:   "conditional",
+			source: `request.is_admin ? true : owner == request.user`,
+			vars:   map[string]any{"request": request},
+			want:   "owner = 'alice'",
+		},
+		{
+			name:   "evaluated_subexpression",
+			source: `org_id > request.org_id * 2 + 1`,
+			vars:   map[string]any{"request": request},
+			want:   "org_id > 85",
+		},
+		{
+			name:   "list_value",
+			source: `owner in request.roles`,
+			vars:   map[string]any{"request": request},
+			want:   "owner = ANY(ARRAY['editor', 'viewer'])",
+		},
+		{
+			name:   "inside_comprehension",
+			source: `tags.exists(t, t == request.user || request.is_admin)`,
+			vars:   map[string]any{"request": request},
+			want:   "EXISTS (SELECT 1 FROM UNNEST(tags) AS t WHERE t = 'alice')",
+		},
+		{
+			name:   "unbound_untouched",
+			source: `org_id == 1 && owner == "bob"`,
+			vars:   map[string]any{"request": request},
+			want:   "org_id = 1 AND owner = 'bob'",
+		},
+		{
+			name:   "timestamp_comparison",
+			source: `now > timestamp("2024-01-01T00:00:00Z") && org_id == 1`,
+			vars:   map[string]any{"now": time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+			want:   "org_id = 1",
+		},
+		{
+			name:    "unrepresentable_variable",
+			source:  `org_id == 1 && now > now`,
+			vars:    map[string]any{"now": struct{}{}},
+			wantErr: "cannot inline variable now: unsupported value of type struct {}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.ConvertWithActivation(ast, tt.vars)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package cel2sql
+
+import (
+	"fmt"
+	"strconv"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// Array functions declared by sqltypes.ArrayFunctionDeclarations, and slice of the lists
+// extension of cel-go
+const (
+	containsAllFunction = "containsAll"
+	containsAnyFunction = "containsAny"
+	indexOfFunction     = "indexOf"
+	sliceFunction       = "slice"
+)
+
+// arrayOperators are the PostgreSQL operators of the array functions, for arrays and for JSONB
+// arrays of strings
+var arrayOperators = map[string]struct{ array, json string }{
+	containsAllFunction: {array: "@>", json: "?&"},
+	containsAnyFunction: {array: "&&", json: "?|"},
+}
+
+// arrayFunctionArgs returns the operands of containsAll and containsAny, called either as
+// containsAll(arr, elems) or arr.containsAll(elems)
+func arrayFunctionArgs(target *exprpb.Expr, args []*exprpb.Expr) (arr, elems *exprpb.Expr, err error) {
+	if target != nil {
+		args = append([]*exprpb.Expr{target}, args...)
+	}
+	if len(args) != 2 {
+		return nil, nil, fmt.Errorf("array function expects 2 arguments, got %d", len(args))
+	}
+	return args[0], args[1], nil
+}
+
+// checkArrayFunction checks that the elements tested against a JSON array are strings, which
+// are the only values the ?& and ?| operators look for
+func (con *converter) checkArrayFunction(fun string, arr, elems *exprpb.Expr) error {
+	if !con.isJSONArray(arr) {
+		return nil
+	}
+	if elemType := con.getType(elems).GetListType().GetElemType(); elemType.GetPrimitive() != exprpb.Type_STRING {
+		return fmt.Errorf("%w: %s() on a JSON array requires a list of strings", ErrUnsupportedFunction, fun)
+	}
+	return nil
+}
+
+// isJSONArray checks if expr is an array stored in a JSON column rather than a PostgreSQL array
+func (con *converter) isJSONArray(expr *exprpb.Expr) bool {
+	return isFieldAccessExpression(expr) && (con.isJSONArrayField(expr) || con.isJSONArrayColumn(expr))
+}
+
+// callArrayFunction renders containsAll as arr @> elems and containsAny as arr && elems, or with
+// the ?& and ?| operators when arr is a JSON array, which PostgreSQL can all answer from a GIN
+// index instead of unnesting the array
+func (con *converter) callArrayFunction(fun string, target *exprpb.Expr, args []*exprpb.Expr) error {
+	arr, elems, err := arrayFunctionArgs(target, args)
+	if err != nil {
+		return err
+	}
+	if err := con.checkArrayFunction(fun, arr, elems); err != nil {
+		return err
+	}
+	op := arrayOperators[fun]
+	if con.isJSONArray(arr) {
+		if err := con.visitJSONArray(arr); err != nil {
+			return err
+		}
+		if !con.isJSONBField(arr) {
+			// The ?& and ?| operators are only defined for jsonb
+			con.str.WriteString("::jsonb")
+		}
+		con.str.WriteString(" ")
+		con.str.WriteString(op.json)
+		con.str.WriteString(" ")
+		return con.visitMaybeNested(elems, isBinaryOrTernaryOperator(elems))
+	}
+	if err := con.visitMaybeNested(arr, isBinaryOrTernaryOperator(arr)); err != nil {
+		return err
+	}
+	con.str.WriteString(" ")
+	con.str.WriteString(op.array)
+	con.str.WriteString(" ")
+	return con.visitMaybeNested(elems, isBinaryOrTernaryOperator(elems))
+}
+
+// checkSlice checks that a list sliced with slice() is a PostgreSQL array, since JSON arrays have
+// no slice syntax
+func (con *converter) checkSlice(target *exprpb.Expr, args []*exprpb.Expr) error {
+	if target == nil || len(args) != 2 {
+		return fmt.Errorf("slice function expects a list and 2 arguments, got %d", len(args))
+	}
+	if con.isJSONArray(target) {
+		return fmt.Errorf("%w: slice() on a JSON array", ErrUnsupportedFunction)
+	}
+	return nil
+}
+
+// callSlice renders list.slice(start, end) as list[start + 1:end], since PostgreSQL arrays are
+// 1-indexed and their slices include the upper bound
+func (con *converter) callSlice(target *exprpb.Expr, args []*exprpb.Expr) error {
+	if err := con.checkSlice(target, args); err != nil {
+		return err
+	}
+	// Subscripts apply to columns, or to other expressions in parentheses
+	nested := !isFieldAccessExpression(target) && target.GetIdentExpr() == nil
+	if err := con.visitMaybeNested(target, nested); err != nil {
+		return err
+	}
+	con.str.WriteString("[")
+	start, end := args[0], args[1]
+	if constExpr := start.GetConstExpr(); constExpr != nil {
+		con.str.WriteString(strconv.FormatInt(constExpr.GetInt64Value()+1, 10))
+	} else {
+		if err := con.visit(start); err != nil {
+			return err
+		}
+		con.str.WriteString(" + 1")
+	}
+	con.str.WriteString(":")
+	if constExpr := end.GetConstExpr(); constExpr != nil {
+		con.str.WriteString(strconv.FormatInt(constExpr.GetInt64Value(), 10))
+	} else if err := con.visit(end); err != nil {
+		return err
+	}
+	con.str.WriteString("]")
+	return nil
+}
+
+// isListIndexOf checks if indexOf() is called on a list rather than on a string, as declared by
+// the strings extension of cel-go
+func (con *converter) isListIndexOf(target *exprpb.Expr) bool {
+	return target != nil && (isListType(con.getType(target)) || con.isJSONArray(target))
+}
+
+// checkIndexOf checks that a list searched with indexOf() is a PostgreSQL array
+func (con *converter) checkIndexOf(target *exprpb.Expr, args []*exprpb.Expr) error {
+	if target == nil || len(args) != 1 {
+		return fmt.Errorf("indexOf function expects a list and 1 argument, got %d", len(args))
+	}
+	if con.isJSONArray(target) {
+		return fmt.Errorf("%w: indexOf() on a JSON array", ErrUnsupportedFunction)
+	}
+	return nil
+}
+
+// callIndexOf renders list.indexOf(x) as COALESCE(array_position(list, x) - 1, -1), since
+// array_position counts from 1 and is NULL when x is missing, where CEL returns -1
+func (con *converter) callIndexOf(target *exprpb.Expr, args []*exprpb.Expr) error {
+	if err := con.checkIndexOf(target, args); err != nil {
+		return err
+	}
+	con.str.WriteString("COALESCE(array_position(")
+	if err := con.visit(target); err != nil {
+		return err
+	}
+	con.str.WriteString(", ")
+	if err := con.visit(args[0]); err != nil {
+		return err
+	}
+	con.str.WriteString(") - 1, -1)")
+	return nil
+}
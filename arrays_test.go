@@ -0,0 +1,482 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertArrayFunctions(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"posts": {
+			{Name: "tags", Type: "text", Repeated: true},
+			{Name: "scores", Type: "integer", Repeated: true},
+			{Name: "labels", Type: "jsonb"},
+			{Name: "topics", Type: "json"},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas), cel2sql.RowVariable("posts"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "contains_all",
+			source: `containsAll(posts.tags, ["go", "sql"])`,
+			want:   "posts.tags @> ARRAY['go', 'sql']",
+		},
+		{
+			name:   "contains_any",
+			source: `containsAny(posts.tags, ["go", "sql"])`,
+			want:   "posts.tags && ARRAY['go', 'sql']",
+		},
+		{
+			name:   "member_call",
+			source: `posts.scores.containsAny([1, 2]) && !row.tags.containsAll(["draft"])`,
+			want:   "posts.scores && ARRAY[1, 2] AND NOT row.tags @> ARRAY['draft']",
+		},
+		{
+			name:   "concatenated_array",
+			source: `containsAll(posts.tags + ["go"], posts.tags)`,
+			want:   "(posts.tags || ARRAY['go']) @> posts.tags",
+		},
+		{
+			name:   "jsonb_array",
+			source: `posts.labels.containsAll(["go", "sql"])`,
+			want:   "posts.labels ?& ARRAY['go', 'sql']",
+		},
+		{
+			name:   "json_array",
+			source: `containsAny(posts.topics, ["go"])`,
+			want:   "posts.topics::jsonb ?| ARRAY['go']",
+		},
+		{
+			name:    "json_array_of_numbers",
+			source:  `posts.labels.containsAny([1, 2])`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			if tt.wantErr {
+				require.ErrorIs(t, err, cel2sql.ErrUnsupportedFunction)
+				assert.ErrorIs(t, cel2sql.Validate(ast, cel2sql.WithSchemas(schemas)), cel2sql.ErrUnsupportedFunction)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, cel2sql.Validate(ast, cel2sql.WithSchemas(schemas)))
+		})
+	}
+}
+
+func TestConvertSlice(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"readings": {
+			{Name: "values", Type: "integer", Repeated: true},
+			{Name: "samples", Type: "jsonb"},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas),
+		ext.Lists(),
+		cel.Variable("n", cel.IntType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "constant_bounds",
+			source: `readings.values.slice(1, 3) == [2, 3]`,
+			want:   "readings.values[2:3] = ARRAY[2, 3]",
+		},
+		{
+			name:   "variable_bounds",
+			source: `size(readings.values.slice(n, n + 2)) == 2`,
+			want:   "ARRAY_LENGTH(readings.values[n + 1:n + 2], 1) = 2",
+		},
+		{
+			name:   "windowed_check",
+			source: `readings.values.slice(0, 3).all(v, v > 10)`,
+			want:   "NOT EXISTS (SELECT 1 FROM UNNEST(readings.values[1:3]) AS v WHERE NOT (v > 10))",
+		},
+		{
+			name:   "list_literal",
+			source: `[1, 2, 3].slice(1, 2) == [n]`,
+			want:   "(ARRAY[1, 2, 3])[2:2] = ARRAY[n]",
+		},
+		{
+			name:    "json_array",
+			source:  `readings.samples.slice(0, 1) == [1]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			if tt.wantErr {
+				require.ErrorIs(t, err, cel2sql.ErrUnsupportedFunction)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, cel2sql.Validate(ast, cel2sql.WithSchemas(schemas)))
+		})
+	}
+}
+
+func TestConvertIndexOf(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"queues": {
+			{Name: "name", Type: "text"},
+			{Name: "workers", Type: "text", Repeated: true},
+			{Name: "history", Type: "jsonb"},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas),
+		ext.Strings(),
+		cel.Variable("worker", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "found",
+			source: `queues.workers.indexOf(worker) == 0`,
+			want:   "COALESCE(array_position(queues.workers, worker) - 1, -1) = 0",
+		},
+		{
+			name:   "missing",
+			source: `queues.workers.indexOf("w1") < 0`,
+			want:   "COALESCE(array_position(queues.workers, 'w1') - 1, -1) < 0",
+		},
+		{
+			name:   "list_literal",
+			source: `["a", "b"].indexOf(queues.name) >= 0`,
+			want:   "COALESCE(array_position(ARRAY['a', 'b'], queues.name) - 1, -1) >= 0",
+		},
+		{
+			name:    "json_array",
+			source:  `queues.history.indexOf("w1") == 0`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			if tt.wantErr {
+				require.ErrorIs(t, err, cel2sql.ErrUnsupportedFunction)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, cel2sql.Validate(ast, cel2sql.WithSchemas(schemas)))
+		})
+	}
+}
+
+func TestConvertFilterSize(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"teams": {
+			{Name: "scores", Type: "integer", Repeated: true},
+			{Name: "members", Type: "jsonb"},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas),
+		cel.Variable("employees", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "member_size",
+			source: `employees.filter(e, e.active).size() > 3`,
+			want:   "(SELECT COUNT(*) FROM UNNEST(employees) AS e WHERE e.active) > 3",
+		},
+		{
+			name:   "global_size",
+			source: `size(teams.scores.filter(s, s >= 90)) == 0`,
+			want:   "(SELECT COUNT(*) FROM UNNEST(teams.scores) AS s WHERE s >= 90) = 0",
+		},
+		{
+			name:   "size_of_filtered_map",
+			source: `teams.scores.map(s, s >= 90, s * 2).size() > 1`,
+			want:   "(SELECT COUNT(*) FROM UNNEST(teams.scores) AS s WHERE s >= 90) > 1",
+		},
+		{
+			name:   "size_of_map",
+			source: `teams.scores.map(s, s * 2).size() > 1`,
+			want:   "ARRAY_LENGTH(ARRAY(SELECT s * 2 FROM UNNEST(teams.scores) AS s), 1) > 1",
+		},
+		{
+			name:   "filtered_array",
+			source: `teams.scores.filter(s, s >= 90) == [100]`,
+			want:   "ARRAY(SELECT s FROM UNNEST(teams.scores) AS s WHERE s >= 90) = ARRAY[100]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	ast, issues := env.Compile(`employees.filter(e, e.active).size() > 3`)
+	require.NoError(t, issues.Err())
+	_, err = cel2sql.Convert(ast, cel2sql.WithLimits(cel2sql.Limits{MaxSubqueries: 1}))
+	require.NoError(t, err)
+
+	ast, issues = env.Compile(`employees.exists(x, employees.filter(e, e.active).size() > 3)`)
+	require.NoError(t, issues.Err())
+	_, err = cel2sql.Convert(ast, cel2sql.WithLimits(cel2sql.Limits{MaxSubqueries: 1}))
+	var limitErr *cel2sql.LimitError
+	require.ErrorAs(t, err, &limitErr)
+}
+
+func TestConvertComprehensionChains(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"teams": {
+			{Name: "scores", Type: "integer", Repeated: true},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas),
+		cel.Variable("employees", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "filter_map",
+			source: `employees.filter(e, e.active).map(e, e.email)`,
+			want:   "ARRAY(SELECT e.email FROM UNNEST(employees) AS e WHERE e.active)",
+		},
+		{
+			name:   "filter_filter",
+			source: `teams.scores.filter(s, s > 1).filter(s, s < 5 || s == 9) == [2]`,
+			want:   "ARRAY(SELECT s FROM UNNEST(teams.scores) AS s WHERE s > 1 AND (s < 5 OR s = 9)) = ARRAY[2]",
+		},
+		{
+			name:   "filter_filtered_map",
+			source: `teams.scores.filter(s, s > 1).filter(s, s < 5).map(s, s != 3, s * 2) == [4]`,
+			want:   "ARRAY(SELECT s * 2 FROM UNNEST(teams.scores) AS s WHERE s > 1 AND s < 5 AND s != 3) = ARRAY[4]",
+		},
+		{
+			name:   "filter_size",
+			source: `teams.scores.filter(s, s > 1).filter(s, s < 5).size() == 2`,
+			want:   "(SELECT COUNT(*) FROM UNNEST(teams.scores) AS s WHERE s > 1 AND s < 5) = 2",
+		},
+		{
+			name:   "other_variable",
+			source: `employees.filter(e, e.active).map(x, x.email)`,
+			want:   "ARRAY(SELECT x.email FROM UNNEST(ARRAY(SELECT e FROM UNNEST(employees) AS e WHERE e.active)) AS x)",
+		},
+		{
+			name:   "map_map",
+			source: `teams.scores.map(s, s * 2).map(s, s + 1) == [3]`,
+			want:   "ARRAY(SELECT s + 1 FROM UNNEST(ARRAY(SELECT s * 2 FROM UNNEST(teams.scores) AS s)) AS s) = ARRAY[3]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertCompositeArrayComprehensions(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"documents": {
+			{Name: "title", Type: "text"},
+			{Name: "sections", Type: "composite", Repeated: true, Schema: []pg.FieldSchema{
+				{Name: "heading", Type: "text"},
+				{Name: "page_count", Type: "bigint"},
+				{Name: "author", Type: "composite", Schema: []pg.FieldSchema{
+					{Name: "name", Type: "text"},
+				}},
+				{Name: "notes", Type: "composite", Repeated: true, Schema: []pg.FieldSchema{
+					{Name: "text", Type: "text"},
+				}},
+			}},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("doc", cel.ObjectType("documents")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "exists",
+			source: `doc.sections.exists(s, s.page_count > 5)`,
+			want:   "EXISTS (SELECT 1 FROM UNNEST(doc.sections) AS s WHERE (s).page_count > 5)",
+		},
+		{
+			name:   "nested_composite",
+			source: `doc.sections.all(s, s.author.name != "")`,
+			want:   "NOT EXISTS (SELECT 1 FROM UNNEST(doc.sections) AS s WHERE NOT ((s).author.name != ''))",
+		},
+		{
+			name:   "nested_array",
+			source: `doc.sections.exists(s, s.notes.exists(n, n.text.contains("todo")))`,
+			want:   "EXISTS (SELECT 1 FROM UNNEST(doc.sections) AS s WHERE EXISTS (SELECT 1 FROM UNNEST((s).notes) AS n WHERE POSITION('todo' IN (n).text) > 0))",
+		},
+		{
+			name:   "map",
+			source: `doc.sections.map(s, s.heading)`,
+			want:   "ARRAY(SELECT (s).heading FROM UNNEST(doc.sections) AS s)",
+		},
+		{
+			name:   "element",
+			source: `doc.sections.filter(s, s.page_count > 1).size() > 0 && doc.title != ""`,
+			want:   "(SELECT COUNT(*) FROM UNNEST(doc.sections) AS s WHERE (s).page_count > 1) > 0 AND doc.title != ''",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertIndexedComprehensions(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"documents": {
+			{Name: "labels", Type: "text", Repeated: true},
+			{Name: "tags", Type: "jsonb"},
+			{Name: "sections", Type: "composite", Repeated: true, Schema: []pg.FieldSchema{
+				{Name: "heading", Type: "text"},
+			}},
+		},
+	}
+	env, err := cel.NewEnv(
+		ext.TwoVarComprehensions(),
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("doc", cel.ObjectType("documents")),
+		cel.Variable("steps", cel.ListType(cel.StringType)),
+		cel.Variable("limits", cel.MapType(cel.StringType, cel.IntType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "exists",
+			source: `doc.labels.exists(i, l, i == 0 && l == "draft")`,
+			want:   "EXISTS (SELECT 1 FROM UNNEST(doc.labels) WITH ORDINALITY AS l(l, i) WHERE (i - 1) = 0 AND l = 'draft')",
+		},
+		{
+			name:   "all",
+			source: `steps.all(i, s, i < 3 || s != "")`,
+			want:   "NOT EXISTS (SELECT 1 FROM UNNEST(steps) WITH ORDINALITY AS s(s, i) WHERE NOT ((i - 1) < 3 OR s != ''))",
+		},
+		{
+			name:   "exists_one",
+			source: `steps.existsOne(i, s, i > 0 && s == "review")`,
+			want:   "(SELECT COUNT(*) FROM UNNEST(steps) WITH ORDINALITY AS s(s, i) WHERE (i - 1) > 0 AND s = 'review') = 1",
+		},
+		{
+			name:   "transform_list",
+			source: `steps.transformList(i, s, i % 2 == 0, s)`,
+			want:   "ARRAY(SELECT s FROM UNNEST(steps) WITH ORDINALITY AS s(s, i) WHERE MOD((i - 1), 2) = 0)",
+		},
+		{
+			name:   "filtered_list_is_renumbered",
+			source: `steps.filter(s, s != "").transformList(i, s, i)`,
+			want:   "ARRAY(SELECT (i - 1) FROM UNNEST(ARRAY(SELECT s FROM UNNEST(steps) AS s WHERE s != '')) WITH ORDINALITY AS s(s, i))",
+		},
+		{
+			name:   "json_array",
+			source: `doc.tags.exists(i, t, i == 0 && t == "urgent")`,
+			want:   "EXISTS (SELECT 1 FROM jsonb_array_elements_text(doc.tags) WITH ORDINALITY AS t(t, i) WHERE doc.tags IS NOT NULL AND jsonb_typeof(doc.tags) = 'array' AND (i - 1) = 0 AND t = 'urgent')",
+		},
+		{
+			name:   "composite_array",
+			source: `doc.sections.exists(i, s, i == 0 && s.heading == "Summary")`,
+			want:   "EXISTS (SELECT 1 FROM UNNEST(doc.sections) WITH ORDINALITY AS s WHERE (s.ordinality - 1) = 0 AND (s).heading = 'Summary')",
+		},
+		{
+			name:   "index_shadowed",
+			source: `steps.exists(i, s, steps.exists(i, i == s))`,
+			want:   "EXISTS (SELECT 1 FROM UNNEST(steps) WITH ORDINALITY AS s(s, i) WHERE EXISTS (SELECT 1 FROM UNNEST(steps) AS i WHERE i = s))",
+		},
+		{
+			name:    "map",
+			source:  `limits.all(k, v, v > 0)`,
+			wantErr: cel2sql.ErrUnsupportedComprehension,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Package bigquery provides a CEL type provider for BigQuery tables, built from table schemas in
+// the JSON format of the BigQuery API, as printed by "bq show --schema" and produced by the Go
+// client with bigquery.Schema.ToJSONFields:
+//
+//	metadata, err := client.Dataset("analytics").Table("events").Metadata(ctx)
+//	schemaJSON, err := metadata.Schema.ToJSONFields()
+//	err = provider.LoadTableSchema("analytics.events", schemaJSON)
+//
+// RECORD fields are nested object types and REPEATED fields lists, as composite and array
+// columns are with the pg package.
+package bigquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2/sqltypes"
+)
+
+// FieldSchema represents a BigQuery field with its name, type and mode.
+type FieldSchema struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`           // STRING, INTEGER, RECORD, etc.
+	Mode        string        `json:"mode,omitempty"` // NULLABLE, REQUIRED or REPEATED
+	Description string        `json:"description,omitempty"`
+	Fields      []FieldSchema `json:"fields,omitempty"` // for RECORD fields
+}
+
+// Schema represents a BigQuery table schema as a slice of field schemas.
+type Schema []FieldSchema
+
+// ParseSchema parses a table schema in the JSON format of the BigQuery API, either an array of
+// fields or an object with a "fields" array
+func ParseSchema(schemaJSON []byte) (Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		var table struct {
+			Fields Schema `json:"fields"`
+		}
+		if err := json.Unmarshal(schemaJSON, &table); err != nil {
+			return nil, fmt.Errorf("failed to decode schema: %w", err)
+		}
+		schema = table.Fields
+	}
+	if err := validateSchema(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// validateSchema checks that fields are named and typed
+func validateSchema(schema Schema) error {
+	for _, field := range schema {
+		if field.Name == "" {
+			return errors.New("field without a name")
+		}
+		if field.Type == "" {
+			return fmt.Errorf("field %s has no type", field.Name)
+		}
+		if err := validateSchema(field.Fields); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// TypeProvider interface for BigQuery type providers. A TypeProvider is safe for concurrent use.
+type TypeProvider interface {
+	types.Provider
+	LoadTableSchema(tableName string, schemaJSON []byte) error
+	Tables() []string
+}
+
+type typeProvider struct {
+	mu      sync.RWMutex // guards schemas
+	schemas map[string]Schema
+}
+
+// NewTypeProvider creates a new BigQuery type provider with pre-defined schemas. The map is
+// copied, so the caller may keep modifying it without affecting the provider.
+func NewTypeProvider(schemas map[string]Schema) TypeProvider {
+	p := &typeProvider{schemas: make(map[string]Schema, len(schemas))}
+	for tableName, schema := range schemas {
+		p.schemas[tableName] = schema
+	}
+	return p
+}
+
+// Tables returns the names of the known tables, sorted
+func (p *typeProvider) Tables() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Sorted(maps.Keys(p.schemas))
+}
+
+// LoadTableSchema registers the schema of a table, in the JSON format of the BigQuery API, under
+// the given name, usually qualified with its dataset, e.g. "analytics.events"
+func (p *typeProvider) LoadTableSchema(tableName string, schemaJSON []byte) error {
+	schema, err := ParseSchema(schemaJSON)
+	if err != nil {
+		return fmt.Errorf("table %s: %w", tableName, err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.schemas[tableName] = schema
+	return nil
+}
+
+// findSchema finds the schema of a table or RECORD field. Since table names are usually
+// qualified with their dataset, the longest known table name prefixing the type name is used,
+// and the rest of it is the path of RECORD fields below the table.
+func (p *typeProvider) findSchema(typeName string) (Schema, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	typeNames := strings.Split(typeName, ".")
+	for i := len(typeNames); i > 0; i-- {
+		schema, found := p.schemas[strings.Join(typeNames[:i], ".")]
+		if !found {
+			continue
+		}
+		for _, name := range typeNames[i:] {
+			field, found := findField(schema, name)
+			if !found || len(field.Fields) == 0 {
+				return nil, false
+			}
+			schema = field.Fields
+		}
+		return schema, len(schema) > 0
+	}
+	return nil, false
+}
+
+// findField finds a field of a schema by name. BigQuery field names are case insensitive.
+func findField(schema Schema, name string) (FieldSchema, bool) {
+	for _, field := range schema {
+		if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return FieldSchema{}, false
+}
+
+func (p *typeProvider) EnumValue(enumName string) ref.Val {
+	return types.NewErr("unknown enum name '%s'", enumName)
+}
+
+func (p *typeProvider) FindIdent(_ string) (ref.Val, bool) {
+	return nil, false
+}
+
+func (p *typeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, found := p.findSchema(structType); !found {
+		return nil, false
+	}
+	return types.NewObjectType(structType), true
+}
+
+func (p *typeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	schema, found := p.findSchema(structType)
+	if !found {
+		return nil, false
+	}
+	fieldNames := make([]string, len(schema))
+	for i, field := range schema {
+		fieldNames[i] = field.Name
+	}
+	return fieldNames, true
+}
+
+func (p *typeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	schema, found := p.findSchema(structType)
+	if !found {
+		return nil, false
+	}
+	field, found := findField(schema, fieldName)
+	if !found {
+		return nil, false
+	}
+
+	exprType := fieldType(field.Type)
+	if exprType == nil {
+		exprType = decls.NewObjectType(structType + "." + fieldName)
+	}
+	if strings.EqualFold(field.Mode, "REPEATED") {
+		exprType = decls.NewListType(exprType)
+	}
+	celType, err := types.ExprTypeToType(exprType)
+	if err != nil {
+		return nil, false
+	}
+	return &types.FieldType{Type: celType}, true
+}
+
+func (p *typeProvider) NewValue(structType string, _ map[string]ref.Val) ref.Val {
+	return types.NewErr("unknown type '%s'", structType)
+}
+
+var _ types.Provider = new(typeProvider)
+
+// fieldType maps a BigQuery field type, in its legacy or standard SQL name, to a CEL type, or
+// returns nil for RECORD fields
+func fieldType(fieldType string) *exprpb.Type {
+	switch strings.ToUpper(fieldType) {
+	case "RECORD", "STRUCT":
+		return nil
+	case "INTEGER", "INT64":
+		return decls.Int
+	case "FLOAT", "FLOAT64", "NUMERIC", "BIGNUMERIC", "DECIMAL", "BIGDECIMAL":
+		return decls.Double
+	case "BOOLEAN", "BOOL":
+		return decls.Bool
+	case "BYTES":
+		return decls.Bytes
+	case "TIMESTAMP", "DATETIME":
+		return decls.Timestamp
+	case "DATE":
+		return sqltypes.Date
+	case "TIME":
+		return sqltypes.Time
+	case "INTERVAL":
+		return decls.Duration
+	case "JSON":
+		// JSON fields are dynamic objects in CEL
+		return decls.Dyn
+	default:
+		// STRING, GEOGRAPHY and RANGE values are strings
+		return decls.String
+	}
+}
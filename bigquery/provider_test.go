@@ -0,0 +1,105 @@
+package bigquery_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/bigquery"
+)
+
+const eventsSchema = `[
+	{"name": "id", "type": "INTEGER", "mode": "REQUIRED"},
+	{"name": "name", "type": "STRING"},
+	{"name": "score", "type": "FLOAT64"},
+	{"name": "active", "type": "BOOLEAN"},
+	{"name": "payload", "type": "JSON"},
+	{"name": "created_at", "type": "TIMESTAMP"},
+	{"name": "day", "type": "DATE"},
+	{"name": "tags", "type": "STRING", "mode": "REPEATED"},
+	{"name": "location", "type": "RECORD", "fields": [
+		{"name": "city", "type": "STRING"},
+		{"name": "geo", "type": "RECORD", "fields": [{"name": "lat", "type": "FLOAT"}]}
+	]},
+	{"name": "items", "type": "RECORD", "mode": "REPEATED", "fields": [
+		{"name": "sku", "type": "STRING"},
+		{"name": "quantity", "type": "INT64"}
+	]}
+]`
+
+func TestTypeProvider_LoadTableSchema(t *testing.T) {
+	provider := bigquery.NewTypeProvider(nil)
+	require.NoError(t, provider.LoadTableSchema("analytics.events", []byte(eventsSchema)))
+	require.NoError(t, provider.LoadTableSchema("users", []byte(`{"fields": [{"name": "email", "type": "STRING"}]}`)))
+
+	tests := []struct {
+		structType string
+		fieldName  string
+		wantType   *types.Type
+	}{
+		{structType: "analytics.events", fieldName: "id", wantType: types.IntType},
+		{structType: "analytics.events", fieldName: "name", wantType: types.StringType},
+		{structType: "analytics.events", fieldName: "score", wantType: types.DoubleType},
+		{structType: "analytics.events", fieldName: "active", wantType: types.BoolType},
+		{structType: "analytics.events", fieldName: "payload", wantType: types.DynType},
+		{structType: "analytics.events", fieldName: "created_at", wantType: types.TimestampType},
+		{structType: "analytics.events", fieldName: "day", wantType: types.NewOpaqueType("DATE")},
+		{structType: "analytics.events", fieldName: "tags", wantType: types.NewListType(types.StringType)},
+		{structType: "analytics.events", fieldName: "location", wantType: types.NewObjectType("analytics.events.location")},
+		{structType: "analytics.events.location", fieldName: "geo", wantType: types.NewObjectType("analytics.events.location.geo")},
+		{structType: "analytics.events.location.geo", fieldName: "lat", wantType: types.DoubleType},
+		{structType: "analytics.events", fieldName: "items", wantType: types.NewListType(types.NewObjectType("analytics.events.items"))},
+		{structType: "analytics.events.items", fieldName: "quantity", wantType: types.IntType},
+		{structType: "users", fieldName: "email", wantType: types.StringType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.structType+"."+tt.fieldName, func(t *testing.T) {
+			got, found := provider.FindStructFieldType(tt.structType, tt.fieldName)
+			require.True(t, found)
+			assert.Equal(t, tt.wantType.String(), got.Type.String())
+		})
+	}
+
+	_, found := provider.FindStructType("analytics.events.name")
+	assert.False(t, found)
+	_, found = provider.FindStructFieldType("analytics.events", "missing")
+	assert.False(t, found)
+}
+
+func TestParseSchema_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		schemaJSON string
+		wantErr    string
+	}{
+		{name: "invalid json", schemaJSON: `[{`, wantErr: "failed to decode schema"},
+		{name: "unnamed field", schemaJSON: `[{"type": "STRING"}]`, wantErr: "field without a name"},
+		{name: "untyped nested field", schemaJSON: `[{"name": "a", "type": "RECORD", "fields": [{"name": "b"}]}]`, wantErr: "field a: field b has no type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bigquery.ParseSchema([]byte(tt.schemaJSON))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestTypeProvider_Convert(t *testing.T) {
+	provider := bigquery.NewTypeProvider(nil)
+	require.NoError(t, provider.LoadTableSchema("events", []byte(eventsSchema)))
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("event", cel.ObjectType("events")),
+	)
+	require.NoError(t, err)
+
+	ast, issues := env.Compile(`event.location.city == "Paris" && event.items.exists(i, i.quantity > 2)`)
+	require.NoError(t, issues.Err())
+	got, err := cel2sql.Convert(ast)
+	require.NoError(t, err)
+	assert.Equal(t, "event.location.city = 'Paris' AND EXISTS (SELECT 1 FROM UNNEST(event.items) AS i WHERE (i).quantity > 2)", got)
+}
@@ -0,0 +1,146 @@
+package cel2sql
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultCacheSize is the number of conversions held by a Cache created with a size that is not
+// positive.
+const DefaultCacheSize = 1024
+
+// Cache memoizes the SQL of expressions converted with a fixed set of options, for services
+// converting the same saved filters over and over. Expressions are keyed by a hash of their
+// checked AST, so that the same filter compiled again hits the cache, and the least recently
+// used conversions are evicted once the cache is full. Failed conversions are not cached.
+//
+// The options are fixed when the cache is created, since options such as field name mappers
+// cannot be compared, and are not part of the key: every set of options needs its own cache,
+// and a new cache is needed when the schemas passed with WithSchemas change. A Cache is safe
+// for concurrent use.
+type Cache struct {
+	opts    []ConvertOption
+	size    int
+	metrics MetricsSink // of the options, reporting hits as conversions
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List // of *cacheEntry, most recently used first
+	hits    uint64
+	misses  uint64
+}
+
+// cacheEntry is a cached conversion
+type cacheEntry struct {
+	key  [sha256.Size]byte
+	plan *Plan
+}
+
+// CacheStats counts the lookups of a Cache.
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int // number of cached conversions
+}
+
+// NewCache creates a cache holding up to size conversions with the given options. The options
+// hold for every conversion of the cache, so a cache must not be shared between call sites
+// converting with different options. With WithParameters or WithLiteralPlaceholders, use
+// Compile, whose plans return the values to bind to the placeholders.
+func NewCache(size int, opts ...ConvertOption) *Cache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	var o convertOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Cache{
+		opts:    opts,
+		size:    size,
+		metrics: o.metrics,
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Convert converts a CEL AST like Convert with the options of the cache, returning the cached
+// SQL if the same expression was converted before.
+func (c *Cache) Convert(ast *cel.Ast) (string, error) {
+	plan, err := c.Compile(ast)
+	if err != nil {
+		return "", err
+	}
+	return plan.SQL(), nil
+}
+
+// Compile compiles a CEL AST like Compile with the options of the cache, returning the cached
+// plan if the same expression was compiled before. Plans hold the parameters of the SQL and the
+// values of its literal placeholders, see Plan.SQLWithParams.
+func (c *Cache) Compile(ast *cel.Ast) (*Plan, error) {
+	var start time.Time
+	if c.metrics != nil {
+		start = time.Now()
+	}
+	checkedExpr, err := cel.AstToCheckedExpr(ast)
+	if err != nil {
+		return nil, err
+	}
+	key, err := fingerprint(checkedExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if elem, found := c.entries[key]; found {
+		c.order.MoveToFront(elem)
+		c.hits++
+		plan := elem.Value.(*cacheEntry).plan
+		c.mu.Unlock()
+		if c.metrics != nil {
+			plan.observeCached(c.metrics, start)
+		}
+		return plan, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	plan, err := compileCheckedExpr(checkedExpr, c.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, found := c.entries[key]; !found {
+		c.entries[key] = c.order.PushFront(&cacheEntry{key: key, plan: plan})
+		if c.order.Len() > c.size {
+			oldest := c.order.Remove(c.order.Back()).(*cacheEntry)
+			delete(c.entries, oldest.key)
+		}
+	}
+	return plan, nil
+}
+
+// Stats returns the number of hits, misses and cached conversions.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len()}
+}
+
+// fingerprint hashes a checked expression: its expression, types, references and source
+// positions, which warnings and debug comments refer to
+func fingerprint(checkedExpr *exprpb.CheckedExpr) ([sha256.Size]byte, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(checkedExpr)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
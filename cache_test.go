@@ -0,0 +1,123 @@
+package cel2sql_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestCache(t *testing.T) {
+	env, schemas := newColumnsTestEnv(t)
+	compile := func(source string) *cel.Ast {
+		ast, issues := env.Compile(source)
+		require.NoError(t, issues.Err())
+		return ast
+	}
+	cache := cel2sql.NewCache(2, cel2sql.WithSchemas(schemas), cel2sql.WithDeniedColumns("users.ssn"))
+
+	sql, err := cache.Convert(compile(`users.age > 30`))
+	require.NoError(t, err)
+	assert.Equal(t, "users.age > 30", sql)
+	assert.Equal(t, cel2sql.CacheStats{Misses: 1, Entries: 1}, cache.Stats())
+
+	// The same filter compiled again hits the cache
+	sql, err = cache.Convert(compile(`users.age > 30`))
+	require.NoError(t, err)
+	assert.Equal(t, "users.age > 30", sql)
+	assert.Equal(t, cel2sql.CacheStats{Hits: 1, Misses: 1, Entries: 1}, cache.Stats())
+
+	// Failed conversions are not cached
+	_, err = cache.Convert(compile(`users.ssn == "x"`))
+	require.Error(t, err)
+	assert.Equal(t, cel2sql.CacheStats{Hits: 1, Misses: 2, Entries: 1}, cache.Stats())
+
+	// The least recently used conversion is evicted
+	_, err = cache.Convert(compile(`users.name == "a"`))
+	require.NoError(t, err)
+	_, err = cache.Convert(compile(`users.age > 30`))
+	require.NoError(t, err)
+	_, err = cache.Convert(compile(`users.name == "b"`))
+	require.NoError(t, err)
+	assert.Equal(t, cel2sql.CacheStats{Hits: 2, Misses: 4, Entries: 2}, cache.Stats())
+
+	sql, err = cache.Convert(compile(`users.age > 30`))
+	require.NoError(t, err)
+	assert.Equal(t, "users.age > 30", sql)
+	_, err = cache.Convert(compile(`users.name == "a"`))
+	require.NoError(t, err)
+	assert.Equal(t, cel2sql.CacheStats{Hits: 3, Misses: 5, Entries: 2}, cache.Stats())
+}
+
+func TestCacheCompile(t *testing.T) {
+	env, schemas := newColumnsTestEnv(t)
+	cache := cel2sql.NewCache(0, cel2sql.WithSchemas(schemas), cel2sql.WithParameters("region"), cel2sql.WithLiteralPlaceholders())
+
+	for range 2 {
+		ast, issues := env.Compile(`users.age > 30 && users.name == region`)
+		require.NoError(t, issues.Err())
+		plan, err := cache.Compile(ast)
+		require.NoError(t, err)
+
+		sql, args, err := plan.SQLWithParams(map[string]any{"region": "eu"})
+		require.NoError(t, err)
+		assert.Equal(t, "users.age > $1 AND users.name = $2", sql)
+		assert.Equal(t, []any{int64(30), "eu"}, args)
+		assert.Equal(t, []string{"", "region"}, plan.Result().Parameters)
+	}
+	assert.Equal(t, cel2sql.CacheStats{Hits: 1, Misses: 1, Entries: 1}, cache.Stats())
+}
+
+func TestCacheMetrics(t *testing.T) {
+	env, schemas := newColumnsTestEnv(t)
+	sink := &recordingSink{}
+	cache := cel2sql.NewCache(0, cel2sql.WithSchemas(schemas), cel2sql.WithMetrics(sink))
+
+	// Conversions served from the cache are reported like the first one
+	for range 2 {
+		ast, issues := env.Compile(`users.name.matches("^a")`)
+		require.NoError(t, issues.Err())
+		sql, err := cache.Convert(ast)
+		require.NoError(t, err)
+		assert.Equal(t, "users.name ~ '^a'", sql)
+	}
+	assert.Equal(t, cel2sql.CacheStats{Hits: 1, Misses: 1, Entries: 1}, cache.Stats())
+	assert.Equal(t, []int{17, 17}, sink.conversions)
+	assert.Equal(t, map[string]int{cel2sql.FeatureRegex: 2}, sink.features)
+}
+
+func TestCacheConcurrentUse(t *testing.T) {
+	env, _ := newColumnsTestEnv(t)
+	sources := []string{`users.age > 30`, `users.name == "a"`, `orders.total < 10.0`}
+	cache := cel2sql.NewCache(0)
+
+	var wg sync.WaitGroup
+	for i := range 30 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			source := sources[i%len(sources)]
+			ast, issues := env.Compile(source)
+			if !assert.NoError(t, issues.Err()) {
+				return
+			}
+			want, err := cel2sql.Convert(ast)
+			if !assert.NoError(t, err) {
+				return
+			}
+			got, err := cache.Convert(ast)
+			if assert.NoError(t, err) {
+				assert.Equal(t, want, got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(30), stats.Hits+stats.Misses)
+	assert.Equal(t, len(sources), stats.Entries)
+}
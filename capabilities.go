@@ -0,0 +1,114 @@
+package cel2sql
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/overloads"
+)
+
+// Dialect identifies the SQL dialect generated by the converter.
+type Dialect string
+
+// DialectPostgreSQL is the PostgreSQL dialect, currently the only one supported.
+const DialectPostgreSQL Dialect = "postgresql"
+
+// FunctionCapability describes a supported CEL function and the SQL it is converted to.
+type FunctionCapability struct {
+	Name string // CEL function name, e.g. "startsWith"
+	SQL  string // SQL construct it is rendered as, e.g. "STARTS_WITH"
+}
+
+// CapabilityReport describes the CEL functions, operators, macros and types supported for a dialect.
+type CapabilityReport struct {
+	Dialect   Dialect
+	Functions []FunctionCapability
+	Operators []string // CEL operator symbols, e.g. "==" or "in"; unary minus is "-_"
+	Macros    []string // CEL macro names, e.g. "exists"
+	Types     []string // CEL type names, e.g. "google.protobuf.Timestamp"
+}
+
+// capabilityFunctions lists the functions with a dedicated conversion, keyed by their CEL
+// function name. Validate checks calls against this list, which keeps the report in sync with the converter.
+var capabilityFunctions = []struct {
+	fun string
+	FunctionCapability
+}{
+	{overloads.Contains, FunctionCapability{Name: "contains", SQL: "POSITION"}},
+	{overloads.StartsWith, FunctionCapability{Name: "startsWith", SQL: "STARTS_WITH"}},
+	{overloads.EndsWith, FunctionCapability{Name: "endsWith", SQL: "ENDS_WITH"}},
+	{overloads.Matches, FunctionCapability{Name: "matches", SQL: "~, ~*"}},
+	{overloads.Size, FunctionCapability{Name: "size", SQL: "LENGTH, ARRAY_LENGTH, jsonb_array_length"}},
+	{overloads.TypeConvertBool, FunctionCapability{Name: "bool", SQL: "CAST"}},
+	{overloads.TypeConvertBytes, FunctionCapability{Name: "bytes", SQL: "CAST"}},
+	{overloads.TypeConvertDouble, FunctionCapability{Name: "double", SQL: "CAST"}},
+	{overloads.TypeConvertInt, FunctionCapability{Name: "int", SQL: "CAST, UNIX_SECONDS"}},
+	{overloads.TypeConvertString, FunctionCapability{Name: "string", SQL: "CAST"}},
+	{overloads.TypeConvertUint, FunctionCapability{Name: "uint", SQL: "CAST"}},
+	{overloads.TypeConvertDyn, FunctionCapability{Name: "dyn", SQL: "(none)"}},
+	{overloads.TypeConvertDuration, FunctionCapability{Name: "duration", SQL: "INTERVAL"}},
+	{"timestamp", FunctionCapability{Name: "timestamp", SQL: "CAST, TIMESTAMP"}},
+	{"interval", FunctionCapability{Name: "interval", SQL: "INTERVAL"}},
+	{containsAllFunction, FunctionCapability{Name: "containsAll", SQL: "@>, ?&"}},
+	{containsAnyFunction, FunctionCapability{Name: "containsAny", SQL: "&&, ?|"}},
+	{sliceFunction, FunctionCapability{Name: "slice", SQL: "[:]"}},
+	{indexOfFunction, FunctionCapability{Name: "indexOf", SQL: "array_position"}},
+	{overloads.TimeGetFullYear, FunctionCapability{Name: "getFullYear", SQL: "EXTRACT(YEAR)"}},
+	{overloads.TimeGetMonth, FunctionCapability{Name: "getMonth", SQL: "EXTRACT(MONTH)"}},
+	{overloads.TimeGetDate, FunctionCapability{Name: "getDate", SQL: "EXTRACT(DAY)"}},
+	{overloads.TimeGetDayOfMonth, FunctionCapability{Name: "getDayOfMonth", SQL: "EXTRACT(DAY)"}},
+	{overloads.TimeGetDayOfWeek, FunctionCapability{Name: "getDayOfWeek", SQL: "EXTRACT(DAYOFWEEK)"}},
+	{overloads.TimeGetDayOfYear, FunctionCapability{Name: "getDayOfYear", SQL: "EXTRACT(DAYOFYEAR)"}},
+	{overloads.TimeGetHours, FunctionCapability{Name: "getHours", SQL: "EXTRACT(HOUR)"}},
+	{overloads.TimeGetMinutes, FunctionCapability{Name: "getMinutes", SQL: "EXTRACT(MINUTE)"}},
+	{overloads.TimeGetSeconds, FunctionCapability{Name: "getSeconds", SQL: "EXTRACT(SECOND)"}},
+	{overloads.TimeGetMilliseconds, FunctionCapability{Name: "getMilliseconds", SQL: "EXTRACT(MILLISECOND)"}},
+}
+
+// capabilityOperators lists the supported operators by CEL function name and display symbol
+var capabilityOperators = []struct {
+	fun    string
+	symbol string
+}{
+	{operators.LogicalAnd, "&&"},
+	{operators.LogicalOr, "||"},
+	{operators.LogicalNot, "!"},
+	{operators.Conditional, "?:"},
+	{operators.Equals, "=="},
+	{operators.NotEquals, "!="},
+	{operators.Less, "<"},
+	{operators.LessEquals, "<="},
+	{operators.Greater, ">"},
+	{operators.GreaterEquals, ">="},
+	{operators.Add, "+"},
+	{operators.Subtract, "-"},
+	{operators.Multiply, "*"},
+	{operators.Divide, "/"},
+	{operators.Modulo, "%"},
+	{operators.Negate, "-_"},
+	{operators.In, "in"},
+	{operators.Index, "[]"},
+}
+
+// Capabilities reports what the converter supports for the given dialect, so that expression
+// builders can offer only convertible constructs and documentation can be generated from it.
+func Capabilities(dialect Dialect) (CapabilityReport, error) {
+	if dialect != DialectPostgreSQL {
+		return CapabilityReport{}, fmt.Errorf("unsupported dialect: %s", dialect)
+	}
+	report := CapabilityReport{
+		Dialect: dialect,
+		Macros:  []string{"has", "all", "exists", "exists_one", "map", "filter"},
+		Types: []string{
+			"bool", "int", "uint", "double", "string", "bytes", "null_type", "list", "map",
+			"google.protobuf.Timestamp", "google.protobuf.Duration",
+		},
+	}
+	for _, f := range capabilityFunctions {
+		report.Functions = append(report.Functions, f.FunctionCapability)
+	}
+	for _, op := range capabilityOperators {
+		report.Operators = append(report.Operators, op.symbol)
+	}
+	return report, nil
+}
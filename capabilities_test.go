@@ -0,0 +1,27 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestCapabilities(t *testing.T) {
+	report, err := cel2sql.Capabilities(cel2sql.DialectPostgreSQL)
+	require.NoError(t, err)
+
+	assert.Equal(t, cel2sql.DialectPostgreSQL, report.Dialect)
+	assert.Contains(t, report.Functions, cel2sql.FunctionCapability{Name: "startsWith", SQL: "STARTS_WITH"})
+	assert.Contains(t, report.Functions, cel2sql.FunctionCapability{Name: "matches", SQL: "~, ~*"})
+	assert.Contains(t, report.Operators, "in")
+	assert.Contains(t, report.Operators, "&&")
+	assert.Contains(t, report.Macros, "exists_one")
+	assert.NotContains(t, report.Macros, "transformMap")
+	assert.Contains(t, report.Types, "google.protobuf.Timestamp")
+
+	_, err = cel2sql.Capabilities("mysql")
+	assert.EqualError(t, err, "unsupported dialect: mysql")
+}
@@ -2,43 +2,235 @@
 package cel2sql
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
 	"github.com/google/cel-go/common/operators"
 	"github.com/google/cel-go/common/overloads"
 	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2/pg"
 )
 
 // Implementations based on `google/cel-go`'s unparser
 // https://github.com/google/cel-go/blob/master/parser/unparser.go
 
 // Convert converts a CEL AST to a PostgreSQL SQL WHERE clause condition.
-func Convert(ast *cel.Ast) (string, error) {
-	checkedExpr, err := cel.AstToCheckedExpr(ast)
+func Convert(ast *cel.Ast, opts ...ConvertOption) (string, error) {
+	sql, _, err := ConvertWithWarnings(ast, opts...)
+	return sql, err
+}
+
+// ConvertWithWarnings converts a CEL AST like Convert and also reports the places where the
+// generated SQL may behave differently from the CEL expression, such as regular expressions
+// that POSIX interprets differently or JSON values compared as numbers.
+func ConvertWithWarnings(ast *cel.Ast, opts ...ConvertOption) (string, []Warning, error) {
+	un, _, err := convert(ast, opts)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+	defer un.release()
+	return un.str.String(), un.resolveWarnings(), nil
+}
+
+// ConvertCheckedExpr converts a checked expression in its protobuf form like Convert. Services
+// that store or cache checked expressions avoid the conversion of the AST to its protobuf form
+// that Convert does on every call.
+func ConvertCheckedExpr(checked *exprpb.CheckedExpr, opts ...ConvertOption) (string, error) {
+	if checked.GetExpr() == nil || len(checked.GetTypeMap()) == 0 {
+		return "", errors.New("cannot convert unchecked expression")
 	}
-	un := &converter{
-		typeMap: checkedExpr.TypeMap,
+	un := newConverter(checked.GetTypeMap())
+	un.checkedSourceInfo = checked.GetSourceInfo()
+	if err := un.convert(checked.GetExpr(), opts); err != nil {
+		return "", err
 	}
-	if err := un.visit(checkedExpr.Expr); err != nil {
+	defer un.release()
+	return un.str.String(), nil
+}
+
+// ConvertAST converts a checked cel-go native AST like Convert, for callers holding the
+// common/ast representation rather than a cel.Ast.
+func ConvertAST(ast *celast.AST, opts ...ConvertOption) (string, error) {
+	un, _, err := convertAST(ast, opts)
+	if err != nil {
 		return "", err
 	}
+	defer un.release()
 	return un.str.String(), nil
 }
 
+// convert runs the conversion and returns the converter holding the generated SQL, together
+// with the checked expression it was generated from. The caller releases the converter once it
+// is done with it.
+func convert(ast *cel.Ast, opts []ConvertOption) (*converter, *exprpb.Expr, error) {
+	return convertAST(ast.NativeRep(), opts)
+}
+
+// convertAST runs the conversion of a native AST like convert
+func convertAST(ast *celast.AST, opts []ConvertOption) (*converter, *exprpb.Expr, error) {
+	if !ast.IsChecked() {
+		return nil, nil, errors.New("cannot convert unchecked ast")
+	}
+	checkedExpr, err := celast.ToProto(ast)
+	if err != nil {
+		return nil, nil, err
+	}
+	un := newConverter(checkedExpr.TypeMap)
+	un.sourceInfo = ast.SourceInfo()
+	un.checkedSourceInfo = checkedExpr.SourceInfo
+	if err := un.convert(checkedExpr.Expr, opts); err != nil {
+		return nil, nil, err
+	}
+	return un, checkedExpr.Expr, nil
+}
+
+// convert applies opts and converts checked into con.str, releasing con if it fails
+func (con *converter) convert(checked *exprpb.Expr, opts []ConvertOption) error {
+	for _, opt := range opts {
+		opt(&con.opts)
+	}
+	var start time.Time
+	if con.opts.metrics != nil {
+		start = time.Now()
+	}
+	err := con.run(checked)
+	if con.opts.metrics != nil {
+		con.observe(checked, start, err)
+	}
+	if err != nil {
+		con.release()
+		return err
+	}
+	return nil
+}
+
+// run converts a checked expression into con.str
+func (con *converter) run(checked *exprpb.Expr) error {
+	con.root = checked
+	if err := con.checkDepth(checked); err != nil {
+		return err
+	}
+	if err := con.checkParameterNames(); err != nil {
+		return err
+	}
+	if con.opts.collectErrors {
+		if err := con.validate(checked); err != nil {
+			return err
+		}
+	}
+	if err := con.checkColumns(checked); err != nil {
+		return err
+	}
+	if err := con.checkFeatures(checked); err != nil {
+		return err
+	}
+	expr := checked
+	if con.opts.normalize {
+		expr = con.normalizeExpr(expr)
+	}
+	if con.opts.debugComments {
+		con.markDebugFragments(expr)
+	}
+	if con.opts.tableAlias != nil {
+		con.localIdents = comprehensionIdents(expr)
+		if err := con.checkIterVarNames(expr); err != nil {
+			return err
+		}
+	}
+	con.root = expr
+	con.growBuffer(expr)
+	if err := con.visit(expr); err != nil {
+		return err
+	}
+	return con.checkSQLLength()
+}
+
 type converter struct {
-	str     strings.Builder
-	typeMap map[int64]*exprpb.Type
+	str        bytes.Buffer // not a strings.Builder, whose String would alias pooled memory
+	typeMap    map[int64]*exprpb.Type
+	opts       convertOptions
+	subqueries int
+	warnings   []pendingWarning
+	// localIdents holds the IDs of identifiers bound by comprehensions, set with WithTableAlias or
+	// on first use, see isCompositeElement
+	localIdents map[int64]bool
+	// parameters names the variable bound to each positional placeholder written so far,
+	// empty for literal placeholders
+	parameters []string
+	// literalCasts holds the type that literals compared with columns are cast to, see castLiterals
+	literalCasts map[int64]string
+	// literalValues holds the value of each literal placeholder by its index in parameters
+	literalValues map[int]any
+	// iterVars tells how the comprehension variables in scope iterate over JSON values, see
+	// bindIterVar
+	iterVars map[string]jsonIterVar
+	// features holds the features used by the expression once reported to the metrics sink
+	features []string
+	// indexVars maps the index variables of the two-variable comprehensions in scope to their
+	// ordinality column, see writeIterAlias
+	indexVars map[string]string
+
+	// sourceInfo locates the converted expression in its source, or checkedSourceInfo when it
+	// was converted from its protobuf form, see source. checkedSourceInfo also holds the macro
+	// calls, see WithMacro.
+	sourceInfo        *celast.SourceInfo
+	checkedSourceInfo *exprpb.SourceInfo
+	debugFragments    map[int64]bool
+	root              *exprpb.Expr // the expression being converted
+
+	// collecting makes visit record the error of each node in errs and carry on, see validate
+	collecting bool
+	errs       []error
+	// rejectUnknownFunctions makes calls of functions that are neither built in nor registered
+	// an error instead of passing them through to SQL, see Validate
+	rejectUnknownFunctions bool
+}
+
+// source returns the source info of the converted expression, converting it from its protobuf
+// form on first use
+func (con *converter) source() *celast.SourceInfo {
+	if con.sourceInfo == nil && con.checkedSourceInfo != nil {
+		con.sourceInfo, _ = celast.ProtoToSourceInfo(con.checkedSourceInfo)
+	}
+	return con.sourceInfo
 }
 
+// visit converts expr. While collecting errors, the error of a node is recorded where it arises
+// and the node is written as NULL, so that the rest of the expression is still checked.
 func (con *converter) visit(expr *exprpb.Expr) error {
+	err := con.dispatch(expr)
+	if err == nil && !con.collecting {
+		// Stop as soon as the SQL outgrows MaxSQLLength rather than rendering the rest
+		return con.checkSQLLength()
+	}
+	if err == nil || !con.collecting {
+		return err
+	}
+	con.errs = append(con.errs, con.locate(expr.GetId(), err))
+	con.str.WriteString("NULL")
+	return nil
+}
+
+// dispatch converts expr with the visitor for its kind
+func (con *converter) dispatch(expr *exprpb.Expr) error {
+	con.writeDebugComment(expr)
+	if call, fn, found := con.macroCall(expr); found {
+		return con.callCustomFunction(call, fn)
+	}
 	switch expr.ExprKind.(type) {
 	case *exprpb.Expr_CallExpr:
+		if len(con.opts.interceptors) > 0 {
+			return con.intercept(expr, NodeCall)
+		}
 		return con.visitCall(expr)
 	// Comprehensions are supported (all, exists, exists_one, filter, map).
 	case *exprpb.Expr_ComprehensionExpr:
@@ -50,6 +242,9 @@ func (con *converter) visit(expr *exprpb.Expr) error {
 	case *exprpb.Expr_ListExpr:
 		return con.visitList(expr)
 	case *exprpb.Expr_SelectExpr:
+		if len(con.opts.interceptors) > 0 {
+			return con.intercept(expr, NodeSelect)
+		}
 		return con.visitSelect(expr)
 	case *exprpb.Expr_StructExpr:
 		return con.visitStruct(expr)
@@ -60,6 +255,9 @@ func (con *converter) visit(expr *exprpb.Expr) error {
 func (con *converter) visitCall(expr *exprpb.Expr) error {
 	c := expr.GetCallExpr()
 	fun := c.GetFunction()
+	if fn, found := con.customFunction(fun); found {
+		return con.callCustomFunction(expr, fn)
+	}
 	switch fun {
 	// ternary operator
 	case operators.Conditional:
@@ -96,10 +294,14 @@ func (con *converter) visitCallBinary(expr *exprpb.Expr) error {
 	c := expr.GetCallExpr()
 	fun := c.GetFunction()
 	args := c.GetArgs()
-	lhs := args[0]
+	lhs, rhs := args[0], args[1]
+	if (fun == operators.Equals || fun == operators.NotEquals) && (isNullLiteral(lhs) || isBoolLiteral(lhs)) &&
+		!isNullLiteral(rhs) && !isBoolLiteral(rhs) {
+		// null and booleans are compared with IS, which takes them on its right
+		lhs, rhs = rhs, lhs
+	}
 	// add parens if the current operator is lower precedence than the lhs expr operator.
 	lhsParen := isComplexOperatorWithRespectTo(fun, lhs)
-	rhs := args[1]
 	// add parens if the current operator is lower precedence than the rhs expr operator,
 	// or the same precedence and the operator is left recursive.
 	rhsParen := isComplexOperatorWithRespectTo(fun, rhs)
@@ -112,11 +314,21 @@ func (con *converter) visitCallBinary(expr *exprpb.Expr) error {
 	if !rhsParen && isLeftRecursive(fun) {
 		rhsParen = isSamePrecedence(fun, rhs)
 	}
+	if (fun == operators.Equals || fun == operators.NotEquals) &&
+		((isMapLiteral(unwrapDyn(rhs)) && isMessageType(lhsType)) || (isMapLiteral(unwrapDyn(lhs)) && isMessageType(rhsType))) {
+		return con.callCompositeComparison(fun, lhs, rhs)
+	}
+	if fun == operators.In && con.isJSONArrayColumn(rhs) {
+		return con.callJSONMembership(lhs, rhs)
+	}
+	if err := con.castLiterals(fun, lhs, rhs); err != nil {
+		return err
+	}
 
-	// Check if we need numeric casting for JSON text extraction
-	needsNumericCasting := false
-	if con.isJSONTextExtraction(lhs) && isNumericComparison(fun) && isNumericType(rhsType) {
-		needsNumericCasting = true
+	// JSON text compared with numbers or booleans is cast to their type
+	lhsCast := con.jsonComparisonCast(fun, lhs, rhsType)
+	rhsCast := con.jsonComparisonCast(fun, rhs, lhsType)
+	if lhsCast != "" {
 		con.str.WriteString("(")
 	}
 
@@ -124,8 +336,11 @@ func (con *converter) visitCallBinary(expr *exprpb.Expr) error {
 		return err
 	}
 
-	if needsNumericCasting {
-		con.str.WriteString(")::numeric")
+	if lhsCast != "" {
+		con.writeJSONComparisonCast(expr, lhsCast)
+	} else if con.isJSONTextExtraction(lhs) && isNumericComparison(fun) && fun != operators.Equals && fun != operators.NotEquals &&
+		rhsType.GetPrimitive() == exprpb.Type_STRING {
+		con.warn(expr, WarningJSONComparison, "JSON value is compared as text; numbers stored in JSON are ordered lexically")
 	}
 	var operator string
 	if fun == operators.Add && (lhsType.GetPrimitive() == exprpb.Type_STRING && rhsType.GetPrimitive() == exprpb.Type_STRING) {
@@ -141,6 +356,10 @@ func (con *converter) visitCallBinary(expr *exprpb.Expr) error {
 		operator = "IS"
 	} else if fun == operators.NotEquals && (isNullLiteral(rhs) || isBoolLiteral(rhs)) {
 		operator = "IS NOT"
+	} else if fun == operators.Equals && con.isNullSafeComparison(expr, lhs, rhs) {
+		operator = "IS NOT DISTINCT FROM"
+	} else if fun == operators.NotEquals && con.isNullSafeComparison(expr, lhs, rhs) {
+		operator = "IS DISTINCT FROM"
 	} else if fun == operators.In && isListType(rhsType) {
 		operator = "="
 	} else if fun == operators.In && isFieldAccessExpression(rhs) {
@@ -159,9 +378,19 @@ func (con *converter) visitCallBinary(expr *exprpb.Expr) error {
 	con.str.WriteString(" ")
 	con.str.WriteString(operator)
 	con.str.WriteString(" ")
+	if fun == operators.In {
+		if list := rhs.GetListExpr(); list != nil {
+			if err := con.checkInListSize(len(list.GetElements())); err != nil {
+				return err
+			}
+		}
+	}
 	if fun == operators.In && (isListType(rhsType) || isFieldAccessExpression(rhs)) {
 		// Check if we're dealing with a JSON array
 		if isFieldAccessExpression(rhs) && con.isJSONArrayField(rhs) {
+			if err := con.countSubquery(); err != nil {
+				return err
+			}
 			// For JSON arrays, use jsonb_array_elements with ANY
 			jsonFunc := con.getJSONArrayFunction(rhs)
 			con.str.WriteString("ANY(ARRAY(SELECT ")
@@ -188,9 +417,15 @@ func (con *converter) visitCallBinary(expr *exprpb.Expr) error {
 		}
 		con.str.WriteString("ANY(")
 	}
+	if rhsCast != "" {
+		con.str.WriteString("(")
+	}
 	if err := con.visitMaybeNested(rhs, rhsParen); err != nil {
 		return err
 	}
+	if rhsCast != "" {
+		con.writeJSONComparisonCast(expr, rhsCast)
+	}
 	if fun == operators.In && (isListType(rhsType) || isFieldAccessExpression(rhs)) {
 		// Check if we're dealing with a JSON array - already handled above for JSON arrays
 		if !isFieldAccessExpression(rhs) || !con.isJSONArrayField(rhs) {
@@ -268,10 +503,23 @@ func (con *converter) callCasting(function string, _ *exprpb.Expr, args []*exprp
 		con.str.WriteString(")")
 		return nil
 	}
+	if function == overloads.TypeConvertUint {
+		if err := checkUintConversion(arg); err != nil {
+			return err
+		}
+	}
+	// CEL truncates doubles converted to integers, where SQL rounds them
+	truncate := function == overloads.TypeConvertUint && con.getType(arg).GetPrimitive() == exprpb.Type_DOUBLE
 	con.str.WriteString("CAST(")
+	if truncate {
+		con.str.WriteString("TRUNC(")
+	}
 	if err := con.visit(arg); err != nil {
 		return err
 	}
+	if truncate {
+		con.str.WriteString(")")
+	}
 	con.str.WriteString(" AS ")
 	switch function {
 	case overloads.TypeConvertBool:
@@ -285,7 +533,7 @@ func (con *converter) callCasting(function string, _ *exprpb.Expr, args []*exprp
 	case overloads.TypeConvertString:
 		con.str.WriteString("STRING")
 	case overloads.TypeConvertUint:
-		con.str.WriteString("INT64")
+		con.str.WriteString(uintSQLType)
 	}
 	con.str.WriteString(")")
 	return nil
@@ -295,11 +543,11 @@ func (con *converter) callCasting(function string, _ *exprpb.Expr, args []*exprp
 func (con *converter) callMatches(target *exprpb.Expr, args []*exprpb.Expr) error {
 	// CEL matches function: string.matches(pattern) or matches(string, pattern)
 	// Convert to PostgreSQL: string ~ 'posix_pattern'
-	
+
 	// Get the string to match against
 	var stringExpr *exprpb.Expr
 	var patternExpr *exprpb.Expr
-	
+
 	if target != nil {
 		// Method call: string.matches(pattern)
 		stringExpr = target
@@ -311,37 +559,68 @@ func (con *converter) callMatches(target *exprpb.Expr, args []*exprpb.Expr) erro
 		stringExpr = args[0]
 		patternExpr = args[1]
 	}
-	
+
 	if stringExpr == nil || patternExpr == nil {
 		return errors.New("matches function requires both string and pattern arguments")
 	}
-	
+
+	// Convert the pattern first, as a case-insensitive pattern changes the operator
+	var posix posixRegex
+	converted := false
+	if constExpr := patternExpr.GetConstExpr(); constExpr != nil && constExpr.GetStringValue() != "" {
+		re2Pattern := constExpr.GetStringValue()
+		if con.opts.strictRegex {
+			if err := checkRegexConvertible(re2Pattern); err != nil {
+				return err
+			}
+		}
+		var err error
+		if posix, err = convertRE2ToPOSIX(re2Pattern); err != nil {
+			return err
+		}
+		if con.opts.strictRegex && len(posix.approximations) > 0 {
+			return fmt.Errorf("%w: %q: %s", ErrUnsupportedRegex, re2Pattern, posix.approximations[0])
+		}
+		converted = true
+		if posix.pattern != re2Pattern {
+			con.logDebug("rewrote RE2 pattern for POSIX", "pattern", re2Pattern, "posix", posix.pattern)
+		}
+		for _, approximation := range posix.approximations {
+			con.warn(patternExpr, WarningRegex, "pattern %q: %s", re2Pattern, approximation)
+		}
+	} else if con.opts.strictRegex && constExpr == nil {
+		return fmt.Errorf("%w: pattern must be a string literal", ErrUnsupportedRegex)
+	} else if constExpr == nil {
+		con.logDebug("passed non-literal pattern to PostgreSQL without RE2 to POSIX conversion")
+	}
+
 	// Visit the string expression
 	if err := con.visit(stringExpr); err != nil {
 		return err
 	}
-	
-	con.str.WriteString(" ~ ")
-	
-	// Visit the pattern expression and convert from RE2 to POSIX if it's a string literal
-	if constExpr := patternExpr.GetConstExpr(); constExpr != nil && constExpr.GetStringValue() != "" {
-		// Convert RE2 pattern to POSIX
-		re2Pattern := constExpr.GetStringValue()
-		posixPattern := convertRE2ToPOSIX(re2Pattern)
-		
+
+	if posix.caseInsensitive {
+		con.str.WriteString(" ~* ")
+	} else {
+		con.str.WriteString(" ~ ")
+	}
+
+	if converted {
 		// Write the converted pattern as a string literal
-		escaped := strings.ReplaceAll(posixPattern, "'", "''")
 		con.str.WriteString("'")
-		con.str.WriteString(escaped)
+		con.str.WriteString(strings.ReplaceAll(posix.pattern, "'", "''"))
 		con.str.WriteString("'")
 	} else {
 		// For non-literal patterns, we can't convert at compile time
 		// Just use the pattern as-is and hope it's POSIX compatible
+		if patternExpr.GetConstExpr() == nil {
+			con.warn(patternExpr, WarningRegex, "pattern is not a literal and is passed to PostgreSQL without RE2 to POSIX conversion")
+		}
 		if err := con.visit(patternExpr); err != nil {
 			return err
 		}
 	}
-	
+
 	return nil
 }
 
@@ -361,6 +640,18 @@ func (con *converter) visitCallFunc(expr *exprpb.Expr) error {
 		return con.callInterval(target, args)
 	case "timestamp":
 		return con.callTimestampFromString(target, args)
+	case containsAllFunction, containsAnyFunction:
+		return con.callArrayFunction(fun, target, args)
+	case sliceFunction:
+		return con.callSlice(target, args)
+	case indexOfFunction:
+		if con.isListIndexOf(target) {
+			return con.callIndexOf(target, args)
+		}
+	case overloads.StartsWith, overloads.EndsWith:
+		if con.isObjectKey(target) && isStringLiteral(args[0]) {
+			return con.callKeyPattern(fun, target, args[0])
+		}
 	case overloads.TimeGetFullYear,
 		overloads.TimeGetMonth,
 		overloads.TimeGetDate,
@@ -372,6 +663,9 @@ func (con *converter) visitCallFunc(expr *exprpb.Expr) error {
 		overloads.TimeGetDayOfMonth,
 		overloads.TimeGetDayOfWeek:
 		return con.callExtractFromTimestamp(fun, target, args)
+	case overloads.TypeConvertDyn:
+		// dyn() only affects type checking and has no SQL representation
+		return con.visit(args[0])
 	case overloads.TypeConvertBool,
 		overloads.TypeConvertBytes,
 		overloads.TypeConvertDouble,
@@ -383,6 +677,11 @@ func (con *converter) visitCallFunc(expr *exprpb.Expr) error {
 	sqlFun, ok := standardSQLFunctions[fun]
 	if !ok {
 		if fun == overloads.Size {
+			// Normalize the receiver-style call x.size() to size(x)
+			if target != nil {
+				args = append([]*exprpb.Expr{target}, args...)
+				target = nil
+			}
 			argType := con.getType(args[0])
 			switch {
 			case argType.GetPrimitive() == exprpb.Type_STRING:
@@ -390,6 +689,10 @@ func (con *converter) visitCallFunc(expr *exprpb.Expr) error {
 			case argType.GetPrimitive() == exprpb.Type_BYTES:
 				sqlFun = "LENGTH"
 			case isListType(argType):
+				// Count the elements kept by a filter instead of building the filtered array
+				if info := con.filterComprehension(args[0]); info != nil {
+					return con.visitFilterCount(args[0], info)
+				}
 				// Check if this is a JSON array field
 				if len(args) > 0 && con.isJSONArrayField(args[0]) {
 					// For JSON arrays, use jsonb_array_length
@@ -423,10 +726,15 @@ func (con *converter) visitCallFunc(expr *exprpb.Expr) error {
 				con.str.WriteString(", 1)")
 				return nil
 			default:
-				return fmt.Errorf("unsupported type: %v", argType)
+				return fmt.Errorf("%w: %v", ErrUnknownType, argType)
 			}
 		} else {
+			// Other functions are passed through as SQL functions of the same name
+			if err := validateFieldName(fun); err != nil || con.rejectUnknownFunctions {
+				return fmt.Errorf("%w: %s", ErrUnsupportedFunction, fun)
+			}
 			sqlFun = strings.ToUpper(fun)
+			con.logDebug("passed unknown function through to SQL", "function", fun, "sql", sqlFun)
 		}
 	}
 	con.str.WriteString(sqlFun)
@@ -453,7 +761,16 @@ func (con *converter) visitCallFunc(expr *exprpb.Expr) error {
 }
 
 func (con *converter) visitCallIndex(expr *exprpb.Expr) error {
-	if isMapType(con.getType(expr.GetCallExpr().GetArgs()[0])) {
+	args := expr.GetCallExpr().GetArgs()
+	if con.isMaskedColumnAccess(args[0]) && con.maskedColumnType(args[0]) == "" {
+		// Masked values that are not typed as arrays cannot be subscripted
+		con.writeMaskedNull(expr)
+		return nil
+	}
+	if con.isJSONObjectIndex(args) {
+		return con.visitJSONObjectIndex(expr)
+	}
+	if isMapType(con.getType(args[0])) {
 		return con.visitCallMapIndex(expr)
 	}
 	return con.visitCallListIndex(expr)
@@ -463,16 +780,40 @@ func (con *converter) visitCallMapIndex(expr *exprpb.Expr) error {
 	c := expr.GetCallExpr()
 	args := c.GetArgs()
 	m := args[0]
+	fieldName, err := extractFieldName(args[1])
+	if err != nil {
+		return err
+	}
+	if isMapLiteral(m) {
+		// Map literals are built with jsonb_build_object, so index them with JSON operators
+		return con.writeJSONFieldExtraction(m, fieldName, con.getType(expr))
+	}
 	nested := isBinaryOrTernaryOperator(m)
 	if err := con.visitMaybeNested(m, nested); err != nil {
 		return err
 	}
-	fieldName, err := extractFieldName(args[1])
-	if err != nil {
+	con.str.WriteString(".")
+	con.str.WriteString(fieldName)
+	return nil
+}
+
+// writeJSONFieldExtraction indexes a jsonb object by key, casting the extracted text to the
+// expected CEL result type where PostgreSQL would otherwise compare text against numbers/booleans.
+func (con *converter) writeJSONFieldExtraction(m *exprpb.Expr, fieldName string, resultType *exprpb.Type) error {
+	cast := jsonTextCast(resultType)
+	if cast != "" {
+		con.str.WriteString("(")
+	}
+	if err := con.visit(m); err != nil {
 		return err
 	}
-	con.str.WriteString(".")
+	con.str.WriteString("->>'")
 	con.str.WriteString(fieldName)
+	con.str.WriteString("'")
+	if cast != "" {
+		con.str.WriteString(")::")
+		con.str.WriteString(cast)
+	}
 	return nil
 }
 
@@ -521,6 +862,9 @@ func (con *converter) visitComprehension(expr *exprpb.Expr) error {
 	if err != nil {
 		return fmt.Errorf("failed to identify comprehension: %w", err)
 	}
+	if err := con.countSubquery(); err != nil {
+		return err
+	}
 
 	switch info.Type {
 	case ComprehensionAll:
@@ -540,7 +884,7 @@ func (con *converter) visitComprehension(expr *exprpb.Expr) error {
 	case ComprehensionTransformMapEntry:
 		return con.visitTransformMapEntryComprehension(expr, info)
 	default:
-		return fmt.Errorf("unsupported comprehension type: %v", info.Type)
+		return fmt.Errorf("%w: %v", ErrUnsupportedComprehension, info.Type)
 	}
 }
 
@@ -557,6 +901,11 @@ func (con *converter) visitAllComprehension(expr *exprpb.Expr, info *Comprehensi
 	}
 
 	iterRange := comprehension.GetIterRange()
+	if err := con.checkIndexRange(iterRange, info); err != nil {
+		return err
+	}
+	defer con.bindIterVar(info.IterVar, iterRange)()
+	defer con.bindIndexVar(iterRange, info.IterVar, info.IndexVar)()
 	isJSONArray := con.isJSONArrayField(iterRange)
 
 	con.str.WriteString("NOT EXISTS (SELECT 1 FROM ")
@@ -565,10 +914,15 @@ func (con *converter) visitAllComprehension(expr *exprpb.Expr, info *Comprehensi
 		jsonFunc := con.getJSONArrayFunction(iterRange)
 		con.str.WriteString(jsonFunc)
 		con.str.WriteString("(")
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range in ALL comprehension: %w", err)
 		}
 		con.str.WriteString(")")
+	} else if con.isJSONObject(iterRange) {
+		// Comprehensions over maps iterate over their keys
+		if err := con.writeJSONObjectKeys(iterRange); err != nil {
+			return fmt.Errorf("failed to visit iter range in ALL comprehension: %w", err)
+		}
 	} else {
 		con.str.WriteString("UNNEST(")
 		if err := con.visit(iterRange); err != nil {
@@ -577,21 +931,20 @@ func (con *converter) visitAllComprehension(expr *exprpb.Expr, info *Comprehensi
 		con.str.WriteString(")")
 	}
 
-	con.str.WriteString(" AS ")
-	con.str.WriteString(info.IterVar)
+	con.writeIterAlias(iterRange, info.IterVar, info.IndexVar)
 
 	con.str.WriteString(" WHERE ")
 
 	// Add null checks for JSON arrays
 	if isJSONArray {
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range for null check: %w", err)
 		}
 		con.str.WriteString(" IS NOT NULL AND ")
 		typeofFunc := con.getJSONTypeofFunction(iterRange)
 		con.str.WriteString(typeofFunc)
 		con.str.WriteString("(")
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range for type check: %w", err)
 		}
 		con.str.WriteString(") = 'array'")
@@ -624,6 +977,11 @@ func (con *converter) visitExistsComprehension(expr *exprpb.Expr, info *Comprehe
 	}
 
 	iterRange := comprehension.GetIterRange()
+	if err := con.checkIndexRange(iterRange, info); err != nil {
+		return err
+	}
+	defer con.bindIterVar(info.IterVar, iterRange)()
+	defer con.bindIndexVar(iterRange, info.IterVar, info.IndexVar)()
 	isJSONArray := con.isJSONArrayField(iterRange)
 
 	con.str.WriteString("EXISTS (SELECT 1 FROM ")
@@ -632,10 +990,15 @@ func (con *converter) visitExistsComprehension(expr *exprpb.Expr, info *Comprehe
 		jsonFunc := con.getJSONArrayFunction(iterRange)
 		con.str.WriteString(jsonFunc)
 		con.str.WriteString("(")
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range in EXISTS comprehension: %w", err)
 		}
 		con.str.WriteString(")")
+	} else if con.isJSONObject(iterRange) {
+		// Comprehensions over maps iterate over their keys
+		if err := con.writeJSONObjectKeys(iterRange); err != nil {
+			return fmt.Errorf("failed to visit iter range in EXISTS comprehension: %w", err)
+		}
 	} else {
 		con.str.WriteString("UNNEST(")
 		if err := con.visit(iterRange); err != nil {
@@ -644,21 +1007,20 @@ func (con *converter) visitExistsComprehension(expr *exprpb.Expr, info *Comprehe
 		con.str.WriteString(")")
 	}
 
-	con.str.WriteString(" AS ")
-	con.str.WriteString(info.IterVar)
+	con.writeIterAlias(iterRange, info.IterVar, info.IndexVar)
 
 	con.str.WriteString(" WHERE ")
 
 	// Add null checks for JSON arrays
 	if isJSONArray {
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range for null check: %w", err)
 		}
 		con.str.WriteString(" IS NOT NULL AND ")
 		typeofFunc := con.getJSONTypeofFunction(iterRange)
 		con.str.WriteString(typeofFunc)
 		con.str.WriteString("(")
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range for type check: %w", err)
 		}
 		con.str.WriteString(") = 'array'")
@@ -689,6 +1051,11 @@ func (con *converter) visitExistsOneComprehension(expr *exprpb.Expr, info *Compr
 	}
 
 	iterRange := comprehension.GetIterRange()
+	if err := con.checkIndexRange(iterRange, info); err != nil {
+		return err
+	}
+	defer con.bindIterVar(info.IterVar, iterRange)()
+	defer con.bindIndexVar(iterRange, info.IterVar, info.IndexVar)()
 	isJSONArray := con.isJSONArrayField(iterRange)
 
 	con.str.WriteString("(SELECT COUNT(*) FROM ")
@@ -697,10 +1064,15 @@ func (con *converter) visitExistsOneComprehension(expr *exprpb.Expr, info *Compr
 		jsonFunc := con.getJSONArrayFunction(iterRange)
 		con.str.WriteString(jsonFunc)
 		con.str.WriteString("(")
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range in EXISTS_ONE comprehension: %w", err)
 		}
 		con.str.WriteString(")")
+	} else if con.isJSONObject(iterRange) {
+		// Comprehensions over maps iterate over their keys
+		if err := con.writeJSONObjectKeys(iterRange); err != nil {
+			return fmt.Errorf("failed to visit iter range in EXISTS_ONE comprehension: %w", err)
+		}
 	} else {
 		con.str.WriteString("UNNEST(")
 		if err := con.visit(iterRange); err != nil {
@@ -709,21 +1081,20 @@ func (con *converter) visitExistsOneComprehension(expr *exprpb.Expr, info *Compr
 		con.str.WriteString(")")
 	}
 
-	con.str.WriteString(" AS ")
-	con.str.WriteString(info.IterVar)
+	con.writeIterAlias(iterRange, info.IterVar, info.IndexVar)
 
 	con.str.WriteString(" WHERE ")
 
 	// Add null checks for JSON arrays
 	if isJSONArray {
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range for null check: %w", err)
 		}
 		con.str.WriteString(" IS NOT NULL AND ")
 		typeofFunc := con.getJSONTypeofFunction(iterRange)
 		con.str.WriteString(typeofFunc)
 		con.str.WriteString("(")
-		if err := con.visit(iterRange); err != nil {
+		if err := con.visitJSONArray(iterRange); err != nil {
 			return fmt.Errorf("failed to visit iter range for type check: %w", err)
 		}
 		con.str.WriteString(") = 'array'")
@@ -753,8 +1124,21 @@ func (con *converter) visitMapComprehension(expr *exprpb.Expr, info *Comprehensi
 		return errors.New("expression is not a comprehension")
 	}
 
+	// Add filter condition if present (for map with filter)
+	var filters []*exprpb.Expr
+	if info.Filter != nil {
+		filters = append(filters, info.Filter)
+	}
 	iterRange := comprehension.GetIterRange()
-	isJSONArray := con.isJSONArrayField(iterRange)
+	if !info.IsTwoVar {
+		// Merged filters would renumber the elements
+		iterRange, filters = con.mergeFilters(iterRange, info.IterVar, filters)
+	}
+	if err := con.checkIndexRange(iterRange, info); err != nil {
+		return err
+	}
+	defer con.bindIterVar(info.IterVar, iterRange)()
+	defer con.bindIndexVar(iterRange, info.IterVar, info.IndexVar)()
 
 	con.str.WriteString("ARRAY(SELECT ")
 
@@ -768,33 +1152,8 @@ func (con *converter) visitMapComprehension(expr *exprpb.Expr, info *Comprehensi
 		con.str.WriteString(info.IterVar)
 	}
 
-	con.str.WriteString(" FROM ")
-
-	if isJSONArray {
-		jsonFunc := con.getJSONArrayFunction(iterRange)
-		con.str.WriteString(jsonFunc)
-		con.str.WriteString("(")
-		if err := con.visit(iterRange); err != nil {
-			return fmt.Errorf("failed to visit iter range in MAP comprehension: %w", err)
-		}
-		con.str.WriteString(")")
-	} else {
-		con.str.WriteString("UNNEST(")
-		if err := con.visit(iterRange); err != nil {
-			return fmt.Errorf("failed to visit iter range in MAP comprehension: %w", err)
-		}
-		con.str.WriteString(")")
-	}
-
-	con.str.WriteString(" AS ")
-	con.str.WriteString(info.IterVar)
-
-	// Add filter condition if present (for map with filter)
-	if info.Filter != nil {
-		con.str.WriteString(" WHERE ")
-		if err := con.visit(info.Filter); err != nil {
-			return fmt.Errorf("failed to visit filter in MAP comprehension: %w", err)
-		}
+	if err := con.writeFilterSource(iterRange, info.IterVar, info.IndexVar, filters); err != nil {
+		return err
 	}
 
 	con.str.WriteString(")")
@@ -811,40 +1170,91 @@ func (con *converter) visitFilterComprehension(expr *exprpb.Expr, info *Comprehe
 		return errors.New("expression is not a comprehension")
 	}
 
-	iterRange := comprehension.GetIterRange()
-	isJSONArray := con.isJSONArrayField(iterRange)
-
 	con.str.WriteString("ARRAY(SELECT ")
 	con.str.WriteString(info.IterVar)
+	iterRange, filters := con.mergeFilters(comprehension.GetIterRange(), info.IterVar, []*exprpb.Expr{info.Predicate})
+	defer con.bindIterVar(info.IterVar, iterRange)()
+	if err := con.writeFilterSource(iterRange, info.IterVar, "", filters); err != nil {
+		return err
+	}
+	con.str.WriteString(")")
+	return nil
+}
+
+// visitFilterCount renders the size of a filter comprehension, list.filter(x, p).size(), as
+// (SELECT COUNT(*) FROM UNNEST(list) AS x WHERE p) rather than measuring the filtered array
+func (con *converter) visitFilterCount(expr *exprpb.Expr, info *ComprehensionInfo) error {
+	if err := con.countSubquery(); err != nil {
+		return err
+	}
+	con.str.WriteString("(SELECT COUNT(*)")
+	iterRange, filters := con.mergeFilters(expr.GetComprehensionExpr().GetIterRange(), info.IterVar, []*exprpb.Expr{info.Predicate})
+	defer con.bindIterVar(info.IterVar, iterRange)()
+	if err := con.writeFilterSource(iterRange, info.IterVar, "", filters); err != nil {
+		return err
+	}
+	con.str.WriteString(")")
+	return nil
+}
+
+// mergeFilters flattens a chain of filters over the same iteration variable into one subquery,
+// e.g. list.filter(e, p).map(e, t) into ARRAY(SELECT t FROM UNNEST(list) AS e WHERE p). It
+// returns the list the chain starts from and the predicates of its filters, innermost first,
+// followed by filters.
+func (con *converter) mergeFilters(iterRange *exprpb.Expr, iterVar string, filters []*exprpb.Expr) (*exprpb.Expr, []*exprpb.Expr) {
+	for {
+		inner := con.filterComprehension(iterRange)
+		// Filters over another variable, or transforming their elements, would need renaming
+		if inner == nil || inner.IterVar != iterVar || (inner.Transform != nil && inner.Transform.GetIdentExpr().GetName() != iterVar) {
+			return iterRange, filters
+		}
+		filters = append([]*exprpb.Expr{inner.Predicate}, filters...)
+		iterRange = iterRange.GetComprehensionExpr().GetIterRange()
+	}
+}
+
+// writeFilterSource writes the FROM and WHERE clauses selecting the elements of iterRange that
+// satisfy all filters, numbered for the index variable of two-variable comprehensions
+func (con *converter) writeFilterSource(iterRange *exprpb.Expr, iterVar, indexVar string, filters []*exprpb.Expr) error {
+	isJSONArray := con.isJSONArrayField(iterRange)
+
 	con.str.WriteString(" FROM ")
 
 	if isJSONArray {
 		jsonFunc := con.getJSONArrayFunction(iterRange)
 		con.str.WriteString(jsonFunc)
 		con.str.WriteString("(")
-		if err := con.visit(iterRange); err != nil {
-			return fmt.Errorf("failed to visit iter range in FILTER comprehension: %w", err)
+		if err := con.visitJSONArray(iterRange); err != nil {
+			return fmt.Errorf("failed to visit iter range of comprehension: %w", err)
 		}
 		con.str.WriteString(")")
+	} else if con.isJSONObject(iterRange) {
+		// Comprehensions over maps iterate over their keys
+		if err := con.writeJSONObjectKeys(iterRange); err != nil {
+			return fmt.Errorf("failed to visit iter range of comprehension: %w", err)
+		}
 	} else {
 		con.str.WriteString("UNNEST(")
 		if err := con.visit(iterRange); err != nil {
-			return fmt.Errorf("failed to visit iter range in FILTER comprehension: %w", err)
+			return fmt.Errorf("failed to visit iter range of comprehension: %w", err)
 		}
 		con.str.WriteString(")")
 	}
 
-	con.str.WriteString(" AS ")
-	con.str.WriteString(info.IterVar)
+	con.writeIterAlias(iterRange, iterVar, indexVar)
 
-	if info.Predicate != nil {
-		con.str.WriteString(" WHERE ")
-		if err := con.visit(info.Predicate); err != nil {
-			return fmt.Errorf("failed to visit predicate in FILTER comprehension: %w", err)
+	keyword := " WHERE "
+	for _, filter := range filters {
+		if filter == nil {
+			continue
+		}
+		con.str.WriteString(keyword)
+		keyword = " AND "
+		nested := len(filters) > 1 && isComplexOperatorWithRespectTo(operators.LogicalAnd, filter)
+		if err := con.visitMaybeNested(filter, nested); err != nil {
+			return fmt.Errorf("failed to visit filter of comprehension: %w", err)
 		}
 	}
-
-	con.str.WriteString(")")
 	return nil
 }
 
@@ -895,18 +1305,30 @@ func (con *converter) visitTransformMapComprehension(_ *exprpb.Expr, _ *Comprehe
 	// Generate SQL for TRANSFORM_MAP comprehension: work with map entries
 	// This is complex for PostgreSQL - maps are typically represented as JSON or composite types
 	// For now, return an error indicating this needs special handling
-	return errors.New("TRANSFORM_MAP comprehension requires map/JSON support: not yet implemented")
+	return fmt.Errorf("%w: TRANSFORM_MAP requires map/JSON support: not yet implemented", ErrUnsupportedComprehension)
 }
 
 func (con *converter) visitTransformMapEntryComprehension(_ *exprpb.Expr, _ *ComprehensionInfo) error {
 	// Generate SQL for TRANSFORM_MAP_ENTRY comprehension: work with map key-value pairs
 	// This is complex for PostgreSQL - maps are typically represented as JSON or composite types
 	// For now, return an error indicating this needs special handling
-	return errors.New("TRANSFORM_MAP_ENTRY comprehension requires map/JSON support: not yet implemented")
+	return fmt.Errorf("%w: TRANSFORM_MAP_ENTRY requires map/JSON support: not yet implemented", ErrUnsupportedComprehension)
 }
 
 func (con *converter) visitConst(expr *exprpb.Expr) error {
 	c := expr.GetConstExpr()
+	if math.IsNaN(c.GetDoubleValue()) {
+		con.warn(expr, WarningNaNComparison, "NaN is equal to itself and greater than all numbers in PostgreSQL, while comparisons with NaN are false in CEL")
+	}
+	if con.opts.literalPlaceholders {
+		switch c.ConstantKind.(type) {
+		case *exprpb.Constant_BytesValue, *exprpb.Constant_DoubleValue, *exprpb.Constant_Int64Value,
+			*exprpb.Constant_StringValue, *exprpb.Constant_Uint64Value:
+			con.writePlaceholder(constValue(c))
+			con.writeLiteralCast(expr)
+			return nil
+		}
+	}
 	switch c.ConstantKind.(type) {
 	case *exprpb.Constant_BoolValue:
 		if c.GetBoolValue() {
@@ -920,8 +1342,16 @@ func (con *converter) visitConst(expr *exprpb.Expr) error {
 		con.str.WriteString(bytesToOctets(b))
 		con.str.WriteString(`"`)
 	case *exprpb.Constant_DoubleValue:
-		d := strconv.FormatFloat(c.GetDoubleValue(), 'g', -1, 64)
-		con.str.WriteString(d)
+		switch d := c.GetDoubleValue(); {
+		case math.IsNaN(d):
+			con.str.WriteString("'NaN'::float8")
+		case math.IsInf(d, 1):
+			con.str.WriteString("'Infinity'::float8")
+		case math.IsInf(d, -1):
+			con.str.WriteString("'-Infinity'::float8")
+		default:
+			con.str.WriteString(con.formatDouble(d))
+		}
 	case *exprpb.Constant_Int64Value:
 		i := strconv.FormatInt(c.GetInt64Value(), 10)
 		con.str.WriteString(i)
@@ -938,24 +1368,42 @@ func (con *converter) visitConst(expr *exprpb.Expr) error {
 	case *exprpb.Constant_Uint64Value:
 		ui := strconv.FormatUint(c.GetUint64Value(), 10)
 		con.str.WriteString(ui)
+		if _, cast := con.literalCasts[expr.GetId()]; !cast && exceedsBigint(c) {
+			con.str.WriteString("::numeric")
+		}
 	default:
 		return fmt.Errorf("unimplemented : %v", expr)
 	}
+	con.writeLiteralCast(expr)
 	return nil
 }
 
 func (con *converter) visitIdent(expr *exprpb.Expr) error {
 	identName := expr.GetIdentExpr().GetName()
+	if con.writeIndexVar(identName) {
+		return nil
+	}
+
+	if con.isMaskedColumnAccess(expr) {
+		con.writeMaskedNull(expr)
+		return nil
+	}
+	if con.isParameter(identName) {
+		con.writeParameter(identName)
+		return nil
+	}
 
 	// Check if this identifier needs numeric casting for JSON comprehensions
 	if con.needsNumericCasting(identName) {
+		con.logDebug("cast variable to numeric by name", "variable", identName)
 		con.str.WriteString("(")
-		con.str.WriteString(identName)
+		if err := con.writeVariable(expr); err != nil {
+			return err
+		}
 		con.str.WriteString(")::numeric")
-	} else {
-		con.str.WriteString(identName)
+		return nil
 	}
-	return nil
+	return con.writeVariable(expr)
 }
 
 func (con *converter) visitList(expr *exprpb.Expr) error {
@@ -978,6 +1426,15 @@ func (con *converter) visitList(expr *exprpb.Expr) error {
 func (con *converter) visitSelect(expr *exprpb.Expr) error {
 	sel := expr.GetSelectExpr()
 
+	if con.isMaskedColumnAccess(expr) {
+		if sel.GetTestOnly() {
+			con.str.WriteString("FALSE")
+		} else {
+			con.writeMaskedNull(expr)
+		}
+		return nil
+	}
+
 	// Handle the case when the select expression was generated by the has() macro.
 	if sel.GetTestOnly() {
 		return con.visitHasFunction(expr)
@@ -987,6 +1444,9 @@ func (con *converter) visitSelect(expr *exprpb.Expr) error {
 	// We need to determine if the operand is a JSON/JSONB field
 	useJSONPath := con.shouldUseJSONPath(sel.GetOperand(), sel.GetField())
 	useJSONObjectAccess := con.isJSONObjectFieldAccess(expr)
+	if useJSONPath {
+		con.logJSONHeuristic(sel)
+	}
 
 	// Check if this is a nested JSON path that requires special handling
 	if useJSONPath && !useJSONObjectAccess {
@@ -994,10 +1454,15 @@ func (con *converter) visitSelect(expr *exprpb.Expr) error {
 		return con.buildJSONPath(expr)
 	}
 
-	nested := !sel.GetTestOnly() && isBinaryOrTernaryOperator(sel.GetOperand())
+	if !useJSONPath && !useJSONObjectAccess && con.isUnqualifiedTable(sel.GetOperand()) {
+		return con.writeColumn(sel.GetOperand(), sel.GetField())
+	}
+
+	nested := !sel.GetTestOnly() && (isBinaryOrTernaryOperator(sel.GetOperand()) || con.isCompositeElement(sel.GetOperand()))
 
 	if useJSONObjectAccess && con.isNumericJSONField(sel.GetField()) {
 		// For numeric JSON fields, wrap in parentheses for casting
+		con.logDebug("cast JSON field to numeric by name", "key", sel.GetField())
 		con.str.WriteString("(")
 	}
 
@@ -1026,7 +1491,9 @@ func (con *converter) visitSelect(expr *exprpb.Expr) error {
 	default:
 		// Regular field selection
 		con.str.WriteString(".")
-		con.str.WriteString(sel.GetField())
+		if err := con.writeColumn(sel.GetOperand(), sel.GetField()); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -1038,6 +1505,22 @@ func (con *converter) visitHasFunction(expr *exprpb.Expr) error {
 	operand := sel.GetOperand()
 	field := sel.GetField()
 
+	// Maps are stored as JSONB or hstore columns, which both test for keys with ?
+	if isMapType(con.getType(operand)) {
+		err := con.visitMaybeNested(operand, isBinaryOrTernaryOperator(operand))
+		if err != nil {
+			return err
+		}
+		con.str.WriteString(" ? '")
+		con.str.WriteString(field)
+		con.str.WriteString("'")
+		return nil
+	}
+
+	if con.opts.hasSemantics != HasDefault && con.hasJSONFieldInChain(operand) {
+		return con.visitJSONHas(expr)
+	}
+
 	// Check if this is a direct JSON field access (e.g., table.json_column.key)
 	if con.isDirectJSONFieldAccess(operand, field) {
 		// For direct JSON field access, use the appropriate existence operator
@@ -1066,13 +1549,23 @@ func (con *converter) visitHasFunction(expr *exprpb.Expr) error {
 		return con.visitNestedJSONHas(expr)
 	}
 
+	// Columns declared NOT NULL are always present
+	if field, found := con.findField(expr); found && field.NotNull {
+		con.str.WriteString("TRUE")
+		return nil
+	}
+
 	// For regular struct fields, check if the field is not null
-	err := con.visitMaybeNested(operand, isBinaryOrTernaryOperator(operand))
-	if err != nil {
+	if !con.isUnqualifiedTable(operand) {
+		err := con.visitMaybeNested(operand, isBinaryOrTernaryOperator(operand))
+		if err != nil {
+			return err
+		}
+		con.str.WriteString(".")
+	}
+	if err := con.writeColumn(operand, field); err != nil {
 		return err
 	}
-	con.str.WriteString(".")
-	con.str.WriteString(field)
 	con.str.WriteString(" IS NOT NULL")
 
 	return nil
@@ -1080,6 +1573,10 @@ func (con *converter) visitHasFunction(expr *exprpb.Expr) error {
 
 // isDirectJSONFieldAccess checks if this represents a direct JSON field access (table.json_column.key)
 func (con *converter) isDirectJSONFieldAccess(operand *exprpb.Expr, _ string) bool {
+	if field, column, found := con.findJSONField(operand); found {
+		return isJSONType(field.Type) && column.Type == ""
+	}
+
 	// Check if operand is a select expression that refers to a JSON column
 	if selectExpr := operand.GetSelectExpr(); selectExpr != nil {
 		parentField := selectExpr.GetField()
@@ -1096,6 +1593,41 @@ func (con *converter) isDirectJSONFieldAccess(operand *exprpb.Expr, _ string) bo
 	return false
 }
 
+// visitJSONHas handles has() on JSON keys using the configured HasSemantics, independent of
+// how deeply the key is nested
+func (con *converter) visitJSONHas(expr *exprpb.Expr) error {
+	base, path := con.splitJSONPath(expr)
+	if len(path) == 0 {
+		return errors.New("expected JSON field access in has()")
+	}
+
+	if con.opts.hasSemantics == HasKeyExists {
+		if con.isJSONBField(base) {
+			// jsonb: test the parent object for the key with ?
+			if err := con.writeJSONPath(base, path[:len(path)-1], false); err != nil {
+				return err
+			}
+			con.str.WriteString(" ? '")
+			con.str.WriteString(path[len(path)-1])
+			con.str.WriteString("'")
+			return nil
+		}
+		// json: -> yields SQL NULL only when the key is missing
+		if err := con.writeJSONPath(base, path, false); err != nil {
+			return err
+		}
+		con.str.WriteString(" IS NOT NULL")
+		return nil
+	}
+
+	// ->> and the *_text functions yield SQL NULL for both missing keys and JSON nulls
+	if err := con.writeJSONPath(base, path, true); err != nil {
+		return err
+	}
+	con.str.WriteString(" IS NOT NULL")
+	return nil
+}
+
 // visitNestedJSONHas handles has() for deeply nested JSON paths
 func (con *converter) visitNestedJSONHas(expr *exprpb.Expr) error {
 	// For nested JSON paths, we use jsonb_extract_path_text and check for NOT NULL
@@ -1124,10 +1656,18 @@ func (con *converter) visitNestedJSONHas(expr *exprpb.Expr) error {
 // visitJSONColumnReference visits a JSON column reference without adding JSON access operators
 // This is used for jsonb_extract_path_text where we need the column reference as-is
 func (con *converter) visitJSONColumnReference(expr *exprpb.Expr) error {
+	if con.isMaskedColumnAccess(expr) {
+		con.writeMaskedNull(expr)
+		return nil
+	}
 	if selectExpr := expr.GetSelectExpr(); selectExpr != nil {
 		operand := selectExpr.GetOperand()
 		field := selectExpr.GetField()
 
+		if con.isUnqualifiedTable(operand) {
+			return con.writeColumn(operand, field)
+		}
+
 		// Visit the operand (table name)
 		if err := con.visit(operand); err != nil {
 			return err
@@ -1135,8 +1675,7 @@ func (con *converter) visitJSONColumnReference(expr *exprpb.Expr) error {
 
 		// Add the field name with a simple dot notation
 		con.str.WriteString(".")
-		con.str.WriteString(field)
-		return nil
+		return con.writeColumn(operand, field)
 	}
 
 	// If it's not a SelectExpr, just visit it normally
@@ -1217,44 +1756,144 @@ func (con *converter) visitStruct(expr *exprpb.Expr) error {
 	return con.visitStructMap(expr)
 }
 
+// visitStructMsg renders message construction as a row constructor cast to the composite type:
+// Address{street: "Main", zip: 1} -> ROW('Main', 1)::Address
+// The composite type is named after the last segment of the (qualified) message name. When the
+// schema of the message type is known (WithSchemas) values are ordered by column position and
+// omitted fields are filled with NULL; otherwise the literal order is used.
 func (con *converter) visitStructMsg(expr *exprpb.Expr) error {
 	m := expr.GetStructExpr()
-	entries := m.GetEntries()
-	con.str.WriteString(m.GetMessageName())
-	con.str.WriteString("{")
-	for i, entry := range entries {
-		f := entry.GetFieldKey()
-		con.str.WriteString(f)
-		con.str.WriteString(": ")
-		v := entry.GetValue()
-		err := con.visit(v)
-		if err != nil {
-			return err
+	messageName := m.GetMessageName()
+	compositeType := messageName[strings.LastIndex(messageName, ".")+1:]
+	if err := validateFieldName(compositeType); err != nil {
+		return fmt.Errorf("invalid composite type name for %s: %w", messageName, err)
+	}
+
+	values := make(map[string]*exprpb.Expr, len(m.GetEntries()))
+	fieldNames := make([]string, 0, len(m.GetEntries()))
+	for _, entry := range m.GetEntries() {
+		values[entry.GetFieldKey()] = entry.GetValue()
+		fieldNames = append(fieldNames, entry.GetFieldKey())
+	}
+	if schema, found := con.findSchema(messageName); found {
+		fieldNames = fieldNames[:0]
+		for _, field := range schema {
+			fieldNames = append(fieldNames, field.Name)
 		}
-		if i < len(entries)-1 {
+	}
+
+	con.str.WriteString("ROW(")
+	for i, fieldName := range fieldNames {
+		if v, ok := values[fieldName]; ok {
+			if err := con.visit(v); err != nil {
+				return err
+			}
+		} else {
+			con.str.WriteString("NULL")
+		}
+		if i < len(fieldNames)-1 {
 			con.str.WriteString(", ")
 		}
 	}
-	con.str.WriteString("}")
+	con.str.WriteString(")::")
+	// Composite types of schema-qualified tables live in the same schema
+	if table, path, found := pg.FindTable(con.opts.schemas, messageName); found && len(path) > 0 && strings.Count(table, ".") == 1 {
+		if schemaName, _ := pg.SplitTableName(table); schemaName != "" {
+			con.str.WriteString(quoteIdentifier(schemaName))
+			con.str.WriteString(".")
+		}
+	}
+	con.str.WriteString(compositeType)
 	return nil
 }
 
+// visitStructMap renders a CEL map literal as a PostgreSQL jsonb object:
+// {"k": v} -> jsonb_build_object('k', v)
 func (con *converter) visitStructMap(expr *exprpb.Expr) error {
 	m := expr.GetStructExpr()
 	entries := m.GetEntries()
-	con.str.WriteString("STRUCT(")
+	con.str.WriteString("jsonb_build_object(")
 	for i, entry := range entries {
-		v := entry.GetValue()
-		if err := con.visit(v); err != nil {
+		if err := con.visit(entry.GetMapKey()); err != nil {
 			return err
 		}
-		con.str.WriteString(" AS ")
+		con.str.WriteString(", ")
+		if err := con.visit(entry.GetValue()); err != nil {
+			return err
+		}
+		if i < len(entries)-1 {
+			con.str.WriteString(", ")
+		}
+	}
+	con.str.WriteString(")")
+	return nil
+}
+
+// callCompositeComparison compares a composite-typed expression against a map literal,
+// rendering the literal as a row constructor: obj == {"a": 1} -> obj = ROW(1)
+func (con *converter) callCompositeComparison(fun string, lhs, rhs *exprpb.Expr) error {
+	operator := "="
+	if fun == operators.NotEquals {
+		operator = "!="
+	}
+	typeName := con.getType(lhs).GetMessageType()
+	if typeName == "" {
+		typeName = con.getType(rhs).GetMessageType()
+	}
+	if err := con.visitCompositeOperand(lhs, typeName); err != nil {
+		return err
+	}
+	con.str.WriteString(" ")
+	con.str.WriteString(operator)
+	con.str.WriteString(" ")
+	return con.visitCompositeOperand(rhs, typeName)
+}
+
+func (con *converter) visitCompositeOperand(expr *exprpb.Expr, typeName string) error {
+	if literal := unwrapDyn(expr); isMapLiteral(literal) {
+		return con.visitStructMapAsRow(literal, typeName)
+	}
+	return con.visitMaybeNested(expr, isBinaryOrTernaryOperator(expr))
+}
+
+// visitStructMapAsRow renders a CEL map literal as a PostgreSQL row constructor so that it
+// can be compared against a column of the composite type typeName: {"a": 1, "b": 2} -> ROW(1, 2)
+// Like message literals, values are emitted in the field order of the type's schema supplied
+// with WithSchemas, with NULL for missing fields, or else in the order of the literal.
+func (con *converter) visitStructMapAsRow(expr *exprpb.Expr, typeName string) error {
+	entries := expr.GetStructExpr().GetEntries()
+	values := make(map[string]*exprpb.Expr, len(entries))
+	fieldNames := make([]string, 0, len(entries))
+	for _, entry := range entries {
 		fieldName, err := extractFieldName(entry.GetMapKey())
 		if err != nil {
 			return err
 		}
-		con.str.WriteString(fieldName)
-		if i < len(entries)-1 {
+		values[fieldName] = entry.GetValue()
+		fieldNames = append(fieldNames, fieldName)
+	}
+	if schema, found := con.findSchema(typeName); found {
+		for _, fieldName := range fieldNames {
+			if !slices.ContainsFunc(schema, func(field pg.FieldSchema) bool { return field.Name == fieldName }) {
+				return fmt.Errorf("composite type %s has no field %s", typeName, fieldName)
+			}
+		}
+		fieldNames = fieldNames[:0]
+		for _, field := range schema {
+			fieldNames = append(fieldNames, field.Name)
+		}
+	}
+
+	con.str.WriteString("ROW(")
+	for i, fieldName := range fieldNames {
+		if v, ok := values[fieldName]; ok {
+			if err := con.visit(v); err != nil {
+				return err
+			}
+		} else {
+			con.str.WriteString("NULL")
+		}
+		if i < len(fieldNames)-1 {
 			con.str.WriteString(", ")
 		}
 	}
@@ -1280,6 +1919,53 @@ func (con *converter) getType(node *exprpb.Expr) *exprpb.Type {
 	return con.typeMap[node.GetId()]
 }
 
+// findSchema looks up the schema for a (possibly nested) type name in the schemas supplied
+// with WithSchemas, e.g. "trigrams" or "trigrams.cell".
+func (con *converter) findSchema(typeName string) (pg.Schema, bool) {
+	table, path, found := pg.FindTable(con.opts.schemas, typeName)
+	if !found {
+		return nil, false
+	}
+	schema := con.opts.schemas[table]
+	for _, tn := range path {
+		var nested pg.Schema
+		for _, field := range schema {
+			if field.Name == tn {
+				nested = field.Schema
+				break
+			}
+		}
+		if len(nested) == 0 {
+			return nil, false
+		}
+		schema = nested
+	}
+	return schema, true
+}
+
+// findField looks up the schema of the column selected by expr in the schemas supplied with
+// WithSchemas
+func (con *converter) findField(expr *exprpb.Expr) (pg.FieldSchema, bool) {
+	sel := expr.GetSelectExpr()
+	if sel == nil {
+		return pg.FieldSchema{}, false
+	}
+	typ := con.getType(sel.GetOperand())
+	if !isMessageType(typ) {
+		return pg.FieldSchema{}, false
+	}
+	schema, found := con.findSchema(typ.GetMessageType())
+	if !found {
+		return pg.FieldSchema{}, false
+	}
+	for _, field := range schema {
+		if field.Name == sel.GetField() {
+			return field, true
+		}
+	}
+	return pg.FieldSchema{}, false
+}
+
 // isLeftRecursive indicates whether the parser resolves the call in a left-recursive manner as
 // this can have an effect of how parentheses affect the order of operations in the AST.
 func isLeftRecursive(op string) bool {
@@ -1339,50 +2025,3 @@ func isBinaryOrTernaryOperator(expr *exprpb.Expr) bool {
 	_, isBinaryOp := operators.FindReverseBinaryOperator(expr.GetCallExpr().GetFunction())
 	return isBinaryOp || isSamePrecedence(operators.Conditional, expr)
 }
-
-// convertRE2ToPOSIX converts a subset of RE2 regex patterns to POSIX ERE (Extended Regular Expression)
-// Note: This is a basic conversion for common patterns. Full RE2 to POSIX conversion is complex.
-func convertRE2ToPOSIX(re2Pattern string) string {
-	posixPattern := re2Pattern
-	
-	// Basic conversions for common differences between RE2 and POSIX:
-	
-	// 1. Word boundaries: \b -> [[:<:]] and [[:<:]] (PostgreSQL extension)
-	//    Note: PostgreSQL supports \y for word boundaries in some contexts
-	posixPattern = strings.ReplaceAll(posixPattern, `\b`, `\y`)
-	
-	// 2. Non-word boundaries: \B -> [^[:alnum:]_] (approximate)
-	//    This is a simplification; exact conversion is complex
-	posixPattern = strings.ReplaceAll(posixPattern, `\B`, `[^[:alnum:]_]`)
-	
-	// 3. Digit shortcuts: \d -> [[:digit:]] or [0-9]
-	posixPattern = strings.ReplaceAll(posixPattern, `\d`, `[[:digit:]]`)
-	
-	// 4. Non-digit shortcuts: \D -> [^[:digit:]] or [^0-9]
-	posixPattern = strings.ReplaceAll(posixPattern, `\D`, `[^[:digit:]]`)
-	
-	// 5. Word character shortcuts: \w -> [[:alnum:]_]
-	posixPattern = strings.ReplaceAll(posixPattern, `\w`, `[[:alnum:]_]`)
-	
-	// 6. Non-word character shortcuts: \W -> [^[:alnum:]_]
-	posixPattern = strings.ReplaceAll(posixPattern, `\W`, `[^[:alnum:]_]`)
-	
-	// 7. Whitespace shortcuts: \s -> [[:space:]]
-	posixPattern = strings.ReplaceAll(posixPattern, `\s`, `[[:space:]]`)
-	
-	// 8. Non-whitespace shortcuts: \S -> [^[:space:]]
-	posixPattern = strings.ReplaceAll(posixPattern, `\S`, `[^[:space:]]`)
-	
-	// Note: Many RE2 features are not directly convertible to POSIX ERE:
-	// - Lookahead/lookbehind assertions (?=...), (?!...), (?<=...), (?<!...)
-	// - Non-capturing groups (?:...)
-	// - Named groups (?P<name>...)
-	// - Case-insensitive flags (?i)
-	// - Multiline flags (?m)
-	// - Unicode character classes
-	// 
-	// For these cases, the pattern is returned as-is, which may cause PostgreSQL errors
-	// if the pattern uses unsupported RE2 features.
-	
-	return posixPattern
-}
@@ -4,10 +4,12 @@ import (
 	"testing"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/test/proto3pb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
 	"github.com/spandigital/cel2sql/v2/sqltypes"
 )
 
@@ -117,19 +119,19 @@ func TestConvert(t *testing.T) {
 		{
 			name:    "matches_with_digit_class",
 			args:    args{source: `name.matches("\\d{3}-\\d{4}")`},
-			want:    "name ~ '[[:digit:]]{3}-[[:digit:]]{4}'",
+			want:    "name ~ '[0-9]{3}-[0-9]{4}'",
 			wantErr: false,
 		},
 		{
 			name:    "matches_with_word_class",
 			args:    args{source: `name.matches("\\w+@\\w+\\.\\w+")`},
-			want:    "name ~ '[[:alnum:]_]+@[[:alnum:]_]+\\.[[:alnum:]_]+'",
+			want:    "name ~ '[0-9A-Z_a-z]+@[0-9A-Z_a-z]+\\.[0-9A-Z_a-z]+'",
 			wantErr: false,
 		},
 		{
 			name:    "matches_complex_pattern",
 			args:    args{source: `name.matches(".*pattern.*")`},
-			want:    "name ~ '.*pattern.*'",
+			want:    "name ~ '[^\\n]*pattern[^\\n]*'",
 			wantErr: false,
 		},
 		{
@@ -225,7 +227,19 @@ func TestConvert(t *testing.T) {
 		{
 			name:    "map",
 			args:    args{source: `{"one": 1, "two": 2, "three": 3}["one"] == 1`},
-			want:    "STRUCT(1 AS one, 2 AS two, 3 AS three).one = 1",
+			want:    "(jsonb_build_object('one', 1, 'two', 2, 'three', 3)->>'one')::numeric = 1",
+			wantErr: false,
+		},
+		{
+			name:    "map_string_value",
+			args:    args{source: `{"a": "x"}["a"] == name`},
+			want:    "jsonb_build_object('a', 'x')->>'a' = name",
+			wantErr: false,
+		},
+		{
+			name:    "map_in_comprehension",
+			args:    args{source: `string_list.map(s, {"name": s})`},
+			want:    "ARRAY(SELECT jsonb_build_object('name', s) FROM UNNEST(string_list) AS s)",
 			wantErr: false,
 		},
 		{
@@ -234,6 +248,18 @@ func TestConvert(t *testing.T) {
 			want:    "string_int_map.one = 1",
 			wantErr: false,
 		},
+		{
+			name:    "map_var_has",
+			args:    args{source: `has(string_int_map.one) && string_int_map.one == 1`},
+			want:    "string_int_map ? 'one' AND string_int_map.one = 1",
+			wantErr: false,
+		},
+		{
+			name:    "map_literal_has",
+			args:    args{source: `has({"a": name}.a)`},
+			want:    "jsonb_build_object('a', name) ? 'a'",
+			wantErr: false,
+		},
 		{
 			name:    "invalidFieldType",
 			args:    args{source: `{1: 1}[1]`},
@@ -480,6 +506,12 @@ func TestConvert(t *testing.T) {
 			want:    "LENGTH(CAST('test' AS BYTES))",
 			wantErr: false,
 		},
+		{
+			name:    "size_list_member",
+			args:    args{source: `string_list.size()`},
+			want:    "ARRAY_LENGTH(string_list, 1)",
+			wantErr: false,
+		},
 		{
 			name:    "size_list",
 			args:    args{source: `size(string_list)`},
@@ -501,3 +533,182 @@ func TestConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertCompositeComparison(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"Address": {
+			{Name: "street", Type: "text"},
+			{Name: "zip", Type: "integer"},
+		},
+	}
+	provider := pg.NewTypeProvider(schemas)
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("addr", cel.ObjectType("Address")),
+		cel.Variable("other", cel.DynType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		opts    []cel2sql.ConvertOption
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "equals_row",
+			source: `addr == dyn({"street": "Main", "zip": 1234})`,
+			want:   "addr = ROW('Main', 1234)",
+		},
+		{
+			name:   "not_equals_row_lhs",
+			source: `dyn({"street": "Main", "zip": 1234}) != addr`,
+			want:   "ROW('Main', 1234) != addr",
+		},
+		{
+			name:   "dyn_stays_jsonb",
+			source: `other == {"street": "Main"}`,
+			want:   "other = jsonb_build_object('street', 'Main')",
+		},
+		{
+			name:   "schema_order",
+			source: `addr == dyn({"zip": 1234, "street": "Main"})`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas)},
+			want:   "addr = ROW('Main', 1234)",
+		},
+		{
+			name:   "schema_order_lhs",
+			source: `dyn({"zip": 1234, "street": "Main"}) != addr`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas)},
+			want:   "ROW('Main', 1234) != addr",
+		},
+		{
+			name:   "missing_field",
+			source: `addr == dyn({"zip": 1234})`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas)},
+			want:   "addr = ROW(NULL, 1234)",
+		},
+		{
+			name:    "unknown_field",
+			source:  `addr == dyn({"street": "Main", "city": "Durban"})`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas)},
+			wantErr: "composite type Address has no field city",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertStructMessage(t *testing.T) {
+	const messageName = "google.expr.proto3.test.TestAllTypes.NestedMessage"
+	env, err := cel.NewEnv(
+		cel.Types(&proto3pb.TestAllTypes{}),
+		cel.Container("google.expr.proto3.test"),
+		cel.Variable("msg", cel.ObjectType("google.expr.proto3.test.TestAllTypes")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+		want   string
+	}{
+		{
+			name:   "literal_order",
+			source: `msg.single_nested_message == TestAllTypes.NestedMessage{bb: 7}`,
+			want:   "msg.single_nested_message = ROW(7)::NestedMessage",
+		},
+		{
+			name:   "schema_order",
+			source: `msg.single_nested_message == TestAllTypes.NestedMessage{bb: 7}`,
+			opts: []cel2sql.ConvertOption{cel2sql.WithSchemas(map[string]pg.Schema{
+				messageName: {
+					{Name: "id", Type: "integer"},
+					{Name: "bb", Type: "integer"},
+				},
+			})},
+			want: "msg.single_nested_message = ROW(NULL, 7)::NestedMessage",
+		},
+		{
+			name:   "in_list",
+			source: `msg.single_nested_message in [TestAllTypes.NestedMessage{bb: 1}, TestAllTypes.NestedMessage{bb: 2}]`,
+			want:   "msg.single_nested_message = ANY(ARRAY[ROW(1)::NestedMessage, ROW(2)::NestedMessage])",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertNonFiniteDoubles(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("ratio", cel.DoubleType),
+	)
+	require.NoError(t, err)
+	folder, err := cel.NewConstantFoldingOptimizer()
+	require.NoError(t, err)
+	optimizer := cel.NewStaticOptimizer(folder)
+
+	tests := []struct {
+		name         string
+		source       string
+		want         string
+		wantWarnings []cel2sql.WarningKind
+	}{
+		{
+			name:         "nan",
+			source:       `ratio != double("NaN")`,
+			want:         "ratio != 'NaN'::float8",
+			wantWarnings: []cel2sql.WarningKind{cel2sql.WarningNaNComparison},
+		},
+		{
+			name:   "infinity",
+			source: `ratio < 1.0 / 0.0`,
+			want:   "ratio < 'Infinity'::float8",
+		},
+		{
+			name:   "negative_infinity",
+			source: `ratio > -double("Infinity") && ratio <= double("inf")`,
+			want:   "ratio > '-Infinity'::float8 AND ratio <= 'Infinity'::float8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+			ast, issues = optimizer.Optimize(env, ast)
+			require.NoError(t, issues.Err())
+
+			got, warnings, err := cel2sql.ConvertWithWarnings(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			var kinds []cel2sql.WarningKind
+			for _, w := range warnings {
+				kinds = append(kinds, w.Kind)
+			}
+			assert.Equal(t, tt.wantWarnings, kinds)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package cel2sqlgrpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/spandigital/cel2sql/v2/cel2sqlpb"
+)
+
+// UnaryHandler is a minimal http.Handler serving the unary calls of the conversion service with
+// the gRPC protocol over HTTP/2, for deployments that do not depend on google.golang.org/grpc.
+// It is not a gRPC server and does not support:
+//
+//   - streaming calls, or more than one message per call
+//   - compression: messages with the compressed flag set fail with Unimplemented
+//   - deadlines set with grpc-timeout, beyond the cancellation of the HTTP request
+//   - metadata, except for the status trailers it writes
+//   - reflection, health checking, interceptors or gRPC-Web
+//
+// Register the Server with a google.golang.org/grpc server for any of these.
+type UnaryHandler struct {
+	server *Server
+}
+
+// NewUnaryHandler creates a handler serving the unary calls of the conversion service with
+// server. Serve it over HTTP/2, which net/http only negotiates with TLS.
+func NewUnaryHandler(server *Server) *UnaryHandler {
+	return &UnaryHandler{server: server}
+}
+
+// ServeHTTP serves a unary call, writing its status as the Grpc-Status and Grpc-Message
+// trailers
+func (h *UnaryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/grpc" && contentType != "application/grpc+proto" {
+		http.Error(w, "unsupported content type "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	resp, err := h.call(r.Context(), r.URL.Path, r.Body)
+	if err != nil {
+		writeStatus(w, err)
+		return
+	}
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		writeStatus(w, &statusError{code: codeInternal, message: err.Error()})
+		return
+	}
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data))) //nolint:gosec // bounded by the message
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(frame, data...))
+	writeStatus(w, nil)
+}
+
+// call reads the request message of a method from a request body and calls the method
+func (h *UnaryHandler) call(ctx context.Context, path string, body io.Reader) (proto.Message, error) {
+	method, found := strings.CutPrefix(path, "/"+serviceName+"/")
+	if !found {
+		return nil, &statusError{code: codeUnimplemented, message: "unknown service " + strings.TrimPrefix(path, "/")}
+	}
+	var req proto.Message
+	switch method {
+	case "Convert":
+		req = &cel2sqlpb.ConvertRequest{}
+	case "Validate":
+		req = &cel2sqlpb.ValidateRequest{}
+	case "Capabilities":
+		req = &cel2sqlpb.CapabilitiesRequest{}
+	default:
+		return nil, &statusError{code: codeUnimplemented, message: "unknown method " + method + " for service " + serviceName}
+	}
+	if err := h.readMessage(body, req); err != nil {
+		return nil, err
+	}
+
+	switch req := req.(type) {
+	case *cel2sqlpb.ConvertRequest:
+		return h.server.Convert(ctx, req)
+	case *cel2sqlpb.ValidateRequest:
+		return h.server.Validate(ctx, req)
+	default:
+		return h.server.Capabilities(ctx, req.(*cel2sqlpb.CapabilitiesRequest))
+	}
+}
+
+// readMessage reads a length-prefixed message, the only one of a unary call. The first byte of
+// the prefix is the compressed flag, which must be unset.
+func (h *UnaryHandler) readMessage(body io.Reader, msg proto.Message) error {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return &statusError{code: codeInvalidArgument, message: "missing request message"}
+	}
+	if prefix[0] != 0 {
+		return &statusError{code: codeUnimplemented, message: "compressed messages are not supported"}
+	}
+	maxSize := h.server.maxMessageSize
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if uint64(size) > uint64(maxSize) { //nolint:gosec // the limit is not negative
+		return &statusError{code: codeResourceExhausted, message: fmt.Sprintf("request message larger than max (%d vs. %d)", size, maxSize)}
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(body, data); err != nil {
+		return &statusError{code: codeInvalidArgument, message: "truncated request message"}
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return &statusError{code: codeInvalidArgument, message: fmt.Sprintf("invalid request message: %v", err)}
+	}
+	return nil
+}
+
+// writeStatus writes the status of a call as trailers
+func writeStatus(w http.ResponseWriter, err error) {
+	code, message := codeOK, ""
+	if err != nil {
+		code, message = codeInternal, err.Error()
+		var statusErr *statusError
+		if errors.As(err, &statusErr) {
+			code = statusErr.code
+		}
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeMessage(message))
+	}
+}
+
+// encodeMessage percent-encodes a status message as the gRPC protocol requires
+func encodeMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
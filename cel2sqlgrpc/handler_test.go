@@ -0,0 +1,85 @@
+package cel2sqlgrpc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/spandigital/cel2sql/v2/cel2sqlgrpc"
+	"github.com/spandigital/cel2sql/v2/cel2sqlpb"
+)
+
+func TestUnaryHandler(t *testing.T) {
+	ts := httptest.NewUnstartedServer(cel2sqlgrpc.NewUnaryHandler(newTestServer()))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	send := func(t *testing.T, method string, flag byte, req proto.Message) (*http.Response, []byte) {
+		t.Helper()
+		data, err := proto.Marshal(req)
+		require.NoError(t, err)
+		frame := make([]byte, 5)
+		frame[0] = flag
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+		httpReq, err := http.NewRequest(http.MethodPost, ts.URL+"/cel2sql.v1.ConversionService/"+method, bytes.NewReader(append(frame, data...)))
+		require.NoError(t, err)
+		httpReq.Header.Set("Content-Type", "application/grpc")
+		httpReq.Header.Set("TE", "trailers")
+		resp, err := ts.Client().Do(httpReq)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, 2, resp.ProtoMajor)
+		return resp, body
+	}
+	call := func(t *testing.T, method string, req proto.Message) (*http.Response, []byte) {
+		t.Helper()
+		return send(t, method, 0, req)
+	}
+
+	t.Run("convert", func(t *testing.T) {
+		resp, body := call(t, "Convert", &cel2sqlpb.ConvertRequest{
+			Expression: `order.id == id`,
+			Schema:     "shop",
+			Tables:     map[string]string{"order": "orders"},
+			Parameters: map[string]string{"id": "int"},
+		})
+		assert.Equal(t, "application/grpc", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+		require.GreaterOrEqual(t, len(body), 5)
+		assert.Equal(t, uint32(len(body)-5), binary.BigEndian.Uint32(body[1:5]))
+
+		var got cel2sqlpb.ConvertResponse
+		require.NoError(t, proto.Unmarshal(body[5:], &got))
+		assert.Equal(t, "order.id = $1", got.GetSql())
+		assert.Equal(t, []string{"id"}, got.GetParameters())
+	})
+
+	t.Run("invalid_argument", func(t *testing.T) {
+		resp, body := call(t, "Convert", &cel2sqlpb.ConvertRequest{Expression: "true", Schema: "billing"})
+		assert.Empty(t, body)
+		assert.Equal(t, "3", resp.Trailer.Get("Grpc-Status"))
+		assert.Equal(t, `unknown schema "billing"`, resp.Trailer.Get("Grpc-Message"))
+	})
+
+	t.Run("unimplemented", func(t *testing.T) {
+		resp, _ := call(t, "Explain", &cel2sqlpb.ValidateRequest{})
+		assert.Equal(t, "12", resp.Trailer.Get("Grpc-Status"))
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		resp, body := send(t, "Capabilities", 1, &cel2sqlpb.CapabilitiesRequest{})
+		assert.Empty(t, body)
+		assert.Equal(t, "12", resp.Trailer.Get("Grpc-Status"))
+		assert.Equal(t, "compressed messages are not supported", resp.Trailer.Get("Grpc-Message"))
+	})
+}
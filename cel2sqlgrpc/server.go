@@ -0,0 +1,206 @@
+// Package cel2sqlgrpc implements the cel2sql.v1.ConversionService defined in
+// proto/cel2sql/v1/conversion.proto, so that platforms in any language can convert CEL filters
+// against the schemas known to a central service.
+//
+// The methods of Server have the signatures of the service's server interface, so a Server can
+// be registered with a google.golang.org/grpc server using stubs generated by
+// protoc-gen-go-grpc. Without further dependencies, a UnaryHandler serves the service's unary
+// calls over HTTP/2 instead, see NewUnaryHandler:
+//
+//	server := cel2sqlgrpc.NewServer(map[string]map[string]pg.Schema{"shop": shopTables})
+//	srv := &http.Server{Addr: ":8443", Handler: cel2sqlgrpc.NewUnaryHandler(server)}
+//	err := srv.ListenAndServeTLS(certFile, keyFile)
+package cel2sqlgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/cel2sqlpb"
+	"github.com/spandigital/cel2sql/v2/internal/service"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// serviceName is the full name of the conversion service
+const serviceName = "cel2sql.v1.ConversionService"
+
+// defaultMaxMessageSize is the default limit of the size of request messages, as in gRPC
+const defaultMaxMessageSize = 4 << 20
+
+// gRPC status codes of the errors returned by Server
+const (
+	codeOK                = 0
+	codeInvalidArgument   = 3
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeInternal          = 13
+)
+
+// statusError is an error with a gRPC status code
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// Server implements the conversion service. It is safe for concurrent use.
+type Server struct {
+	catalog        *service.Catalog
+	envOpts        []cel.EnvOption
+	convertOpts    []cel2sql.ConvertOption
+	maxMessageSize int
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithEnvOptions adds options to the CEL environments expressions are compiled in, e.g. to
+// declare custom functions or further variables.
+func WithEnvOptions(opts ...cel.EnvOption) Option {
+	return func(s *Server) {
+		s.envOpts = append(s.envOpts, opts...)
+	}
+}
+
+// WithConvertOptions adds options to every conversion and validation, e.g. cel2sql.WithLimits
+// or cel2sql.WithStrictColumns.
+func WithConvertOptions(opts ...cel2sql.ConvertOption) Option {
+	return func(s *Server) {
+		s.convertOpts = append(s.convertOpts, opts...)
+	}
+}
+
+// WithMaxMessageSize limits the size of request messages served by a UnaryHandler, 4 MiB by
+// default.
+func WithMaxMessageSize(n int) Option {
+	return func(s *Server) {
+		s.maxMessageSize = n
+	}
+}
+
+// NewServer creates a server converting expressions against the given schemas, sets of tables
+// by name that requests reference. Conversions are passed the tables of their schema with
+// cel2sql.WithSchemas.
+func NewServer(schemas map[string]map[string]pg.Schema, opts ...Option) *Server {
+	s := &Server{maxMessageSize: defaultMaxMessageSize}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.catalog = service.NewCatalog(schemas, s.envOpts...)
+	return s
+}
+
+// Convert converts an expression to SQL. Invalid requests and expressions that cannot be
+// converted fail with InvalidArgument.
+func (s *Server) Convert(_ context.Context, req *cel2sqlpb.ConvertRequest) (*cel2sqlpb.ConvertResponse, error) {
+	style, err := placeholderStyle(req.GetPlaceholderStyle())
+	if err != nil {
+		return nil, err
+	}
+	ast, opts, err := s.catalog.Compile(service.Request{
+		Expression: req.GetExpression(),
+		Schema:     req.GetSchema(),
+		Tables:     req.GetTables(),
+		Parameters: req.GetParameters(),
+	})
+	if err != nil {
+		return nil, &statusError{code: codeInvalidArgument, message: err.Error()}
+	}
+
+	opts = append(opts, cel2sql.WithPlaceholderStyle(style))
+	result, err := cel2sql.ConvertWithResult(ast, append(opts, s.convertOpts...)...)
+	if err != nil {
+		return nil, &statusError{code: codeInvalidArgument, message: err.Error()}
+	}
+
+	resp := &cel2sqlpb.ConvertResponse{
+		Sql:        result.SQL,
+		Parameters: result.Parameters,
+		Columns:    result.Columns,
+	}
+	for _, w := range result.Warnings {
+		resp.Warnings = append(resp.Warnings, &cel2sqlpb.Warning{
+			Kind:    w.Kind.String(),
+			Message: w.Message,
+			Line:    int32(w.Line),   //nolint:gosec // source positions fit in int32
+			Column:  int32(w.Column), //nolint:gosec // source positions fit in int32
+		})
+	}
+	return resp, nil
+}
+
+// Validate checks that an expression compiles and can be converted, reporting every problem
+// rather than failing.
+func (s *Server) Validate(_ context.Context, req *cel2sqlpb.ValidateRequest) (*cel2sqlpb.ValidateResponse, error) {
+	ast, opts, err := s.catalog.Compile(service.Request{
+		Expression: req.GetExpression(),
+		Schema:     req.GetSchema(),
+		Tables:     req.GetTables(),
+		Parameters: req.GetParameters(),
+	})
+	if err != nil {
+		return &cel2sqlpb.ValidateResponse{Errors: []string{err.Error()}}, nil
+	}
+	err = cel2sql.Validate(ast, append(opts, s.convertOpts...)...)
+	if err == nil {
+		return &cel2sqlpb.ValidateResponse{Valid: true}, nil
+	}
+
+	resp := &cel2sqlpb.ValidateResponse{}
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		for _, e := range joined.Unwrap() {
+			resp.Errors = append(resp.Errors, e.Error())
+		}
+	} else {
+		resp.Errors = []string{err.Error()}
+	}
+	return resp, nil
+}
+
+// Capabilities describes the CEL functions, operators, macros and types supported for a
+// dialect. Unsupported dialects fail with InvalidArgument.
+func (s *Server) Capabilities(_ context.Context, req *cel2sqlpb.CapabilitiesRequest) (*cel2sqlpb.CapabilitiesResponse, error) {
+	dialect := cel2sql.Dialect(req.GetDialect())
+	if dialect == "" {
+		dialect = cel2sql.DialectPostgreSQL
+	}
+	report, err := cel2sql.Capabilities(dialect)
+	if err != nil {
+		return nil, &statusError{code: codeInvalidArgument, message: err.Error()}
+	}
+
+	resp := &cel2sqlpb.CapabilitiesResponse{
+		Dialect:   string(report.Dialect),
+		Operators: report.Operators,
+		Macros:    report.Macros,
+		Types:     report.Types,
+	}
+	for _, f := range report.Functions {
+		resp.Functions = append(resp.Functions, &cel2sqlpb.FunctionCapability{Name: f.Name, Sql: f.SQL})
+	}
+	return resp, nil
+}
+
+// placeholderStyle returns the placeholder style of a request
+func placeholderStyle(style cel2sqlpb.PlaceholderStyle) (cel2sql.PlaceholderStyle, error) {
+	switch style {
+	case cel2sqlpb.PlaceholderStyle_PLACEHOLDER_STYLE_UNSPECIFIED, cel2sqlpb.PlaceholderStyle_PLACEHOLDER_STYLE_DOLLAR:
+		return cel2sql.PlaceholderDollar, nil
+	case cel2sqlpb.PlaceholderStyle_PLACEHOLDER_STYLE_QUESTION:
+		return cel2sql.PlaceholderQuestion, nil
+	case cel2sqlpb.PlaceholderStyle_PLACEHOLDER_STYLE_COLON:
+		return cel2sql.PlaceholderColon, nil
+	case cel2sqlpb.PlaceholderStyle_PLACEHOLDER_STYLE_AT:
+		return cel2sql.PlaceholderAt, nil
+	default:
+		return 0, &statusError{code: codeInvalidArgument, message: fmt.Sprintf("unknown placeholder style %d", style)}
+	}
+}
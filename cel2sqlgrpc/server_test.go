@@ -0,0 +1,183 @@
+package cel2sqlgrpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/cel2sqlgrpc"
+	"github.com/spandigital/cel2sql/v2/cel2sqlpb"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func newTestServer() *cel2sqlgrpc.Server {
+	return cel2sqlgrpc.NewServer(map[string]map[string]pg.Schema{
+		"shop": {
+			"orders": {
+				{Name: "id", Type: "bigint", NotNull: true},
+				{Name: "status", Type: "text", NotNull: true},
+				{Name: "total", Type: "numeric", NotNull: true},
+				{Name: "coupon", Type: "text"},
+				{Name: "coupon_code", Type: "text"},
+			},
+		},
+	},
+		cel2sqlgrpc.WithConvertOptions(cel2sql.WithDeniedColumns("orders.coupon_code")),
+		cel2sqlgrpc.WithEnvOptions(cel.Function("ns.custom",
+			cel.Overload("ns_custom_string", []*cel.Type{cel.StringType}, cel.BoolType))),
+	)
+}
+
+func TestServerConvert(t *testing.T) {
+	server := newTestServer()
+
+	tests := []struct {
+		name    string
+		req     *cel2sqlpb.ConvertRequest
+		want    *cel2sqlpb.ConvertResponse
+		wantErr string
+	}{
+		{
+			name: "convert",
+			req: &cel2sqlpb.ConvertRequest{
+				Expression: `order.total > min_total && order.status in statuses`,
+				Schema:     "shop",
+				Tables:     map[string]string{"order": "orders"},
+				Parameters: map[string]string{"min_total": "double", "statuses": "list(string)"},
+			},
+			want: &cel2sqlpb.ConvertResponse{
+				Sql:        "order.total > $1 AND order.status = ANY($2)",
+				Parameters: []string{"min_total", "statuses"},
+				Columns:    []string{"orders.status", "orders.total"},
+			},
+		},
+		{
+			name: "placeholder_style",
+			req: &cel2sqlpb.ConvertRequest{
+				Expression:       `order.id == id`,
+				Schema:           "shop",
+				Tables:           map[string]string{"order": "orders"},
+				Parameters:       map[string]string{"id": "int"},
+				PlaceholderStyle: cel2sqlpb.PlaceholderStyle_PLACEHOLDER_STYLE_COLON,
+			},
+			want: &cel2sqlpb.ConvertResponse{
+				Sql:        "order.id = :id",
+				Parameters: []string{"id"},
+				Columns:    []string{"orders.id"},
+			},
+		},
+		{
+			name: "warnings",
+			req: &cel2sqlpb.ConvertRequest{
+				Expression: `order.coupon != "WELCOME"`,
+				Schema:     "shop",
+				Tables:     map[string]string{"order": "orders"},
+			},
+			want: &cel2sqlpb.ConvertResponse{
+				Sql: "order.coupon != 'WELCOME'",
+				Warnings: []*cel2sqlpb.Warning{{
+					Kind:    "null_comparison",
+					Message: "rows where the column is NULL do not match !=; use WithNullSafeEquality to compare NULL like CEL",
+					Line:    1,
+					Column:  14,
+				}},
+				Columns: []string{"orders.coupon"},
+			},
+		},
+		{
+			name:    "unknown_schema",
+			req:     &cel2sqlpb.ConvertRequest{Expression: "true", Schema: "billing"},
+			wantErr: `unknown schema "billing"`,
+		},
+		{
+			name: "conversion_error",
+			req: &cel2sqlpb.ConvertRequest{
+				Expression: `order.coupon_code == "WELCOME"`,
+				Schema:     "shop",
+				Tables:     map[string]string{"order": "orders"},
+			},
+			wantErr: "orders.coupon_code",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := server.Convert(context.Background(), tt.req)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(tt.want, got), "got %v", got)
+		})
+	}
+}
+
+func TestServerValidate(t *testing.T) {
+	server := newTestServer()
+
+	tests := []struct {
+		name       string
+		req        *cel2sqlpb.ValidateRequest
+		wantValid  bool
+		wantErrors []string
+	}{
+		{
+			name: "valid",
+			req: &cel2sqlpb.ValidateRequest{
+				Expression: `order.status == "shipped"`,
+				Schema:     "shop",
+				Tables:     map[string]string{"order": "orders"},
+			},
+			wantValid: true,
+		},
+		{
+			name: "compile_error",
+			req: &cel2sqlpb.ValidateRequest{
+				Expression: `order.missing == 1`,
+				Schema:     "shop",
+				Tables:     map[string]string{"order": "orders"},
+			},
+			wantErrors: []string{"undefined field 'missing'"},
+		},
+		{
+			name: "unsupported",
+			req: &cel2sqlpb.ValidateRequest{
+				Expression: `ns.custom(order.status) || ns.custom(order.coupon)`,
+				Schema:     "shop",
+				Tables:     map[string]string{"order": "orders"},
+			},
+			wantErrors: []string{"1:10: unsupported function: ns.custom", "1:37: unsupported function: ns.custom"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := server.Validate(context.Background(), tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValid, got.GetValid())
+			require.Len(t, got.GetErrors(), len(tt.wantErrors))
+			for i, want := range tt.wantErrors {
+				assert.Contains(t, got.GetErrors()[i], want)
+			}
+		})
+	}
+}
+
+func TestServerCapabilities(t *testing.T) {
+	server := newTestServer()
+
+	got, err := server.Capabilities(context.Background(), &cel2sqlpb.CapabilitiesRequest{})
+	require.NoError(t, err)
+	report, err := cel2sql.Capabilities(cel2sql.DialectPostgreSQL)
+	require.NoError(t, err)
+	assert.Equal(t, "postgresql", got.GetDialect())
+	assert.Equal(t, report.Operators, got.GetOperators())
+	assert.Len(t, got.GetFunctions(), len(report.Functions))
+
+	_, err = server.Capabilities(context.Background(), &cel2sqlpb.CapabilitiesRequest{Dialect: "oracle"})
+	require.Error(t, err)
+}
@@ -0,0 +1,197 @@
+// Package cel2sqlhttp serves the converter over HTTP, so that services not written in Go can
+// convert CEL filters against the schemas known to a Go service.
+//
+//	mux.Handle("/cel2sql/", http.StripPrefix("/cel2sql", cel2sqlhttp.NewHandler(map[string]map[string]pg.Schema{
+//		"shop": {"orders": ordersSchema, "customers": customersSchema},
+//	})))
+//
+// POST /convert takes a JSON request naming the schema to convert against, the tables bound to
+// CEL variables and the types of parameters:
+//
+//	{
+//	  "expression": "order.total > min_total && order.status in statuses",
+//	  "schema": "shop",
+//	  "tables": {"order": "orders"},
+//	  "parameters": {"min_total": "double", "statuses": "list(string)"},
+//	  "placeholderStyle": "dollar"
+//	}
+//
+// and responds with the SQL, the parameter bound to each placeholder, warnings about lossy
+// conversions and the referenced columns:
+//
+//	{
+//	  "sql": "order.total > $1 AND order.status = ANY($2)",
+//	  "parameters": ["min_total", "statuses"],
+//	  "warnings": [],
+//	  "columns": ["orders.status", "orders.total"]
+//	}
+//
+// Errors are responded as {"error": "..."}, with status 400 for invalid requests and
+// expressions, and 422 for expressions that cannot be converted.
+package cel2sqlhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/internal/service"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// defaultMaxBodySize is the default limit of the size of request bodies
+const defaultMaxBodySize = 1 << 20
+
+// Handler serves POST /convert. It is safe for concurrent use.
+type Handler struct {
+	catalog     *service.Catalog
+	envOpts     []cel.EnvOption
+	convertOpts []cel2sql.ConvertOption
+	maxBodySize int64
+}
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithEnvOptions adds options to the CEL environments expressions are compiled in, e.g. to
+// declare custom functions or further variables.
+func WithEnvOptions(opts ...cel.EnvOption) Option {
+	return func(h *Handler) {
+		h.envOpts = append(h.envOpts, opts...)
+	}
+}
+
+// WithConvertOptions adds options to every conversion, e.g. cel2sql.WithLimits or
+// cel2sql.WithStrictColumns.
+func WithConvertOptions(opts ...cel2sql.ConvertOption) Option {
+	return func(h *Handler) {
+		h.convertOpts = append(h.convertOpts, opts...)
+	}
+}
+
+// WithMaxBodySize limits the size of request bodies, 1 MiB by default.
+func WithMaxBodySize(n int64) Option {
+	return func(h *Handler) {
+		h.maxBodySize = n
+	}
+}
+
+// NewHandler creates a handler converting expressions against the given schemas, sets of
+// tables by name that requests reference. Conversions are passed the tables of their schema
+// with cel2sql.WithSchemas.
+func NewHandler(schemas map[string]map[string]pg.Schema, opts ...Option) *Handler {
+	h := &Handler{maxBodySize: defaultMaxBodySize}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.catalog = service.NewCatalog(schemas, h.envOpts...)
+	return h
+}
+
+// Request is the body of POST /convert
+type Request struct {
+	Expression       string            `json:"expression"`
+	Schema           string            `json:"schema"`
+	Tables           map[string]string `json:"tables"`           // tables by CEL variable
+	Parameters       map[string]string `json:"parameters"`       // CEL types of parameters by name, e.g. "int" or "list(string)"
+	PlaceholderStyle string            `json:"placeholderStyle"` // dollar (default), question, colon or at
+}
+
+// Response is the body of a successful conversion
+type Response struct {
+	SQL        string    `json:"sql"`
+	Parameters []string  `json:"parameters"` // see cel2sql.ConvertWithParameters
+	Warnings   []Warning `json:"warnings"`
+	Columns    []string  `json:"columns"` // referenced columns as "table.column", sorted
+}
+
+// Warning is a lossy conversion, see cel2sql.Warning
+type Warning struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// errorResponse is the body of a failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP serves POST /convert
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/convert" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	var req Request
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	resp, status, err := h.convert(req)
+	if err != nil {
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// convert converts the expression of a request, returning the status of a failure
+func (h *Handler) convert(req Request) (Response, int, error) {
+	style, err := service.ParsePlaceholderStyle(req.PlaceholderStyle)
+	if err != nil {
+		return Response{}, http.StatusBadRequest, err
+	}
+	ast, opts, err := h.catalog.Compile(service.Request{
+		Expression: req.Expression,
+		Schema:     req.Schema,
+		Tables:     req.Tables,
+		Parameters: req.Parameters,
+	})
+	if err != nil {
+		return Response{}, http.StatusBadRequest, err
+	}
+
+	opts = append(opts, cel2sql.WithPlaceholderStyle(style))
+	result, err := cel2sql.ConvertWithResult(ast, append(opts, h.convertOpts...)...)
+	if err != nil {
+		return Response{}, http.StatusUnprocessableEntity, err
+	}
+
+	resp := Response{
+		SQL:        result.SQL,
+		Parameters: result.Parameters,
+		Warnings:   make([]Warning, 0, len(result.Warnings)),
+		Columns:    result.Columns,
+	}
+	if resp.Parameters == nil {
+		resp.Parameters = []string{}
+	}
+	if resp.Columns == nil {
+		resp.Columns = []string{}
+	}
+	for _, w := range result.Warnings {
+		resp.Warnings = append(resp.Warnings, Warning{Kind: w.Kind.String(), Message: w.Message, Line: w.Line, Column: w.Column})
+	}
+	return resp, http.StatusOK, nil
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,127 @@
+package cel2sqlhttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/cel2sqlhttp"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestHandler(t *testing.T) {
+	handler := cel2sqlhttp.NewHandler(map[string]map[string]pg.Schema{
+		"shop": {
+			"orders": {
+				{Name: "id", Type: "bigint", NotNull: true},
+				{Name: "status", Type: "text", NotNull: true},
+				{Name: "total", Type: "numeric", NotNull: true},
+				{Name: "coupon", Type: "text"},
+				{Name: "coupon_code", Type: "text"},
+			},
+		},
+	}, cel2sqlhttp.WithConvertOptions(cel2sql.WithDeniedColumns("orders.coupon_code")))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "convert",
+			body:       `{"expression": "order.total > min_total && order.status in statuses", "schema": "shop", "tables": {"order": "orders"}, "parameters": {"min_total": "double", "statuses": "list(string)"}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"sql": "order.total > $1 AND order.status = ANY($2)", "parameters": ["min_total", "statuses"], "warnings": [], "columns": ["orders.status", "orders.total"]}`,
+		},
+		{
+			name:       "placeholder_style",
+			body:       `{"expression": "order.id == id || order.total > double(id)", "schema": "shop", "tables": {"order": "orders"}, "parameters": {"id": "int"}, "placeholderStyle": "question"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"sql": "order.id = ? OR order.total > CAST(? AS FLOAT64)", "parameters": ["id", "id"], "warnings": [], "columns": ["orders.id", "orders.total"]}`,
+		},
+		{
+			name:       "warnings",
+			body:       `{"expression": "order.coupon != \"WELCOME\"", "schema": "shop", "tables": {"order": "orders"}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"sql": "order.coupon != 'WELCOME'", "parameters": [], "warnings": [{"kind": "null_comparison", "message": "rows where the column is NULL do not match !=; use WithNullSafeEquality to compare NULL like CEL", "line": 1, "column": 14}], "columns": ["orders.coupon"]}`,
+		},
+		{
+			name:       "unknown_schema",
+			body:       `{"expression": "true", "schema": "billing"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "unknown schema \"billing\""}`,
+		},
+		{
+			name:       "unknown_table",
+			body:       `{"expression": "true", "schema": "shop", "tables": {"invoice": "invoices"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "unknown table \"invoices\" in schema \"shop\""}`,
+		},
+		{
+			name:       "unknown_parameter_type",
+			body:       `{"expression": "true", "schema": "shop", "parameters": {"since": "date"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "parameter since: unknown type \"date\""}`,
+		},
+		{
+			name:       "unknown_placeholder_style",
+			body:       `{"expression": "true", "schema": "shop", "placeholderStyle": "percent"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "unknown placeholder style \"percent\""}`,
+		},
+		{
+			name:       "unknown_field",
+			body:       `{"expr": "true", "schema": "shop"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "invalid request: json: unknown field \"expr\""}`,
+		},
+		{
+			name:       "compile_error",
+			body:       `{"expression": "order.missing == 1", "schema": "shop", "tables": {"order": "orders"}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "conversion_error",
+			body:       `{"expression": "order.coupon_code == \"WELCOME\"", "schema": "shop", "tables": {"order": "orders"}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "method_not_allowed",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"error": "method not allowed"}`,
+		},
+		{
+			name:       "not_found",
+			path:       "/translate",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, path := tt.method, tt.path
+			if method == "" {
+				method = http.MethodPost
+			}
+			if path == "" {
+				path = "/convert"
+			}
+			req := httptest.NewRequest(method, path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+// Package cel2sqlpb holds the messages of the cel2sql.v1.ConversionService defined in
+// proto/cel2sql/v1/conversion.proto, generated with make proto. The service is implemented by
+// package cel2sqlgrpc.
+package cel2sqlpb
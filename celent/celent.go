@@ -0,0 +1,55 @@
+// Package celent applies CEL filters converted by cel2sql to ent queries. A Predicate is
+// validated against the columns of an ent schema once, and converted for the selector of each
+// query it is applied to:
+//
+//	pred, err := celent.New(ast, user.Columns)
+//	users, err := client.User.Query().
+//		Where(func(s *sql.Selector) {
+//			query, err := pred.SQL(s.TableName())
+//			if err != nil {
+//				s.AddError(err)
+//				return
+//			}
+//			s.Where(sql.ExprP(query))
+//		}).
+//		All(ctx)
+package celent
+
+import (
+	"slices"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+// Predicate is a CEL filter for the selectors of ent queries
+type Predicate struct {
+	ast  *cel.Ast
+	opts []cel2sql.ConvertOption
+}
+
+// New validates that a checked CEL AST only references the given columns of an ent schema,
+// typically the Columns of a generated entity package, and returns it as a predicate. Further
+// options are applied whenever the predicate is converted.
+func New(ast *cel.Ast, columns []string, opts ...cel2sql.ConvertOption) (*Predicate, error) {
+	allowed := make([]string, len(columns))
+	for i, column := range columns {
+		allowed[i] = "*." + column
+	}
+	p := &Predicate{
+		ast:  ast,
+		opts: slices.Clip(append(opts, cel2sql.WithStrictColumns(allowed...))),
+	}
+	if _, err := p.SQL(""); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SQL converts the predicate with its columns qualified by the table name or alias of a
+// selector, as returned by Selector.TableName, or unqualified if table is empty. It is safe for
+// concurrent use.
+func (p *Predicate) SQL(table string) (string, error) {
+	return cel2sql.Convert(p.ast, append(p.opts, cel2sql.WithTableAlias(table))...)
+}
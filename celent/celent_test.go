@@ -0,0 +1,66 @@
+package celent_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/celent"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// columns of the ent schema of users, as listed by the generated user.Columns
+var columns = []string{"id", "name", "age", "org_id"}
+
+func TestPredicate(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(map[string]pg.Schema{
+			"users": {
+				{Name: "id", Type: "bigint"},
+				{Name: "name", Type: "text"},
+				{Name: "age", Type: "bigint"},
+				{Name: "org_id", Type: "bigint"},
+				{Name: "password_hash", Type: "text"},
+			},
+		})),
+		cel.Variable("user", cel.ObjectType("users")),
+	)
+	require.NoError(t, err)
+
+	ast, issues := env.Compile(`user.age >= 18 && user.name.startsWith("A")`)
+	require.NoError(t, issues.Err())
+	pred, err := celent.New(ast, columns)
+	require.NoError(t, err)
+
+	tests := []struct {
+		table string
+		want  string
+	}{
+		{table: "t1", want: "t1.age >= 18 AND STARTS_WITH(t1.name, 'A')"},
+		{table: "users", want: "users.age >= 18 AND STARTS_WITH(users.name, 'A')"},
+		{table: "", want: "age >= 18 AND STARTS_WITH(name, 'A')"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			got, err := pred.SQL(tt.table)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	ast, issues = env.Compile(`user.password_hash == "x"`)
+	require.NoError(t, issues.Err())
+	_, err = celent.New(ast, columns)
+	assert.ErrorContains(t, err, "column users.password_hash is not allowed")
+
+	ast, issues = env.Compile(`user.org_id == 7`)
+	require.NoError(t, issues.Err())
+	pred, err = celent.New(ast, columns, cel2sql.WithFieldNameMapper(func(_, field string) string { return "u_" + field }))
+	require.NoError(t, err)
+	got, err := pred.SQL("t0")
+	require.NoError(t, err)
+	assert.Equal(t, "t0.u_org_id = 7", got)
+}
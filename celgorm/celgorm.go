@@ -0,0 +1,132 @@
+// Package celgorm adapts CEL filters converted by cel2sql to GORM. Where returns the SQL and
+// bind variables of a filter in the form of GORM's clause.Expr, so that it can be passed to
+// db.Where(expr.SQL, expr.Vars...) or used as clause.Expr{SQL: expr.SQL, Vars: expr.Vars}, and
+// maps the field names of models to columns with GORM's naming strategy.
+package celgorm
+
+import (
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/internal/placeholders"
+)
+
+// Expr is a converted filter with its bind variables, rendered as ? placeholders. It has the
+// fields of GORM's clause.Expr.
+type Expr struct {
+	SQL  string
+	Vars []any
+}
+
+// Namer maps the fields of models to columns. GORM's schema.NamingStrategy and other
+// implementations of schema.Namer satisfy it.
+type Namer interface {
+	ColumnName(table, column string) string
+}
+
+// Option configures Where
+type Option func(*options)
+
+type options struct {
+	namer   Namer
+	table   string
+	vars    map[string]any
+	convert []cel2sql.ConvertOption
+}
+
+// WithNamingStrategy maps the fields of the CEL types, named after the fields of GORM models,
+// to columns with a naming strategy, e.g. schema.NamingStrategy{} maps CustomerID to customer_id.
+func WithNamingStrategy(namer Namer) Option {
+	return func(o *options) {
+		o.namer = namer
+	}
+}
+
+// WithTable qualifies columns with a table name or alias, for queries joining several tables.
+// By default columns are unqualified, as they are in queries over the table of a single model.
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithVars binds CEL variables, typically request context, as bind variables instead of
+// columns.
+func WithVars(vars map[string]any) Option {
+	return func(o *options) {
+		o.vars = vars
+	}
+}
+
+// WithConvertOptions passes further options to the conversion, e.g. cel2sql.WithSchemas.
+func WithConvertOptions(opts ...cel2sql.ConvertOption) Option {
+	return func(o *options) {
+		o.convert = append(o.convert, opts...)
+	}
+}
+
+// Where converts a checked CEL AST to a GORM expression.
+//
+// GORM binds every ? of an expression that has variables, including a ? in a string literal or
+// the jsonb ? operator. Filters containing one are rendered with their variables inlined as
+// literals instead, as by cel2sql.ConvertWithActivation, and without bind variables.
+func Where(ast *cel.Ast, opts ...Option) (Expr, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	convertOpts := []cel2sql.ConvertOption{cel2sql.WithTableAlias(o.table)}
+	if o.namer != nil {
+		convertOpts = append(convertOpts, cel2sql.WithFieldNameMapper(o.namer.ColumnName))
+	}
+	convertOpts = append(convertOpts, o.convert...)
+	if len(o.vars) == 0 {
+		sql, err := cel2sql.Convert(ast, convertOpts...)
+		if err != nil {
+			return Expr{}, err
+		}
+		return Expr{SQL: sql}, nil
+	}
+
+	names := slices.Sorted(maps.Keys(o.vars))
+	sql, parameters, err := cel2sql.ConvertWithParameters(ast, append(convertOpts, cel2sql.WithParameters(names...))...)
+	if err != nil {
+		return Expr{}, err
+	}
+	expr, err := bindVars(sql, parameters, o.vars)
+	if errors.Is(err, errQuestionMark) {
+		sql, err = cel2sql.ConvertWithActivation(ast, o.vars, convertOpts...)
+		if err != nil {
+			return Expr{}, err
+		}
+		return Expr{SQL: sql}, nil
+	}
+	return expr, err
+}
+
+// errQuestionMark is returned by bindVars for SQL containing a ? that is not a placeholder
+var errQuestionMark = errors.New("SQL contains ? that is not a placeholder")
+
+// bindVars replaces the positional placeholders of SQL with ?, and lists the variable bound to
+// each occurrence
+func bindVars(sql string, parameters []string, vars map[string]any) (Expr, error) {
+	var bound []any
+	sql, err := placeholders.Replace(sql, parameters, func(name string) string {
+		bound = append(bound, vars[name])
+		return "?"
+	}, func(c byte) (string, error) {
+		if c == '?' {
+			return "", errQuestionMark
+		}
+		return string(c), nil
+	})
+	if err != nil {
+		return Expr{}, err
+	}
+	return Expr{SQL: sql, Vars: bound}, nil
+}
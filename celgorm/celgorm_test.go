@@ -0,0 +1,94 @@
+package celgorm_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/celgorm"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// namingStrategy maps field names to columns like GORM's default schema.NamingStrategy
+type namingStrategy struct{}
+
+func (namingStrategy) ColumnName(table, column string) string {
+	return cel2sql.SnakeCaseFieldNames(table, column)
+}
+
+func TestWhere(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"orders": {
+			{Name: "ID", Type: "bigint"},
+			{Name: "CustomerID", Type: "bigint"},
+			{Name: "Status", Type: "text"},
+			{Name: "Total", Type: "numeric"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("order", cel.ObjectType("orders")),
+		cel.Variable("user_id", cel.IntType),
+		cel.Variable("min_total", cel.DoubleType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []celgorm.Option
+		want   celgorm.Expr
+	}{
+		{
+			name:   "unqualified",
+			source: `order.Status == "shipped" && order.Total > 100.0`,
+			want:   celgorm.Expr{SQL: "Status = 'shipped' AND Total > 100"},
+		},
+		{
+			name:   "naming_strategy",
+			source: `order.CustomerID == 7`,
+			opts:   []celgorm.Option{celgorm.WithNamingStrategy(namingStrategy{})},
+			want:   celgorm.Expr{SQL: "customer_id = 7"},
+		},
+		{
+			name:   "table",
+			source: `order.Status == "shipped"`,
+			opts:   []celgorm.Option{celgorm.WithTable("orders")},
+			want:   celgorm.Expr{SQL: "orders.Status = 'shipped'"},
+		},
+		{
+			name:   "vars",
+			source: `(order.CustomerID == user_id || order.ID == user_id) && order.Total >= min_total`,
+			opts: []celgorm.Option{
+				celgorm.WithNamingStrategy(namingStrategy{}),
+				celgorm.WithVars(map[string]any{"user_id": int64(42), "min_total": 9.5}),
+			},
+			want: celgorm.Expr{
+				SQL:  "(customer_id = ? OR id = ?) AND total >= ?",
+				Vars: []any{int64(42), int64(42), 9.5},
+			},
+		},
+		{
+			name:   "question_mark_inlines_vars",
+			source: `order.Status == "why?" && order.CustomerID == user_id`,
+			opts: []celgorm.Option{
+				celgorm.WithNamingStrategy(namingStrategy{}),
+				celgorm.WithVars(map[string]any{"user_id": int64(42)}),
+			},
+			want: celgorm.Expr{SQL: "status = 'why?' AND customer_id = 42"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := celgorm.Where(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+// Package celsqlc generates SQL fragments from CEL filters for embedding in sqlc queries. The
+// variables of a filter are rendered as sqlc named parameters cast to their SQL types, so that
+// sqlc generates typed Go bindings for them, and a manifest lists the parameters with their
+// types.
+package celsqlc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/internal/placeholders"
+)
+
+// Style is the syntax of sqlc named parameters
+type Style int
+
+const (
+	// Arg renders parameters as sqlc.arg(name)
+	Arg Style = iota
+	// At renders parameters as @name
+	At
+)
+
+// Fragment is a converted filter with sqlc named parameters
+type Fragment struct {
+	SQL    string
+	Params []Param // parameters in order of first use
+}
+
+// Param is a named parameter of a fragment and its PostgreSQL type, e.g. "bigint" or "text[]"
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Option configures New
+type Option func(*options)
+
+type options struct {
+	table   *string
+	params  []string
+	style   Style
+	convert []cel2sql.ConvertOption
+}
+
+// WithTable qualifies columns with a table name or alias, or leaves them unqualified if empty.
+// By default columns are qualified with the CEL variables of the tables.
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = &table
+	}
+}
+
+// WithParams renders CEL variables as named parameters instead of columns
+func WithParams(names ...string) Option {
+	return func(o *options) {
+		o.params = append(o.params, names...)
+	}
+}
+
+// WithStyle selects the syntax of the named parameters, sqlc.arg(name) by default
+func WithStyle(style Style) Option {
+	return func(o *options) {
+		o.style = style
+	}
+}
+
+// WithConvertOptions passes further options to the conversion, e.g. cel2sql.WithSchemas.
+func WithConvertOptions(opts ...cel2sql.ConvertOption) Option {
+	return func(o *options) {
+		o.convert = append(o.convert, opts...)
+	}
+}
+
+// New converts a checked CEL AST to a sqlc fragment. Each parameter is cast to the SQL type of
+// its CEL type, e.g. sqlc.arg(min_total)::double precision, since sqlc cannot infer the types of
+// parameters in every position. Parameters of dynamic, map or message types are an error.
+func New(ast *cel.Ast, opts ...Option) (Fragment, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var convertOpts []cel2sql.ConvertOption
+	if o.table != nil {
+		convertOpts = append(convertOpts, cel2sql.WithTableAlias(*o.table))
+	}
+	convertOpts = append(convertOpts, o.convert...)
+	convertOpts = append(convertOpts, cel2sql.WithParameters(o.params...))
+
+	sql, parameters, err := cel2sql.ConvertWithParameters(ast, convertOpts...)
+	if err != nil {
+		return Fragment{}, err
+	}
+
+	varTypes := variableTypes(ast)
+	params := make([]Param, 0, len(parameters))
+	sqlTypes := make(map[string]string, len(parameters))
+	for _, name := range parameters {
+		if name == "" {
+			continue
+		}
+		sqlType, err := sqlTypeOf(varTypes[name])
+		if err != nil {
+			return Fragment{}, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		params = append(params, Param{Name: name, Type: sqlType})
+		sqlTypes[name] = sqlType
+	}
+
+	sql, err = placeholders.Replace(sql, parameters, func(name string) string {
+		if o.style == At {
+			return "@" + name + "::" + sqlTypes[name]
+		}
+		return "sqlc.arg(" + name + ")::" + sqlTypes[name]
+	}, nil)
+	if err != nil {
+		return Fragment{}, err
+	}
+	return Fragment{SQL: sql, Params: params}, nil
+}
+
+// variableTypes returns the types of the variables referenced by a checked AST
+func variableTypes(ast *cel.Ast) map[string]*types.Type {
+	native := ast.NativeRep()
+	found := make(map[string]*types.Type)
+	celast.PreOrderVisit(native.Expr(), celast.NewExprVisitor(func(e celast.Expr) {
+		if e.Kind() != celast.IdentKind {
+			return
+		}
+		if _, seen := found[e.AsIdent()]; !seen {
+			found[e.AsIdent()] = native.GetType(e.ID())
+		}
+	}))
+	return found
+}
+
+// sqlTypeOf returns the PostgreSQL type of parameters of a CEL type
+func sqlTypeOf(t *types.Type) (string, error) {
+	if t == nil {
+		return "", errors.New("unknown type")
+	}
+	switch t.Kind() {
+	case types.BoolKind:
+		return "boolean", nil
+	case types.BytesKind:
+		return "bytea", nil
+	case types.DoubleKind:
+		return "double precision", nil
+	case types.DurationKind:
+		return "interval", nil
+	case types.IntKind, types.UintKind:
+		return "bigint", nil
+	case types.StringKind:
+		return "text", nil
+	case types.TimestampKind:
+		return "timestamptz", nil
+	case types.ListKind:
+		elem, err := sqlTypeOf(t.Parameters()[0])
+		if err != nil {
+			return "", err
+		}
+		return elem + "[]", nil
+	default:
+		return "", fmt.Errorf("type %s has no SQL type", t)
+	}
+}
@@ -0,0 +1,100 @@
+package celsqlc_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2/celsqlc"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestNew(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"orders": {
+			{Name: "id", Type: "bigint"},
+			{Name: "customer_id", Type: "bigint"},
+			{Name: "status", Type: "text"},
+			{Name: "total", Type: "numeric"},
+			{Name: "created_at", Type: "timestamptz"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("order", cel.ObjectType("orders")),
+		cel.Variable("user_id", cel.IntType),
+		cel.Variable("min_total", cel.DoubleType),
+		cel.Variable("statuses", cel.ListType(cel.StringType)),
+		cel.Variable("since", cel.TimestampType),
+		cel.Variable("attributes", cel.MapType(cel.StringType, cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		opts    []celsqlc.Option
+		want    celsqlc.Fragment
+		wantErr string
+	}{
+		{
+			name:   "no_params",
+			source: `order.status == "shipped"`,
+			want:   celsqlc.Fragment{SQL: "order.status = 'shipped'", Params: []celsqlc.Param{}},
+		},
+		{
+			name:   "arg",
+			source: `order.customer_id == user_id && order.total > min_total && order.id != user_id`,
+			opts: []celsqlc.Option{
+				celsqlc.WithTable("o"),
+				celsqlc.WithParams("user_id", "min_total"),
+			},
+			want: celsqlc.Fragment{
+				SQL: "o.customer_id = sqlc.arg(user_id)::bigint AND o.total > sqlc.arg(min_total)::double precision" +
+					" AND o.id != sqlc.arg(user_id)::bigint",
+				Params: []celsqlc.Param{
+					{Name: "user_id", Type: "bigint"},
+					{Name: "min_total", Type: "double precision"},
+				},
+			},
+		},
+		{
+			name:   "at",
+			source: `order.status in statuses && order.created_at >= since`,
+			opts: []celsqlc.Option{
+				celsqlc.WithTable(""),
+				celsqlc.WithParams("statuses", "since"),
+				celsqlc.WithStyle(celsqlc.At),
+			},
+			want: celsqlc.Fragment{
+				SQL: "status = ANY(@statuses::text[]) AND created_at >= @since::timestamptz",
+				Params: []celsqlc.Param{
+					{Name: "statuses", Type: "text[]"},
+					{Name: "since", Type: "timestamptz"},
+				},
+			},
+		},
+		{
+			name:    "untyped_param",
+			source:  `attributes["tier"] == "gold"`,
+			opts:    []celsqlc.Option{celsqlc.WithParams("attributes")},
+			wantErr: "parameter attributes: type map(string, string) has no SQL type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := celsqlc.New(ast, tt.opts...)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package celsqlx adapts CEL filters converted by cel2sql to sqlx. Named returns the SQL of a
+// filter with named bind parameters and the arguments map to pass along, so that converted
+// filters compose with sqlx.Named, sqlx.In and NamedQuery.
+package celsqlx
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/internal/placeholders"
+)
+
+// Option configures Named
+type Option func(*options)
+
+type options struct {
+	table   *string
+	vars    map[string]any
+	convert []cel2sql.ConvertOption
+}
+
+// WithTable qualifies columns with a table name or alias, or leaves them unqualified if empty.
+// By default columns are qualified with the CEL variables of the tables.
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = &table
+	}
+}
+
+// WithVars binds CEL variables, typically request context, as named parameters instead of
+// columns.
+func WithVars(vars map[string]any) Option {
+	return func(o *options) {
+		o.vars = vars
+	}
+}
+
+// WithConvertOptions passes further options to the conversion, e.g. cel2sql.WithSchemas.
+func WithConvertOptions(opts ...cel2sql.ConvertOption) Option {
+	return func(o *options) {
+		o.convert = append(o.convert, opts...)
+	}
+}
+
+// Named converts a checked CEL AST to SQL with a named parameter, e.g. :user_id, for each
+// variable bound with WithVars, and returns the arguments of the parameters it references.
+// Membership in a slice variable is rendered as IN (:name), which sqlx.In expands after
+// sqlx.Named. Other colons, as in casts, are doubled, which sqlx reads as a literal colon:
+//
+//	query, args, err := celsqlx.Named(ast, celsqlx.WithVars(map[string]any{"statuses": statuses}))
+//	query, params, err := sqlx.Named("SELECT * FROM orders WHERE "+query, args)
+//	query, params, err = sqlx.In(query, params...)
+//	err = db.Select(&orders, db.Rebind(query), params...)
+//
+// sqlx.In and Rebind treat every ? as a bind variable, including a ? in a string literal or the
+// jsonb ? operator.
+func Named(ast *cel.Ast, opts ...Option) (string, map[string]any, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var convertOpts []cel2sql.ConvertOption
+	if o.table != nil {
+		convertOpts = append(convertOpts, cel2sql.WithTableAlias(*o.table))
+	}
+	convertOpts = append(convertOpts, o.convert...)
+	names := slices.Sorted(maps.Keys(o.vars))
+	convertOpts = append(convertOpts, cel2sql.WithParameters(names...))
+
+	sql, parameters, err := cel2sql.ConvertWithParameters(ast, convertOpts...)
+	if err != nil {
+		return "", nil, err
+	}
+	return nameParameters(sql, parameters, o.vars)
+}
+
+// nameParameters replaces the positional placeholders of SQL with named parameters, doubling
+// other colons
+func nameParameters(sql string, parameters []string, vars map[string]any) (string, map[string]any, error) {
+	args := make(map[string]any, len(parameters))
+	sql, err := placeholders.Replace(sql, parameters, func(name string) string {
+		args[name] = vars[name]
+		return ":" + name
+	}, func(c byte) (string, error) {
+		if c == ':' {
+			return "::", nil
+		}
+		return string(c), nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return expandMembership(sql, vars), args, nil
+}
+
+// expandMembership renders membership in slice parameters, = ANY(:name), as IN (:name) for
+// sqlx.In
+func expandMembership(sql string, vars map[string]any) string {
+	for name, value := range vars {
+		v := reflect.ValueOf(value)
+		if !v.IsValid() || v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+			continue
+		}
+		sql = strings.ReplaceAll(sql, "= ANY(:"+name+")", "IN (:"+name+")")
+	}
+	return sql
+}
@@ -0,0 +1,79 @@
+package celsqlx_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2/celsqlx"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestNamed(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(map[string]pg.Schema{
+			"orders": {
+				{Name: "customer_id", Type: "bigint"},
+				{Name: "status", Type: "text"},
+				{Name: "placed_at", Type: "timestamptz"},
+				{Name: "details", Type: "jsonb"},
+			},
+		})),
+		cel.Variable("order", cel.ObjectType("orders")),
+		cel.Variable("user_id", cel.IntType),
+		cel.Variable("statuses", cel.ListType(cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		source   string
+		opts     []celsqlx.Option
+		wantSQL  string
+		wantArgs map[string]any
+	}{
+		{
+			name:     "no_vars",
+			source:   `order.status == "a:b"`,
+			wantSQL:  `order.status = 'a::b'`,
+			wantArgs: map[string]any{},
+		},
+		{
+			name:     "named",
+			source:   `order.customer_id == user_id || order.status == "open"`,
+			opts:     []celsqlx.Option{celsqlx.WithVars(map[string]any{"user_id": int64(42), "statuses": []string{"x"}})},
+			wantSQL:  `order.customer_id = :user_id OR order.status = 'open'`,
+			wantArgs: map[string]any{"user_id": int64(42)},
+		},
+		{
+			name:   "membership",
+			source: `order.status in statuses && order.customer_id == user_id`,
+			opts: []celsqlx.Option{
+				celsqlx.WithTable(""),
+				celsqlx.WithVars(map[string]any{"user_id": int64(42), "statuses": []string{"open", "paid"}}),
+			},
+			wantSQL:  `status IN (:statuses) AND customer_id = :user_id`,
+			wantArgs: map[string]any{"user_id": int64(42), "statuses": []string{"open", "paid"}},
+		},
+		{
+			name:     "casts",
+			source:   `order.details.total > 10 && order.customer_id == user_id`,
+			opts:     []celsqlx.Option{celsqlx.WithTable("o"), celsqlx.WithVars(map[string]any{"user_id": 1})},
+			wantSQL:  `(o.details->>'total')::::numeric > 10 AND o.customer_id = :user_id`,
+			wantArgs: map[string]any{"user_id": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			gotSQL, gotArgs, err := celsqlx.Named(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSQL, gotSQL)
+			assert.Equal(t, tt.wantArgs, gotArgs)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package celsquirrel exposes CEL filters converted by cel2sql as squirrel Sqlizers, so that
+// they compose with squirrel builders:
+//
+//	expr, err := celsquirrel.New(ast, celsquirrel.WithVars(map[string]any{"org_id": orgID}))
+//	query, args, err := sq.Select("*").From("users").Where(expr).
+//		PlaceholderFormat(sq.Dollar).ToSql()
+package celsquirrel
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/internal/placeholders"
+)
+
+// Expr is a converted filter with its arguments, rendered as ? placeholders. It implements
+// squirrel.Sqlizer.
+type Expr struct {
+	SQL  string
+	Args []any
+}
+
+// ToSql returns the SQL and arguments of the filter
+func (e Expr) ToSql() (string, []any, error) { //nolint:revive // squirrel.Sqlizer
+	return e.SQL, e.Args, nil
+}
+
+// Option configures New
+type Option func(*options)
+
+type options struct {
+	table   *string
+	vars    map[string]any
+	convert []cel2sql.ConvertOption
+}
+
+// WithTable qualifies columns with a table name or alias, or leaves them unqualified if empty.
+// By default columns are qualified with the CEL variables of the tables.
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = &table
+	}
+}
+
+// WithVars binds CEL variables, typically request context, as arguments instead of columns.
+func WithVars(vars map[string]any) Option {
+	return func(o *options) {
+		o.vars = vars
+	}
+}
+
+// WithConvertOptions passes further options to the conversion, e.g. cel2sql.WithSchemas.
+func WithConvertOptions(opts ...cel2sql.ConvertOption) Option {
+	return func(o *options) {
+		o.convert = append(o.convert, opts...)
+	}
+}
+
+// New converts a checked CEL AST to a squirrel expression. Variables bound with WithVars are
+// ? placeholders, which squirrel renumbers with the rest of the query, and every other ?, as in
+// a string literal or the jsonb ? operator, is escaped as ?? so that squirrel keeps it.
+func New(ast *cel.Ast, opts ...Option) (Expr, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var convertOpts []cel2sql.ConvertOption
+	if o.table != nil {
+		convertOpts = append(convertOpts, cel2sql.WithTableAlias(*o.table))
+	}
+	convertOpts = append(convertOpts, o.convert...)
+	convertOpts = append(convertOpts, cel2sql.WithParameters(slices.Sorted(maps.Keys(o.vars))...))
+
+	sql, parameters, err := cel2sql.ConvertWithParameters(ast, convertOpts...)
+	if err != nil {
+		return Expr{}, err
+	}
+	var args []any
+	sql, err = placeholders.Replace(sql, parameters, func(name string) string {
+		args = append(args, o.vars[name])
+		return "?"
+	}, func(c byte) (string, error) {
+		if c == '?' {
+			return "??", nil
+		}
+		return string(c), nil
+	})
+	if err != nil {
+		return Expr{}, err
+	}
+	return Expr{SQL: sql, Args: args}, nil
+}
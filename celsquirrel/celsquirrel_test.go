@@ -0,0 +1,113 @@
+package celsquirrel_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/celsquirrel"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestNew(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"orders": {
+			{Name: "id", Type: "bigint"},
+			{Name: "customer_id", Type: "bigint"},
+			{Name: "status", Type: "text"},
+			{Name: "total", Type: "numeric"},
+			{Name: "attributes", Type: "jsonb"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("order", cel.ObjectType("orders")),
+		cel.Variable("user_id", cel.IntType),
+		cel.Variable("statuses", cel.ListType(cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []celsquirrel.Option
+		want   celsquirrel.Expr
+	}{
+		{
+			name:   "qualified",
+			source: `order.status == "shipped" && order.total > 100.0`,
+			want:   celsquirrel.Expr{SQL: "order.status = 'shipped' AND order.total > 100"},
+		},
+		{
+			name:   "table",
+			source: `order.status == "shipped"`,
+			opts:   []celsquirrel.Option{celsquirrel.WithTable("o")},
+			want:   celsquirrel.Expr{SQL: "o.status = 'shipped'"},
+		},
+		{
+			name:   "vars",
+			source: `order.customer_id == user_id && order.id != user_id`,
+			opts: []celsquirrel.Option{
+				celsquirrel.WithTable(""),
+				celsquirrel.WithVars(map[string]any{"user_id": int64(7)}),
+			},
+			want: celsquirrel.Expr{
+				SQL:  "customer_id = ? AND id != ?",
+				Args: []any{int64(7), int64(7)},
+			},
+		},
+		{
+			name:   "list_var",
+			source: `order.status in statuses`,
+			opts: []celsquirrel.Option{
+				celsquirrel.WithTable(""),
+				celsquirrel.WithVars(map[string]any{"statuses": []string{"new", "paid"}}),
+			},
+			want: celsquirrel.Expr{
+				SQL:  "status = ANY(?)",
+				Args: []any{[]string{"new", "paid"}},
+			},
+		},
+		{
+			name:   "escaped_question_mark",
+			source: `order.status == "why?" && order.customer_id == user_id`,
+			opts: []celsquirrel.Option{
+				celsquirrel.WithTable(""),
+				celsquirrel.WithVars(map[string]any{"user_id": int64(7)}),
+			},
+			want: celsquirrel.Expr{
+				SQL:  "status = 'why??' AND customer_id = ?",
+				Args: []any{int64(7)},
+			},
+		},
+		{
+			name:   "convert_options",
+			source: `order.status == "shipped"`,
+			opts: []celsquirrel.Option{
+				celsquirrel.WithTable(""),
+				celsquirrel.WithConvertOptions(cel2sql.WithFieldNameMapper(func(_, field string) string {
+					return "order_" + field
+				})),
+			},
+			want: celsquirrel.Expr{SQL: "order_status = 'shipped'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := celsquirrel.New(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			sql, args, err := got.ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.SQL, sql)
+			assert.Equal(t, tt.want.Args, args)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertCheckedExprAndAST(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("salaries", cel.ListType(cel.IntType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+		want   string
+	}{
+		{
+			name:   "comparison",
+			source: `name == "a" && age > 10`,
+			want:   "name = 'a' AND age > 10",
+		},
+		{
+			name:   "comprehension",
+			source: `salaries.exists(s, s > 50000)`,
+			want:   "EXISTS (SELECT 1 FROM UNNEST(salaries) AS s WHERE s > 50000)",
+		},
+		{
+			name:   "debug_comments",
+			source: `name == "a" || salaries.exists(s, s > 50000)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithDebugComments()},
+			want:   "/* cel: name == \"a\" */ name = 'a' OR /* cel: salaries.exists(s, s > 50000) */ EXISTS (SELECT 1 FROM UNNEST(salaries) AS s WHERE s > 50000)",
+		},
+		{
+			name:   "parameters",
+			source: `age >= 18`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithLiteralPlaceholders()},
+			want:   "age >= $1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			want, err := cel2sql.Convert(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, want)
+
+			checkedExpr, err := cel.AstToCheckedExpr(ast)
+			require.NoError(t, err)
+			got, err := cel2sql.ConvertCheckedExpr(checkedExpr, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+
+			got, err = cel2sql.ConvertAST(ast.NativeRep(), tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestConvertUnchecked(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("age", cel.IntType))
+	require.NoError(t, err)
+	ast, issues := env.Parse(`age > 10`)
+	require.NoError(t, issues.Err())
+
+	_, err = cel2sql.ConvertAST(ast.NativeRep())
+	require.Error(t, err)
+
+	parsedExpr, err := cel.AstToParsedExpr(ast)
+	require.NoError(t, err)
+	_, err = cel2sql.ConvertCheckedExpr(&exprpb.CheckedExpr{Expr: parsedExpr.GetExpr(), SourceInfo: parsedExpr.GetSourceInfo()})
+	require.Error(t, err)
+
+	_, err = cel2sql.ConvertCheckedExpr(nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,244 @@
+package cel2sql
+
+import (
+	"fmt"
+	"strings"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// columnRef describes a column referenced by an expression.
+type columnRef struct {
+	Table  string // CEL type name of the table or composite type (schema key); empty for bare variables
+	Column string
+}
+
+// String returns the qualified column name, e.g. "users.name"
+func (r columnRef) String() string {
+	if r.Table == "" {
+		return r.Column
+	}
+	return r.Table + "." + r.Column
+}
+
+// walkColumnRefs calls fn for every column referenced by expr. Field selections on
+// message-typed operands are columns of that message type; bare variables that are not
+// tables are columns without a table. Comprehension variables are not columns, but fields
+// selected from them are.
+func (con *converter) walkColumnRefs(expr *exprpb.Expr, scope map[string]bool, fn func(columnRef) error) error {
+	if expr == nil {
+		return nil
+	}
+	switch expr.GetExprKind().(type) {
+	case *exprpb.Expr_IdentExpr:
+		name := expr.GetIdentExpr().GetName()
+		typ := con.getType(expr)
+		if scope[name] || con.isParameter(name) || isMessageType(typ) || typ.GetAbstractType() != nil || typ.GetType() != nil {
+			return nil
+		}
+		return fn(columnRef{Column: name})
+	case *exprpb.Expr_SelectExpr:
+		sel := expr.GetSelectExpr()
+		operand := sel.GetOperand()
+		if typ := con.getType(operand); isMessageType(typ) {
+			if err := fn(columnRef{Table: typ.GetMessageType(), Column: sel.GetField()}); err != nil {
+				return err
+			}
+		}
+		return con.walkColumnRefs(operand, scope, fn)
+	case *exprpb.Expr_CallExpr:
+		call := expr.GetCallExpr()
+		if err := con.walkColumnRefs(call.GetTarget(), scope, fn); err != nil {
+			return err
+		}
+		for _, arg := range call.GetArgs() {
+			if err := con.walkColumnRefs(arg, scope, fn); err != nil {
+				return err
+			}
+		}
+	case *exprpb.Expr_ListExpr:
+		for _, elem := range expr.GetListExpr().GetElements() {
+			if err := con.walkColumnRefs(elem, scope, fn); err != nil {
+				return err
+			}
+		}
+	case *exprpb.Expr_StructExpr:
+		for _, entry := range expr.GetStructExpr().GetEntries() {
+			if err := con.walkColumnRefs(entry.GetMapKey(), scope, fn); err != nil {
+				return err
+			}
+			if err := con.walkColumnRefs(entry.GetValue(), scope, fn); err != nil {
+				return err
+			}
+		}
+	case *exprpb.Expr_ComprehensionExpr:
+		comp := expr.GetComprehensionExpr()
+		if err := con.walkColumnRefs(comp.GetIterRange(), scope, fn); err != nil {
+			return err
+		}
+		if err := con.walkColumnRefs(comp.GetAccuInit(), scope, fn); err != nil {
+			return err
+		}
+		inner := make(map[string]bool, len(scope)+3)
+		for name := range scope {
+			inner[name] = true
+		}
+		inner[comp.GetIterVar()] = true
+		if comp.GetIterVar2() != "" {
+			inner[comp.GetIterVar2()] = true
+		}
+		inner[comp.GetAccuVar()] = true
+		for _, e := range []*exprpb.Expr{comp.GetLoopCondition(), comp.GetLoopStep(), comp.GetResult()} {
+			if err := con.walkColumnRefs(e, inner, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkColumns enforces WithDeniedColumns and WithStrictColumns, returning the first violation
+func (con *converter) checkColumns(expr *exprpb.Expr) error {
+	if len(con.opts.deniedColumns) == 0 && !con.opts.strictColumns {
+		return nil
+	}
+	return con.walkColumnRefs(expr, nil, con.checkColumn)
+}
+
+// checkColumn checks a single column reference against the denylist and, with
+// WithStrictColumns, against the schemas and column allowlist.
+func (con *converter) checkColumn(ref columnRef) error {
+	if matchesColumnPattern(con.opts.deniedColumns, ref) {
+		return fmt.Errorf("column %s is denied", ref)
+	}
+	if con.opts.strictColumns && !con.isColumnInSchemas(ref) && !matchesColumnPattern(con.opts.allowedColumns, ref) {
+		return fmt.Errorf("column %s is not allowed: it is not present in the schema or column allowlist", ref)
+	}
+	return nil
+}
+
+// isMaskedColumnAccess checks if a field selection or variable reference goes through a column
+// masked with WithMaskedColumns, e.g. users.ssn or users.profile.address.street.
+func (con *converter) isMaskedColumnAccess(expr *exprpb.Expr) bool {
+	if len(con.opts.maskedColumns) == 0 {
+		return false
+	}
+	for current := expr; current != nil; {
+		switch current.GetExprKind().(type) {
+		case *exprpb.Expr_SelectExpr:
+			sel := current.GetSelectExpr()
+			if typ := con.getType(sel.GetOperand()); isMessageType(typ) {
+				if matchesColumnPattern(con.opts.maskedColumns, columnRef{Table: typ.GetMessageType(), Column: sel.GetField()}) {
+					return true
+				}
+			}
+			current = sel.GetOperand()
+		case *exprpb.Expr_CallExpr:
+			// Follow index access such as users.addresses[0].street
+			call := current.GetCallExpr()
+			if !isFieldAccessExpression(current) || len(call.GetArgs()) == 0 {
+				return false
+			}
+			current = call.GetArgs()[0]
+		case *exprpb.Expr_IdentExpr:
+			typ := con.getType(current)
+			if isMessageType(typ) || typ.GetAbstractType() != nil || typ.GetType() != nil {
+				return false
+			}
+			return matchesColumnPattern(con.opts.maskedColumns, columnRef{Column: current.GetIdentExpr().GetName()})
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// writeMaskedNull writes the NULL that replaces a masked column. NULLs standing for array and
+// JSON columns are typed and parenthesized so that they can still be indexed, unnested and
+// measured like the column, e.g. (NULL::text[])[1].
+func (con *converter) writeMaskedNull(expr *exprpb.Expr) {
+	typeName := con.maskedColumnType(expr)
+	if typeName == "" {
+		con.str.WriteString("NULL")
+		return
+	}
+	con.str.WriteString("(NULL::")
+	con.str.WriteString(typeName)
+	con.str.WriteString(")")
+}
+
+// maskedColumnType returns the SQL type of a masked array or JSON column, from its schema or
+// else from its CEL type, or "" for other columns
+func (con *converter) maskedColumnType(expr *exprpb.Expr) string {
+	if field, found := con.findField(expr); found {
+		switch {
+		case field.Repeated:
+			return field.Type + "[]"
+		case isJSONType(field.Type):
+			return field.Type
+		}
+		return ""
+	}
+	typ := con.getType(expr)
+	switch {
+	case isListType(typ):
+		if elem := primitiveSQLType(typ.GetListType().GetElemType()); elem != "" {
+			return elem + "[]"
+		}
+	case isMapType(typ):
+		return "jsonb"
+	}
+	return ""
+}
+
+// primitiveSQLType returns the PostgreSQL type of values of a primitive CEL type, or "" for
+// other types
+func primitiveSQLType(typ *exprpb.Type) string {
+	switch typ.GetPrimitive() {
+	case exprpb.Type_BOOL:
+		return "boolean"
+	case exprpb.Type_INT64, exprpb.Type_UINT64:
+		return "bigint"
+	case exprpb.Type_DOUBLE:
+		return "double precision"
+	case exprpb.Type_STRING:
+		return "text"
+	case exprpb.Type_BYTES:
+		return "bytea"
+	}
+	return ""
+}
+
+// isColumnInSchemas checks if the column exists in the schemas supplied with WithSchemas
+func (con *converter) isColumnInSchemas(ref columnRef) bool {
+	if ref.Table == "" {
+		return false
+	}
+	schema, found := con.findSchema(ref.Table)
+	if !found {
+		return false
+	}
+	for _, field := range schema {
+		if field.Name == ref.Column {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesColumnPattern checks a column against patterns of the form "table.column",
+// "*.column", "table.*" or a bare "column" for variables without a table.
+func matchesColumnPattern(patterns []string, ref columnRef) bool {
+	for _, pattern := range patterns {
+		table, column := "", pattern
+		if i := strings.LastIndex(pattern, "."); i >= 0 {
+			table, column = pattern[:i], pattern[i+1:]
+		} else if ref.Table != "" {
+			continue
+		}
+		if (table == "*" || table == ref.Table) && (column == "*" || column == ref.Column) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func newColumnsTestEnv(t *testing.T) (*cel.Env, map[string]pg.Schema) {
+	t.Helper()
+	schemas := map[string]pg.Schema{
+		"users": {
+			{Name: "name", Type: "text"},
+			{Name: "age", Type: "integer"},
+			{Name: "ssn", Type: "text"},
+			{Name: "password_hash", Type: "text"},
+			{Name: "preferences", Type: "jsonb"},
+			{Name: "tags", Type: "text", Repeated: true},
+		},
+		"orders": {
+			{Name: "total", Type: "double precision"},
+			{Name: "password_hash", Type: "text"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("users", cel.ObjectType("users")),
+		cel.Variable("orders", cel.ObjectType("orders")),
+		cel.Variable("employees", cel.ListType(cel.ObjectType("users"))),
+		cel.Variable("region", cel.StringType),
+	)
+	require.NoError(t, err)
+	return env, schemas
+}
+
+func TestConvertStrictColumns(t *testing.T) {
+	env, schemas := newColumnsTestEnv(t)
+
+	tests := []struct {
+		name    string
+		source  string
+		opts    []cel2sql.ConvertOption
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "schema_columns",
+			source: `users.name == "a" && users.age > 1`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas), cel2sql.WithStrictColumns()},
+			want:   "users.name = 'a' AND users.age > 1",
+		},
+		{
+			name:    "bare_variable_rejected",
+			source:  `users.name == region`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas), cel2sql.WithStrictColumns()},
+			wantErr: "column region is not allowed",
+		},
+		{
+			name:   "bare_variable_allowed",
+			source: `users.name == region`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas), cel2sql.WithStrictColumns("region")},
+			want:   "users.name = region",
+		},
+		{
+			name:    "allowlist_only",
+			source:  `users.name == "a" && users.age > 1`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithStrictColumns("users.name")},
+			wantErr: "column users.age is not allowed",
+		},
+		{
+			name:   "allowlist_table_wildcard",
+			source: `users.name == "a" && users.age > 1`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithStrictColumns("users.*")},
+			want:   "users.name = 'a' AND users.age > 1",
+		},
+		{
+			name:    "allowlist_column_wildcard",
+			source:  `users.name == "a" && orders.total > 1.0`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithStrictColumns("*.name")},
+			wantErr: "column orders.total is not allowed",
+		},
+		{
+			name:   "json_keys_are_not_columns",
+			source: `users.preferences.theme == "dark"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithStrictColumns("users.preferences")},
+			want:   "users.preferences->>'theme' = 'dark'",
+		},
+		{
+			name:   "comprehension_variable",
+			source: `users.tags.exists(t, t == "x")`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithStrictColumns("users.tags")},
+			want:   "EXISTS (SELECT 1 FROM UNNEST(users.tags) AS t WHERE t = 'x')",
+		},
+		{
+			name:    "comprehension_element_field",
+			source:  `employees.exists(e, e.ssn == "x")`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithStrictColumns("employees", "users.name")},
+			wantErr: "column users.ssn is not allowed",
+		},
+		{
+			name:   "no_enforcement_by_default",
+			source: `users.ssn == "x"`,
+			want:   "users.ssn = 'x'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertDeniedAndMaskedColumns(t *testing.T) {
+	env, schemas := newColumnsTestEnv(t)
+
+	tests := []struct {
+		name    string
+		source  string
+		opts    []cel2sql.ConvertOption
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "denied_column",
+			source:  `users.name == "a" || users.ssn == "123"`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithDeniedColumns("users.ssn")},
+			wantErr: "column users.ssn is denied",
+		},
+		{
+			name:    "denied_wildcard_table",
+			source:  `orders.password_hash == "x"`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithDeniedColumns("*.password_hash")},
+			wantErr: "column orders.password_hash is denied",
+		},
+		{
+			name:    "denied_overrides_schema",
+			source:  `users.ssn == "123"`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas), cel2sql.WithStrictColumns(), cel2sql.WithDeniedColumns("users.ssn")},
+			wantErr: "column users.ssn is denied",
+		},
+		{
+			name:    "denied_in_comprehension",
+			source:  `employees.exists(e, e.password_hash == "x")`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithDeniedColumns("*.password_hash")},
+			wantErr: "column users.password_hash is denied",
+		},
+		{
+			name:   "not_denied",
+			source: `users.name == "a"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithDeniedColumns("users.ssn")},
+			want:   "users.name = 'a'",
+		},
+		{
+			name:   "masked_column",
+			source: `users.name == "a" || users.ssn == "123"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("users.ssn")},
+			want:   "users.name = 'a' OR NULL = '123'",
+		},
+		{
+			name:   "masked_json_column",
+			source: `users.preferences.theme == "dark"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("users.preferences")},
+			want:   "NULL = 'dark'",
+		},
+		{
+			name:   "masked_has",
+			source: `has(users.ssn)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("*.ssn")},
+			want:   "FALSE",
+		},
+		{
+			name:   "masked_variable",
+			source: `region == "eu"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("region")},
+			want:   "NULL = 'eu'",
+		},
+		{
+			name:   "masked_in_comprehension",
+			source: `employees.exists(e, e.ssn == "x")`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("users.ssn")},
+			want:   "EXISTS (SELECT 1 FROM UNNEST(employees) AS e WHERE NULL = 'x')",
+		},
+		{
+			name:   "masked_array_in",
+			source: `"a" in users.tags`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("users.tags")},
+			want:   "'a' = ANY((NULL::text[]))",
+		},
+		{
+			name:   "masked_array_index",
+			source: `users.tags[0] == "x"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("users.tags")},
+			want:   "(NULL::text[])[1] = 'x'",
+		},
+		{
+			name:   "masked_array_size",
+			source: `size(users.tags) > 0`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("users.tags")},
+			want:   "ARRAY_LENGTH((NULL::text[]), 1) > 0",
+		},
+		{
+			name:   "masked_array_comprehension",
+			source: `users.tags.exists(t, t == "x")`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas), cel2sql.WithMaskedColumns("users.tags")},
+			want:   "EXISTS (SELECT 1 FROM UNNEST((NULL::text[])) AS t WHERE t = 'x')",
+		},
+		{
+			name:   "masked_json_in",
+			source: `"a" in users.preferences.tags`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("users.preferences")},
+			want:   "'a' = ANY(ARRAY(SELECT jsonb_array_elements_text(NULL)))",
+		},
+		{
+			name:   "masked_json_index",
+			source: `users.preferences.tags[0] == "x"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithMaskedColumns("users.preferences")},
+			want:   "NULL = 'x'",
+		},
+		{
+			name:   "masked_json_comprehension",
+			source: `users.preferences.exists(k, k == "x")`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas), cel2sql.WithMaskedColumns("users.preferences")},
+			want:   "EXISTS (SELECT 1 FROM jsonb_array_elements((NULL::jsonb)) AS k WHERE (NULL::jsonb) IS NOT NULL AND jsonb_typeof((NULL::jsonb)) = 'array' AND k = 'x')",
+		},
+		{
+			name:   "masked_json_path_comprehension",
+			source: `users.preferences.tags.exists(t, t == "x")`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas), cel2sql.WithMaskedColumns("users.preferences")},
+			want:   "EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(NULL) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(NULL))) WHEN 'array' THEN NULL END) AS t WHERE t = 'x')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -52,7 +52,7 @@ func (ct ComprehensionType) String() string {
 type ComprehensionInfo struct {
 	Type      ComprehensionType
 	IterVar   string
-	IndexVar  string // for two-variable comprehensions
+	IndexVar  string // index variable of two-variable comprehensions
 	AccuVar   string
 	HasFilter bool
 	IsTwoVar  bool
@@ -81,10 +81,12 @@ func (con *converter) analyzeComprehensionPattern(comp *exprpb.Expr_Comprehensio
 		AccuVar: comp.GetAccuVar(),
 	}
 
-	// Check for two-variable comprehensions
+	// Two-variable comprehensions over lists bind the index of each element to the first
+	// variable and the element to the second
 	if comp.GetIterVar2() != "" {
 		info.IsTwoVar = true
-		info.IndexVar = comp.GetIterVar2()
+		info.IndexVar = comp.GetIterVar()
+		info.IterVar = comp.GetIterVar2()
 	}
 
 	// Check accumulator initialization to determine type
@@ -146,7 +148,7 @@ func (con *converter) analyzeComprehensionPattern(comp *exprpb.Expr_Comprehensio
 
 	// If we can't identify the pattern, mark as unknown for now
 	info.Type = ComprehensionUnknown
-	return info, fmt.Errorf("unrecognized comprehension pattern for %s", comp.String())
+	return info, fmt.Errorf("%w: unrecognized pattern for %s", ErrUnsupportedComprehension, comp.String())
 }
 
 // Helper functions to identify patterns in comprehension expressions
@@ -314,3 +316,114 @@ func (con *converter) extractFilterAndTransformFromConditionalStep(step *exprpb.
 	}
 	return nil, nil
 }
+
+// filterComprehension returns expr as a filter comprehension if it keeps the elements of a list
+// matching a predicate, as filter(x, p) does, or transforms them, as map(x, p, t) does, which
+// keeps as many elements. Comprehensions converted by a macro set with WithMacro are not.
+func (con *converter) filterComprehension(expr *exprpb.Expr) *ComprehensionInfo {
+	if expr.GetComprehensionExpr() == nil {
+		return nil
+	}
+	if _, _, isMacro := con.macroCall(expr); isMacro {
+		return nil
+	}
+	info, err := con.identifyComprehension(expr)
+	// The positions of the elements change when the filters are merged
+	if err != nil || info.IsTwoVar {
+		return nil
+	}
+	switch {
+	case info.Type == ComprehensionFilter:
+		return info
+	case info.Type == ComprehensionMap && info.HasFilter:
+		return &ComprehensionInfo{
+			Type:      ComprehensionFilter,
+			IterVar:   info.IterVar,
+			AccuVar:   info.AccuVar,
+			Transform: info.Transform,
+			Predicate: info.Filter,
+		}
+	}
+	return nil
+}
+
+// checkIndexRange rejects two-variable comprehensions over maps, whose first variable is a key
+// rather than an index
+func (con *converter) checkIndexRange(iterRange *exprpb.Expr, info *ComprehensionInfo) error {
+	if info.IsTwoVar && (con.getType(iterRange).GetMapType() != nil || con.isJSONObject(iterRange)) {
+		return fmt.Errorf("%w: two-variable %s over a map", ErrUnsupportedComprehension, info.Type)
+	}
+	return nil
+}
+
+// isCompositeArray checks if expr is an array of composite values, which UNNEST expands into
+// their fields
+func (con *converter) isCompositeArray(expr *exprpb.Expr) bool {
+	return isMessageType(con.getType(expr).GetListType().GetElemType())
+}
+
+// bindIndexVar binds the index variable of a two-variable comprehension over iterRange to the
+// 1-based ordinality column written by writeIterAlias while its body is converted, and returns
+// a function restoring the enclosing bindings. The element variable shadows an enclosing index
+// variable of the same name.
+func (con *converter) bindIndexVar(iterRange *exprpb.Expr, iterVar, indexVar string) func() {
+	if con.indexVars == nil {
+		con.indexVars = make(map[string]string)
+	}
+	prevIter, iterBound := con.indexVars[iterVar]
+	prevIndex, indexBound := con.indexVars[indexVar]
+	delete(con.indexVars, iterVar)
+	if indexVar != "" {
+		if con.isCompositeArray(iterRange) {
+			con.indexVars[indexVar] = iterVar + ".ordinality"
+		} else {
+			con.indexVars[indexVar] = indexVar
+		}
+	}
+	return func() {
+		delete(con.indexVars, iterVar)
+		delete(con.indexVars, indexVar)
+		if iterBound {
+			con.indexVars[iterVar] = prevIter
+		}
+		if indexBound {
+			con.indexVars[indexVar] = prevIndex
+		}
+	}
+}
+
+// writeIterAlias writes the alias of the elements of iterRange in the FROM clause of a
+// comprehension. The elements of two-variable comprehensions are numbered in an ordinality
+// column named after the index variable: UNNEST(list) WITH ORDINALITY AS v(v, i). UNNEST
+// expands arrays of composite values into their fields, which keep their names, so their
+// ordinality column keeps its name too.
+func (con *converter) writeIterAlias(iterRange *exprpb.Expr, iterVar, indexVar string) {
+	if indexVar == "" {
+		con.str.WriteString(" AS ")
+		con.str.WriteString(iterVar)
+		return
+	}
+	con.str.WriteString(" WITH ORDINALITY AS ")
+	con.str.WriteString(iterVar)
+	if con.isCompositeArray(iterRange) {
+		return
+	}
+	con.str.WriteString("(")
+	con.str.WriteString(iterVar)
+	con.str.WriteString(", ")
+	con.str.WriteString(indexVar)
+	con.str.WriteString(")")
+}
+
+// writeIndexVar writes the 0-based CEL index of a two-variable comprehension from the 1-based
+// ordinality column it is bound to, reporting whether name is an index variable in scope
+func (con *converter) writeIndexVar(name string) bool {
+	column, found := con.indexVars[name]
+	if !found {
+		return false
+	}
+	con.str.WriteString("(")
+	con.str.WriteString(column)
+	con.str.WriteString(" - 1)")
+	return true
+}
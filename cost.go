@@ -0,0 +1,112 @@
+package cel2sql
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/overloads"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// Weights used to compute Cost.Total
+const (
+	subqueryCost       = 10
+	regexMatchCost     = 5
+	sequentialScanCost = 3
+)
+
+// Cost is a static estimate of how expensive the SQL generated for an expression is to execute.
+type Cost struct {
+	Subqueries      int // comprehensions and JSON array membership tests, each rendered as a subquery
+	RegexMatches    int // matches() calls, rendered with the ~ or ~* operator
+	SequentialScans int // predicates that cannot use a plain index, e.g. contains(), endsWith() or functions applied to columns
+	Total           int // weighted sum of the above
+}
+
+// Estimate scores an expression before conversion so that callers can reject overly
+// expensive filters without running EXPLAIN against the database.
+func Estimate(ast *cel.Ast, opts ...ConvertOption) (Cost, error) {
+	checkedExpr, err := cel.AstToCheckedExpr(ast)
+	if err != nil {
+		return Cost{}, err
+	}
+	con := &converter{
+		typeMap: checkedExpr.TypeMap,
+	}
+	for _, opt := range opts {
+		opt(&con.opts)
+	}
+	if err := con.checkDepth(checkedExpr.Expr); err != nil {
+		return Cost{}, err
+	}
+
+	return con.estimate(checkedExpr.Expr), nil
+}
+
+// estimate computes the cost of expr
+func (con *converter) estimate(expr *exprpb.Expr) Cost {
+	var cost Cost
+	stack := []*exprpb.Expr{expr}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		con.estimateExpr(node, &cost)
+		stack = append(stack, childExprs(node)...)
+	}
+	cost.Total = cost.Subqueries*subqueryCost + cost.RegexMatches*regexMatchCost + cost.SequentialScans*sequentialScanCost
+	return cost
+}
+
+// estimateExpr adds the cost of a single node, excluding its children
+func (con *converter) estimateExpr(expr *exprpb.Expr, cost *Cost) {
+	if expr.GetComprehensionExpr() != nil {
+		cost.Subqueries++
+		return
+	}
+	call := expr.GetCallExpr()
+	if call == nil {
+		return
+	}
+	switch call.GetFunction() {
+	case overloads.Matches:
+		cost.RegexMatches++
+	case overloads.Contains, overloads.EndsWith,
+		overloads.TypeConvertBool, overloads.TypeConvertBytes, overloads.TypeConvertDouble,
+		overloads.TypeConvertInt, overloads.TypeConvertString, overloads.TypeConvertUint,
+		overloads.TimeGetFullYear, overloads.TimeGetMonth, overloads.TimeGetDate,
+		overloads.TimeGetHours, overloads.TimeGetMinutes, overloads.TimeGetSeconds,
+		overloads.TimeGetMilliseconds, overloads.TimeGetDayOfYear, overloads.TimeGetDayOfMonth,
+		overloads.TimeGetDayOfWeek, overloads.Size:
+		if con.referencesColumn(call) {
+			cost.SequentialScans++
+		}
+	case operators.In, operators.OldIn:
+		if args := call.GetArgs(); len(args) == 2 && isFieldAccessExpression(args[1]) &&
+			con.isJSONArrayField(args[1]) && !con.isJSONArrayColumn(args[1]) {
+			cost.Subqueries++
+		}
+	case operators.Greater, operators.GreaterEquals, operators.Less, operators.LessEquals, operators.Equals, operators.NotEquals:
+		// JSON text compared with numbers or booleans is cast for every row
+		if args := call.GetArgs(); len(args) == 2 && (con.jsonComparisonCast(call.GetFunction(), args[0], con.getType(args[1])) != "" ||
+			con.jsonComparisonCast(call.GetFunction(), args[1], con.getType(args[0])) != "") {
+			cost.SequentialScans++
+		}
+	}
+}
+
+// referencesColumn checks if a call is applied to anything other than literals
+func (con *converter) referencesColumn(call *exprpb.Expr_Call) bool {
+	operands := append([]*exprpb.Expr{call.GetTarget()}, call.GetArgs()...)
+	for len(operands) > 0 {
+		expr := operands[len(operands)-1]
+		operands = operands[:len(operands)-1]
+		if expr == nil {
+			continue
+		}
+		switch expr.GetExprKind().(type) {
+		case *exprpb.Expr_IdentExpr, *exprpb.Expr_SelectExpr, *exprpb.Expr_ComprehensionExpr:
+			return true
+		}
+		operands = append(operands, childExprs(expr)...)
+	}
+	return false
+}
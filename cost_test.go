@@ -0,0 +1,62 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestEstimate(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("tags", cel.ListType(cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   cel2sql.Cost
+	}{
+		{
+			name:   "simple_comparison",
+			source: `name == "a" && age > 10`,
+			want:   cel2sql.Cost{},
+		},
+		{
+			name:   "regex",
+			source: `name.matches("^a.*")`,
+			want:   cel2sql.Cost{RegexMatches: 1, Total: 5},
+		},
+		{
+			name:   "contains_on_column",
+			source: `name.contains("abc") || name.endsWith("z")`,
+			want:   cel2sql.Cost{SequentialScans: 2, Total: 6},
+		},
+		{
+			name:   "contains_on_literal",
+			source: `"abc".contains("b")`,
+			want:   cel2sql.Cost{},
+		},
+		{
+			name:   "nested_comprehensions",
+			source: `tags.exists(t, tags.all(u, u.matches(t)))`,
+			want:   cel2sql.Cost{Subqueries: 2, RegexMatches: 1, Total: 25},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Estimate(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package cel2sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// markDebugFragments selects the expressions annotated with WithDebugComments: comprehensions
+// and the operands of && and || chains.
+func (con *converter) markDebugFragments(expr *exprpb.Expr) {
+	if con.debugFragments == nil {
+		con.debugFragments = make(map[int64]bool)
+	}
+	stack := []*exprpb.Expr{expr}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node.GetComprehensionExpr() != nil {
+			con.debugFragments[node.GetId()] = true
+		}
+		if fun := node.GetCallExpr().GetFunction(); fun == operators.LogicalAnd || fun == operators.LogicalOr {
+			for _, arg := range node.GetCallExpr().GetArgs() {
+				if arg.GetCallExpr().GetFunction() != fun {
+					con.debugFragments[arg.GetId()] = true
+				}
+			}
+		}
+		stack = append(stack, con.convertedChildren(node)...)
+	}
+}
+
+// writeDebugComment writes the CEL source of expr as a SQL comment if it is a debug fragment
+func (con *converter) writeDebugComment(expr *exprpb.Expr) {
+	if !con.debugFragments[expr.GetId()] {
+		return
+	}
+	source := con.celSource(expr)
+	if source == "" {
+		return
+	}
+	if con.opts.literalPlaceholders || len(con.opts.parameters) > 0 {
+		// Keep the values of placeholders out of the SQL, and the SQL the same for any value
+		source = redactLiterals(source)
+	}
+	// PostgreSQL block comments nest, so neither delimiter may appear inside
+	source = strings.ReplaceAll(source, "/*", "/ *")
+	source = strings.ReplaceAll(source, "*/", "* /")
+	con.str.WriteString("/* cel: ")
+	con.str.WriteString(source)
+	con.str.WriteString(" */ ")
+}
+
+// redactLiterals replaces the string, bytes and number literals in CEL source with ?
+func redactLiterals(source string) string {
+	var b strings.Builder
+	for i := 0; i < len(source); {
+		c := source[i]
+		startsIdent := i == 0 || !isIdentByte(source[i-1])
+		switch {
+		case c == '"' || c == '\'' ||
+			startsIdent && (c == 'b' || c == 'B') && i+1 < len(source) && (source[i+1] == '"' || source[i+1] == '\''):
+			if c == 'b' || c == 'B' {
+				i++
+			}
+			quote := source[i]
+			for i++; i < len(source) && source[i] != quote; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+			i++
+			b.WriteByte('?')
+		case c >= '0' && c <= '9' && startsIdent:
+			for i++; i < len(source); i++ {
+				next := source[i]
+				exponentSign := (next == '+' || next == '-') && (source[i-1] == 'e' || source[i-1] == 'E')
+				if !isIdentByte(next) && next != '.' && !exponentSign {
+					break
+				}
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// isIdentByte checks if c can be part of a CEL identifier
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// celSource renders expr back to CEL source text, or returns an empty string if it cannot
+func (con *converter) celSource(expr *exprpb.Expr) string {
+	if expr.GetComprehensionExpr() != nil {
+		info, err := con.identifyComprehension(expr)
+		if err != nil {
+			return ""
+		}
+		target := con.celSource(expr.GetComprehensionExpr().GetIterRange())
+		args := []string{info.IterVar}
+		if info.IsTwoVar {
+			args = []string{info.IndexVar, info.IterVar}
+		}
+		for _, e := range []*exprpb.Expr{info.Predicate, info.Filter, info.Transform} {
+			if e == nil {
+				continue
+			}
+			arg := con.celSource(e)
+			if arg == "" {
+				return ""
+			}
+			args = append(args, arg)
+		}
+		if target == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s.%s(%s)", target, info.Type, strings.Join(args, ", "))
+	}
+	native, err := celast.ProtoToExpr(expr)
+	if err != nil {
+		return ""
+	}
+	source, err := cel.ExprToString(native, con.source())
+	if err != nil {
+		return ""
+	}
+	return source
+}
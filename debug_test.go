@@ -0,0 +1,105 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertDebugComments(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("salaries", cel.ListType(cel.IntType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "single_expression",
+			source: `age > 10`,
+			want:   "age > 10",
+		},
+		{
+			name:   "and_chain",
+			source: `name == "a" && age > 10 && age < 20`,
+			want:   "/* cel: name == \"a\" */ name = 'a' AND /* cel: age > 10 */ age > 10 AND /* cel: age < 20 */ age < 20",
+		},
+		{
+			name:   "comprehension",
+			source: `salaries.exists(s, s > 50000)`,
+			want:   "/* cel: salaries.exists(s, s > 50000) */ EXISTS (SELECT 1 FROM UNNEST(salaries) AS s WHERE s > 50000)",
+		},
+		{
+			name:   "comment_delimiters_escaped",
+			source: `name == "*/ DROP TABLE users; /*" || age > 1`,
+			want:   "/* cel: name == \"* / DROP TABLE users; / *\" */ name = '*/ DROP TABLE users; /*' OR /* cel: age > 1 */ age > 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithDebugComments())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertDebugCommentsWithPlaceholders(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("min_age", cel.IntType),
+		cel.Variable("names", cel.ListType(cel.StringType)),
+		cel.Variable("ratio", cel.DoubleType),
+		cel.Variable("digest", cel.BytesType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+		want   string
+	}{
+		{
+			name:   "literal_placeholders",
+			source: `name == "secret" && age > 10 && ratio < 2.5e+1`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithLiteralPlaceholders()},
+			want:   "/* cel: name == ? */ name = $1 AND /* cel: age > ? */ age > $2 AND /* cel: ratio < ? */ ratio < $3",
+		},
+		{
+			name:   "comprehension",
+			source: `names.exists(n, n == "x\"y") || digest == b"\x00"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithLiteralPlaceholders()},
+			want:   "/* cel: names.exists(n, n == ?) */ EXISTS (SELECT 1 FROM UNNEST(names) AS n WHERE n = $1) OR /* cel: digest == ? */ digest = $2",
+		},
+		{
+			name:   "parameters",
+			source: `name == 'x' || age >= min_age`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithParameters("min_age")},
+			want:   "/* cel: name == ? */ name = 'x' OR /* cel: age >= min_age */ age >= $1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, append(tt.opts, cel2sql.WithDebugComments())...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
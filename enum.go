@@ -0,0 +1,23 @@
+package cel2sql
+
+import (
+	"fmt"
+	"slices"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// enumCast validates a string literal compared with an enum column and returns the enum type it
+// is cast to, so that PostgreSQL compares it in enum sort order
+func enumCast(field pg.FieldSchema, value *exprpb.Constant) (string, error) {
+	label, ok := value.GetConstantKind().(*exprpb.Constant_StringValue)
+	if !ok {
+		return "", nil
+	}
+	if !slices.Contains(field.Enum, label.StringValue) {
+		return "", fmt.Errorf("%w: %q is not a label of %s", ErrInvalidEnumValue, label.StringValue, field.Type)
+	}
+	return field.Type, nil
+}
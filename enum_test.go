@@ -0,0 +1,99 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertEnumComparisons(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"orders": {
+			{Name: "id", Type: "integer"},
+			{Name: "status", Type: "order_status", Enum: []string{"pending", "shipped", "delivered"}},
+			{Name: "history", Type: "order_status", Repeated: true, Enum: []string{"pending", "shipped", "delivered"}},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("order", cel.ObjectType("orders")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		opts    []cel2sql.ConvertOption
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "equals",
+			source: `order.status == "shipped"`,
+			want:   "order.status = 'shipped'::order_status",
+		},
+		{
+			name:   "literal_first",
+			source: `"pending" != order.status`,
+			want:   "'pending'::order_status != order.status",
+		},
+		{
+			name:   "ordering",
+			source: `order.status < "delivered"`,
+			want:   "order.status < 'delivered'::order_status",
+		},
+		{
+			name:   "in_list",
+			source: `order.status in ["pending", "shipped"]`,
+			want:   "order.status = ANY(ARRAY['pending'::order_status, 'shipped'::order_status])",
+		},
+		{
+			name:   "array_membership",
+			source: `"shipped" in order.history`,
+			want:   "'shipped'::order_status = ANY(order.history)",
+		},
+		{
+			name:   "placeholders",
+			source: `order.status == "shipped"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithLiteralPlaceholders()},
+			want:   "order.status = $1::order_status",
+		},
+		{
+			name:    "invalid_label",
+			source:  `order.status == "lost"`,
+			wantErr: cel2sql.ErrInvalidEnumValue,
+		},
+		{
+			name:    "invalid_label_in_list",
+			source:  `order.status in ["pending", "lost"]`,
+			wantErr: cel2sql.ErrInvalidEnumValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, append(tt.opts, cel2sql.WithSchemas(schemas))...)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// Without schemas, enum columns are compared as text
+	ast, issues := env.Compile(`order.status == "lost"`)
+	require.NoError(t, issues.Err())
+	got, err := cel2sql.Convert(ast)
+	require.NoError(t, err)
+	assert.Equal(t, "order.status = 'lost'", got)
+}
@@ -0,0 +1,70 @@
+package cel2sql
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/spandigital/cel2sql/v2/sqltypes"
+)
+
+// tableLister is implemented by the type providers of cel2sql, which know their tables
+type tableLister interface {
+	Tables() []string
+}
+
+// NewEnv creates a CEL environment for expressions converted to SQL. It resolves table types
+// with provider, declares the date and time functions and the array functions of the sqltypes
+// package, and declares a variable for each table known to provider, named like the table, e.g.
+// users.name for the "users" table. Further options, such as TableVariable or RowVariable to
+// declare a table under another name or cel.Variable for request parameters, are applied last.
+func NewEnv(provider types.Provider, opts ...cel.EnvOption) (*cel.Env, error) {
+	envOpts := []cel.EnvOption{
+		cel.CustomTypeProvider(provider),
+		sqltypes.SQLFunctionDeclarations,
+		sqltypes.ArrayFunctionDeclarations,
+	}
+	if lister, ok := provider.(tableLister); ok {
+		envOpts = append(envOpts, tableVariables(lister))
+	}
+	return cel.NewEnv(append(envOpts, opts...)...)
+}
+
+// TableVariable declares a variable of the type of a table, e.g. TableVariable("user", "users")
+// for filters written as user.name == "Alice".
+func TableVariable(name, table string) cel.EnvOption {
+	return cel.Variable(name, cel.ObjectType(table))
+}
+
+// RowVariable declares a variable named row of the type of a table, for filters on a single
+// table written as row.name == "Alice".
+func RowVariable(table string) cel.EnvOption {
+	return TableVariable("row", table)
+}
+
+// TableVariables declares a variable for each table known to provider, named like the table,
+// e.g. users.name for the "users" table, so that the declarations cannot drift from the loaded
+// schemas. Tables loaded after the environment is created are not declared. The type providers
+// of cel2sql all list their tables; other providers are an error.
+func TableVariables(provider types.Provider) cel.EnvOption {
+	lister, ok := provider.(tableLister)
+	if !ok {
+		return func(*cel.Env) (*cel.Env, error) {
+			return nil, fmt.Errorf("type provider %T does not list its tables", provider)
+		}
+	}
+	return tableVariables(lister)
+}
+
+func tableVariables(lister tableLister) cel.EnvOption {
+	return func(env *cel.Env) (*cel.Env, error) {
+		var err error
+		for _, table := range lister.Tables() {
+			if env, err = TableVariable(table, table)(env); err != nil {
+				return nil, err
+			}
+		}
+		return env, nil
+	}
+}
@@ -0,0 +1,105 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func testProvider() pg.TypeProvider {
+	return pg.NewTypeProvider(map[string]pg.Schema{
+		"users": {
+			{Name: "name", Type: "text"},
+			{Name: "birthday", Type: "date"},
+			{Name: "created_at", Type: "timestamptz"},
+		},
+		"analytics.events": {
+			{Name: "kind", Type: "text"},
+		},
+	})
+}
+
+func TestNewEnv(t *testing.T) {
+	env, err := cel2sql.NewEnv(testProvider(),
+		cel2sql.TableVariable("user", "users"),
+		cel.Variable("min_name", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "table_variable",
+			source: `users.name == "Alice"`,
+			want:   "users.name = 'Alice'",
+		},
+		{
+			name:   "qualified_table_variable",
+			source: `analytics.events.kind == "click"`,
+			want:   "analytics.events.kind = 'click'",
+		},
+		{
+			name:   "renamed_table",
+			source: `user.name >= min_name`,
+			want:   "user.name >= min_name",
+		},
+		{
+			name:   "date_functions",
+			source: `users.birthday > current_date() - interval(18, YEAR)`,
+			want:   "users.birthday > CURRENT_DATE() - INTERVAL 18 YEAR",
+		},
+		{
+			name:   "date_constructors",
+			source: `users.birthday >= date("2000-01-01") && users.birthday.getFullYear() < 2010`,
+			want:   "users.birthday >= DATE('2000-01-01') AND EXTRACT(YEAR FROM users.birthday) < 2010",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTableVariables(t *testing.T) {
+	provider := testProvider()
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel2sql.TableVariables(provider),
+		cel2sql.RowVariable("users"),
+	)
+	require.NoError(t, err)
+
+	for source, want := range map[string]string{
+		`users.name == "Alice"`:            "users.name = 'Alice'",
+		`analytics.events.kind == "click"`: "analytics.events.kind = 'click'",
+		`row.name == "Alice"`:              "row.name = 'Alice'",
+	} {
+		ast, issues := env.Compile(source)
+		require.NoError(t, issues.Err())
+
+		got, err := cel2sql.Convert(ast)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, issues := env.Compile(`orders.total > 1`)
+	assert.Error(t, issues.Err())
+
+	_, err = cel.NewEnv(cel2sql.TableVariables(types.NewEmptyRegistry()))
+	assert.Error(t, err)
+}
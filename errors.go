@@ -0,0 +1,32 @@
+package cel2sql
+
+import "errors"
+
+// Sentinel errors wrapped by the errors returned from Convert and Validate, so that callers
+// can branch with errors.Is instead of matching error strings.
+var (
+	// ErrUnsupportedFunction is returned for function calls that have no SQL equivalent.
+	ErrUnsupportedFunction = errors.New("unsupported function")
+	// ErrUnsupportedComprehension is returned for comprehensions and macros that cannot be converted.
+	ErrUnsupportedComprehension = errors.New("unsupported comprehension")
+	// ErrUnknownType is returned when an operand has a type the converter cannot handle.
+	ErrUnknownType = errors.New("unsupported type")
+	// ErrUnsupportedRegex is returned with WithStrictRegex for patterns that cannot be
+	// converted to PostgreSQL regular expressions.
+	ErrUnsupportedRegex = errors.New("unsupported regular expression")
+	// ErrUnsafeIdentifier is returned for field names, map keys and type names that are not
+	// safe to embed in SQL.
+	ErrUnsafeIdentifier = errors.New("unsafe identifier")
+	// ErrInvalidEnumValue is returned when an enum column is compared with a string that is not
+	// one of the labels of its enum type.
+	ErrInvalidEnumValue = errors.New("invalid enum value")
+	// ErrLiteralOutOfRange is returned when a column is compared for equality with a literal that
+	// exceeds the precision, scale or length of its type, and so can never match.
+	ErrLiteralOutOfRange = errors.New("literal out of range")
+	// ErrInvalidLiteral is returned for literals that CEL fails to convert, such as malformed
+	// timestamps.
+	ErrInvalidLiteral = errors.New("invalid literal")
+	// ErrMissingParameter is returned by Plan.SQLWithParams when no value is given for a
+	// parameter.
+	ErrMissingParameter = errors.New("missing parameter value")
+)
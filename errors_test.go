@@ -0,0 +1,61 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertSentinelErrors(t *testing.T) {
+	env, err := cel.NewEnv(
+		ext.TwoVarComprehensions(),
+		cel.Variable("name", cel.StringType),
+		cel.Variable("tags", cel.ListType(cel.StringType)),
+		cel.Variable("attrs", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Function("ns.custom", cel.Overload("ns_custom_string", []*cel.Type{cel.StringType}, cel.BoolType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		wantErr error
+	}{
+		{
+			name:    "unsupported_function",
+			source:  `ns.custom(name)`,
+			wantErr: cel2sql.ErrUnsupportedFunction,
+		},
+		{
+			name:    "unsupported_comprehension",
+			source:  `tags.transformMap(i, v, v) == {}`,
+			wantErr: cel2sql.ErrUnsupportedComprehension,
+		},
+		{
+			name:    "unknown_type",
+			source:  `size(attrs) > 1`,
+			wantErr: cel2sql.ErrUnknownType,
+		},
+		{
+			name:    "unsafe_identifier",
+			source:  `attrs["x'; DROP TABLE users; --"] == "a"`,
+			wantErr: cel2sql.ErrUnsafeIdentifier,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			_, err := cel2sql.Convert(ast)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.ErrorIs(t, cel2sql.Validate(ast), tt.wantErr)
+		})
+	}
+}
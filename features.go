@@ -0,0 +1,96 @@
+package cel2sql
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/cel-go/common/overloads"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// features lists the features that can be disallowed, in the order they are reported
+var features = []string{FeatureComprehension, FeatureJSONPath, FeatureRegex}
+
+// FeatureError is returned by Convert when an expression uses a feature disallowed with
+// WithDisallowedFeatures.
+type FeatureError struct {
+	Feature string // the disallowed feature, e.g. FeatureRegex
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("expression uses disallowed feature %s", e.Feature)
+}
+
+// WithDisallowedFeatures makes Convert fail with a *FeatureError when the expression uses one
+// of the features FeatureComprehension, FeatureJSONPath or FeatureRegex, e.g. to restrict the
+// filters of latency-sensitive endpoints to predicates that indexes can serve.
+func WithDisallowedFeatures(features ...string) ConvertOption {
+	return func(o *convertOptions) {
+		o.disallowedFeatures = append(o.disallowedFeatures, features...)
+	}
+}
+
+// nodeFeature returns the feature used by a single node, or an empty string. The field
+// selections inside a JSON path are part of the path.
+func (con *converter) nodeFeature(node *exprpb.Expr) string {
+	switch {
+	case node.GetComprehensionExpr() != nil:
+		return FeatureComprehension
+	case node.GetCallExpr().GetFunction() == overloads.Matches:
+		return FeatureRegex
+	}
+	if _, ok := con.jsonPath(node); ok {
+		return FeatureJSONPath
+	}
+	return ""
+}
+
+// usedFeatures returns the features used by expr, in the order of features
+func (con *converter) usedFeatures(expr *exprpb.Expr) []string {
+	used := make(map[string]bool)
+	stack := []*exprpb.Expr{expr}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		feature := con.nodeFeature(node)
+		used[feature] = true
+		if feature == FeatureJSONPath {
+			continue
+		}
+		stack = appendChildExprs(stack, node)
+	}
+	var list []string
+	for _, feature := range features {
+		if used[feature] {
+			list = append(list, feature)
+		}
+	}
+	return list
+}
+
+// checkFeatures checks expr against the features disallowed with WithDisallowedFeatures
+func (con *converter) checkFeatures(expr *exprpb.Expr) error {
+	if len(con.opts.disallowedFeatures) == 0 {
+		return nil
+	}
+	if err := con.checkFeatureNames(); err != nil {
+		return err
+	}
+	for _, feature := range con.usedFeatures(expr) {
+		if slices.Contains(con.opts.disallowedFeatures, feature) {
+			return &FeatureError{Feature: feature}
+		}
+	}
+	return nil
+}
+
+// checkFeatureNames rejects unknown features passed to WithDisallowedFeatures, which would
+// otherwise silently allow what they were meant to disallow
+func (con *converter) checkFeatureNames() error {
+	for _, feature := range con.opts.disallowedFeatures {
+		if !slices.Contains(features, feature) {
+			return fmt.Errorf("unknown feature %q, want one of %v", feature, features)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cel2sql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertDisallowedFeatures(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"users": {
+			{Name: "name", Type: "text"},
+			{Name: "tags", Type: "text", Repeated: true},
+			{Name: "preferences", Type: "jsonb"},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas))
+	require.NoError(t, err)
+
+	indexFriendly := cel2sql.WithDisallowedFeatures(
+		cel2sql.FeatureComprehension, cel2sql.FeatureJSONPath, cel2sql.FeatureRegex)
+	tests := []struct {
+		name        string
+		source      string
+		opts        []cel2sql.ConvertOption
+		want        string
+		wantFeature string
+	}{
+		{
+			name:   "allowed",
+			source: `users.name == "alice" && "admin" in users.tags`,
+			opts:   []cel2sql.ConvertOption{indexFriendly},
+			want:   "users.name = 'alice' AND 'admin' = ANY(users.tags)",
+		},
+		{
+			name:        "comprehension",
+			source:      `users.tags.exists(t, t.startsWith("team-"))`,
+			opts:        []cel2sql.ConvertOption{indexFriendly},
+			wantFeature: cel2sql.FeatureComprehension,
+		},
+		{
+			name:        "json_path",
+			source:      `users.preferences.theme == "dark"`,
+			opts:        []cel2sql.ConvertOption{indexFriendly},
+			wantFeature: cel2sql.FeatureJSONPath,
+		},
+		{
+			name:        "regex",
+			source:      `users.name.matches("^a")`,
+			opts:        []cel2sql.ConvertOption{indexFriendly},
+			wantFeature: cel2sql.FeatureRegex,
+		},
+		{
+			name:   "other_feature_disallowed",
+			source: `users.name.matches("^a")`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithDisallowedFeatures(cel2sql.FeatureComprehension)},
+			want:   "users.name ~ '^a'",
+		},
+		{
+			name:   "options_accumulate",
+			source: `users.name.matches("^a")`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithDisallowedFeatures(cel2sql.FeatureComprehension),
+				cel2sql.WithDisallowedFeatures(cel2sql.FeatureRegex),
+			},
+			wantFeature: cel2sql.FeatureRegex,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			if tt.wantFeature != "" {
+				var featureErr *cel2sql.FeatureError
+				require.True(t, errors.As(err, &featureErr), "expected FeatureError, got %v", err)
+				assert.Equal(t, tt.wantFeature, featureErr.Feature)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertUnknownDisallowedFeature(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("name", cel.StringType))
+	require.NoError(t, err)
+	ast, issues := env.Compile(`name == "a"`)
+	require.NoError(t, issues.Err())
+
+	_, err = cel2sql.Convert(ast, cel2sql.WithDisallowedFeatures("regexp"))
+	assert.ErrorContains(t, err, `unknown feature "regexp"`)
+}
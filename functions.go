@@ -0,0 +1,131 @@
+package cel2sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// FunctionSQL renders a call of a custom CEL function as SQL, given the SQL of its arguments.
+// The receiver of a member call, such as account in account.riskScore(30), is the first
+// argument.
+type FunctionSQL func(args []string) (string, error)
+
+// Template returns a FunctionSQL replacing {0}, {1}, ... in template with the SQL of the
+// corresponding arguments, e.g. Template("risk_score({0}, {1})"). Other braces are kept as is.
+func Template(template string) FunctionSQL {
+	return func(args []string) (string, error) {
+		var sql strings.Builder
+		rest := template
+		for {
+			open := strings.IndexByte(rest, '{')
+			if open < 0 {
+				break
+			}
+			end := strings.IndexByte(rest[open:], '}')
+			if end < 0 {
+				break
+			}
+			end += open
+			n, err := strconv.Atoi(rest[open+1 : end])
+			if err != nil || n < 0 {
+				sql.WriteString(rest[:open+1])
+				rest = rest[open+1:]
+				continue
+			}
+			if n >= len(args) {
+				return "", fmt.Errorf("template %q refers to argument {%d} of a call with %d arguments", template, n, len(args))
+			}
+			sql.WriteString(rest[:open])
+			sql.WriteString(args[n])
+			rest = rest[end+1:]
+		}
+		sql.WriteString(rest)
+		return sql.String(), nil
+	}
+}
+
+var (
+	functionsMu sync.RWMutex
+	functions   = make(map[string]FunctionSQL)
+)
+
+// RegisterFunction registers the SQL of a custom function declared in CEL environments, by its
+// CEL function name, e.g. "riskScore" or "acme.riskScore", for every conversion. Registered
+// functions take precedence over the conversion of built-in functions and operators, see
+// WithFunctions; WithFunction registers a function for a single conversion. RegisterFunction
+// is typically called from init.
+func RegisterFunction(name string, fn FunctionSQL) {
+	functionsMu.Lock()
+	defer functionsMu.Unlock()
+	functions[name] = fn
+}
+
+// WithFunction converts calls of a custom CEL function with fn, taking precedence over the
+// functions registered with RegisterFunction.
+func WithFunction(name string, fn FunctionSQL) ConvertOption {
+	return func(o *convertOptions) {
+		if o.functions == nil {
+			o.functions = make(map[string]FunctionSQL)
+		}
+		o.functions[name] = fn
+	}
+}
+
+// WithFunctions overrides the SQL of functions and operators for a conversion like WithFunction,
+// by their CEL function name, e.g. "matches", "startsWith" or "_%_" for the % operator (see the
+// operators and overloads packages of cel-go):
+//
+//	cel2sql.WithFunctions(map[string]cel2sql.FunctionSQL{
+//		overloads.Matches:    cel2sql.Template("regexp_like({0}, {1})"),
+//		overloads.StartsWith: cel2sql.Template("{0} LIKE {1} || '%'"),
+//		operators.Modulo:     cel2sql.Template("{0} % {1}"),
+//	})
+//
+// The arguments are converted as values: a regular expression literal passed to an overridden
+// matches is not rewritten from RE2 to POSIX. The SQL of an overridden operator is written as
+// is, so it should be parenthesized where its precedence differs from that of the operator.
+func WithFunctions(overrides map[string]FunctionSQL) ConvertOption {
+	return func(o *convertOptions) {
+		for name, fn := range overrides {
+			WithFunction(name, fn)(o)
+		}
+	}
+}
+
+// customFunction looks up the SQL of a function set with WithFunction or RegisterFunction
+func (con *converter) customFunction(fun string) (FunctionSQL, bool) {
+	if fn, found := con.opts.functions[fun]; found {
+		return fn, true
+	}
+	functionsMu.RLock()
+	defer functionsMu.RUnlock()
+	fn, found := functions[fun]
+	return fn, found
+}
+
+// callCustomFunction writes a call of a custom function with the SQL of its arguments
+func (con *converter) callCustomFunction(expr *exprpb.Expr, fn FunctionSQL) error {
+	call := expr.GetCallExpr()
+	operands := call.GetArgs()
+	if call.GetTarget() != nil {
+		operands = append([]*exprpb.Expr{call.GetTarget()}, operands...)
+	}
+	args := make([]string, len(operands))
+	for i, operand := range operands {
+		sql, err := con.capture(func() error { return con.visit(operand) })
+		if err != nil {
+			return err
+		}
+		args[i] = sql
+	}
+	sql, err := fn(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", call.GetFunction(), err)
+	}
+	con.str.WriteString(sql)
+	return nil
+}
@@ -0,0 +1,171 @@
+package cel2sql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/overloads"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		args     []string
+		want     string
+		wantErr  bool
+	}{
+		{name: "in_order", template: "risk_score({0}, {1})", args: []string{"a", "30"}, want: "risk_score(a, 30)"},
+		{name: "reordered", template: "f({1}, {0}, {1})", args: []string{"a", "b"}, want: "f(b, a, b)"},
+		{name: "other_braces", template: "'{x}' || {0} || '{'", args: []string{"a"}, want: "'{x}' || a || '{'"},
+		{name: "no_arguments", template: "now()", want: "now()"},
+		{name: "missing_argument", template: "f({0}, {2})", args: []string{"a", "b"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cel2sql.Template(tt.template)(tt.args)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCustomFunctions(t *testing.T) {
+	cel2sql.RegisterFunction("acme.normalize", cel2sql.Template("acme.normalize_text({0})"))
+
+	env, err := cel.NewEnv(
+		cel.Variable("account", cel.StringType),
+		cel.Variable("name", cel.StringType),
+		cel.Function("riskScore",
+			cel.Overload("riskScore_string_int", []*cel.Type{cel.StringType, cel.IntType}, cel.IntType),
+			cel.MemberOverload("string_riskScore_int", []*cel.Type{cel.StringType, cel.IntType}, cel.IntType)),
+		cel.Function("acme.normalize", cel.Overload("acme_normalize_string", []*cel.Type{cel.StringType}, cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	riskScore := cel2sql.WithFunction("riskScore", cel2sql.Template("risk_score({0}, {1})"))
+	tests := []struct {
+		name    string
+		source  string
+		opts    []cel2sql.ConvertOption
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "global_call",
+			source: `riskScore(account, 30) > 50`,
+			opts:   []cel2sql.ConvertOption{riskScore},
+			want:   "risk_score(account, 30) > 50",
+		},
+		{
+			name:   "member_call",
+			source: `account.riskScore(30 + 1) > 50`,
+			opts:   []cel2sql.ConvertOption{riskScore},
+			want:   "risk_score(account, 30 + 1) > 50",
+		},
+		{
+			name:   "placeholders",
+			source: `riskScore(name, 30) > 50`,
+			opts:   []cel2sql.ConvertOption{riskScore, cel2sql.WithLiteralPlaceholders()},
+			want:   "risk_score(name, $1) > $2",
+		},
+		{
+			name:   "registered",
+			source: `acme.normalize(name) == "bob"`,
+			want:   "acme.normalize_text(name) = 'bob'",
+		},
+		{
+			name:   "option_overrides_registered",
+			source: `acme.normalize(name) == "bob"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithFunction("acme.normalize", cel2sql.Template("lower({0})"))},
+			want:   "lower(name) = 'bob'",
+		},
+		{
+			name:    "template_error",
+			source:  `riskScore(account, 30) > 50`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithFunction("riskScore", cel2sql.Template("risk_score({2})"))},
+			wantErr: true,
+		},
+		{
+			name:   "function_error",
+			source: `riskScore(account, 30) > 50`,
+			opts: []cel2sql.ConvertOption{cel2sql.WithFunction("riskScore", func([]string) (string, error) {
+				return "", errors.New("not available")
+			})},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, cel2sql.Validate(ast, tt.opts...))
+		})
+	}
+}
+
+func TestFunctionOverrides(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+	)
+	require.NoError(t, err)
+
+	overrides := cel2sql.WithFunctions(map[string]cel2sql.FunctionSQL{
+		overloads.Matches:    cel2sql.Template("regexp_like({0}, {1})"),
+		overloads.StartsWith: cel2sql.Template("{0} LIKE {1} || '%'"),
+		operators.Modulo:     cel2sql.Template("({0} % {1})"),
+	})
+	tests := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+		want   string
+	}{
+		{
+			name:   "defaults",
+			source: `name.matches("^a") && name.startsWith("b") && age % 2 == 0`,
+			want:   "name ~ '^a' AND STARTS_WITH(name, 'b') AND MOD(age, 2) = 0",
+		},
+		{
+			name:   "overridden",
+			source: `name.matches("^a") && name.startsWith("b") && age % 2 == 0`,
+			opts:   []cel2sql.ConvertOption{overrides},
+			want:   "regexp_like(name, '^a') AND name LIKE 'b' || '%' AND (age % 2) = 0",
+		},
+		{
+			name:   "binary_operator",
+			source: `name == "a" && age > 1`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithFunction(operators.Equals, cel2sql.Template("{0} IS NOT DISTINCT FROM {1}"))},
+			want:   "name IS NOT DISTINCT FROM 'a' AND age > 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
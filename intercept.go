@@ -0,0 +1,96 @@
+package cel2sql
+
+import (
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// NodeKind identifies the kinds of expressions passed to interceptors.
+type NodeKind int
+
+// Kinds of expressions passed to interceptors
+const (
+	NodeCall   NodeKind = iota // function call or operator, e.g. size(x) or x == y
+	NodeSelect                 // field selection, e.g. user.name
+)
+
+// String returns a string representation of the node kind
+func (k NodeKind) String() string {
+	switch k {
+	case NodeCall:
+		return "call"
+	case NodeSelect:
+		return "select"
+	default:
+		return "unknown"
+	}
+}
+
+// Node is an expression about to be converted, as passed to an Interceptor.
+type Node struct {
+	Kind     NodeKind
+	Expr     *exprpb.Expr // the checked expression
+	Function string       // function name of calls, e.g. "size" or "_==_" (see the operators package)
+	Field    string       // selected field of selections
+
+	con *converter
+}
+
+// Convert converts an expression, typically an argument or operand of the node, with the
+// options of the conversion in progress, for interceptors that write their own SQL around it.
+func (n Node) Convert(expr *exprpb.Expr) (string, error) {
+	return n.con.capture(func() error { return n.con.visit(expr) })
+}
+
+// Interceptor is called with each call and field selection before it is converted. It returns
+// the SQL of the node: next converts the node as cel2sql would, so that an interceptor can
+// inspect the node and call next, wrap the SQL returned by next, write SQL of its own instead,
+// or return an error to reject the expression. Placeholders and warnings recorded by next are
+// kept, so SQL returned by next should not be dropped once next was called.
+//
+// Nodes converted as part of a larger construct, such as the fields of a JSON path or the
+// accumulator of a comprehension, are not passed to interceptors separately.
+type Interceptor func(node Node, next func() (string, error)) (string, error)
+
+// WithInterceptors adds interceptors for calls and field selections, see Interceptor. The first
+// interceptor is the outermost: its next calls the second, and so on.
+func WithInterceptors(interceptors ...Interceptor) ConvertOption {
+	return func(o *convertOptions) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// intercept converts expr through the interceptors
+func (con *converter) intercept(expr *exprpb.Expr, kind NodeKind) error {
+	node := Node{Kind: kind, Expr: expr, con: con}
+	visit := con.visitCall
+	switch kind {
+	case NodeCall:
+		node.Function = expr.GetCallExpr().GetFunction()
+	case NodeSelect:
+		node.Field = expr.GetSelectExpr().GetField()
+		visit = con.visitSelect
+	}
+
+	next := func() (string, error) {
+		return con.capture(func() error { return visit(expr) })
+	}
+	for i := len(con.opts.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := con.opts.interceptors[i], next
+		next = func() (string, error) { return interceptor(node, inner) }
+	}
+	sql, err := next()
+	if err != nil {
+		return err
+	}
+	con.str.WriteString(sql)
+	return nil
+}
+
+// capture runs write and returns what it wrote to con.str instead of keeping it there
+func (con *converter) capture(write func() error) (string, error) {
+	start := con.str.Len()
+	err := write()
+	sql := string(con.str.Bytes()[start:])
+	con.str.Truncate(start)
+	return sql, err
+}
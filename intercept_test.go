@@ -0,0 +1,113 @@
+package cel2sql_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/operators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestWithInterceptors(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("user", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Function("soundex", cel.Overload("soundex_string", []*cel.Type{cel.StringType}, cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	errDenied := errors.New("denied")
+	// custom SQL for soundex, writing its argument with Node.Convert
+	fuzzy := func(node cel2sql.Node, next func() (string, error)) (string, error) {
+		if node.Function != "soundex" {
+			return next()
+		}
+		arg, err := node.Convert(node.Expr.GetCallExpr().GetArgs()[0])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("fuzzy.soundex(%s)", arg), nil
+	}
+	// veto of the % operator
+	noModulo := func(node cel2sql.Node, next func() (string, error)) (string, error) {
+		if node.Kind == cel2sql.NodeCall && node.Function == operators.Modulo {
+			return "", errDenied
+		}
+		return next()
+	}
+	// wrapping of the SQL of field selections
+	lowerFields := func(node cel2sql.Node, next func() (string, error)) (string, error) {
+		sql, err := next()
+		if err != nil || node.Kind != cel2sql.NodeSelect {
+			return sql, err
+		}
+		return "lower(" + sql + ")", nil
+	}
+
+	tests := []struct {
+		name         string
+		source       string
+		interceptors []cel2sql.Interceptor
+		opts         []cel2sql.ConvertOption
+		want         string
+		wantErr      error
+	}{
+		{
+			name:         "passthrough",
+			source:       `name == "a" && age > 1`,
+			interceptors: []cel2sql.Interceptor{fuzzy, noModulo},
+			want:         "name = 'a' AND age > 1",
+		},
+		{
+			name:         "custom_sql",
+			source:       `soundex(name) == soundex("Robert") && age > 1`,
+			interceptors: []cel2sql.Interceptor{fuzzy},
+			want:         "fuzzy.soundex(name) = fuzzy.soundex('Robert') AND age > 1",
+		},
+		{
+			name:         "custom_sql_with_placeholders",
+			source:       `soundex(name) == soundex("Robert") && age > 1`,
+			interceptors: []cel2sql.Interceptor{fuzzy},
+			opts:         []cel2sql.ConvertOption{cel2sql.WithLiteralPlaceholders()},
+			want:         "fuzzy.soundex(name) = fuzzy.soundex($1) AND age > $2",
+		},
+		{
+			name:         "veto",
+			source:       `name == "a" || age % 2 == 0`,
+			interceptors: []cel2sql.Interceptor{noModulo},
+			wantErr:      errDenied,
+		},
+		{
+			name:         "wrap",
+			source:       `user.name == "a"`,
+			interceptors: []cel2sql.Interceptor{lowerFields},
+			want:         "lower(user.name) = 'a'",
+		},
+		{
+			name:         "order",
+			source:       `soundex(user.name) == "R163"`,
+			interceptors: []cel2sql.Interceptor{lowerFields, fuzzy},
+			want:         "fuzzy.soundex(lower(user.name)) = 'R163'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, append(tt.opts, cel2sql.WithInterceptors(tt.interceptors...))...)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Package placeholders rewrites the positional placeholders of SQL generated by cel2sql for
+// the bind parameter syntax of database libraries.
+package placeholders
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Replace rewrites the positional placeholders ($1, $2, ...) of SQL outside of quoted strings
+// and identifiers, parameters being the variable bound to each as returned by
+// cel2sql.ConvertWithParameters. Each placeholder is replaced with the result of bind for its
+// variable, and every other byte, quoted or not, with the result of escape if not nil.
+func Replace(sql string, parameters []string, bind func(name string) string, escape func(c byte) (string, error)) (string, error) {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			n, err := strconv.Atoi(sql[i+1 : j])
+			if err != nil || n > len(parameters) || parameters[n-1] == "" {
+				return "", fmt.Errorf("placeholder %s is not bound to a variable", sql[i:j])
+			}
+			b.WriteString(bind(parameters[n-1]))
+			i = j - 1
+			continue
+		}
+		if escape != nil {
+			s, err := escape(c)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,55 @@
+package placeholders_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2/internal/placeholders"
+)
+
+func TestReplace(t *testing.T) {
+	named := func(name string) string { return ":" + name }
+	doubleColons := func(c byte) (string, error) {
+		if c == ':' {
+			return "::", nil
+		}
+		return string(c), nil
+	}
+
+	tests := []struct {
+		name       string
+		sql        string
+		parameters []string
+		escape     func(byte) (string, error)
+		want       string
+		wantErr    string
+	}{
+		{name: "placeholders", sql: "a = $1 AND b = $2 OR c = $1", parameters: []string{"x", "y"}, want: "a = :x AND b = :y OR c = :x"},
+		{name: "two digits", sql: "a = $10", parameters: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "ten"}, want: "a = :ten"},
+		{name: "quoted", sql: `a = '$1' AND "$1" = $1`, parameters: []string{"x"}, want: `a = '$1' AND "$1" = :x`},
+		{name: "escaped quote", sql: `a = 'it''s $1' AND b = $1`, parameters: []string{"x"}, want: `a = 'it''s $1' AND b = :x`},
+		{name: "escape", sql: "a::int = $1 AND b = 'c:d'", parameters: []string{"x"}, escape: doubleColons, want: "a::::int = :x AND b = 'c::d'"},
+		{name: "escape error", sql: "a = '?'", escape: func(c byte) (string, error) {
+			if c == '?' {
+				return "", errors.New("question mark")
+			}
+			return string(c), nil
+		}, wantErr: "question mark"},
+		{name: "unbound", sql: "a = $2", parameters: []string{"x"}, wantErr: "placeholder $2 is not bound to a variable"},
+		{name: "literal placeholder", sql: "a = $1", parameters: []string{""}, wantErr: "placeholder $1 is not bound to a variable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := placeholders.Replace(tt.sql, tt.parameters, named, tt.escape)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
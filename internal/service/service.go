@@ -0,0 +1,150 @@
+// Package service compiles the conversion requests of the HTTP and gRPC services against named
+// sets of table schemas, so that both handle schemas, tables and parameters alike.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// Catalog holds named sets of tables that requests are converted against. It is safe for
+// concurrent use.
+type Catalog struct {
+	providers map[string]pg.TypeProvider
+	schemas   map[string]map[string]pg.Schema
+	envOpts   []cel.EnvOption
+}
+
+// NewCatalog creates a catalog of sets of tables by name. envOpts are added to the CEL
+// environments of every request.
+func NewCatalog(schemas map[string]map[string]pg.Schema, envOpts ...cel.EnvOption) *Catalog {
+	c := &Catalog{
+		providers: make(map[string]pg.TypeProvider, len(schemas)),
+		schemas:   schemas,
+		envOpts:   envOpts,
+	}
+	for name, tables := range schemas {
+		c.providers[name] = pg.NewTypeProvider(tables)
+	}
+	return c
+}
+
+// Request is an expression to convert against a set of tables
+type Request struct {
+	Expression string
+	Schema     string            // name of the set of tables
+	Tables     map[string]string // tables by CEL variable
+	Parameters map[string]string // CEL types of parameters by name, see ParseType
+}
+
+// Compile compiles the expression of a request, and returns the conversion options binding
+// its tables and parameters. Every error is caused by the request.
+func (c *Catalog) Compile(req Request) (*cel.Ast, []cel2sql.ConvertOption, error) {
+	provider, found := c.providers[req.Schema]
+	if !found {
+		return nil, nil, fmt.Errorf("unknown schema %q", req.Schema)
+	}
+
+	envOpts := []cel.EnvOption{cel.CustomTypeProvider(provider)}
+	for _, name := range slices.Sorted(maps.Keys(req.Tables)) {
+		table := req.Tables[name]
+		if _, found := c.schemas[req.Schema][table]; !found {
+			return nil, nil, fmt.Errorf("unknown table %q in schema %q", table, req.Schema)
+		}
+		envOpts = append(envOpts, cel.Variable(name, cel.ObjectType(table)))
+	}
+	parameters := slices.Sorted(maps.Keys(req.Parameters))
+	for _, name := range parameters {
+		typ, err := ParseType(req.Parameters[name])
+		if err != nil {
+			return nil, nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		envOpts = append(envOpts, cel.Variable(name, typ))
+	}
+	env, err := cel.NewEnv(append(envOpts, c.envOpts...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	ast, issues := env.Compile(req.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, nil, issues.Err()
+	}
+	return ast, []cel2sql.ConvertOption{
+		cel2sql.WithSchemas(c.schemas[req.Schema]),
+		cel2sql.WithParameters(parameters...),
+	}, nil
+}
+
+// ParsePlaceholderStyle parses the name of a placeholder style, see
+// cel2sql.PlaceholderStyle.String. The empty name is the default style.
+func ParsePlaceholderStyle(name string) (cel2sql.PlaceholderStyle, error) {
+	if name == "" {
+		return cel2sql.PlaceholderDollar, nil
+	}
+	for _, style := range []cel2sql.PlaceholderStyle{
+		cel2sql.PlaceholderDollar, cel2sql.PlaceholderQuestion, cel2sql.PlaceholderColon, cel2sql.PlaceholderAt,
+	} {
+		if style.String() == name {
+			return style, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown placeholder style %q", name)
+}
+
+// ParseType parses the CEL type of a parameter: a primitive type name, timestamp, duration,
+// list(T) or map(K, V)
+func ParseType(name string) (*cel.Type, error) {
+	name = strings.TrimSpace(name)
+	switch name {
+	case "bool":
+		return cel.BoolType, nil
+	case "bytes":
+		return cel.BytesType, nil
+	case "double":
+		return cel.DoubleType, nil
+	case "duration":
+		return cel.DurationType, nil
+	case "int":
+		return cel.IntType, nil
+	case "string":
+		return cel.StringType, nil
+	case "timestamp":
+		return cel.TimestampType, nil
+	case "uint":
+		return cel.UintType, nil
+	}
+	if elem, found := strings.CutPrefix(name, "list("); found && strings.HasSuffix(elem, ")") {
+		elemType, err := ParseType(strings.TrimSuffix(elem, ")"))
+		if err != nil {
+			return nil, err
+		}
+		return cel.ListType(elemType), nil
+	}
+	if params, found := strings.CutPrefix(name, "map("); found && strings.HasSuffix(params, ")") {
+		key, value, found := strings.Cut(strings.TrimSuffix(params, ")"), ",")
+		if !found {
+			return nil, fmt.Errorf("invalid type %q", name)
+		}
+		keyType, err := ParseType(key)
+		if err != nil {
+			return nil, err
+		}
+		valueType, err := ParseType(value)
+		if err != nil {
+			return nil, err
+		}
+		return cel.MapType(keyType, valueType), nil
+	}
+	if name == "" {
+		return nil, errors.New("missing type")
+	}
+	return nil, fmt.Errorf("unknown type %q", name)
+}
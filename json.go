@@ -2,8 +2,13 @@ package cel2sql
 
 import (
 	"errors"
+	"strings"
 
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/overloads"
 	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2/pg"
 )
 
 // Constants for PostgreSQL JSON functions
@@ -16,6 +21,11 @@ const (
 
 // shouldUseJSONPath determines if we should use JSON path operators for field access
 func (con *converter) shouldUseJSONPath(operand *exprpb.Expr, _ string) bool {
+	// Values of types of the schemas are JSON when they are held in a JSON column
+	if column, known := con.jsonColumnOf(con.getType(operand)); known {
+		return column.Type != ""
+	}
+
 	// For now, we'll use a simple heuristic: if the operand is a direct field reference
 	// to a field that commonly contains JSON (like 'preferences', 'metadata', 'profile', 'details')
 	// then we use JSON path operators
@@ -27,14 +37,14 @@ func (con *converter) shouldUseJSONPath(operand *exprpb.Expr, _ string) bool {
 	if selectExpr := operand.GetSelectExpr(); selectExpr != nil {
 		// Nested field access - check if the parent field is a JSON field
 		parentField := selectExpr.GetField()
-		jsonFields := []string{"preferences", "metadata", "profile", "details", "settings", "properties", "analytics", 
-		                      "content", "structure", "taxonomy", "classification", "content_structure"}
+		jsonFields := []string{"preferences", "metadata", "profile", "details", "settings", "properties", "analytics",
+			"content", "structure", "taxonomy", "classification", "content_structure"}
 		for _, jsonField := range jsonFields {
 			if parentField == jsonField {
 				return true
 			}
 		}
-		
+
 		// Also check if we have deeper nesting where a JSON field appears earlier in the chain
 		if con.hasJSONFieldInChain(operand) {
 			return true
@@ -46,65 +56,103 @@ func (con *converter) shouldUseJSONPath(operand *exprpb.Expr, _ string) bool {
 
 // hasJSONFieldInChain checks if there's a JSON field anywhere in the select expression chain
 func (con *converter) hasJSONFieldInChain(expr *exprpb.Expr) bool {
+	if field, column, found := con.findJSONField(expr); found {
+		return isJSONType(field.Type) || column.Type != ""
+	}
+
 	if selectExpr := expr.GetSelectExpr(); selectExpr != nil {
 		field := selectExpr.GetField()
 		operand := selectExpr.GetOperand()
-		
+
 		// Check if current field is a JSON field
-		jsonFields := []string{"preferences", "metadata", "profile", "details", "settings", "properties", "analytics", 
-		                      "content", "structure", "taxonomy", "classification", "content_structure"}
+		jsonFields := []string{"preferences", "metadata", "profile", "details", "settings", "properties", "analytics",
+			"content", "structure", "taxonomy", "classification", "content_structure"}
 		for _, jsonField := range jsonFields {
 			if field == jsonField {
 				return true
 			}
 		}
-		
+
 		// Recursively check the operand
 		return con.hasJSONFieldInChain(operand)
 	}
-	
+
 	return false
 }
 
 // isJSONTextExtraction checks if an expression represents a JSON field extraction that returns text
 // This is used to determine if we need numeric casting for comparisons
 func (con *converter) isJSONTextExtraction(expr *exprpb.Expr) bool {
+	// Values of JSON columns indexed by key, e.g. m[k] in m.all(k, m[k] > 0)
+	if call := expr.GetCallExpr(); call.GetFunction() == operators.Index && len(call.GetArgs()) == 2 {
+		return con.isUndescribedJSONColumn(call.GetArgs()[0])
+	}
+
+	// Variables of comprehensions over JSON keys and text elements, unless cast to numeric by name
+	if ident := expr.GetIdentExpr(); ident != nil {
+		return con.iterVars[ident.GetName()] != 0 && !con.needsNumericCasting(ident.GetName())
+	}
+
 	// Check if this is a select expression that would use JSON path operators
 	if selectExpr := expr.GetSelectExpr(); selectExpr != nil {
+		if con.isJSONObjectFieldAccess(expr) {
+			return !con.isNumericJSONField(selectExpr.GetField())
+		}
 		operand := selectExpr.GetOperand()
 		field := selectExpr.GetField()
-		
+
 		// If this would trigger JSON path generation, it's a text extraction
 		return con.shouldUseJSONPath(operand, field)
 	}
-	
+
 	return false
 }
 
+// jsonComparisonCast returns the SQL type that JSON text compared with a value of otherType is
+// cast to, following the type of the value rather than the name of the JSON key. JSON text is
+// compared with strings as is, and with null by IS NULL.
+func (con *converter) jsonComparisonCast(fun string, operand *exprpb.Expr, otherType *exprpb.Type) string {
+	if !isNumericComparison(fun) || !con.isJSONTextExtraction(operand) {
+		return ""
+	}
+	return jsonTextCast(otherType)
+}
+
+// writeJSONComparisonCast closes the cast of JSON text compared with a number or a boolean
+func (con *converter) writeJSONComparisonCast(expr *exprpb.Expr, cast string) {
+	con.str.WriteString(")::")
+	con.str.WriteString(cast)
+	kind := "number"
+	if cast == "boolean" {
+		kind = "boolean"
+	}
+	con.warn(expr, WarningJSONComparison, "JSON value is cast to %s for comparison; rows where it is not a %s fail the query", cast, kind)
+}
+
 // needsNumericCasting checks if an identifier represents a numeric iteration variable from JSON
 func (con *converter) needsNumericCasting(identName string) bool {
 	// Common iteration variable names that come from numeric JSON arrays
 	numericIterationVars := []string{"score", "value", "num", "amount", "count", "level"}
-	
+
 	for _, numericVar := range numericIterationVars {
 		if identName == numericVar {
 			return true
 		}
 	}
-	
+
 	return false
 }
 
 // isNumericJSONField checks if a JSON field name typically contains numeric values
 func (con *converter) isNumericJSONField(fieldName string) bool {
 	numericFields := []string{"level", "score", "value", "count", "amount", "price", "rating", "age", "size", "capacity", "megapixels", "cores", "threads", "ram", "storage", "vram", "weight", "frequency", "helpful"}
-	
+
 	for _, numericField := range numericFields {
 		if fieldName == numericField {
 			return true
 		}
 	}
-	
+
 	return false
 }
 
@@ -135,6 +183,13 @@ func (con *converter) buildJSONPathForArray(expr *exprpb.Expr) error {
 	if selectExpr == nil {
 		return errors.New("expected select expression for JSON array path")
 	}
+	if con.isMaskedColumnAccess(expr) {
+		con.writeMaskedNull(expr)
+		return nil
+	}
+	if con.opts.jsonPathStyle == JSONPathFunctions {
+		return con.buildJSONPathFunction(expr, false)
+	}
 
 	operand := selectExpr.GetOperand()
 	field := selectExpr.GetField()
@@ -157,11 +212,13 @@ func (con *converter) buildJSONPathForArray(expr *exprpb.Expr) error {
 	// Check if this is the base table.jsonfield access
 	if operandIdent := operand.GetIdentExpr(); operandIdent != nil {
 		// This is table.jsonfield - use normal table.field syntax for the base
-		tableName := operandIdent.GetName()
-		con.str.WriteString(tableName)
-		con.str.WriteString(".")
-		con.str.WriteString(field)
-		return nil
+		if !con.isUnqualifiedTable(operand) {
+			if err := con.writeVariable(operand); err != nil {
+				return err
+			}
+			con.str.WriteString(".")
+		}
+		return con.writeColumn(operand, field)
 	}
 
 	// For other cases, visit the operand and add JSON operator
@@ -178,13 +235,13 @@ func (con *converter) buildJSONPathForArray(expr *exprpb.Expr) error {
 func (con *converter) isJSONObjectFieldAccess(expr *exprpb.Expr) bool {
 	if selectExpr := expr.GetSelectExpr(); selectExpr != nil {
 		operand := selectExpr.GetOperand()
-		
+
 		// Check if the operand is an identifier that could be a comprehension variable
 		if identExpr := operand.GetIdentExpr(); identExpr != nil {
 			// Common comprehension variable names that access JSON objects
 			jsonObjectVars := []string{"attr", "item", "element", "obj", "feature", "review"}
 			identName := identExpr.GetName()
-			
+
 			for _, jsonVar := range jsonObjectVars {
 				if identName == jsonVar {
 					return true
@@ -205,6 +262,16 @@ func (con *converter) getJSONTypeofFunction(expr *exprpb.Expr) string {
 
 // isJSONArrayField determines if the expression refers to a JSON/JSONB array field
 func (con *converter) isJSONArrayField(expr *exprpb.Expr) bool {
+	// Keys described in the schemas hold arrays when repeated, while columns are JSON arrays
+	// only if their documents are not described
+	if field, column, found := con.findJSONField(expr); found && (column.Type != "" || !isJSONType(field.Type) || len(field.Schema) > 0) {
+		return column.Type != "" && field.Repeated
+	}
+	// Keys of undescribed documents may hold anything, whatever their name
+	if con.isUndescribedJSONPath(expr) {
+		return false
+	}
+
 	// Check if this is a field selection on a JSON field
 	if selectExpr := expr.GetSelectExpr(); selectExpr != nil {
 		// Get the operand (the table/object being accessed)
@@ -219,10 +286,10 @@ func (con *converter) isJSONArrayField(expr *exprpb.Expr) bool {
 			jsonArrayFields := map[string][]string{
 				"json_users":         {"tags", "scores", "attributes"},
 				"json_products":      {"features", "reviews", "categories"},
-				"users":              {"preferences", "profile"}, // existing test data
-				"products":           {"metadata", "details"},    // existing test data
+				"users":              {"preferences", "profile"},                                        // existing test data
+				"products":           {"metadata", "details"},                                           // existing test data
 				"information_assets": {"metadata", "properties", "classification", "content_structure"}, // nested path test data
-				"documents":          {"content", "structure", "taxonomy", "analytics"},                  // nested path test data
+				"documents":          {"content", "structure", "taxonomy", "analytics"},                 // nested path test data
 			}
 
 			if fields, exists := jsonArrayFields[tableName]; exists {
@@ -252,6 +319,17 @@ func (con *converter) isJSONArrayField(expr *exprpb.Expr) bool {
 
 // isJSONBField determines if the expression refers to a JSONB field (vs JSON field)
 func (con *converter) isJSONBField(expr *exprpb.Expr) bool {
+	if field, column, found := con.findJSONField(expr); found {
+		if column.Type != "" {
+			return column.Type == "jsonb"
+		}
+		return field.Type == "jsonb"
+	}
+	if column, known := con.jsonColumnOf(con.getType(expr)); known && column.Type != "" {
+		// e.g. the iteration variable of a comprehension over a JSON array
+		return column.Type == "jsonb"
+	}
+
 	// Check if this is a field selection on a JSONB field
 	if selectExpr := expr.GetSelectExpr(); selectExpr != nil {
 		operand := selectExpr.GetOperand()
@@ -263,7 +341,7 @@ func (con *converter) isJSONBField(expr *exprpb.Expr) bool {
 
 			// Define which fields are JSONB vs JSON in our test schemas
 			jsonbFields := map[string][]string{
-				"json_users":         {"settings", "tags", "scores"},       // JSONB fields
+				"json_users":         {"settings", "tags", "scores"},        // JSONB fields
 				"json_products":      {"features", "reviews", "properties"}, // JSONB fields
 				"information_assets": {"metadata", "classification"},        // JSONB fields
 				"documents":          {"content", "taxonomy"},               // JSONB fields
@@ -287,6 +365,10 @@ func (con *converter) isJSONBField(expr *exprpb.Expr) bool {
 					return true
 				}
 			}
+			// Otherwise the JSON column the path starts from decides, however deep the path
+			if con.hasJSONFieldInChain(operand) {
+				return con.isJSONBField(operand)
+			}
 		}
 	}
 	return false
@@ -294,12 +376,26 @@ func (con *converter) isJSONBField(expr *exprpb.Expr) bool {
 
 // getJSONArrayFunction returns the appropriate PostgreSQL function for JSON array operations
 func (con *converter) getJSONArrayFunction(expr *exprpb.Expr) string {
+	// Arrays of keys described in the schemas hold objects when their items are JSON
+	if field, column, found := con.findJSONField(expr); found && column.Type != "" {
+		switch {
+		case isJSONType(field.Type) && column.Type == "jsonb":
+			return jsonbArrayElements
+		case isJSONType(field.Type):
+			return jsonArrayElements
+		case column.Type == "jsonb":
+			return jsonbArrayElementsText
+		default:
+			return jsonArrayElementsText
+		}
+	}
+
 	// Determine if this is JSON or JSONB based on the field
 	isJSONB := con.isJSONBField(expr)
-	
+
 	if selectExpr := expr.GetSelectExpr(); selectExpr != nil {
 		field := selectExpr.GetField()
-		
+
 		// Fields that contain simple values (strings, numbers)
 		simpleArrayFields := []string{"tags", "scores", "categories"}
 		for _, simpleField := range simpleArrayFields {
@@ -311,7 +407,7 @@ func (con *converter) getJSONArrayFunction(expr *exprpb.Expr) string {
 				return jsonArrayElementsText
 			}
 		}
-		
+
 		// Fields that contain complex objects
 		complexArrayFields := []string{"attributes", "features", "reviews"}
 		for _, complexField := range complexArrayFields {
@@ -322,7 +418,7 @@ func (con *converter) getJSONArrayFunction(expr *exprpb.Expr) string {
 				return jsonArrayElements
 			}
 		}
-		
+
 		// For nested JSON access, use appropriate array elements function
 		if operand := selectExpr.GetOperand(); operand.GetSelectExpr() != nil {
 			if isJSONB {
@@ -331,7 +427,7 @@ func (con *converter) getJSONArrayFunction(expr *exprpb.Expr) string {
 			return jsonArrayElements
 		}
 	}
-	
+
 	// Default based on field type
 	if isJSONB {
 		return jsonbArrayElements
@@ -341,9 +437,97 @@ func (con *converter) getJSONArrayFunction(expr *exprpb.Expr) string {
 
 // buildJSONPath constructs the full JSON path for nested field access
 func (con *converter) buildJSONPath(expr *exprpb.Expr) error {
+	if con.opts.jsonPathStyle == JSONPathFunctions {
+		return con.buildJSONPathFunction(expr, true)
+	}
 	return con.buildJSONPathInternal(expr, true)
 }
 
+// buildJSONPathFunction renders nested JSON field access using the path extraction functions,
+// e.g. jsonb_extract_path_text(table.jsonfield, 'a', 'b'), instead of chained operators.
+// When asText is false the JSON value is preserved (json[b]_extract_path).
+func (con *converter) buildJSONPathFunction(expr *exprpb.Expr, asText bool) error {
+	base, path := con.splitJSONPath(expr)
+	if len(path) == 0 {
+		return errors.New("expected select expression for JSON path")
+	}
+	return con.writeJSONPath(base, path, asText)
+}
+
+// writeJSONPath renders access to path inside the JSON column base using the configured
+// JSONPathStyle. When asText is true the final value is extracted as text.
+func (con *converter) writeJSONPath(base *exprpb.Expr, path []string, asText bool) error {
+	if len(path) == 0 {
+		return con.visitJSONColumnReference(base)
+	}
+	if con.isMaskedColumnAccess(base) {
+		// Keys of a masked column are NULL as well
+		con.str.WriteString("NULL")
+		return nil
+	}
+
+	if con.opts.jsonPathStyle == JSONPathFunctions {
+		fn := "json_extract_path"
+		if con.isJSONBField(base) {
+			fn = "jsonb_extract_path"
+		}
+		if asText {
+			fn += "_text"
+		}
+		con.str.WriteString(fn)
+		con.str.WriteString("(")
+		if err := con.visitJSONColumnReference(base); err != nil {
+			return err
+		}
+		for _, segment := range path {
+			con.str.WriteString(", '")
+			con.str.WriteString(segment)
+			con.str.WriteString("'")
+		}
+		con.str.WriteString(")")
+		return nil
+	}
+
+	if err := con.visitJSONColumnReference(base); err != nil {
+		return err
+	}
+	for i, segment := range path {
+		if asText && i == len(path)-1 {
+			con.str.WriteString("->>'")
+		} else {
+			con.str.WriteString("->'")
+		}
+		con.str.WriteString(segment)
+		con.str.WriteString("'")
+	}
+	return nil
+}
+
+// splitJSONPath separates a JSON field access chain into the JSON column expression
+// (table.jsonfield) and the keys that are accessed inside of it.
+func (con *converter) splitJSONPath(expr *exprpb.Expr) (*exprpb.Expr, []string) {
+	var path []string
+	current := expr
+	for {
+		selectExpr := current.GetSelectExpr()
+		if selectExpr == nil {
+			break
+		}
+		operand := selectExpr.GetOperand()
+		operandSelect := operand.GetSelectExpr()
+		if operandSelect == nil || !con.hasJSONFieldInChain(operand) {
+			break
+		}
+		path = append([]string{selectExpr.GetField()}, path...)
+		if !con.shouldUseJSONPath(operandSelect.GetOperand(), operandSelect.GetField()) {
+			// The operand is the JSON column itself (table.jsonfield)
+			return operand, path
+		}
+		current = operand
+	}
+	return current, path
+}
+
 // buildJSONPathInternal is the internal implementation that tracks if this is the final field
 func (con *converter) buildJSONPathInternal(expr *exprpb.Expr, isFinalField bool) error {
 	selectExpr := expr.GetSelectExpr()
@@ -365,9 +549,9 @@ func (con *converter) buildJSONPathInternal(expr *exprpb.Expr, isFinalField bool
 			}
 			// Add appropriate JSON path operator based on whether this is the final field
 			if isFinalField {
-				con.str.WriteString("->>'")  // Final field: extract as text
+				con.str.WriteString("->>'") // Final field: extract as text
 			} else {
-				con.str.WriteString("->'")   // Intermediate field: keep as JSON
+				con.str.WriteString("->'") // Intermediate field: keep as JSON
 			}
 			con.str.WriteString(field)
 			con.str.WriteString("'")
@@ -382,11 +566,277 @@ func (con *converter) buildJSONPathInternal(expr *exprpb.Expr, isFinalField bool
 
 	// Add the appropriate JSON path operator based on whether this is the final field
 	if isFinalField {
-		con.str.WriteString("->>'")  // Final field: extract as text
+		con.str.WriteString("->>'") // Final field: extract as text
 	} else {
-		con.str.WriteString("->'")   // Intermediate field: keep as JSON
+		con.str.WriteString("->'") // Intermediate field: keep as JSON
 	}
 	con.str.WriteString(field)
 	con.str.WriteString("'")
 	return nil
-}
\ No newline at end of file
+}
+
+// isJSONType checks if a column type is json or jsonb
+func isJSONType(typeName string) bool {
+	return typeName == "json" || typeName == "jsonb"
+}
+
+// jsonColumnOf finds the JSON column holding the values of a (possibly nested) message type of
+// the schemas supplied with WithSchemas, e.g. the payload column for "events.payload.items",
+// reporting whether the type is known. The column is empty for tables and composite types.
+func (con *converter) jsonColumnOf(typ *exprpb.Type) (pg.FieldSchema, bool) {
+	if !isMessageType(typ) {
+		return pg.FieldSchema{}, false
+	}
+	table, path, found := pg.FindTable(con.opts.schemas, typ.GetMessageType())
+	if !found {
+		return pg.FieldSchema{}, false
+	}
+	schema := con.opts.schemas[table]
+	for _, tn := range path {
+		var next *pg.FieldSchema
+		for i := range schema {
+			if schema[i].Name == tn {
+				next = &schema[i]
+				break
+			}
+		}
+		if next == nil {
+			return pg.FieldSchema{}, false
+		}
+		if isJSONType(next.Type) {
+			return *next, true
+		}
+		schema = next.Schema
+	}
+	return pg.FieldSchema{}, true
+}
+
+// findJSONField looks up the schema of the field selected by expr in the schemas supplied with
+// WithSchemas, along with the JSON column holding it when it is a key of the documents of a
+// JSON column
+func (con *converter) findJSONField(expr *exprpb.Expr) (field, column pg.FieldSchema, found bool) {
+	field, found = con.findField(expr)
+	if !found {
+		return pg.FieldSchema{}, pg.FieldSchema{}, false
+	}
+	column, _ = con.jsonColumnOf(con.getType(expr.GetSelectExpr().GetOperand()))
+	return field, column, true
+}
+
+// visitJSONArray renders a JSON array field, keeping nested arrays as JSON with ->
+func (con *converter) visitJSONArray(expr *exprpb.Expr) error {
+	if con.isNestedJSONAccess(expr) {
+		return con.visitNestedJSONForArray(expr)
+	}
+	return con.visit(expr)
+}
+
+// isUndescribedJSONColumn checks if expr selects a JSON column whose documents are not described
+// in the schemas supplied with WithSchemas
+func (con *converter) isUndescribedJSONColumn(expr *exprpb.Expr) bool {
+	field, column, found := con.findJSONField(expr)
+	return found && column.Type == "" && isJSONType(field.Type) && len(field.Schema) == 0 && !field.Repeated
+}
+
+// isUndescribedJSONPath checks if expr selects a key, at any depth, of the documents of a JSON
+// column that are not described in the schemas supplied with WithSchemas
+func (con *converter) isUndescribedJSONPath(expr *exprpb.Expr) bool {
+	for sel := expr.GetSelectExpr(); sel != nil; sel = sel.GetOperand().GetSelectExpr() {
+		if con.isUndescribedJSONColumn(sel.GetOperand()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONArrayColumn checks if expr selects an undescribed JSON column or one of its keys, which
+// 'in' and the array functions test with the jsonb operators that accept both arrays and objects
+func (con *converter) isJSONArrayColumn(expr *exprpb.Expr) bool {
+	return con.isUndescribedJSONColumn(expr) || con.isUndescribedJSONPath(expr)
+}
+
+// isJSONObject checks if comprehensions iterate over expr as a JSON object, by key like CEL maps:
+// map literals, built with jsonb_build_object, and undescribed JSON columns and their keys at any
+// depth not known to hold arrays, which are only known to be objects at runtime, see
+// writeJSONObjectKeys
+func (con *converter) isJSONObject(expr *exprpb.Expr) bool {
+	return isMapLiteral(expr) ||
+		((con.isUndescribedJSONColumn(expr) || con.isUndescribedJSONPath(expr)) && !con.isJSONArrayField(expr))
+}
+
+// isJSONObjectIndex checks if an index expression reads a JSON object, which is indexed with any
+// key, while other maps are only indexed with literal keys
+func (con *converter) isJSONObjectIndex(args []*exprpb.Expr) bool {
+	if len(args) != 2 {
+		return false
+	}
+	if con.isUndescribedJSONColumn(args[0]) {
+		return true
+	}
+	return isMapLiteral(args[0]) && !isStringLiteral(args[1]) &&
+		con.getType(args[1]).GetPrimitive() == exprpb.Type_STRING
+}
+
+// jsonIterVar tells how a comprehension variable iterates over a JSON value
+type jsonIterVar int
+
+const (
+	jsonObjectKey   jsonIterVar = iota + 1 // the keys of a JSON object
+	jsonTextElement                        // the elements of a JSON array, extracted as text
+)
+
+// bindIterVar records how iterVar iterates over iterRange while the body of its comprehension is
+// converted, and returns a function restoring the enclosing binding
+func (con *converter) bindIterVar(iterVar string, iterRange *exprpb.Expr) func() {
+	if con.iterVars == nil {
+		con.iterVars = make(map[string]jsonIterVar)
+	}
+	prev, bound := con.iterVars[iterVar]
+	switch {
+	case con.isJSONArrayField(iterRange):
+		if fun := con.getJSONArrayFunction(iterRange); fun == jsonbArrayElementsText || fun == jsonArrayElementsText {
+			con.iterVars[iterVar] = jsonTextElement
+		} else {
+			delete(con.iterVars, iterVar)
+		}
+	case con.isJSONObject(iterRange):
+		con.iterVars[iterVar] = jsonObjectKey
+	default:
+		delete(con.iterVars, iterVar)
+	}
+	return func() {
+		if bound {
+			con.iterVars[iterVar] = prev
+		} else {
+			delete(con.iterVars, iterVar)
+		}
+	}
+}
+
+// isObjectKey checks if expr is the variable of a comprehension over the keys of a JSON object
+func (con *converter) isObjectKey(expr *exprpb.Expr) bool {
+	return expr.GetIdentExpr() != nil && con.iterVars[expr.GetIdentExpr().GetName()] == jsonObjectKey
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, and the backslash escaping them
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// callKeyPattern renders k.startsWith(prefix) and k.endsWith(suffix) on the keys of a JSON object
+// as k LIKE 'prefix%' and k LIKE '%suffix', the way key patterns are written in SQL
+func (con *converter) callKeyPattern(fun string, key, affix *exprpb.Expr) error {
+	if err := con.visit(key); err != nil {
+		return err
+	}
+	pattern := likeEscaper.Replace(affix.GetConstExpr().GetStringValue())
+	if fun == overloads.StartsWith {
+		pattern += "%"
+	} else {
+		pattern = "%" + pattern
+	}
+	con.str.WriteString(" LIKE '")
+	con.str.WriteString(strings.ReplaceAll(pattern, "'", "''"))
+	con.str.WriteString("'")
+	return nil
+}
+
+// writeJSONObjectKeys writes the keys of a JSON object as the source of a comprehension. The
+// documents of undescribed JSON columns and their keys may be objects or arrays, so the keys of objects and the
+// elements of arrays are iterated depending on jsonb_typeof, and other values yield no rows:
+// jsonb_array_elements_text(CASE jsonb_typeof(c) WHEN 'object' THEN
+// to_jsonb(ARRAY(SELECT jsonb_object_keys(c))) WHEN 'array' THEN c END)
+func (con *converter) writeJSONObjectKeys(expr *exprpb.Expr) error {
+	if isMapLiteral(expr) {
+		con.str.WriteString("jsonb_object_keys(")
+		if err := con.visit(expr); err != nil {
+			return err
+		}
+		con.str.WriteString(")")
+		return nil
+	}
+	prefix := "json"
+	if con.isJSONBField(expr) {
+		prefix = "jsonb"
+	}
+	con.str.WriteString(prefix + "_array_elements_text(CASE " + prefix + "_typeof(")
+	if err := con.visitJSONArray(expr); err != nil {
+		return err
+	}
+	con.str.WriteString(") WHEN 'object' THEN to_" + prefix + "(ARRAY(SELECT " + prefix + "_object_keys(")
+	if err := con.visitJSONArray(expr); err != nil {
+		return err
+	}
+	con.str.WriteString("))) WHEN 'array' THEN ")
+	if err := con.visitJSONArray(expr); err != nil {
+		return err
+	}
+	con.str.WriteString(" END)")
+	return nil
+}
+
+// visitJSONObjectIndex renders m[key] for a JSON object as m->>key, cast to the CEL type of the
+// value where the map declares it. Values of JSON columns are dynamic, and cast by comparisons.
+func (con *converter) visitJSONObjectIndex(expr *exprpb.Expr) error {
+	args := expr.GetCallExpr().GetArgs()
+	m, key := args[0], args[1]
+	var cast string
+	if isMapLiteral(m) {
+		cast = jsonTextCast(con.getType(expr))
+	}
+	if cast != "" {
+		con.str.WriteString("(")
+	}
+	if err := con.visitMaybeNested(m, isBinaryOrTernaryOperator(m)); err != nil {
+		return err
+	}
+	con.str.WriteString("->>")
+	if err := con.visitMaybeNested(key, isBinaryOrTernaryOperator(key)); err != nil {
+		return err
+	}
+	if cast != "" {
+		con.str.WriteString(")::")
+		con.str.WriteString(cast)
+	}
+	return nil
+}
+
+// callJSONMembership renders elem in column for an undescribed JSON column, which may hold an
+// array or an object: strings are tested with the ? operator, which matches array elements and
+// object keys alike, and other values with @>, which matches array elements, e.g.
+// user.allowed_roles ? user.role and user.allowed_levels @> to_jsonb(user.level)
+func (con *converter) callJSONMembership(elem, column *exprpb.Expr) error {
+	if con.isJSONBField(column) {
+		if err := con.visitJSONArray(column); err != nil {
+			return err
+		}
+	} else {
+		// The ? and @> operators are only defined for jsonb
+		con.str.WriteString("(")
+		if err := con.visitJSONArray(column); err != nil {
+			return err
+		}
+		con.str.WriteString(")::jsonb")
+	}
+	switch con.getType(elem).GetPrimitive() {
+	case exprpb.Type_INT64, exprpb.Type_UINT64, exprpb.Type_DOUBLE, exprpb.Type_BOOL:
+		con.str.WriteString(" @> to_jsonb(")
+		if err := con.visit(elem); err != nil {
+			return err
+		}
+		con.str.WriteString(")")
+		return nil
+	}
+	con.str.WriteString(" ? ")
+	return con.visitMaybeNested(elem, isBinaryOrTernaryOperator(elem))
+}
+
+// jsonTextCast returns the SQL type that JSON values extracted as text are cast to for comparison
+// with values of typ, or "" for text
+func jsonTextCast(typ *exprpb.Type) string {
+	switch typ.GetPrimitive() {
+	case exprpb.Type_INT64, exprpb.Type_UINT64, exprpb.Type_DOUBLE:
+		return "numeric"
+	case exprpb.Type_BOOL:
+		return "boolean"
+	}
+	return ""
+}
@@ -0,0 +1,626 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func newJSONTestEnv(t *testing.T) *cel.Env {
+	t.Helper()
+	provider := pg.NewTypeProvider(map[string]pg.Schema{
+		"information_assets": {
+			{Name: "id", Type: "integer"},
+			{Name: "metadata", Type: "jsonb"},
+			{Name: "properties", Type: "json"},
+		},
+		"json_users": {
+			{Name: "id", Type: "integer"},
+			{Name: "settings", Type: "jsonb"},
+		},
+	})
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("information_assets", cel.ObjectType("information_assets")),
+		cel.Variable("json_users", cel.ObjectType("json_users")),
+	)
+	require.NoError(t, err)
+	return env
+}
+
+func TestConvertJSONPathStyle(t *testing.T) {
+	env := newJSONTestEnv(t)
+
+	tests := []struct {
+		name      string
+		source    string
+		operators string
+		functions string
+	}{
+		{
+			name:      "single_key",
+			source:    `information_assets.properties.visibility == "public"`,
+			operators: `information_assets.properties->>'visibility' = 'public'`,
+			functions: `json_extract_path_text(information_assets.properties, 'visibility') = 'public'`,
+		},
+		{
+			name:      "nested_keys",
+			source:    `information_assets.metadata.corpus.section == "Reference"`,
+			operators: `information_assets.metadata->'corpus'->>'section' = 'Reference'`,
+			functions: `jsonb_extract_path_text(information_assets.metadata, 'corpus', 'section') = 'Reference'`,
+		},
+		{
+			name:      "numeric_comparison",
+			source:    `information_assets.metadata.version.major > 1`,
+			operators: `(information_assets.metadata->'version'->>'major')::numeric > 1`,
+			functions: `(jsonb_extract_path_text(information_assets.metadata, 'version', 'major'))::numeric > 1`,
+		},
+		{
+			name:      "array_membership",
+			source:    `"read" in json_users.settings.permissions`,
+			operators: `'read' = ANY(ARRAY(SELECT jsonb_array_elements_text(json_users.settings->'permissions')))`,
+			functions: `'read' = ANY(ARRAY(SELECT jsonb_array_elements_text(jsonb_extract_path(json_users.settings, 'permissions'))))`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.operators, got)
+
+			got, err = cel2sql.Convert(ast, cel2sql.WithJSONPathStyle(cel2sql.JSONPathOperators))
+			require.NoError(t, err)
+			assert.Equal(t, tt.operators, got)
+
+			got, err = cel2sql.Convert(ast, cel2sql.WithJSONPathStyle(cel2sql.JSONPathFunctions))
+			require.NoError(t, err)
+			assert.Equal(t, tt.functions, got)
+		})
+	}
+}
+
+func TestConvertHasSemantics(t *testing.T) {
+	env := newJSONTestEnv(t)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+		want   string
+	}{
+		{
+			name:   "default_direct",
+			source: `has(information_assets.metadata.corpus)`,
+			want:   `information_assets.metadata ? 'corpus'`,
+		},
+		{
+			name:   "default_nested",
+			source: `has(information_assets.metadata.corpus.section)`,
+			want:   `jsonb_extract_path_text(information_assets.metadata, 'corpus', 'section') IS NOT NULL`,
+		},
+		{
+			name:   "key_exists_direct",
+			source: `has(information_assets.metadata.corpus)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithHasSemantics(cel2sql.HasKeyExists)},
+			want:   `information_assets.metadata ? 'corpus'`,
+		},
+		{
+			name:   "key_exists_nested",
+			source: `has(information_assets.metadata.corpus.section)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithHasSemantics(cel2sql.HasKeyExists)},
+			want:   `information_assets.metadata->'corpus' ? 'section'`,
+		},
+		{
+			name:   "key_exists_nested_functions",
+			source: `has(information_assets.metadata.corpus.section)`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithHasSemantics(cel2sql.HasKeyExists),
+				cel2sql.WithJSONPathStyle(cel2sql.JSONPathFunctions),
+			},
+			want: `jsonb_extract_path(information_assets.metadata, 'corpus') ? 'section'`,
+		},
+		{
+			name:   "key_exists_json",
+			source: `has(information_assets.properties.visibility)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithHasSemantics(cel2sql.HasKeyExists)},
+			want:   `information_assets.properties->'visibility' IS NOT NULL`,
+		},
+		{
+			name:   "non_null_direct",
+			source: `has(information_assets.metadata.corpus)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithHasSemantics(cel2sql.HasNonNullValue)},
+			want:   `information_assets.metadata->>'corpus' IS NOT NULL`,
+		},
+		{
+			name:   "non_null_nested",
+			source: `has(information_assets.metadata.corpus.section)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithHasSemantics(cel2sql.HasNonNullValue)},
+			want:   `information_assets.metadata->'corpus'->>'section' IS NOT NULL`,
+		},
+		{
+			name:   "non_json_field",
+			source: `has(information_assets.id)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithHasSemantics(cel2sql.HasKeyExists)},
+			want:   `information_assets.id IS NOT NULL`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertJSONSchemaColumn(t *testing.T) {
+	payload, err := pg.JSONColumnFromJSONSchema("payload", "jsonb", []byte(`{
+		"type": "object",
+		"properties": {
+			"kind": {"type": "string"},
+			"count": {"type": "integer"},
+			"device": {"type": "object", "properties": {"os": {"type": "string"}}},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"items": {"type": "array", "items": {"$ref": "#/$defs/item"}}
+		},
+		"$defs": {"item": {"type": "object", "properties": {"sku": {"type": "string"}, "price": {"type": "number"}}}}
+	}`))
+	require.NoError(t, err)
+	schemas := map[string]pg.Schema{
+		"events": {
+			{Name: "id", Type: "bigint"},
+			{Name: "metadata", Type: "text"},
+			payload,
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("event", cel.ObjectType("events")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "key",
+			source: `event.payload.kind == "click"`,
+			want:   `event.payload->>'kind' = 'click'`,
+		},
+		{
+			name:   "nested_key",
+			source: `event.payload.device.os == "ios"`,
+			want:   `event.payload->'device'->>'os' = 'ios'`,
+		},
+		{
+			name:   "numeric_key",
+			source: `event.payload.count > 3`,
+			want:   `(event.payload->>'count')::numeric > 3`,
+		},
+		{
+			name:   "array_membership",
+			source: `"beta" in event.payload.tags`,
+			want:   `'beta' = ANY(ARRAY(SELECT jsonb_array_elements_text(event.payload->'tags')))`,
+		},
+		{
+			name:   "array_of_objects",
+			source: `event.payload.items.exists(i, i.sku == "A1")`,
+			want:   `EXISTS (SELECT 1 FROM jsonb_array_elements(event.payload->'items') AS i WHERE event.payload->'items' IS NOT NULL AND jsonb_typeof(event.payload->'items') = 'array' AND i->>'sku' = 'A1')`,
+		},
+		{
+			name:   "column_named_like_json",
+			source: `event.metadata == "x"`,
+			want:   `event.metadata = 'x'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertColumnMembership(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"accounts": {
+			{Name: "role", Type: "text"},
+			{Name: "level", Type: "integer"},
+			{Name: "allowed_roles", Type: "text", Repeated: true},
+			{Name: "allowed_roles_jsonb", Type: "jsonb"},
+			{Name: "allowed_roles_json", Type: "json"},
+			{Name: "allowed_levels", Type: "jsonb"},
+			{Name: "permissions", Type: "jsonb"},
+		},
+		"groups": {
+			{Name: "roles", Type: "text", Repeated: true},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("user", cel.ObjectType("accounts")),
+		cel.Variable("group", cel.ObjectType("groups")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "array_column",
+			source: `user.role in user.allowed_roles`,
+			want:   `user.role = ANY(user.allowed_roles)`,
+		},
+		{
+			name:   "array_column_of_other_table",
+			source: `user.role in group.roles`,
+			want:   `user.role = ANY(group.roles)`,
+		},
+		{
+			name:   "jsonb_array_column",
+			source: `user.role in user.allowed_roles_jsonb`,
+			want:   `user.allowed_roles_jsonb ? user.role`,
+		},
+		{
+			name:   "json_array_column",
+			source: `user.role in user.allowed_roles_json`,
+			want:   `(user.allowed_roles_json)::jsonb ? user.role`,
+		},
+		{
+			name:   "numeric_jsonb_array_column",
+			source: `user.level in user.allowed_levels`,
+			want:   `user.allowed_levels @> to_jsonb(user.level)`,
+		},
+		{
+			name:   "negated",
+			source: `!(user.level + 1 in user.allowed_levels)`,
+			want:   `NOT (user.allowed_levels @> to_jsonb(user.level + 1))`,
+		},
+		{
+			// ? matches the keys of an object as well as the elements of an array, where
+			// jsonb_array_elements_text would fail on an object
+			name:   "jsonb_object_column",
+			source: `"admin" in user.permissions`,
+			want:   `user.permissions ? 'admin'`,
+		},
+		{
+			name:   "concatenated_element",
+			source: `user.role + "_ro" in user.allowed_roles_jsonb`,
+			want:   `user.allowed_roles_jsonb ? (user.role || '_ro')`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertNestedJSONArrays(t *testing.T) {
+	env := newJSONTestEnv(t)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "one_level",
+			source: `information_assets.metadata.attributes.exists_one(attr, attr.name == "x")`,
+			want:   `(SELECT COUNT(*) FROM jsonb_array_elements(information_assets.metadata->'attributes') AS attr WHERE information_assets.metadata->'attributes' IS NOT NULL AND jsonb_typeof(information_assets.metadata->'attributes') = 'array' AND attr->>'name' = 'x') = 1`,
+		},
+		{
+			name:   "two_levels",
+			source: `information_assets.metadata.corpus.attributes.exists_one(attr, attr.name == "x")`,
+			want:   `(SELECT COUNT(*) FROM jsonb_array_elements(information_assets.metadata->'corpus'->'attributes') AS attr WHERE information_assets.metadata->'corpus'->'attributes' IS NOT NULL AND jsonb_typeof(information_assets.metadata->'corpus'->'attributes') = 'array' AND attr->>'name' = 'x') = 1`,
+		},
+		{
+			name:   "three_levels",
+			source: `information_assets.metadata.a.b.attributes.exists_one(attr, attr.name == "x")`,
+			want:   `(SELECT COUNT(*) FROM jsonb_array_elements(information_assets.metadata->'a'->'b'->'attributes') AS attr WHERE information_assets.metadata->'a'->'b'->'attributes' IS NOT NULL AND jsonb_typeof(information_assets.metadata->'a'->'b'->'attributes') = 'array' AND attr->>'name' = 'x') = 1`,
+		},
+		{
+			name:   "nested_text_array",
+			source: `information_assets.metadata.corpus.tags.exists(t, t == "x")`,
+			want:   `EXISTS (SELECT 1 FROM jsonb_array_elements_text(information_assets.metadata->'corpus'->'tags') AS t WHERE information_assets.metadata->'corpus'->'tags' IS NOT NULL AND jsonb_typeof(information_assets.metadata->'corpus'->'tags') = 'array' AND t = 'x')`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertNestedJSONArraysFromSchemas(t *testing.T) {
+	meta, err := pg.JSONColumnFromJSONSchema("meta", "jsonb", []byte(`{
+		"type": "object",
+		"properties": {
+			"a": {"type": "object", "properties": {"b": {"type": "object", "properties": {
+				"items": {"type": "array", "items": {"type": "string"}}
+			}}}}
+		}
+	}`))
+	require.NoError(t, err)
+	schemas := map[string]pg.Schema{
+		"accounts": {
+			{Name: "id", Type: "bigint"},
+			meta,
+			{Name: "raw", Type: "jsonb"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("account", cel.ObjectType("accounts")),
+	)
+	require.NoError(t, err)
+
+	// The schemas, not the names of the keys, tell arrays apart
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "described",
+			source: `account.meta.a.b.items.exists_one(i, i == "x")`,
+			want:   `(SELECT COUNT(*) FROM jsonb_array_elements_text(account.meta->'a'->'b'->'items') AS i WHERE account.meta->'a'->'b'->'items' IS NOT NULL AND jsonb_typeof(account.meta->'a'->'b'->'items') = 'array' AND i = 'x') = 1`,
+		},
+		{
+			name:   "undescribed",
+			source: `account.raw.a.b.items.exists_one(i, i == "x")`,
+			want:   `(SELECT COUNT(*) FROM jsonb_array_elements_text(CASE jsonb_typeof(account.raw->'a'->'b'->'items') WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(account.raw->'a'->'b'->'items'))) WHEN 'array' THEN account.raw->'a'->'b'->'items' END) AS i WHERE i = 'x') = 1`,
+		},
+		{
+			name:   "undescribed_membership",
+			source: `"x" in account.raw.a.b.items`,
+			want:   `account.raw->'a'->'b'->'items' ? 'x'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertJSONObjectComprehensions(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"stats": {
+			{Name: "counts", Type: "jsonb"},
+			{Name: "labels", Type: "json"},
+			{Name: "roles", Type: "jsonb"},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas),
+		cel.Variable("key", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "all_jsonb",
+			source: `stats.counts.all(k, stats.counts[k] > 0)`,
+			want:   "NOT EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(stats.counts) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(stats.counts))) WHEN 'array' THEN stats.counts END) AS k WHERE NOT ((stats.counts->>k)::numeric > 0))",
+		},
+		{
+			name:   "exists_json",
+			source: `stats.labels.exists(k, k.startsWith("env"))`,
+			want:   "EXISTS (SELECT 1 FROM json_array_elements_text(CASE json_typeof(stats.labels) WHEN 'object' THEN to_json(ARRAY(SELECT json_object_keys(stats.labels))) WHEN 'array' THEN stats.labels END) AS k WHERE k LIKE 'env%')",
+		},
+		{
+			name:   "exists_one_jsonb",
+			source: `stats.counts.exists_one(k, stats.counts[k] == 0)`,
+			want:   "(SELECT COUNT(*) FROM jsonb_array_elements_text(CASE jsonb_typeof(stats.counts) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(stats.counts))) WHEN 'array' THEN stats.counts END) AS k WHERE (stats.counts->>k)::numeric = 0) = 1",
+		},
+		{
+			// jsonb_object_keys raises an error on an array, which the column may hold as well
+			name:   "array_valued",
+			source: `stats.roles.exists(r, r == "admin")`,
+			want:   "EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(stats.roles) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(stats.roles))) WHEN 'array' THEN stats.roles END) AS r WHERE r = 'admin')",
+		},
+		{
+			name:   "literal_key",
+			source: `stats.counts["a"] > 1`,
+			want:   "(stats.counts->>'a')::numeric > 1",
+		},
+		{
+			name:   "variable_key",
+			source: `stats.labels[key] == "prod"`,
+			want:   "stats.labels->>key = 'prod'",
+		},
+		{
+			name:   "map_literal",
+			source: `{"a": 1, "b": 2}.all(k, {"a": 1, "b": 2}[k] > 0)`,
+			want:   "NOT EXISTS (SELECT 1 FROM jsonb_object_keys(jsonb_build_object('a', 1, 'b', 2)) AS k WHERE NOT ((jsonb_build_object('a', 1, 'b', 2)->>k)::numeric > 0))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, cel2sql.Validate(ast, cel2sql.WithSchemas(schemas)))
+		})
+	}
+}
+
+func TestConvertJSONObjectKeyPatterns(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"plugins": {
+			{Name: "name", Type: "text"},
+			{Name: "metadata", Type: "jsonb"},
+			{Name: "aliases", Type: "text", Repeated: true},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas),
+		cel.Variable("prefix", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "prefix",
+			source: `plugins.metadata.exists(k, k.startsWith("ext_"))`,
+			want:   `EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(plugins.metadata) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(plugins.metadata))) WHEN 'array' THEN plugins.metadata END) AS k WHERE k LIKE 'ext\_%')`,
+		},
+		{
+			name:   "suffix",
+			source: `plugins.metadata.all(k, !k.endsWith("100%"))`,
+			want:   `NOT EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(plugins.metadata) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(plugins.metadata))) WHEN 'array' THEN plugins.metadata END) AS k WHERE NOT (NOT k LIKE '%100\%'))`,
+		},
+		{
+			name:   "quoted",
+			source: `plugins.metadata.exists(k, k.startsWith("it's"))`,
+			want:   `EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(plugins.metadata) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(plugins.metadata))) WHEN 'array' THEN plugins.metadata END) AS k WHERE k LIKE 'it''s%')`,
+		},
+		{
+			name:   "variable_prefix",
+			source: `plugins.metadata.exists(k, k.startsWith(prefix))`,
+			want:   `EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(plugins.metadata) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(plugins.metadata))) WHEN 'array' THEN plugins.metadata END) AS k WHERE STARTS_WITH(k, prefix))`,
+		},
+		{
+			name:   "array_elements",
+			source: `plugins.aliases.exists(k, k.startsWith("ext_"))`,
+			want:   `EXISTS (SELECT 1 FROM UNNEST(plugins.aliases) AS k WHERE STARTS_WITH(k, 'ext_'))`,
+		},
+		{
+			name:   "shadowed",
+			source: `plugins.metadata.exists(k, plugins.aliases.exists(k, k.startsWith("a")))`,
+			want:   `EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(plugins.metadata) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(plugins.metadata))) WHEN 'array' THEN plugins.metadata END) AS k WHERE EXISTS (SELECT 1 FROM UNNEST(plugins.aliases) AS k WHERE STARTS_WITH(k, 'a')))`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertDynComparisons(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"events": {
+			{Name: "kind", Type: "text"},
+			{Name: "payload", Type: "jsonb"},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "string",
+			source: `events.payload.status == "done"`,
+			want:   "events.payload->>'status' = 'done'",
+		},
+		{
+			name:   "string_on_left",
+			source: `"done" != events.payload.status`,
+			want:   "'done' != events.payload->>'status'",
+		},
+		{
+			name:   "int",
+			source: `events.payload.attempts >= 3`,
+			want:   "(events.payload->>'attempts')::numeric >= 3",
+		},
+		{
+			name:   "double_on_left",
+			source: `0.5 < events.payload.ratio`,
+			want:   "0.5 < (events.payload->>'ratio')::numeric",
+		},
+		{
+			name:   "bool",
+			source: `events.payload.retried == true`,
+			want:   "(events.payload->>'retried')::boolean IS TRUE",
+		},
+		{
+			name:   "bool_on_left",
+			source: `false != events.payload.retried`,
+			want:   "(events.payload->>'retried')::boolean IS NOT FALSE",
+		},
+		{
+			name:   "null",
+			source: `events.payload.error == null`,
+			want:   "events.payload->>'error' IS NULL",
+		},
+		{
+			name:   "null_on_left",
+			source: `null != events.payload.error`,
+			want:   "events.payload->>'error' IS NOT NULL",
+		},
+		{
+			name:   "json_values",
+			source: `events.payload.from == events.payload.to`,
+			want:   "events.payload->>'from' = events.payload->>'to'",
+		},
+		{
+			name:   "object_keys",
+			source: `events.payload.exists(k, events.payload[k] == true)`,
+			want:   "EXISTS (SELECT 1 FROM jsonb_array_elements_text(CASE jsonb_typeof(events.payload) WHEN 'object' THEN to_jsonb(ARRAY(SELECT jsonb_object_keys(events.payload))) WHEN 'array' THEN events.payload END) AS k WHERE (events.payload->>k)::boolean IS TRUE)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
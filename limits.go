@@ -0,0 +1,108 @@
+package cel2sql
+
+import (
+	"fmt"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// DefaultMaxDepth is the expression nesting depth enforced when Limits.MaxDepth is zero.
+// The converter is recursive, so unbounded nesting in programmatically built ASTs could
+// otherwise exhaust the goroutine stack.
+const DefaultMaxDepth = 1000
+
+// Limits bounds the size and complexity of the SQL generated for an expression, protecting
+// the database from pathological user-supplied filters. A zero value disables the limit,
+// except for MaxDepth which always applies DefaultMaxDepth unless it is negative.
+type Limits struct {
+	MaxSQLLength  int // maximum length of the generated SQL in bytes
+	MaxSubqueries int // maximum number of subqueries (comprehensions, JSON array membership tests)
+	MaxInListSize int // maximum number of elements in a list literal on the right of 'in'
+	MaxDepth      int // maximum expression nesting depth; zero means DefaultMaxDepth, negative disables it
+}
+
+// LimitError is returned by Convert when an expression exceeds one of the configured Limits.
+type LimitError struct {
+	Limit  string // name of the exceeded limit, e.g. "MaxSubqueries"
+	Max    int
+	Actual int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("expression exceeds %s: %d > %d", e.Limit, e.Actual, e.Max)
+}
+
+// WithLimits enforces the given limits during conversion.
+func WithLimits(limits Limits) ConvertOption {
+	return func(o *convertOptions) {
+		o.limits = limits
+	}
+}
+
+// countSubquery records a generated subquery and checks it against MaxSubqueries
+func (con *converter) countSubquery() error {
+	con.subqueries++
+	if limit := con.opts.limits.MaxSubqueries; limit > 0 && con.subqueries > limit {
+		return &LimitError{Limit: "MaxSubqueries", Max: limit, Actual: con.subqueries}
+	}
+	return nil
+}
+
+// checkInListSize checks a list literal used with 'in' against MaxInListSize
+func (con *converter) checkInListSize(size int) error {
+	if limit := con.opts.limits.MaxInListSize; limit > 0 && size > limit {
+		return &LimitError{Limit: "MaxInListSize", Max: limit, Actual: size}
+	}
+	return nil
+}
+
+// checkSQLLength checks the SQL generated so far against MaxSQLLength, after each node is visited
+func (con *converter) checkSQLLength() error {
+	if limit := con.opts.limits.MaxSQLLength; limit > 0 && con.str.Len() > limit {
+		return &LimitError{Limit: "MaxSQLLength", Max: limit, Actual: con.str.Len()}
+	}
+	return nil
+}
+
+// checkDepth measures the nesting depth of expr with an explicit stack, so that hostile input
+// is rejected before any of the recursive visitors run.
+func (con *converter) checkDepth(expr *exprpb.Expr) error {
+	limit := con.opts.limits.MaxDepth
+	if limit < 0 {
+		return nil
+	}
+	if limit == 0 {
+		limit = DefaultMaxDepth
+	}
+	if depth := exprDepth(expr, limit); depth > limit {
+		return &LimitError{Limit: "MaxDepth", Max: limit, Actual: depth}
+	}
+	return nil
+}
+
+// exprDepth returns the nesting depth of expr, stopping early once it exceeds stopAfter.
+func exprDepth(expr *exprpb.Expr, stopAfter int) int {
+	type frame struct {
+		expr  *exprpb.Expr
+		depth int
+	}
+	maxDepth := 0
+	stack := make([]frame, 1, 16)
+	stack[0] = frame{expr: expr, depth: 1}
+	var children []*exprpb.Expr
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.depth > maxDepth {
+			maxDepth = f.depth
+			if maxDepth > stopAfter {
+				return maxDepth
+			}
+		}
+		children = appendChildExprs(children[:0], f.expr)
+		for _, child := range children {
+			stack = append(stack, frame{expr: child, depth: f.depth + 1})
+		}
+	}
+	return maxDepth
+}
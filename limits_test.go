@@ -0,0 +1,138 @@
+package cel2sql_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/operators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertLimits(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("tags", cel.ListType(cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		source    string
+		limits    cel2sql.Limits
+		want      string
+		wantLimit string
+	}{
+		{
+			name:   "within_limits",
+			source: `name in ["a", "b"] && tags.exists(t, t == name)`,
+			limits: cel2sql.Limits{MaxSQLLength: 200, MaxSubqueries: 1, MaxInListSize: 2},
+			want:   "name = ANY(ARRAY['a', 'b']) AND EXISTS (SELECT 1 FROM UNNEST(tags) AS t WHERE t = name)",
+		},
+		{
+			name:      "sql_length",
+			source:    `name == "a very long string literal"`,
+			limits:    cel2sql.Limits{MaxSQLLength: 10},
+			wantLimit: "MaxSQLLength",
+		},
+		{
+			name:      "subqueries",
+			source:    `tags.exists(t, t == "a") && tags.all(t, t != "b")`,
+			limits:    cel2sql.Limits{MaxSubqueries: 1},
+			wantLimit: "MaxSubqueries",
+		},
+		{
+			name:      "nested_subqueries",
+			source:    `tags.exists(t, tags.exists(u, u == t))`,
+			limits:    cel2sql.Limits{MaxSubqueries: 1},
+			wantLimit: "MaxSubqueries",
+		},
+		{
+			name:      "in_list_size",
+			source:    `name in ["a", "b", "c"]`,
+			limits:    cel2sql.Limits{MaxInListSize: 2},
+			wantLimit: "MaxInListSize",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithLimits(tt.limits))
+			if tt.wantLimit != "" {
+				var limitErr *cel2sql.LimitError
+				require.True(t, errors.As(err, &limitErr), "expected LimitError, got %v", err)
+				assert.Equal(t, tt.wantLimit, limitErr.Limit)
+				assert.Greater(t, limitErr.Actual, limitErr.Max)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertMaxSQLLengthStopsEarly(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("name", cel.StringType))
+	require.NoError(t, err)
+
+	terms := make([]string, 100)
+	for i := range terms {
+		terms[i] = fmt.Sprintf("name == %q", strings.Repeat("x", i))
+	}
+	ast, issues := env.Compile(strings.Join(terms, " || "))
+	require.Empty(t, issues)
+
+	// The conversion stops at the first node that takes the SQL past the limit
+	calls := 0
+	counter := func(_ cel2sql.Node, next func() (string, error)) (string, error) {
+		calls++
+		return next()
+	}
+	_, err = cel2sql.Convert(ast, cel2sql.WithLimits(cel2sql.Limits{MaxSQLLength: 50}), cel2sql.WithInterceptors(counter))
+	var limitErr *cel2sql.LimitError
+	require.True(t, errors.As(err, &limitErr), "expected LimitError, got %v", err)
+	assert.Equal(t, "MaxSQLLength", limitErr.Limit)
+	assert.Less(t, calls, 20)
+}
+
+func TestConvertMaxDepth(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("adult", cel.BoolType))
+	require.NoError(t, err)
+
+	ast, issues := env.Compile(`!(!(!(!adult)))`)
+	require.Empty(t, issues)
+
+	_, err = cel2sql.Convert(ast, cel2sql.WithLimits(cel2sql.Limits{MaxDepth: 3}))
+	var limitErr *cel2sql.LimitError
+	require.True(t, errors.As(err, &limitErr), "expected LimitError, got %v", err)
+	assert.Equal(t, "MaxDepth", limitErr.Limit)
+
+	got, err := cel2sql.Convert(ast, cel2sql.WithLimits(cel2sql.Limits{MaxDepth: 5}))
+	require.NoError(t, err)
+	assert.Equal(t, "NOT NOT NOT NOT adult", got)
+
+	// Programmatically built ASTs are not bounded by the parser's recursion limit
+	expr := &exprpb.Expr{Id: 1, ExprKind: &exprpb.Expr_IdentExpr{IdentExpr: &exprpb.Expr_Ident{Name: "adult"}}}
+	typeMap := map[int64]*exprpb.Type{1: decls.Bool}
+	for id := int64(2); id <= 100000; id++ {
+		expr = &exprpb.Expr{Id: id, ExprKind: &exprpb.Expr_CallExpr{CallExpr: &exprpb.Expr_Call{
+			Function: operators.LogicalNot,
+			Args:     []*exprpb.Expr{expr},
+		}}}
+		typeMap[id] = decls.Bool
+	}
+	deep := cel.CheckedExprToAst(&exprpb.CheckedExpr{Expr: expr, TypeMap: typeMap})
+
+	_, err = cel2sql.Convert(deep)
+	require.True(t, errors.As(err, &limitErr), "expected LimitError, got %v", err)
+	assert.Equal(t, cel2sql.DefaultMaxDepth, limitErr.Max)
+}
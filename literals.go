@@ -0,0 +1,128 @@
+package cel2sql
+
+import (
+	"strconv"
+
+	"github.com/google/cel-go/common/operators"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// castLiterals validates the literals compared with columns of the schemas supplied with
+// WithSchemas against the column types, and records the casts they are written with, e.g.
+// status = 'active'::order_status or price = 9.99::numeric(10,2).
+func (con *converter) castLiterals(fun string, lhs, rhs *exprpb.Expr) error {
+	switch fun {
+	case operators.Equals, operators.NotEquals, operators.Less, operators.LessEquals, operators.Greater, operators.GreaterEquals:
+		if err := con.castTemporalLiteral(lhs, rhs); err != nil {
+			return err
+		}
+		if err := con.castTemporalLiteral(rhs, lhs); err != nil {
+			return err
+		}
+	}
+	switch fun {
+	case operators.Equals, operators.NotEquals:
+		if err := con.castLiteral(lhs, rhs, false, true); err != nil {
+			return err
+		}
+		return con.castLiteral(rhs, lhs, false, true)
+	case operators.Less, operators.LessEquals, operators.Greater, operators.GreaterEquals:
+		// A bound outside the range of the column is a valid, if trivial, filter
+		if err := con.castLiteral(lhs, rhs, false, false); err != nil {
+			return err
+		}
+		return con.castLiteral(rhs, lhs, false, false)
+	case operators.In, operators.OldIn:
+		for _, elem := range rhs.GetListExpr().GetElements() {
+			if err := con.castLiteral(lhs, elem, false, true); err != nil {
+				return err
+			}
+		}
+		return con.castLiteral(rhs, lhs, true, true)
+	}
+	return nil
+}
+
+// castLiteral validates and records the cast of a literal compared with column, or with the
+// elements of column if repeated is set. Exact comparisons also check that the literal fits the
+// precision, scale or length of the column, as other values can never match.
+func (con *converter) castLiteral(column, literal *exprpb.Expr, repeated, exact bool) error {
+	value := literal.GetConstExpr()
+	if value == nil {
+		return nil
+	}
+	field, found := con.findField(unwrapDyn(column))
+	if !found || field.Repeated != repeated {
+		return nil
+	}
+
+	var cast string
+	var err error
+	switch {
+	case len(field.Enum) > 0:
+		cast, err = enumCast(field, value)
+	case exact:
+		cast, err = typeModifierCast(field, value)
+	}
+	if err != nil || cast == "" {
+		return err
+	}
+	if con.literalCasts == nil {
+		con.literalCasts = make(map[int64]string)
+	}
+	con.literalCasts[literal.GetId()] = cast
+	return nil
+}
+
+// castTemporalLiteral validates a string literal compared with a date or timestamp and records
+// its cast to the type of the other operand, as text would be compared character by character
+func (con *converter) castTemporalLiteral(operand, literal *exprpb.Expr) error {
+	if !isStringLiteral(literal) {
+		return nil
+	}
+	typ := con.getType(unwrapDyn(operand))
+	var cast string
+	switch {
+	case isDateType(typ):
+		cast = "date"
+	case isTimestampType(typ):
+		cast = "timestamptz"
+	default:
+		return nil
+	}
+	if err := checkTemporalString(cast, literal.GetConstExpr().GetStringValue()); err != nil {
+		return err
+	}
+	if con.literalCasts == nil {
+		con.literalCasts = make(map[int64]string)
+	}
+	con.literalCasts[literal.GetId()] = cast
+	return nil
+}
+
+// writeLiteralCast writes the cast recorded for a literal by castLiterals
+func (con *converter) writeLiteralCast(literal *exprpb.Expr) {
+	if cast, found := con.literalCasts[literal.GetId()]; found {
+		con.str.WriteString("::")
+		con.str.WriteString(cast)
+	}
+}
+
+// isNumericColumn checks if field is a numeric or decimal column
+func isNumericColumn(field pg.FieldSchema) bool {
+	return field.Type == "numeric" || field.Type == "decimal"
+}
+
+// formatDouble formats a finite double literal in the form selected with WithFloatStyle and
+// WithFloatPrecision
+func (con *converter) formatDouble(d float64) string {
+	switch {
+	case con.opts.floatPrecision != nil:
+		return strconv.FormatFloat(d, 'f', *con.opts.floatPrecision, 64)
+	case con.opts.floatStyle == FloatDecimal:
+		return strconv.FormatFloat(d, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(d, 'g', -1, 64)
+}
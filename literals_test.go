@@ -0,0 +1,65 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertFloatFormat(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("total", cel.DoubleType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+		want   string
+	}{
+		{
+			name:   "shortest",
+			source: `total > 1000000.0 && total < 0.0000015`,
+			want:   "total > 1e+06 AND total < 1.5e-06",
+		},
+		{
+			name:   "decimal",
+			source: `total > 1000000.0 && total < 0.0000015`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithFloatStyle(cel2sql.FloatDecimal)},
+			want:   "total > 1000000 AND total < 0.0000015",
+		},
+		{
+			name:   "fixed_precision",
+			source: `total >= 19.9 && total != 1234567.126`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithFloatPrecision(2)},
+			want:   "total >= 19.90 AND total != 1234567.13",
+		},
+		{
+			name:   "precision_over_style",
+			source: `total == -0.5`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithFloatStyle(cel2sql.FloatShortest), cel2sql.WithFloatPrecision(0)},
+			want:   "total = -0",
+		},
+		{
+			name:   "integers_unchanged",
+			source: `int(total) > 1000000`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithFloatPrecision(2)},
+			want:   "CAST(total AS INT64) > 1000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
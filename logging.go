@@ -0,0 +1,42 @@
+package cel2sql
+
+import (
+	"context"
+	"log/slog"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// WithLogger logs, at debug level, the decisions that shape the generated SQL without showing
+// in it: regular expressions rewritten from RE2 to POSIX, fields treated as JSON or cast to
+// numeric because of their name rather than a schema, and unknown functions passed through to
+// SQL under the same name. Use slog.New to log to a slog.Handler.
+func WithLogger(logger *slog.Logger) ConvertOption {
+	return func(o *convertOptions) {
+		o.logger = logger
+	}
+}
+
+// logDebug logs a conversion decision when a logger is set with WithLogger
+func (con *converter) logDebug(msg string, args ...any) {
+	if con.opts.logger == nil || !con.opts.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	con.opts.logger.Debug(msg, args...)
+}
+
+// logJSONHeuristic logs a field selection rendered as JSON access because of the name of the
+// selected column rather than its schema
+func (con *converter) logJSONHeuristic(sel *exprpb.Expr_Select) {
+	if con.opts.logger == nil {
+		return
+	}
+	if _, known := con.jsonColumnOf(con.getType(sel.GetOperand())); known {
+		return
+	}
+	if field, found := con.findField(sel.GetOperand()); found && isJSONType(field.Type) {
+		return
+	}
+	con.logDebug("treated field as JSON by name",
+		"field", sel.GetOperand().GetSelectExpr().GetField(), "key", sel.GetField())
+}
@@ -0,0 +1,111 @@
+package cel2sql_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestWithLogger(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"users": {
+			{Name: "name", Type: "text"},
+			{Name: "preferences", Type: "jsonb"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("user", cel.ObjectType("users")),
+		cel.Variable("pattern", cel.StringType),
+		cel.Function("soundex", cel.Overload("soundex_string", []*cel.Type{cel.StringType}, cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+		want   []map[string]any
+	}{
+		{
+			name:   "regex_rewrite",
+			source: `user.name.matches("\\d+")`,
+			want:   []map[string]any{{"msg": "rewrote RE2 pattern for POSIX", "pattern": `\d+`, "posix": "[0-9]+"}},
+		},
+		{
+			name:   "regex_unchanged",
+			source: `user.name.matches("^a")`,
+		},
+		{
+			name:   "regex_not_literal",
+			source: `user.name.matches(pattern)`,
+			want:   []map[string]any{{"msg": "passed non-literal pattern to PostgreSQL without RE2 to POSIX conversion"}},
+		},
+		{
+			name:   "json_by_name",
+			source: `user.preferences.theme == "dark"`,
+			want:   []map[string]any{{"msg": "treated field as JSON by name", "field": "preferences", "key": "theme"}},
+		},
+		{
+			name:   "json_by_schema",
+			source: `user.preferences.theme == "dark"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas)},
+		},
+		{
+			name:   "unknown_function",
+			source: `soundex(user.name) == "A000"`,
+			want:   []map[string]any{{"msg": "passed unknown function through to SQL", "function": "soundex", "sql": "SOUNDEX"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+				Level: slog.LevelDebug,
+				ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+			_, err := cel2sql.Convert(ast, append(tt.opts, cel2sql.WithLogger(logger))...)
+			require.NoError(t, err)
+
+			var got []map[string]any
+			for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+				if len(line) == 0 {
+					continue
+				}
+				var record map[string]any
+				require.NoError(t, json.Unmarshal(line, &record))
+				got = append(got, record)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithLoggerAboveDebug(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("name", cel.StringType))
+	require.NoError(t, err)
+	ast, issues := env.Compile(`name.matches("\\d+")`)
+	require.NoError(t, issues.Err())
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	_, err = cel2sql.Convert(ast, cel2sql.WithLogger(logger))
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,65 @@
+package cel2sql
+
+import (
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// WithMacro converts the expansions of a custom macro, registered with cel.Macros, from the
+// macro call as written rather than from the comprehension or expression the macro expands to,
+// which the converter may not recognize. fn receives the SQL of the target of a receiver macro
+// followed by that of the arguments, e.g. Template("{0} ~ ANY({1})") for name.matchesAny(list).
+//
+// Macro calls are only recorded in ASTs compiled with cel.EnableMacroCallTracking. Arguments
+// that the macro copies into its expansion, rather than reusing, are converted without their
+// types, which is enough for identifiers and literals.
+func WithMacro(name string, fn FunctionSQL) ConvertOption {
+	return func(o *convertOptions) {
+		if o.macros == nil {
+			o.macros = make(map[string]FunctionSQL)
+		}
+		o.macros[name] = fn
+	}
+}
+
+// macroCall returns the call of a macro set with WithMacro that expanded to expr, with its
+// arguments resolved to the expressions of the expansion
+func (con *converter) macroCall(expr *exprpb.Expr) (*exprpb.Expr, FunctionSQL, bool) {
+	if len(con.opts.macros) == 0 {
+		return nil, nil, false
+	}
+	call := con.checkedSourceInfo.GetMacroCalls()[expr.GetId()].GetCallExpr()
+	if call == nil {
+		return nil, nil, false
+	}
+	fn, found := con.opts.macros[call.GetFunction()]
+	if !found {
+		return nil, nil, false
+	}
+	resolved := &exprpb.Expr_Call{
+		Function: call.GetFunction(),
+		Target:   con.resolveMacroArg(call.GetTarget()),
+		Args:     make([]*exprpb.Expr, len(call.GetArgs())),
+	}
+	for i, arg := range call.GetArgs() {
+		resolved.Args[i] = con.resolveMacroArg(arg)
+	}
+	return &exprpb.Expr{Id: expr.GetId(), ExprKind: &exprpb.Expr_CallExpr{CallExpr: resolved}}, fn, true
+}
+
+// resolveMacroArg replaces an argument of a macro call that stands for a nested macro call,
+// recorded by its ID only, with the expansion of that call
+func (con *converter) resolveMacroArg(arg *exprpb.Expr) *exprpb.Expr {
+	if arg == nil || arg.GetExprKind() != nil {
+		return arg
+	}
+	stack := []*exprpb.Expr{con.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node.GetId() == arg.GetId() {
+			return node
+		}
+		stack = appendChildExprs(stack, node)
+	}
+	return arg
+}
@@ -0,0 +1,95 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/overloads"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+// matchesAny expands name.matchesAny(patterns) to a comprehension counting the matching
+// patterns, which the converter does not recognize
+var matchesAny = cel.ReceiverMacro("matchesAny", 1,
+	func(eh parser.ExprHelper, target celast.Expr, args []celast.Expr) (celast.Expr, *common.Error) {
+		step := eh.NewCall(operators.Conditional,
+			eh.NewMemberCall(overloads.Matches, eh.Copy(target), eh.NewIdent("p")),
+			eh.NewCall(operators.Add, eh.NewAccuIdent(), eh.NewLiteral(types.Int(1))),
+			eh.NewAccuIdent())
+		result := eh.NewCall(operators.Greater, eh.NewAccuIdent(), eh.NewLiteral(types.Int(0)))
+		return eh.NewComprehension(args[0], "p", eh.AccuIdentName(), eh.NewLiteral(types.Int(0)),
+			eh.NewLiteral(types.True), step, result), nil
+	})
+
+func TestWithMacro(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("patterns", cel.ListType(cel.StringType)),
+		cel.Macros(matchesAny),
+		cel.EnableMacroCallTracking(),
+	)
+	require.NoError(t, err)
+
+	macro := cel2sql.WithMacro("matchesAny", cel2sql.Template("{0} ~ ANY({1})"))
+	tests := []struct {
+		name    string
+		source  string
+		opts    []cel2sql.ConvertOption
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "list_literal",
+			source: `name.matchesAny(["^a", "^b"])`,
+			opts:   []cel2sql.ConvertOption{macro},
+			want:   "name ~ ANY(ARRAY['^a', '^b'])",
+		},
+		{
+			name:   "nested_macro",
+			source: `name.matchesAny(patterns.filter(p, p != "")) && name != ""`,
+			opts:   []cel2sql.ConvertOption{macro},
+			want:   "name ~ ANY(ARRAY(SELECT p FROM UNNEST(patterns) AS p WHERE p != '')) AND name != ''",
+		},
+		{
+			name:   "placeholders",
+			source: `name.matchesAny(["^a"])`,
+			opts:   []cel2sql.ConvertOption{macro, cel2sql.WithLiteralPlaceholders()},
+			want:   "name ~ ANY(ARRAY[$1])",
+		},
+		{
+			name:    "without_macro",
+			source:  `name.matchesAny(["^a", "^b"])`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Error(t, cel2sql.Validate(ast, tt.opts...))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, cel2sql.Validate(ast, tt.opts...))
+
+			checkedExpr, err := cel.AstToCheckedExpr(ast)
+			require.NoError(t, err)
+			got, err = cel2sql.ConvertCheckedExpr(checkedExpr, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package cel2sql
+
+import (
+	"time"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// CEL features counted with MetricsSink.IncFeature and disallowed with WithDisallowedFeatures
+const (
+	FeatureComprehension = "comprehension" // all, exists, exists_one, filter or map macro
+	FeatureJSONPath      = "json_path"     // field access inside a JSON column
+	FeatureRegex         = "regex"         // matches
+)
+
+// MetricsSink receives measurements of conversions, see WithMetrics. It is typically backed by
+// Prometheus histograms and counters, and must be safe for concurrent use.
+type MetricsSink interface {
+	// ObserveConversion records a conversion: the time spent converting the checked expression,
+	// the length of the generated SQL, and the error if it failed.
+	ObserveConversion(duration time.Duration, sqlLength int, err error)
+	// IncFeature counts an expression using a CEL feature, see FeatureComprehension and the
+	// like. Features are counted once per successful conversion.
+	IncFeature(feature string)
+}
+
+// WithMetrics reports the duration, SQL length and features used of conversions to sink.
+// Conversions served from a Cache are reported with the duration of the lookup.
+func WithMetrics(sink MetricsSink) ConvertOption {
+	return func(o *convertOptions) {
+		o.metrics = sink
+	}
+}
+
+// observe reports a conversion of expr that started at start to the metrics sink
+func (con *converter) observe(expr *exprpb.Expr, start time.Time, err error) {
+	sink := con.opts.metrics
+	if err != nil {
+		sink.ObserveConversion(time.Since(start), 0, err)
+		return
+	}
+	sink.ObserveConversion(time.Since(start), con.str.Len(), nil)
+	con.features = con.usedFeatures(expr)
+	for _, feature := range con.features {
+		sink.IncFeature(feature)
+	}
+}
+
+// observeCached reports a conversion served from a Cache to the metrics sink, taking the time of
+// the lookup that started at start
+func (p *Plan) observeCached(sink MetricsSink, start time.Time) {
+	sink.ObserveConversion(time.Since(start), len(p.result.SQL), nil)
+	for _, feature := range p.features {
+		sink.IncFeature(feature)
+	}
+}
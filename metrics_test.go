@@ -0,0 +1,109 @@
+package cel2sql_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+type recordingSink struct {
+	mu          sync.Mutex
+	conversions []int
+	errors      int
+	features    map[string]int
+}
+
+func (s *recordingSink) ObserveConversion(duration time.Duration, sqlLength int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if duration < 0 {
+		panic("negative duration")
+	}
+	if err != nil {
+		s.errors++
+	}
+	s.conversions = append(s.conversions, sqlLength)
+}
+
+func (s *recordingSink) IncFeature(feature string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.features == nil {
+		s.features = make(map[string]int)
+	}
+	s.features[feature]++
+}
+
+func TestWithMetrics(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(map[string]pg.Schema{
+			"users": {
+				{Name: "name", Type: "text"},
+				{Name: "tags", Type: "text", Repeated: true},
+				{Name: "preferences", Type: "jsonb"},
+			},
+		})),
+		cel.Variable("user", cel.ObjectType("users")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		source       string
+		opts         []cel2sql.ConvertOption
+		wantFeatures map[string]int
+		wantErr      bool
+	}{
+		{
+			name:   "plain",
+			source: `user.name == "a"`,
+		},
+		{
+			name:         "comprehension",
+			source:       `user.tags.exists(t, t == "admin") && user.tags.all(t, t != "")`,
+			wantFeatures: map[string]int{cel2sql.FeatureComprehension: 1},
+		},
+		{
+			name:         "json_path_and_regex",
+			source:       `user.preferences.theme == "dark" && user.name.matches("^a")`,
+			wantFeatures: map[string]int{cel2sql.FeatureJSONPath: 1, cel2sql.FeatureRegex: 1},
+		},
+		{
+			name:    "failure",
+			source:  `user.name.matches("^a")`,
+			opts:    []cel2sql.ConvertOption{cel2sql.WithDeniedColumns("users.name")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			sink := &recordingSink{}
+			sql, err := cel2sql.Convert(ast, append(tt.opts, cel2sql.WithMetrics(sink))...)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, 1, sink.errors)
+				assert.Equal(t, []int{0}, sink.conversions)
+				assert.Empty(t, sink.features)
+				return
+			}
+			require.NoError(t, err)
+			assert.Zero(t, sink.errors)
+			assert.Equal(t, []int{len(sql)}, sink.conversions)
+			if tt.wantFeatures == nil {
+				assert.Empty(t, sink.features)
+			} else {
+				assert.Equal(t, tt.wantFeatures, sink.features)
+			}
+		})
+	}
+}
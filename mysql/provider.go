@@ -0,0 +1,286 @@
+// Package mysql provides a CEL type provider for MySQL tables, mirroring the pg package:
+// schemas are declared directly or introspected from information_schema through database/sql,
+// with any MySQL driver.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2/sqltypes"
+)
+
+// FieldSchema represents a MySQL column with its name and type.
+type FieldSchema struct {
+	Name    string
+	Type    string   // MySQL column type, e.g. int, bigint unsigned, tinyint(1) or varchar(255)
+	Enum    []string // allowed values of ENUM and SET columns, Type being enum or set
+	NotNull bool     // true for columns declared NOT NULL
+}
+
+// Schema represents a MySQL table schema as a slice of field schemas.
+type Schema []FieldSchema
+
+// TypeProvider interface for MySQL type providers. A TypeProvider is safe for concurrent use.
+type TypeProvider interface {
+	types.Provider
+	LoadTableSchema(ctx context.Context, tableName string) error
+	LoadSchema(ctx context.Context, databaseName string) error
+	Tables() []string
+}
+
+type typeProvider struct {
+	mu      sync.RWMutex // guards schemas
+	schemas map[string]Schema
+	db      *sql.DB
+}
+
+// NewTypeProvider creates a new MySQL type provider with pre-defined schemas. The map is copied,
+// so the caller may keep modifying it without affecting the provider.
+func NewTypeProvider(schemas map[string]Schema) TypeProvider {
+	p := &typeProvider{schemas: make(map[string]Schema, len(schemas))}
+	for tableName, schema := range schemas {
+		p.schemas[tableName] = schema
+	}
+	return p
+}
+
+// NewTypeProviderWithDB creates a new MySQL type provider that introspects table schemas
+// through database/sql. The database remains owned by the caller.
+func NewTypeProviderWithDB(db *sql.DB) TypeProvider {
+	return &typeProvider{
+		schemas: make(map[string]Schema),
+		db:      db,
+	}
+}
+
+// columnsQuery selects the columns of the tables and views of database ? (the current database
+// when NULL), or only of table ? when it is not NULL
+const columnsQuery = `
+		SELECT
+			c.TABLE_NAME,
+			c.COLUMN_NAME,
+			c.DATA_TYPE,
+			c.COLUMN_TYPE,
+			c.IS_NULLABLE,
+			c.TABLE_SCHEMA = DATABASE() AS is_current
+		FROM information_schema.COLUMNS c
+		WHERE c.TABLE_SCHEMA = COALESCE(?, DATABASE())
+		AND (? IS NULL OR c.TABLE_NAME = ?)
+		ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION
+	`
+
+// Tables returns the names of the known tables, sorted
+func (p *typeProvider) Tables() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Sorted(maps.Keys(p.schemas))
+}
+
+// LoadTableSchema loads schema information for a table from the database. The table name may
+// be qualified with its database, e.g. "analytics.events", in which case the schema is
+// registered under the qualified name and CEL types must use it too. Unqualified names are
+// looked up in the current database.
+func (p *typeProvider) LoadTableSchema(ctx context.Context, tableName string) error {
+	if p.db == nil {
+		return errors.New("no database connection available")
+	}
+	var databaseName any
+	table := tableName
+	if i := strings.Index(tableName, "."); i >= 0 {
+		databaseName, table = tableName[:i], tableName[i+1:]
+	}
+	schemas, _, err := p.queryColumns(ctx, databaseName, table)
+	if err != nil {
+		return err
+	}
+
+	// Unknown tables are registered without columns
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.schemas[tableName] = schemas[table]
+	return nil
+}
+
+// LoadSchema loads every table and view of a database in a single query. Tables are registered
+// under their qualified names, e.g. "analytics.events", and also under their unqualified names
+// when the database is the current database of the connection.
+func (p *typeProvider) LoadSchema(ctx context.Context, databaseName string) error {
+	if p.db == nil {
+		return errors.New("no database connection available")
+	}
+	schemas, current, err := p.queryColumns(ctx, databaseName, nil)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for tableName, schema := range schemas {
+		p.schemas[databaseName+"."+tableName] = schema
+		if current {
+			p.schemas[tableName] = schema
+		}
+	}
+	return nil
+}
+
+// queryColumns queries the columns of the tables of a database, or only of the given table if
+// not nil, reporting whether the database is the current one
+func (p *typeProvider) queryColumns(ctx context.Context, databaseName, table any) (map[string]Schema, bool, error) {
+	rows, err := p.db.QueryContext(ctx, columnsQuery, databaseName, table, table)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query table schema: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	schemas := make(map[string]Schema)
+	current := false
+	for rows.Next() {
+		var tableName, columnName, dataType, columnType, isNullable string
+		if err := rows.Scan(&tableName, &columnName, &dataType, &columnType, &isNullable, &current); err != nil {
+			return nil, false, fmt.Errorf("failed to scan row: %w", err)
+		}
+		field := FieldSchema{
+			Name:    columnName,
+			Type:    strings.ToLower(columnType),
+			NotNull: isNullable == "NO",
+		}
+		if dataType = strings.ToLower(dataType); dataType == "enum" || dataType == "set" {
+			field.Type = dataType
+			field.Enum = parseValues(columnType)
+		}
+		schemas[tableName] = append(schemas[tableName], field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return schemas, current, nil
+}
+
+// parseValues parses the quoted values of an ENUM or SET column type such as
+// enum('active','suspended'), in which quotes are doubled
+func parseValues(columnType string) []string {
+	values := []string{}
+	var value strings.Builder
+	quoted := false
+	for i := 0; i < len(columnType); i++ {
+		c := columnType[i]
+		switch {
+		case c == '\'' && quoted && i+1 < len(columnType) && columnType[i+1] == '\'':
+			value.WriteByte('\'')
+			i++
+		case c == '\'' && quoted:
+			values = append(values, value.String())
+			value.Reset()
+			quoted = false
+		case c == '\'':
+			quoted = true
+		case quoted:
+			value.WriteByte(c)
+		}
+	}
+	return values
+}
+
+func (p *typeProvider) findSchema(typeName string) (Schema, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	schema, found := p.schemas[typeName]
+	return schema, found && len(schema) > 0
+}
+
+func (p *typeProvider) EnumValue(enumName string) ref.Val {
+	return types.NewErr("unknown enum name '%s'", enumName)
+}
+
+func (p *typeProvider) FindIdent(_ string) (ref.Val, bool) {
+	return nil, false
+}
+
+func (p *typeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, found := p.findSchema(structType); !found {
+		return nil, false
+	}
+	return types.NewObjectType(structType), true
+}
+
+func (p *typeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	schema, found := p.findSchema(structType)
+	if !found {
+		return nil, false
+	}
+	fieldNames := make([]string, len(schema))
+	for i, field := range schema {
+		fieldNames[i] = field.Name
+	}
+	return fieldNames, true
+}
+
+func (p *typeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	schema, found := p.findSchema(structType)
+	if !found {
+		return nil, false
+	}
+	for _, field := range schema {
+		if field.Name != fieldName {
+			continue
+		}
+		celType, err := types.ExprTypeToType(fieldType(field.Type))
+		if err != nil {
+			return nil, false
+		}
+		return &types.FieldType{Type: celType}, true
+	}
+	return nil, false
+}
+
+func (p *typeProvider) NewValue(structType string, _ map[string]ref.Val) ref.Val {
+	return types.NewErr("unknown type '%s'", structType)
+}
+
+var _ types.Provider = new(typeProvider)
+
+// fieldType maps a MySQL column type to a CEL type
+func fieldType(columnType string) *exprpb.Type {
+	switch columnType {
+	case "tinyint(1)", "bit(1)", "bool", "boolean":
+		// MySQL has no boolean type: BOOLEAN is an alias of TINYINT(1)
+		return decls.Bool
+	}
+	baseType := columnType
+	if i := strings.IndexAny(baseType, "( "); i >= 0 {
+		baseType = baseType[:i]
+	}
+	switch baseType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year", "bit":
+		return decls.Int
+	case "decimal", "numeric", "float", "double", "real":
+		return decls.Double
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return decls.Bytes
+	case "datetime", "timestamp":
+		return decls.Timestamp
+	case "date":
+		return sqltypes.Date
+	case "time":
+		return sqltypes.Time
+	case "json":
+		// JSON columns are dynamic objects in CEL
+		return decls.Dyn
+	default:
+		// char, varchar, the text types, enum and set values are strings
+		return decls.String
+	}
+}
@@ -0,0 +1,176 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/mysql"
+)
+
+// column is a row of information_schema.COLUMNS served by the fake driver
+type column struct {
+	schema, table, name, dataType, columnType, isNullable string
+}
+
+// columnsDriver is a database/sql driver answering the introspection query from a fixed set of
+// columns, with "shop" as the current database
+type columnsDriver struct {
+	columns []column
+}
+
+func (d columnsDriver) Open(string) (driver.Conn, error) { return columnsConn(d), nil }
+
+type columnsConn columnsDriver
+
+func (c columnsConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c columnsConn) Close() error                        { return nil }
+func (c columnsConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c columnsConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	database, table := "shop", args[1].Value
+	if args[0].Value != nil {
+		database = args[0].Value.(string)
+	}
+	rows := &columnsRows{}
+	for _, col := range c.columns {
+		if col.schema == database && (table == nil || col.table == table) {
+			rows.values = append(rows.values, []driver.Value{
+				col.table, col.name, col.dataType, col.columnType, col.isNullable, col.schema == "shop",
+			})
+		}
+	}
+	return rows, nil
+}
+
+type columnsRows struct {
+	values [][]driver.Value
+}
+
+func (r *columnsRows) Columns() []string {
+	return []string{"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "IS_NULLABLE", "is_current"}
+}
+
+func (r *columnsRows) Close() error { return nil }
+
+func (r *columnsRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	db := sql.OpenDB(connector{columnsDriver{columns: []column{
+		{"shop", "orders", "id", "bigint", "bigint unsigned", "NO"},
+		{"shop", "orders", "paid", "tinyint", "tinyint(1)", "NO"},
+		{"shop", "orders", "quantity", "tinyint", "tinyint(4)", "YES"},
+		{"shop", "orders", "total", "decimal", "decimal(10,2)", "YES"},
+		{"shop", "orders", "status", "enum", "enum('pending','shipped','on ''hold''')", "NO"},
+		{"shop", "orders", "flags", "set", "set('gift','express')", "YES"},
+		{"shop", "orders", "placed_at", "datetime", "datetime(6)", "YES"},
+		{"shop", "orders", "ship_date", "date", "date", "YES"},
+		{"shop", "orders", "attributes", "json", "json", "YES"},
+		{"shop", "orders", "receipt", "blob", "blob", "YES"},
+		{"analytics", "events", "name", "varchar", "varchar(64)", "YES"},
+	}}})
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+type connector struct {
+	driver columnsDriver
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.driver }
+
+func TestTypeProvider_LoadTableSchema(t *testing.T) {
+	provider := mysql.NewTypeProviderWithDB(newTestDB(t))
+	require.NoError(t, provider.LoadTableSchema(context.Background(), "orders"))
+	require.NoError(t, provider.LoadTableSchema(context.Background(), "analytics.events"))
+	require.NoError(t, provider.LoadTableSchema(context.Background(), "missing"))
+
+	tests := []struct {
+		structType string
+		fieldName  string
+		wantType   *types.Type
+	}{
+		{structType: "orders", fieldName: "id", wantType: types.IntType},
+		{structType: "orders", fieldName: "paid", wantType: types.BoolType},
+		{structType: "orders", fieldName: "quantity", wantType: types.IntType},
+		{structType: "orders", fieldName: "total", wantType: types.DoubleType},
+		{structType: "orders", fieldName: "status", wantType: types.StringType},
+		{structType: "orders", fieldName: "flags", wantType: types.StringType},
+		{structType: "orders", fieldName: "placed_at", wantType: types.TimestampType},
+		{structType: "orders", fieldName: "ship_date", wantType: types.NewOpaqueType("DATE")},
+		{structType: "orders", fieldName: "attributes", wantType: types.DynType},
+		{structType: "orders", fieldName: "receipt", wantType: types.BytesType},
+		{structType: "analytics.events", fieldName: "name", wantType: types.StringType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.structType+"."+tt.fieldName, func(t *testing.T) {
+			got, found := provider.FindStructFieldType(tt.structType, tt.fieldName)
+			require.True(t, found)
+			assert.Equal(t, tt.wantType.String(), got.Type.String())
+		})
+	}
+
+	_, found := provider.FindStructType("missing")
+	assert.False(t, found)
+	_, found = provider.FindStructFieldType("orders", "missing")
+	assert.False(t, found)
+}
+
+func TestTypeProvider_LoadSchema(t *testing.T) {
+	db := newTestDB(t)
+
+	provider := mysql.NewTypeProviderWithDB(db)
+	require.NoError(t, provider.LoadSchema(context.Background(), "shop"))
+	for _, structType := range []string{"orders", "shop.orders"} {
+		fieldNames, found := provider.FindStructFieldNames(structType)
+		require.True(t, found, structType)
+		assert.Len(t, fieldNames, 10)
+	}
+
+	provider = mysql.NewTypeProviderWithDB(db)
+	require.NoError(t, provider.LoadSchema(context.Background(), "analytics"))
+	_, found := provider.FindStructType("analytics.events")
+	assert.True(t, found)
+	_, found = provider.FindStructType("events")
+	assert.False(t, found, "tables of other databases are only registered under qualified names")
+
+	err := mysql.NewTypeProvider(nil).LoadSchema(context.Background(), "shop")
+	assert.EqualError(t, err, "no database connection available")
+}
+
+func TestTypeProvider_Convert(t *testing.T) {
+	provider := mysql.NewTypeProvider(map[string]mysql.Schema{
+		"orders": {
+			{Name: "paid", Type: "tinyint(1)"},
+			{Name: "total", Type: "decimal(10,2)"},
+			{Name: "status", Type: "enum", Enum: []string{"pending", "shipped"}},
+		},
+	})
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("orders", cel.ObjectType("orders")),
+	)
+	require.NoError(t, err)
+
+	ast, issues := env.Compile(`orders.paid && orders.total > 100.0 && orders.status == "shipped"`)
+	require.NoError(t, issues.Err())
+	got, err := cel2sql.Convert(ast)
+	require.NoError(t, err)
+	assert.Equal(t, "orders.paid AND orders.total > 100 AND orders.status = 'shipped'", got)
+}
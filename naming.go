@@ -0,0 +1,172 @@
+package cel2sql
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"unicode"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// writeVariable writes the SQL name of the CEL variable referenced by ident, applying
+// WithTableAliases and WithTableAlias
+func (con *converter) writeVariable(ident *exprpb.Expr) error {
+	name := ident.GetIdentExpr().GetName()
+	alias, ok := con.opts.tableAliases[name]
+	if !ok && con.opts.tableAlias != nil && con.isTableVariable(ident) {
+		alias, ok = *con.opts.tableAlias, true
+	}
+	if ok {
+		if alias == "" {
+			return fmt.Errorf("variable %s is not a table", name)
+		}
+		if err := validateFieldName(alias); err != nil {
+			return err
+		}
+		name = alias
+	}
+	con.str.WriteString(name)
+	return nil
+}
+
+// FieldNameMapper maps a field of a CEL table type, identified by its type name, to the name
+// of its SQL column.
+type FieldNameMapper func(table, field string) string
+
+// SnakeCaseFieldNames is a FieldNameMapper that converts camelCase field names to snake_case,
+// e.g. firstName to first_name and homeURL to home_url.
+func SnakeCaseFieldNames(_, field string) string {
+	runes := []rune(field)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			acronymEnd := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// writeColumn writes the SQL name of a field selected from operand, applying
+// WithFieldNameMapper if operand is a table
+func (con *converter) writeColumn(operand *exprpb.Expr, field string) error {
+	if typ := con.getType(operand); con.opts.fieldNames != nil && isMessageType(typ) {
+		field = con.opts.fieldNames(typ.GetMessageType(), field)
+		if err := validateFieldName(field); err != nil {
+			return err
+		}
+	}
+	con.str.WriteString(field)
+	return nil
+}
+
+// isTableVariable checks if ident references a declared variable of a table type, as opposed
+// to a comprehension variable
+func (con *converter) isTableVariable(ident *exprpb.Expr) bool {
+	return ident.GetIdentExpr() != nil && !con.localIdents[ident.GetId()] && isMessageType(con.getType(ident))
+}
+
+// isCompositeElement checks if expr is a comprehension variable holding an element of an array of
+// composite values, whose fields are selected as (c).field: PostgreSQL reads c.field.subfield as a
+// column of a table c.field
+func (con *converter) isCompositeElement(expr *exprpb.Expr) bool {
+	if expr.GetIdentExpr() == nil || !isMessageType(con.getType(expr)) {
+		return false
+	}
+	if con.localIdents == nil {
+		con.localIdents = comprehensionIdents(con.root)
+	}
+	return con.localIdents[expr.GetId()]
+}
+
+// isUnqualifiedTable checks if the columns of expr are written without a qualifier, see
+// WithTableAlias
+func (con *converter) isUnqualifiedTable(expr *exprpb.Expr) bool {
+	if con.opts.tableAlias == nil || *con.opts.tableAlias != "" || !con.isTableVariable(expr) {
+		return false
+	}
+	_, aliased := con.opts.tableAliases[expr.GetIdentExpr().GetName()]
+	return !aliased
+}
+
+// comprehensionIdents returns the IDs of the identifiers in expr that reference comprehension
+// variables rather than declared variables
+func comprehensionIdents(expr *exprpb.Expr) map[int64]bool {
+	local := make(map[int64]bool)
+	var walk func(e *exprpb.Expr, scope map[string]bool)
+	walk = func(e *exprpb.Expr, scope map[string]bool) {
+		if e == nil {
+			return
+		}
+		if ident := e.GetIdentExpr(); ident != nil {
+			if scope[ident.GetName()] {
+				local[e.GetId()] = true
+			}
+			return
+		}
+		if comp := e.GetComprehensionExpr(); comp != nil {
+			walk(comp.GetIterRange(), scope)
+			walk(comp.GetAccuInit(), scope)
+			inner := maps.Clone(scope)
+			inner[comp.GetIterVar()] = true
+			inner[comp.GetIterVar2()] = true
+			inner[comp.GetAccuVar()] = true
+			walk(comp.GetLoopCondition(), inner)
+			walk(comp.GetLoopStep(), inner)
+			walk(comp.GetResult(), inner)
+			return
+		}
+		for _, child := range childExprs(e) {
+			walk(child, scope)
+		}
+	}
+	walk(expr, map[string]bool{})
+	return local
+}
+
+// checkIterVarNames rejects comprehension variables named like the alias of WithTableAlias or,
+// with unqualified columns, like a column of the table: the alias of the unnested elements would
+// shadow it in the subquery
+func (con *converter) checkIterVarNames(expr *exprpb.Expr) error {
+	if con.opts.tableAlias == nil {
+		return nil
+	}
+	var iterVars []string
+	columns := make(map[string]bool)
+	var walk func(e *exprpb.Expr)
+	walk = func(e *exprpb.Expr) {
+		if comp := e.GetComprehensionExpr(); comp != nil {
+			iterVars = append(iterVars, comp.GetIterVar())
+			if comp.GetIterVar2() != "" {
+				iterVars = append(iterVars, comp.GetIterVar2())
+			}
+		}
+		if sel := e.GetSelectExpr(); sel != nil && con.isUnqualifiedTable(sel.GetOperand()) {
+			field := sel.GetField()
+			if typ := con.getType(sel.GetOperand()); con.opts.fieldNames != nil {
+				field = con.opts.fieldNames(typ.GetMessageType(), field)
+			}
+			columns[field] = true
+		}
+		for _, child := range childExprs(e) {
+			walk(child)
+		}
+	}
+	walk(expr)
+	alias := *con.opts.tableAlias
+	for _, name := range iterVars {
+		if alias != "" && name == alias {
+			return fmt.Errorf("comprehension variable %s clashes with the table alias", name)
+		}
+		if columns[name] {
+			return fmt.Errorf("comprehension variable %s clashes with the column %s", name, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func newNamingTestEnv(t *testing.T) *cel.Env {
+	t.Helper()
+	schemas := map[string]pg.Schema{
+		"users": {
+			{Name: "name", Type: "text"},
+			{Name: "age", Type: "integer"},
+			{Name: "preferences", Type: "jsonb"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("user", cel.ObjectType("users")),
+		cel.Variable("manager", cel.ObjectType("users")),
+		cel.Variable("req", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("team", cel.ListType(cel.ObjectType("users"))),
+	)
+	require.NoError(t, err)
+	return env
+}
+
+func TestConvertTableAliases(t *testing.T) {
+	env := newNamingTestEnv(t)
+	aliases := cel2sql.WithTableAliases(map[string]string{"user": "u", "manager": "m", "req": ""})
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "columns",
+			source: `user.age > manager.age && user.name == "a"`,
+			want:   "u.age > m.age AND u.name = 'a'",
+		},
+		{
+			name:   "json_path",
+			source: `user.preferences.theme == "dark"`,
+			want:   "u.preferences->>'theme' = 'dark'",
+		},
+		{
+			name:   "has",
+			source: `has(user.name)`,
+			want:   "u.name IS NOT NULL",
+		},
+		{
+			name:    "not_a_table",
+			source:  `user.name == req.name`,
+			wantErr: "variable req is not a table",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, aliases)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertTableAliasesWithActivation(t *testing.T) {
+	env := newNamingTestEnv(t)
+	ast, issues := env.Compile(`user.name == req.name`)
+	require.NoError(t, issues.Err())
+
+	got, err := cel2sql.ConvertWithActivation(ast, map[string]any{"req": map[string]any{"name": "alice"}},
+		cel2sql.WithTableAliases(map[string]string{"user": "u", "req": ""}))
+	require.NoError(t, err)
+	assert.Equal(t, "u.name = 'alice'", got)
+}
+
+func TestConvertFieldNameMapper(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"people": {
+			{Name: "firstName", Type: "text"},
+			{Name: "homeURL", Type: "text"},
+			{Name: "settings", Type: "jsonb"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("person", cel.ObjectType("people")),
+	)
+	require.NoError(t, err)
+
+	renamed := map[string]map[string]string{"people": {"firstName": "given_name"}}
+	perTable := func(table, field string) string {
+		if name, ok := renamed[table][field]; ok {
+			return name
+		}
+		return field
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		mapper  cel2sql.FieldNameMapper
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "snake_case",
+			source: `person.firstName == "a" && person.homeURL != ""`,
+			mapper: cel2sql.SnakeCaseFieldNames,
+			want:   "person.first_name = 'a' AND person.home_url != ''",
+		},
+		{
+			name:   "json_keys_untouched",
+			source: `person.settings.darkMode == "on"`,
+			mapper: cel2sql.SnakeCaseFieldNames,
+			want:   "person.settings->>'darkMode' = 'on'",
+		},
+		{
+			name:   "has",
+			source: `has(person.firstName)`,
+			mapper: cel2sql.SnakeCaseFieldNames,
+			want:   "person.first_name IS NOT NULL",
+		},
+		{
+			name:   "per_table",
+			source: `person.firstName == "a" && person.homeURL == "b"`,
+			mapper: perTable,
+			want:   "person.given_name = 'a' AND person.homeURL = 'b'",
+		},
+		{
+			name:    "unsafe_name",
+			source:  `person.firstName == "a"`,
+			mapper:  func(_, field string) string { return field + "; DROP TABLE people" },
+			wantErr: `unsafe identifier: invalid field name "firstName; DROP TABLE people"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithFieldNameMapper(tt.mapper))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertOpenAPISchemas(t *testing.T) {
+	spec, err := pg.SchemasFromOpenAPI([]byte(`
+components:
+  schemas:
+    Order:
+      type: object
+      properties:
+        customerId: {type: integer, x-column: customer_id}
+        shipping:
+          type: object
+          properties:
+            city: {type: string}
+`))
+	require.NoError(t, err)
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(spec.Schemas)),
+		cel.Variable("order", cel.ObjectType("Order")),
+	)
+	require.NoError(t, err)
+
+	ast, issues := env.Compile(`order.customerId == 7 && order.shipping.city == "Cape Town"`)
+	require.NoError(t, issues.Err())
+	got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(spec.Schemas), cel2sql.WithFieldNameMapper(spec.ColumnName))
+	require.NoError(t, err)
+	assert.Equal(t, "order.customer_id = 7 AND order.shipping->>'city' = 'Cape Town'", got)
+}
+
+func TestSnakeCaseFieldNames(t *testing.T) {
+	tests := map[string]string{
+		"name":        "name",
+		"firstName":   "first_name",
+		"homeURL":     "home_url",
+		"HTTPServer":  "http_server",
+		"address2Zip": "address2_zip",
+		"already_ok":  "already_ok",
+	}
+	for field, want := range tests {
+		assert.Equal(t, want, cel2sql.SnakeCaseFieldNames("", field), field)
+	}
+}
+
+func TestConvertTableAlias(t *testing.T) {
+	env := newNamingTestEnv(t)
+
+	tests := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+		want   string
+	}{
+		{
+			name:   "alias",
+			source: `user.age > 18 && user.preferences.theme == "dark"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithTableAlias("t")},
+			want:   "t.age > 18 AND t.preferences->>'theme' = 'dark'",
+		},
+		{
+			name:   "unqualified",
+			source: `user.age > 18 && has(user.name) && user.preferences.theme == "dark"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithTableAlias("")},
+			want:   "age > 18 AND name IS NOT NULL AND preferences->>'theme' = 'dark'",
+		},
+		{
+			name:   "comprehension_variable_kept",
+			source: `team.exists(member, member.age > user.age)`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithTableAlias("")},
+			want:   "EXISTS (SELECT 1 FROM UNNEST(team) AS member WHERE (member).age > age)",
+		},
+		{
+			name:   "explicit_alias_wins",
+			source: `user.age > manager.age`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithTableAlias(""),
+				cel2sql.WithTableAliases(map[string]string{"manager": "m"}),
+			},
+			want: "age > m.age",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertTableAliasIterVarClash(t *testing.T) {
+	env := newNamingTestEnv(t)
+
+	tests := []struct {
+		name   string
+		source string
+		alias  string
+	}{
+		{
+			name:   "table_alias",
+			source: `team.exists(t, t.age > user.age)`,
+			alias:  "t",
+		},
+		{
+			name:   "unqualified_column",
+			source: `team.exists(name, name.name == user.name)`,
+			alias:  "",
+		},
+		{
+			name:   "unqualified_column_outside_comprehension",
+			source: `user.age > 18 && team.all(age, age.name != "")`,
+			alias:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			_, err := cel2sql.Convert(ast, cel2sql.WithTableAlias(tt.alias))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "clashes with")
+		})
+	}
+}
+
+// constructibleTypeProvider reports struct types as type values, as the checker expects when
+// message construction expressions refer to them
+type constructibleTypeProvider struct {
+	pg.TypeProvider
+}
+
+func (p constructibleTypeProvider) FindStructType(structType string) (*types.Type, bool) {
+	t, found := p.TypeProvider.FindStructType(structType)
+	if !found {
+		return nil, false
+	}
+	return types.NewTypeTypeWithParam(t), true
+}
+
+func TestConvertSchemaQualifiedTables(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"analytics.events": {
+			{Name: "name", Type: "text"},
+			{Name: "location", Type: "composite", Schema: []pg.FieldSchema{
+				{Name: "city", Type: "text"},
+				{Name: "country", Type: "text"},
+			}},
+		},
+		"Tenant.events": {
+			{Name: "location", Type: "composite", Schema: []pg.FieldSchema{{Name: "city", Type: "text"}}},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(constructibleTypeProvider{pg.NewTypeProvider(schemas)}),
+		cel.Variable("event", cel.ObjectType("analytics.events")),
+		cel.Variable("tenant_event", cel.ObjectType("Tenant.events")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "columns",
+			source: `event.name == "signup" && event.location.city == "Cape Town"`,
+			want:   "event.name = 'signup' AND event.location.city = 'Cape Town'",
+		},
+		{
+			name:   "composite_constructor",
+			source: `event.location == analytics.events.location{country: "ZA"}`,
+			want:   "event.location = ROW(NULL, 'ZA')::analytics.location",
+		},
+		{
+			name:   "quoted_schema",
+			source: `tenant_event.location == Tenant.events.location{city: "Durban"}`,
+			want:   `tenant_event.location = ROW('Durban')::"Tenant".location`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas), cel2sql.WithStrictColumns())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
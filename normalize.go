@@ -0,0 +1,142 @@
+package cel2sql
+
+import (
+	"sort"
+
+	"github.com/google/cel-go/common/operators"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+// normalizeExpr returns a copy of expr with the operands of every && and || chain and of every
+// commutative operator sorted by their generated SQL, so that filters differing only in operand
+// order convert identically.
+func (con *converter) normalizeExpr(expr *exprpb.Expr) *exprpb.Expr {
+	normalized := proto.Clone(expr).(*exprpb.Expr)
+	con.sortOperands(normalized)
+	return normalized
+}
+
+// sortOperands sorts the operands of the commutative operators in expr in place. Chains of &&,
+// ||, + and * are flattened before their operands are visited, so that each operand is rendered
+// once per chain, and rebuilt left-deep, reusing the IDs of their call nodes so that the type map
+// stays valid. Comprehension accumulator plumbing is left untouched, only the macro arguments
+// are sorted.
+func (con *converter) sortOperands(expr *exprpb.Expr) {
+	fun := expr.GetCallExpr().GetFunction()
+	switch {
+	case fun == operators.Equals || fun == operators.NotEquals:
+		for _, child := range con.convertedChildren(expr) {
+			con.sortOperands(child)
+		}
+		args := expr.GetCallExpr().GetArgs()
+		if len(args) == 2 && con.newSortOperand(args[1]).less(con.newSortOperand(args[0])) {
+			args[0], args[1] = args[1], args[0]
+		}
+		return
+	case fun == operators.LogicalAnd || fun == operators.LogicalOr || fun == operators.Multiply:
+	case fun == operators.Add && isNumericType(con.getType(expr)):
+		// string, bytes and list concatenation are not commutative
+	default:
+		for _, child := range con.convertedChildren(expr) {
+			con.sortOperands(child)
+		}
+		return
+	}
+
+	var operands []sortOperand
+	var ids []int64
+	var flatten func(*exprpb.Expr)
+	flatten = func(e *exprpb.Expr) {
+		if e.GetCallExpr().GetFunction() != fun {
+			con.sortOperands(e)
+			operands = append(operands, con.newSortOperand(e))
+			return
+		}
+		ids = append(ids, e.GetId())
+		for _, arg := range e.GetCallExpr().GetArgs() {
+			flatten(arg)
+		}
+	}
+	flatten(expr)
+	sort.SliceStable(operands, func(i, j int) bool {
+		return operands[i].less(operands[j])
+	})
+
+	chain := operands[0].expr
+	for i, operand := range operands[1:] {
+		chain = &exprpb.Expr{
+			Id: ids[len(ids)-1-i],
+			ExprKind: &exprpb.Expr_CallExpr{CallExpr: &exprpb.Expr_Call{
+				Function: fun,
+				Args:     []*exprpb.Expr{chain, operand.expr},
+			}},
+		}
+	}
+	expr.ExprKind = chain.ExprKind
+}
+
+// Sort ranks of operands, columns first so that they lead the values they are compared with or
+// combined with, e.g. age = 1 and name = $1 rather than 1 = age and $1 = name
+const (
+	rankColumn = iota
+	rankExpr
+	rankValue
+)
+
+// sortOperand is an operand of a commutative operator with its rank and rendered sort key
+type sortOperand struct {
+	expr *exprpb.Expr
+	rank int
+	key  string
+}
+
+// newSortOperand ranks and renders expr, whose own operands must already be sorted
+func (con *converter) newSortOperand(expr *exprpb.Expr) sortOperand {
+	return sortOperand{expr: expr, rank: con.sortRank(expr), key: con.sortKey(expr)}
+}
+
+// less orders operands by rank, then by sort key
+func (o sortOperand) less(other sortOperand) bool {
+	if o.rank != other.rank {
+		return o.rank < other.rank
+	}
+	return o.key < other.key
+}
+
+// sortRank ranks literals and parameters as values, and variables and their fields as columns
+func (con *converter) sortRank(expr *exprpb.Expr) int {
+	switch {
+	case expr.GetConstExpr() != nil:
+		return rankValue
+	case expr.GetIdentExpr() != nil:
+		if con.isParameter(expr.GetIdentExpr().GetName()) {
+			return rankValue
+		}
+		return rankColumn
+	case expr.GetSelectExpr() != nil && !expr.GetSelectExpr().GetTestOnly():
+		if con.sortRank(expr.GetSelectExpr().GetOperand()) == rankColumn {
+			return rankColumn
+		}
+	}
+	return rankExpr
+}
+
+// sortKey renders expr with literals intact, for ordering operands. Interceptors, the logger and
+// the metrics sink are left out, as they observe the conversion itself rather than the keys.
+func (con *converter) sortKey(expr *exprpb.Expr) string {
+	sub := newConverter(con.typeMap)
+	defer sub.release()
+	sub.localIdents = con.localIdents
+	sub.checkedSourceInfo, sub.root = con.checkedSourceInfo, con.root
+	sub.opts = con.opts
+	sub.opts.literalPlaceholders = false
+	sub.opts.debugComments = false
+	sub.opts.interceptors = nil
+	sub.opts.logger = nil
+	sub.opts.metrics = nil
+	if err := sub.visit(expr); err != nil {
+		return ""
+	}
+	return sub.str.String()
+}
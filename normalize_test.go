@@ -0,0 +1,174 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertNormalizedOutput(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("active", cel.BoolType),
+		cel.Variable("tags", cel.ListType(cel.StringType)),
+		cel.Variable("height", cel.IntType),
+		cel.Variable("weight", cel.IntType),
+		cel.Variable("nickname", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		sources []string
+		opts    []cel2sql.ConvertOption
+		want    string
+	}{
+		{
+			name: "and_chain_order",
+			sources: []string{
+				`name == "a" && age > 10 && active`,
+				`active && age > 10 && name == "a"`,
+				`(age > 10 && active) && name == "a"`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "active AND age > 10 AND name = 'a'",
+		},
+		{
+			name: "nested_chains",
+			sources: []string{
+				`(name == "b" || name == "a") && age > 1`,
+				`age > 1 && (name == "a" || name == "b")`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "age > 1 AND (name = 'a' OR name = 'b')",
+		},
+		{
+			name: "inside_comprehension",
+			sources: []string{
+				`tags.exists(t, t == "x" || t == "a")`,
+				`tags.exists(t, t == "a" || t == "x")`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "EXISTS (SELECT 1 FROM UNNEST(tags) AS t WHERE t = 'a' OR t = 'x')",
+		},
+		{
+			name: "literal_placeholders",
+			sources: []string{
+				`name == "a" && age > 10`,
+				`age > 99 && name == "zzz"`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput(), cel2sql.WithLiteralPlaceholders()},
+			want: "age > $1 AND name = $2",
+		},
+		{
+			name: "equality_literal_last",
+			sources: []string{
+				`1 == age`,
+				`age == 1`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "age = 1",
+		},
+		{
+			name: "parameter_last",
+			sources: []string{
+				`nickname == name`,
+				`name == nickname`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput(), cel2sql.WithParameters("nickname")},
+			want: "name = $1",
+		},
+		{
+			name: "column_before_expression",
+			sources: []string{
+				`size(tags) * height > 1`,
+				`height * size(tags) > 1`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "height * ARRAY_LENGTH(tags, 1) > 1",
+		},
+		{
+			name: "inequality_order",
+			sources: []string{
+				`nickname != name`,
+				`name != nickname`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "name != nickname",
+		},
+		{
+			name: "addition_chain",
+			sources: []string{
+				`weight + height + 1 > age`,
+				`1 + (height + weight) > age`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "height + weight + 1 > age",
+		},
+		{
+			name: "multiplication",
+			sources: []string{
+				`weight * height > 100`,
+				`height * weight > 100`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "height * weight > 100",
+		},
+		{
+			name: "concatenation_kept",
+			sources: []string{
+				`nickname + name == "ab"`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithNormalizedOutput()},
+			want: "nickname || name = 'ab'",
+		},
+		{
+			name: "bool_kept",
+			sources: []string{
+				`name == "a" || active == true`,
+			},
+			opts: []cel2sql.ConvertOption{cel2sql.WithLiteralPlaceholders()},
+			want: "name = $1 OR active IS TRUE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, source := range tt.sources {
+				ast, issues := env.Compile(source)
+				require.Empty(t, issues)
+
+				got, err := cel2sql.Convert(ast, tt.opts...)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got, source)
+			}
+		})
+	}
+}
+
+func TestConvertNormalizedOutputInterceptors(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("age", cel.IntType),
+		cel.Variable("height", cel.IntType),
+	)
+	require.NoError(t, err)
+	ast, issues := env.Compile(`height * age == 2 && age + height != 3`)
+	require.Empty(t, issues)
+
+	// interceptors run once per node, not again for the keys the operands are sorted by
+	count := func(opts ...cel2sql.ConvertOption) int {
+		calls := 0
+		counter := func(_ cel2sql.Node, next func() (string, error)) (string, error) {
+			calls++
+			return next()
+		}
+		_, err := cel2sql.Convert(ast, append(opts, cel2sql.WithInterceptors(counter))...)
+		require.NoError(t, err)
+		return calls
+	}
+	assert.Equal(t, count(), count(cel2sql.WithNormalizedOutput()))
+}
@@ -0,0 +1,34 @@
+package cel2sql
+
+import (
+	"github.com/google/cel-go/common/operators"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// isNullableColumn checks if expr selects a column of the schemas supplied with WithSchemas that
+// is not declared NOT NULL
+func (con *converter) isNullableColumn(expr *exprpb.Expr) bool {
+	field, found := con.findField(expr)
+	return found && !field.NotNull
+}
+
+// isNullSafeComparison checks if an == or != comparison involves a nullable column, in which
+// case SQL yields NULL where CEL yields true or false. With WithNullSafeEquality the comparison
+// is rendered with IS [NOT] DISTINCT FROM; otherwise the difference is reported as a warning:
+// != excludes the rows where the column is NULL, and == never matches two NULL columns.
+func (con *converter) isNullSafeComparison(expr, lhs, rhs *exprpb.Expr) bool {
+	lhsNullable, rhsNullable := con.isNullableColumn(lhs), con.isNullableColumn(rhs)
+	if !lhsNullable && !rhsNullable {
+		return false
+	}
+	if con.opts.nullSafeEquality {
+		return true
+	}
+	fun := expr.GetCallExpr().GetFunction()
+	if fun == operators.NotEquals {
+		con.warn(expr, WarningNullComparison, "rows where the column is NULL do not match !=; use WithNullSafeEquality to compare NULL like CEL")
+	} else if lhsNullable && rhsNullable {
+		con.warn(expr, WarningNullComparison, "rows where both columns are NULL do not match ==; use WithNullSafeEquality to compare NULL like CEL")
+	}
+	return false
+}
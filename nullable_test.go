@@ -0,0 +1,88 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertNullableColumns(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"users": {
+			{Name: "id", Type: "integer", NotNull: true},
+			{Name: "name", Type: "text", NotNull: true},
+			{Name: "nickname", Type: "text"},
+			{Name: "referrer", Type: "text"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("user", cel.ObjectType("users")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		source       string
+		opts         []cel2sql.ConvertOption
+		want         string
+		wantWarnings []string
+	}{
+		{
+			name:   "has_not_null",
+			source: `has(user.name) && has(user.nickname)`,
+			want:   "TRUE AND user.nickname IS NOT NULL",
+		},
+		{
+			name:   "equals_not_null",
+			source: `user.name == "a" && user.name != "b"`,
+			want:   "user.name = 'a' AND user.name != 'b'",
+		},
+		{
+			name:   "equals_nullable",
+			source: `user.nickname == "a"`,
+			want:   "user.nickname = 'a'",
+		},
+		{
+			name:         "not_equals_nullable",
+			source:       `user.nickname != "a"`,
+			want:         "user.nickname != 'a'",
+			wantWarnings: []string{"rows where the column is NULL do not match !=; use WithNullSafeEquality to compare NULL like CEL"},
+		},
+		{
+			name:         "equals_nullable_columns",
+			source:       `user.nickname == user.referrer`,
+			want:         "user.nickname = user.referrer",
+			wantWarnings: []string{"rows where both columns are NULL do not match ==; use WithNullSafeEquality to compare NULL like CEL"},
+		},
+		{
+			name:   "null_safe",
+			source: `user.nickname != "a" && user.nickname == user.referrer && user.name != "b"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithNullSafeEquality()},
+			want:   "user.nickname IS DISTINCT FROM 'a' AND user.nickname IS NOT DISTINCT FROM user.referrer AND user.name != 'b'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, warnings, err := cel2sql.ConvertWithWarnings(ast, append(tt.opts, cel2sql.WithSchemas(schemas))...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			var messages []string
+			for _, w := range warnings {
+				assert.Equal(t, cel2sql.WarningNullComparison, w.Kind)
+				messages = append(messages, w.Message)
+			}
+			assert.Equal(t, tt.wantWarnings, messages)
+		})
+	}
+}
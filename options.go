@@ -0,0 +1,364 @@
+package cel2sql
+
+import (
+	"log/slog"
+
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// ConvertOption configures how Convert renders a CEL expression as SQL.
+type ConvertOption func(*convertOptions)
+
+// convertOptions holds the settings applied by ConvertOption values.
+type convertOptions struct {
+	jsonPathStyle  JSONPathStyle
+	hasSemantics   HasSemantics
+	floatStyle     FloatStyle
+	floatPrecision *int
+	timestampStyle TimestampLiteralStyle
+	schemas        map[string]pg.Schema
+
+	strictColumns  bool
+	allowedColumns []string
+	deniedColumns  []string
+	maskedColumns  []string
+
+	limits             Limits
+	disallowedFeatures []string
+
+	collectErrors bool
+	strictRegex   bool
+	debugComments bool
+
+	normalize           bool
+	literalPlaceholders bool
+	nullSafeEquality    bool
+
+	parameters       []string
+	placeholderStyle PlaceholderStyle
+	tableAliases     map[string]string
+	fieldNames       FieldNameMapper
+	tableAlias       *string
+
+	metrics      MetricsSink
+	logger       *slog.Logger
+	interceptors []Interceptor
+	functions    map[string]FunctionSQL
+	macros       map[string]FunctionSQL
+}
+
+// JSONPathStyle selects how nested JSON/JSONB field access is rendered.
+type JSONPathStyle int
+
+// JSON path extraction styles supported by cel2sql
+const (
+	JSONPathOperators JSONPathStyle = iota // col->'a'->>'b'
+	JSONPathFunctions                      // jsonb_extract_path_text(col, 'a', 'b')
+)
+
+// String returns a string representation of the JSON path style
+func (s JSONPathStyle) String() string {
+	switch s {
+	case JSONPathOperators:
+		return "operators"
+	case JSONPathFunctions:
+		return "functions"
+	default:
+		return "unknown"
+	}
+}
+
+// WithJSONPathStyle selects between chained JSON operators (the default) and the
+// json[b]_extract_path[_text] function form for JSON field access.
+func WithJSONPathStyle(style JSONPathStyle) ConvertOption {
+	return func(o *convertOptions) {
+		o.jsonPathStyle = style
+	}
+}
+
+// HasSemantics selects what has() means when applied to a key inside a JSON/JSONB column.
+type HasSemantics int
+
+// has() semantics supported by cel2sql
+const (
+	// HasDefault checks key existence for direct access (has(t.json.key)) and a non-null
+	// value for nested paths (has(t.json.a.b)).
+	HasDefault HasSemantics = iota
+	// HasKeyExists checks that the key is present, even if its value is null.
+	HasKeyExists
+	// HasNonNullValue checks that the key is present and its value is not null.
+	HasNonNullValue
+)
+
+// String returns a string representation of the has() semantics
+func (s HasSemantics) String() string {
+	switch s {
+	case HasDefault:
+		return "default"
+	case HasKeyExists:
+		return "key_exists"
+	case HasNonNullValue:
+		return "non_null_value"
+	default:
+		return "unknown"
+	}
+}
+
+// WithHasSemantics makes has() on JSON/JSONB keys consistently mean either "key exists"
+// or "value is non-null", regardless of nesting depth.
+func WithHasSemantics(semantics HasSemantics) ConvertOption {
+	return func(o *convertOptions) {
+		o.hasSemantics = semantics
+	}
+}
+
+// FloatStyle selects how double literals are written.
+type FloatStyle int
+
+// Double literal styles supported by cel2sql
+const (
+	FloatShortest FloatStyle = iota // shortest exact form, with an exponent for large and small values: 1e+06
+	FloatDecimal                    // plain decimal without exponent: 1000000
+)
+
+// String returns a string representation of the double literal style
+func (s FloatStyle) String() string {
+	switch s {
+	case FloatShortest:
+		return "shortest"
+	case FloatDecimal:
+		return "decimal"
+	default:
+		return "unknown"
+	}
+}
+
+// WithFloatStyle selects between the shortest form of double literals (the default) and plain
+// decimals, which SQL proxies and audit tools that mis-handle exponents can read.
+func WithFloatStyle(style FloatStyle) ConvertOption {
+	return func(o *convertOptions) {
+		o.floatStyle = style
+	}
+}
+
+// WithFloatPrecision writes double literals as plain decimals rounded to digits places after the
+// decimal point, e.g. 19.90 with two digits for money amounts. It takes precedence over
+// WithFloatStyle; a negative precision writes every significant digit, like FloatDecimal.
+func WithFloatPrecision(digits int) ConvertOption {
+	return func(o *convertOptions) {
+		o.floatPrecision = &digits
+	}
+}
+
+// TimestampLiteralStyle selects how strings converted with timestamp() are written.
+type TimestampLiteralStyle int
+
+// Timestamp conversion styles supported by cel2sql
+const (
+	TimestampCast         TimestampLiteralStyle = iota // CAST('2023-01-01T00:00:00Z' AS TIMESTAMP WITH TIME ZONE)
+	TimestampTypedLiteral                              // TIMESTAMPTZ '2023-01-01T00:00:00Z'
+	TimestampCastOperator                              // '2023-01-01T00:00:00Z'::timestamptz
+)
+
+// String returns a string representation of the timestamp conversion style
+func (s TimestampLiteralStyle) String() string {
+	switch s {
+	case TimestampCast:
+		return "cast"
+	case TimestampTypedLiteral:
+		return "typed_literal"
+	case TimestampCastOperator:
+		return "cast_operator"
+	default:
+		return "unknown"
+	}
+}
+
+// WithTimestampLiteralStyle selects between the standard CAST (the default), typed literals and
+// the :: cast operator for timestamp(). Typed literals only apply to string literals, and other
+// strings are converted with CAST.
+func WithTimestampLiteralStyle(style TimestampLiteralStyle) ConvertOption {
+	return func(o *convertOptions) {
+		o.timestampStyle = style
+	}
+}
+
+// WithSchemas provides the PostgreSQL schemas backing the CEL types used in the expression,
+// keyed by the same type names given to pg.NewTypeProvider. The converter uses them where
+// the SQL depends on column layout, such as the field order of composite type constructors.
+func WithSchemas(schemas map[string]pg.Schema) ConvertOption {
+	return func(o *convertOptions) {
+		o.schemas = schemas
+	}
+}
+
+// WithStrictColumns makes Convert fail when the expression references a column that is
+// neither present in the schemas supplied with WithSchemas nor matched by one of the allowed
+// patterns. Patterns take the form "table.column", "*.column", "table.*", or a bare "column"
+// for variables that are not fields of a table; tables are identified by their CEL type name.
+func WithStrictColumns(allowed ...string) ConvertOption {
+	return func(o *convertOptions) {
+		o.strictColumns = true
+		o.allowedColumns = append(o.allowedColumns, allowed...)
+	}
+}
+
+// WithDeniedColumns makes Convert fail when the expression references a column matching one
+// of the patterns (see WithStrictColumns for the pattern syntax), e.g. "users.ssn" or
+// "*.password_hash". Denied columns are rejected even if they are present in the schema.
+func WithDeniedColumns(patterns ...string) ConvertOption {
+	return func(o *convertOptions) {
+		o.deniedColumns = append(o.deniedColumns, patterns...)
+	}
+}
+
+// WithMaskedColumns replaces references to columns matching one of the patterns with NULL,
+// so that they can never influence the result of the filter. has() on a masked column is FALSE.
+// The NULLs standing for array and JSON columns are typed like the column, e.g. (NULL::text[]).
+func WithMaskedColumns(patterns ...string) ConvertOption {
+	return func(o *convertOptions) {
+		o.maskedColumns = append(o.maskedColumns, patterns...)
+	}
+}
+
+// WithCollectErrors makes Convert report every problem in the expression instead of stopping
+// at the first one. The problems are joined into a single error that implements
+// Unwrap() []error, each prefixed with its line:column in the source.
+func WithCollectErrors() ConvertOption {
+	return func(o *convertOptions) {
+		o.collectErrors = true
+	}
+}
+
+// WithStrictRegex makes Convert fail when a matches() pattern uses RE2 features that
+// PostgreSQL regular expressions cannot express, such as lookarounds, named groups or the
+// multi-line flag, instead of approximating them. Patterns that are not literals are rejected.
+func WithStrictRegex() ConvertOption {
+	return func(o *convertOptions) {
+		o.strictRegex = true
+	}
+}
+
+// WithDebugComments annotates the generated SQL with the CEL source of each comprehension and
+// of each operand of && and ||, e.g. "/* cel: e.salary > 50000 */ e.salary > 50000", to help
+// debug complex filters. With WithLiteralPlaceholders or WithParameters, literals in the comments
+// are written as ? to keep their values out of query logs. It is not intended for production
+// queries.
+func WithDebugComments() ConvertOption {
+	return func(o *convertOptions) {
+		o.debugComments = true
+	}
+}
+
+// WithNormalizedOutput sorts the operands of && and || chains, of ==, != and *, and of + on
+// numbers by their generated SQL, with literals last, so that filters that differ only in
+// operand order produce the same SQL, e.g. for caching.
+func WithNormalizedOutput() ConvertOption {
+	return func(o *convertOptions) {
+		o.normalize = true
+	}
+}
+
+// WithLiteralPlaceholders replaces string, bytes and numeric literals with placeholders ($1,
+// $2, ..., see WithPlaceholderStyle) in the generated SQL. Combined with WithNormalizedOutput
+// it yields a fingerprint that groups filters like pg_stat_statements does. NULL and boolean
+// literals are kept, as they determine the shape of IS comparisons.
+func WithLiteralPlaceholders() ConvertOption {
+	return func(o *convertOptions) {
+		o.literalPlaceholders = true
+	}
+}
+
+// WithNullSafeEquality renders == and != on columns that may be NULL, as known from the schemas
+// supplied with WithSchemas, as IS NOT DISTINCT FROM and IS DISTINCT FROM, so that NULL compares
+// like CEL's null instead of making the comparison unknown. Columns declared NOT NULL keep the
+// plain operators, which indexes serve better.
+func WithNullSafeEquality() ConvertOption {
+	return func(o *convertOptions) {
+		o.nullSafeEquality = true
+	}
+}
+
+// WithParameters marks CEL variables as runtime parameters. Each one is rendered as a
+// placeholder ($1, $2, ..., see WithPlaceholderStyle) instead of a column, numbered in order of
+// first use, so that the generated SQL can be prepared once and executed with different
+// values. Parameters are not treated as columns by WithStrictColumns. Use ConvertWithParameters
+// to get the parameter order.
+func WithParameters(names ...string) ConvertOption {
+	return func(o *convertOptions) {
+		o.parameters = append(o.parameters, names...)
+	}
+}
+
+// PlaceholderStyle selects the syntax of the placeholders of parameters and literals.
+type PlaceholderStyle int
+
+// Placeholder styles supported by cel2sql
+const (
+	PlaceholderDollar   PlaceholderStyle = iota // $1, as used by PostgreSQL and pgx
+	PlaceholderQuestion                         // ?, as used by MySQL and SQLite
+	PlaceholderColon                            // :name, as used by sqlx and Oracle
+	PlaceholderAt                               // @name, as used by Spanner and BigQuery
+)
+
+// String returns a string representation of the placeholder style
+func (s PlaceholderStyle) String() string {
+	switch s {
+	case PlaceholderDollar:
+		return "dollar"
+	case PlaceholderQuestion:
+		return "question"
+	case PlaceholderColon:
+		return "colon"
+	case PlaceholderAt:
+		return "at"
+	default:
+		return "unknown"
+	}
+}
+
+// WithPlaceholderStyle selects the syntax of the placeholders written for WithParameters and
+// WithLiteralPlaceholders, $1 by default. The parameters returned by ConvertWithParameters
+// follow the style: for $1 they are in order of number, for ? there is one per placeholder in
+// order of appearance, and for the named styles they are the distinct names in order of first
+// use. Named styles name literal placeholders p1, p2, ... after their position, and reject
+// parameters with names of that form.
+func WithPlaceholderStyle(style PlaceholderStyle) ConvertOption {
+	return func(o *convertOptions) {
+		o.placeholderStyle = style
+	}
+}
+
+// WithTableAliases maps CEL variables to the SQL table aliases used in the FROM clause of the
+// surrounding query, e.g. {"user": "u"} renders user.name as u.name. A variable mapped to ""
+// is not a table, typically request context, and referencing it fails unless it is bound with
+// ConvertWithActivation or WithParameters.
+func WithTableAliases(aliases map[string]string) ConvertOption {
+	return func(o *convertOptions) {
+		if o.tableAliases == nil {
+			o.tableAliases = make(map[string]string, len(aliases))
+		}
+		for name, alias := range aliases {
+			o.tableAliases[name] = alias
+		}
+	}
+}
+
+// WithFieldNameMapper renames the fields of table types to their SQL column names, so that the
+// CEL model exposed to API users can diverge from the physical schema. Keys inside JSON columns
+// are not renamed. See SnakeCaseFieldNames for the common camelCase to snake_case mapping.
+func WithFieldNameMapper(mapper FieldNameMapper) ConvertOption {
+	return func(o *convertOptions) {
+		o.fieldNames = mapper
+	}
+}
+
+// WithTableAlias renders every table variable as the given alias, e.g. "t" renders user.name as
+// t.name. An empty alias writes unqualified column names, for queries over a single table.
+// Variables mapped with WithTableAliases keep their own alias; comprehension variables are not
+// affected.
+func WithTableAlias(alias string) ConvertOption {
+	return func(o *convertOptions) {
+		o.tableAlias = &alias
+	}
+}
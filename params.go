@@ -0,0 +1,121 @@
+package cel2sql
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/google/cel-go/cel"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// ConvertWithParameters converts a CEL AST like Convert, rendering the variables named with
+// WithParameters as placeholders. It also returns the parameter order: the variable bound to $1
+// is at index 0, and so on, or as described by WithPlaceholderStyle for other styles.
+// Placeholders for literals (see WithLiteralPlaceholders) have an empty name in positional
+// styles.
+func ConvertWithParameters(ast *cel.Ast, opts ...ConvertOption) (string, []string, error) {
+	con, _, err := convert(ast, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	defer con.release()
+	return con.str.String(), con.parameters, nil
+}
+
+// literalPlaceholderName matches the names generated for literal placeholders in named
+// placeholder styles, e.g. p1
+var literalPlaceholderName = regexp.MustCompile(`^p[0-9]+$`)
+
+// checkParameterNames rejects parameters named like generated literal placeholders, which
+// would bind two different values to the same name
+func (con *converter) checkParameterNames() error {
+	style := con.opts.placeholderStyle
+	if !con.opts.literalPlaceholders || (style != PlaceholderColon && style != PlaceholderAt) {
+		return nil
+	}
+	for _, name := range con.opts.parameters {
+		if literalPlaceholderName.MatchString(name) {
+			return fmt.Errorf("parameter %s clashes with the names of literal placeholders", name)
+		}
+	}
+	return nil
+}
+
+// isParameter checks if a variable is bound at runtime, see WithParameters
+func (con *converter) isParameter(name string) bool {
+	return slices.Contains(con.opts.parameters, name)
+}
+
+// writeParameter writes the placeholder of a runtime parameter in the placeholder style,
+// reusing its number or name if the parameter was already referenced.
+func (con *converter) writeParameter(name string) {
+	switch con.opts.placeholderStyle {
+	case PlaceholderQuestion:
+		con.parameters = append(con.parameters, name)
+		con.str.WriteString("?")
+	case PlaceholderColon, PlaceholderAt:
+		if !slices.Contains(con.parameters, name) {
+			con.parameters = append(con.parameters, name)
+		}
+		con.writeNamedPlaceholder(name)
+	default:
+		n := slices.Index(con.parameters, name) + 1
+		if n == 0 {
+			con.parameters = append(con.parameters, name)
+			n = len(con.parameters)
+		}
+		con.str.WriteString("$")
+		con.str.WriteString(strconv.Itoa(n))
+	}
+}
+
+// writePlaceholder writes the placeholder of a literal in the placeholder style, e.g. $1, and
+// records its value for Plan.SQLWithParams
+func (con *converter) writePlaceholder(value any) {
+	if con.literalValues == nil {
+		con.literalValues = make(map[int]any)
+	}
+	con.literalValues[len(con.parameters)] = value
+	switch con.opts.placeholderStyle {
+	case PlaceholderQuestion:
+		con.parameters = append(con.parameters, "")
+		con.str.WriteString("?")
+	case PlaceholderColon, PlaceholderAt:
+		name := "p" + strconv.Itoa(len(con.parameters)+1)
+		con.parameters = append(con.parameters, name)
+		con.writeNamedPlaceholder(name)
+	default:
+		con.parameters = append(con.parameters, "")
+		con.str.WriteString("$")
+		con.str.WriteString(strconv.Itoa(len(con.parameters)))
+	}
+}
+
+// writeNamedPlaceholder writes a placeholder in a named placeholder style, e.g. :name
+func (con *converter) writeNamedPlaceholder(name string) {
+	if con.opts.placeholderStyle == PlaceholderAt {
+		con.str.WriteString("@")
+	} else {
+		con.str.WriteString(":")
+	}
+	con.str.WriteString(name)
+}
+
+// constValue returns the Go value of a literal bound to a placeholder
+func constValue(c *exprpb.Constant) any {
+	switch kind := c.GetConstantKind().(type) {
+	case *exprpb.Constant_BytesValue:
+		return kind.BytesValue
+	case *exprpb.Constant_DoubleValue:
+		return kind.DoubleValue
+	case *exprpb.Constant_Int64Value:
+		return kind.Int64Value
+	case *exprpb.Constant_StringValue:
+		return kind.StringValue
+	case *exprpb.Constant_Uint64Value:
+		return kind.Uint64Value
+	}
+	return nil
+}
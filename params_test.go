@@ -0,0 +1,171 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertWithParameters(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("min_age", cel.IntType),
+		cel.Variable("max_age", cel.IntType),
+		cel.Variable("names", cel.ListType(cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		source     string
+		opts       []cel2sql.ConvertOption
+		want       string
+		wantParams []string
+	}{
+		{
+			name:       "single",
+			source:     `age >= min_age`,
+			opts:       []cel2sql.ConvertOption{cel2sql.WithParameters("min_age")},
+			want:       "age >= $1",
+			wantParams: []string{"min_age"},
+		},
+		{
+			name:       "order_of_first_use",
+			source:     `age <= max_age && age >= min_age && age != max_age`,
+			opts:       []cel2sql.ConvertOption{cel2sql.WithParameters("min_age", "max_age")},
+			want:       "age <= $1 AND age >= $2 AND age != $1",
+			wantParams: []string{"max_age", "min_age"},
+		},
+		{
+			name:       "list_membership",
+			source:     `name in names`,
+			opts:       []cel2sql.ConvertOption{cel2sql.WithParameters("names")},
+			want:       "name = ANY($1)",
+			wantParams: []string{"names"},
+		},
+		{
+			name:       "with_literal_placeholders",
+			source:     `name == "a" && age >= min_age`,
+			opts:       []cel2sql.ConvertOption{cel2sql.WithParameters("min_age"), cel2sql.WithLiteralPlaceholders()},
+			want:       "name = $1 AND age >= $2",
+			wantParams: []string{"", "min_age"},
+		},
+		{
+			name:   "none",
+			source: `age >= min_age`,
+			want:   "age >= min_age",
+		},
+		{
+			name:   "question_style",
+			source: `age <= max_age && age >= min_age && age != max_age`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithParameters("min_age", "max_age"),
+				cel2sql.WithPlaceholderStyle(cel2sql.PlaceholderQuestion),
+			},
+			want:       "age <= ? AND age >= ? AND age != ?",
+			wantParams: []string{"max_age", "min_age", "max_age"},
+		},
+		{
+			name:   "colon_style",
+			source: `age <= max_age && age >= min_age && age != max_age`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithParameters("min_age", "max_age"),
+				cel2sql.WithPlaceholderStyle(cel2sql.PlaceholderColon),
+			},
+			want:       "age <= :max_age AND age >= :min_age AND age != :max_age",
+			wantParams: []string{"max_age", "min_age"},
+		},
+		{
+			name:   "at_style",
+			source: `name in names`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithParameters("names"),
+				cel2sql.WithPlaceholderStyle(cel2sql.PlaceholderAt),
+			},
+			want:       "name = ANY(@names)",
+			wantParams: []string{"names"},
+		},
+		{
+			name:   "question_style_with_literal_placeholders",
+			source: `name == "a" && age >= min_age`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithParameters("min_age"),
+				cel2sql.WithLiteralPlaceholders(),
+				cel2sql.WithPlaceholderStyle(cel2sql.PlaceholderQuestion),
+			},
+			want:       "name = ? AND age >= ?",
+			wantParams: []string{"", "min_age"},
+		},
+		{
+			name:   "at_style_with_literal_placeholders",
+			source: `name == "a" && age >= min_age && age < 65`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithParameters("min_age"),
+				cel2sql.WithLiteralPlaceholders(),
+				cel2sql.WithPlaceholderStyle(cel2sql.PlaceholderAt),
+			},
+			want:       "name = @p1 AND age >= @min_age AND age < @p3",
+			wantParams: []string{"p1", "min_age", "p3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, params, err := cel2sql.ConvertWithParameters(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantParams, params)
+		})
+	}
+}
+
+func TestConvertParameterNamedLikeLiteralPlaceholder(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("p1", cel.StringType),
+	)
+	require.NoError(t, err)
+	ast, issues := env.Compile(`name == "a" || name == p1`)
+	require.NoError(t, issues.Err())
+
+	for _, style := range []cel2sql.PlaceholderStyle{cel2sql.PlaceholderColon, cel2sql.PlaceholderAt} {
+		_, _, err := cel2sql.ConvertWithParameters(ast,
+			cel2sql.WithParameters("p1"), cel2sql.WithLiteralPlaceholders(), cel2sql.WithPlaceholderStyle(style))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "parameter p1 clashes with the names of literal placeholders")
+	}
+
+	got, params, err := cel2sql.ConvertWithParameters(ast, cel2sql.WithParameters("p1"), cel2sql.WithLiteralPlaceholders())
+	require.NoError(t, err)
+	assert.Equal(t, "name = $1 OR name = $2", got)
+	assert.Equal(t, []string{"", "p1"}, params)
+
+	got, _, err = cel2sql.ConvertWithParameters(ast,
+		cel2sql.WithParameters("p1"), cel2sql.WithPlaceholderStyle(cel2sql.PlaceholderColon))
+	require.NoError(t, err)
+	assert.Equal(t, "name = 'a' OR name = :p1", got)
+}
+
+func TestConvertParametersAreNotColumns(t *testing.T) {
+	env, schemas := newColumnsTestEnv(t)
+	ast, issues := env.Compile(`users.name == region`)
+	require.NoError(t, issues.Err())
+
+	_, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas), cel2sql.WithStrictColumns())
+	require.Error(t, err)
+
+	result, err := cel2sql.ConvertWithResult(ast,
+		cel2sql.WithSchemas(schemas), cel2sql.WithStrictColumns(), cel2sql.WithParameters("region"))
+	require.NoError(t, err)
+	assert.Equal(t, "users.name = $1", result.SQL)
+	assert.Equal(t, []string{"region"}, result.Parameters)
+	assert.Equal(t, []string{"users.name"}, result.Columns)
+}
@@ -0,0 +1,246 @@
+package pg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SchemaFromAvro derives a table schema from an Avro record schema, such as the value schema of
+// a change data capture topic, so that filters validated against the records can be converted
+// for the tables they are written to. The fields of the record are the columns:
+//
+//   - boolean, int, long, float, double, bytes and string have the corresponding types
+//   - the date, time, timestamp, local-timestamp, decimal and uuid logical types are date, time,
+//     timestamptz, timestamp, numeric and uuid
+//   - enums are text and fixed values bytea
+//   - unions of null and another type are nullable, and other fields NOT NULL
+//   - arrays are arrays of their items
+//   - records are jsonb columns with their fields described, as by JSONColumnFromJSONSchema,
+//     and maps are jsonb
+//
+// Named types may be referenced by their full or short names. Recursive references and unions
+// of several types are untyped JSON.
+func SchemaFromAvro(data []byte) (Schema, error) {
+	p := &avroParser{names: make(map[string]json.RawMessage)}
+	var record avroType
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	if record.Type != "record" {
+		return nil, errors.New("Avro schema is not a record")
+	}
+	p.define(data, record, "")
+	return p.fields(record, "", "")
+}
+
+// avroType holds the attributes of an Avro schema declared as an object
+type avroType struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Namespace   string          `json:"namespace"`
+	Doc         string          `json:"doc"`
+	Fields      []avroField     `json:"fields"`
+	Items       json.RawMessage `json:"items"`
+	LogicalType string          `json:"logicalType"`
+	Precision   int             `json:"precision"`
+	Scale       int             `json:"scale"`
+}
+
+// avroField is a field of an Avro record
+type avroField struct {
+	Name string          `json:"name"`
+	Type json.RawMessage `json:"type"`
+	Doc  string          `json:"doc"`
+}
+
+// avroParser derives fields from Avro schemas, resolving named types
+type avroParser struct {
+	names     map[string]json.RawMessage // named types by full and short name
+	resolving []string                   // full names of the records being derived
+}
+
+// define registers a named type under its full and short names
+func (p *avroParser) define(data json.RawMessage, t avroType, namespace string) {
+	if t.Name == "" {
+		return
+	}
+	fullName := avroFullName(t, namespace)
+	p.names[fullName] = data
+	p.names[fullName[strings.LastIndex(fullName, ".")+1:]] = data
+}
+
+// fields derives the fields of a record declared in a namespace. jsonType is the type of the
+// JSON column the record is stored in, or empty for the columns of a row.
+func (p *avroParser) fields(record avroType, namespace, jsonType string) (Schema, error) {
+	fullName := avroFullName(record, namespace)
+	p.resolving = append(p.resolving, fullName)
+	defer func() { p.resolving = p.resolving[:len(p.resolving)-1] }()
+
+	namespace = ""
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		namespace = fullName[:i]
+	}
+	schema := make(Schema, 0, len(record.Fields))
+	for _, f := range record.Fields {
+		if f.Name == "" {
+			return nil, fmt.Errorf("field of record %s without a name", fullName)
+		}
+		field, err := p.parse(f.Name, f.Type, namespace, jsonType)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		field.Doc = f.Doc
+		schema = append(schema, field)
+	}
+	return schema, nil
+}
+
+// parse derives a field from an Avro schema, a type name, union or object
+func (p *avroParser) parse(name string, data json.RawMessage, namespace, jsonType string) (FieldSchema, error) {
+	untyped := FieldSchema{Name: name, Type: jsonType}
+	if jsonType == "" {
+		untyped.Type = "jsonb"
+	}
+
+	var union []json.RawMessage
+	if err := json.Unmarshal(data, &union); err == nil {
+		var typed []json.RawMessage
+		for _, member := range union {
+			if string(member) != `"null"` {
+				typed = append(typed, member)
+			}
+		}
+		if len(typed) != 1 {
+			return untyped, nil
+		}
+		field, err := p.parse(name, typed[0], namespace, jsonType)
+		field.NotNull = field.NotNull && len(union) == 1
+		return field, err
+	}
+
+	var t avroType
+	var typeName string
+	if err := json.Unmarshal(data, &typeName); err == nil {
+		if definition, found := p.lookup(typeName, namespace); found {
+			if err := json.Unmarshal(definition, &t); err != nil {
+				return FieldSchema{}, err
+			}
+			data = definition
+		} else {
+			t.Type = typeName
+		}
+	} else if err := json.Unmarshal(data, &t); err != nil {
+		return FieldSchema{}, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	if t.Type == "" {
+		return FieldSchema{}, errors.New("Avro schema without a type")
+	}
+
+	field := FieldSchema{Name: name, NotNull: true}
+	switch t.Type {
+	case "boolean":
+		field.Type = "boolean"
+	case "int":
+		field.Type = "integer"
+	case "long":
+		field.Type = "bigint"
+	case "float":
+		field.Type = "real"
+	case "double":
+		field.Type = "double precision"
+	case "bytes", "fixed":
+		field.Type = "bytea"
+		if t.Type == "fixed" {
+			p.define(data, t, namespace)
+		}
+	case "string":
+		field.Type = "text"
+	case "enum":
+		field.Type = "text"
+		p.define(data, t, namespace)
+	case "array":
+		if len(t.Items) == 0 {
+			return FieldSchema{}, errors.New("array without items")
+		}
+		items, err := p.parse(name, t.Items, namespace, jsonType)
+		if err != nil {
+			return FieldSchema{}, err
+		}
+		field.Type, field.Schema, field.Repeated = items.Type, items.Schema, true
+		return field, nil
+	case "map":
+		untyped.NotNull = true
+		return untyped, nil
+	case "record":
+		fullName := avroFullName(t, namespace)
+		for _, resolving := range p.resolving {
+			if resolving == fullName {
+				// Recursive structures cannot be typed
+				return untyped, nil
+			}
+		}
+		p.define(data, t, namespace)
+		field.Type = jsonType
+		if jsonType == "" {
+			field.Type = "jsonb"
+		}
+		var err error
+		if field.Schema, err = p.fields(t, namespace, field.Type); err != nil {
+			return FieldSchema{}, err
+		}
+		return field, nil
+	case "null":
+		return FieldSchema{}, errors.New("null is only supported in unions")
+	default:
+		return FieldSchema{}, fmt.Errorf("unknown Avro type %s", t.Type)
+	}
+
+	// Logical types annotate the types of the values stored in rows, while JSON holds the
+	// underlying values
+	if jsonType != "" {
+		return field, nil
+	}
+	switch t.LogicalType {
+	case "date":
+		field.Type = "date"
+	case "time-millis", "time-micros":
+		field.Type = "time"
+	case "timestamp-millis", "timestamp-micros", "timestamp-nanos":
+		field.Type = "timestamptz"
+	case "local-timestamp-millis", "local-timestamp-micros", "local-timestamp-nanos":
+		field.Type = "timestamp"
+	case "decimal":
+		field.Type, field.Precision, field.Scale = "numeric", t.Precision, t.Scale
+	case "uuid":
+		field.Type = "uuid"
+	}
+	return field, nil
+}
+
+// lookup finds a named type by its full name, or its name in the enclosing namespace
+func (p *avroParser) lookup(typeName, namespace string) (json.RawMessage, bool) {
+	if namespace != "" && !strings.Contains(typeName, ".") {
+		if definition, found := p.names[namespace+"."+typeName]; found {
+			return definition, true
+		}
+	}
+	definition, found := p.names[typeName]
+	return definition, found
+}
+
+// avroFullName returns the full name of a named type, qualifying its name with its namespace or
+// else the enclosing namespace
+func avroFullName(t avroType, namespace string) string {
+	switch {
+	case strings.Contains(t.Name, "."):
+		return t.Name
+	case t.Namespace != "":
+		return t.Namespace + "." + t.Name
+	case namespace != "":
+		return namespace + "." + t.Name
+	default:
+		return t.Name
+	}
+}
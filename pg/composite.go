@@ -0,0 +1,100 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+)
+
+// compositeTypeColumn selects the relation id of the composite type of composite and composite
+// array columns, or NULL for other columns
+const compositeTypeColumn = `(SELECT t.typrelid::bigint
+				FROM ` + columnBaseType + `
+				AND t.typtype = 'c'
+			) as composite_type`
+
+// maxCompositeDepth bounds the nesting of composite types loaded for a column
+const maxCompositeDepth = 16
+
+// setCompositeType loads the attributes of the composite type selected by compositeTypeColumn
+// as the nested schema of the field
+func (p *typeProvider) setCompositeType(ctx context.Context, field *FieldSchema, relid int64, depth int) error {
+	if depth > maxCompositeDepth {
+		return fmt.Errorf("composite type of column %s is nested more than %d levels deep", field.Name, maxCompositeDepth)
+	}
+
+	rows, err := p.db.Query(ctx, `
+		SELECT
+			a.attname,
+			t.typcategory = 'A' as repeated,
+			format_type(bt.oid, NULL) as type_name,
+			CASE WHEN bt.oid = 'numeric'::regtype AND a.atttypmod > 0 THEN ((a.atttypmod - 4) >> 16) & 65535 END as numeric_precision,
+			CASE WHEN bt.oid = 'numeric'::regtype AND a.atttypmod > 0 THEN (a.atttypmod - 4) & 65535 END as numeric_scale,
+			CASE WHEN bt.oid IN ('varchar'::regtype, 'bpchar'::regtype) AND a.atttypmod > 0 THEN a.atttypmod - 4 END as character_maximum_length,
+			CASE WHEN bt.typtype = 'c' THEN bt.typrelid::bigint END as composite_type,
+			CASE WHEN bt.typtype = 'e' THEN
+				(SELECT json_build_object(
+					'name', format_type(bt.oid, NULL),
+					'labels', json_agg(e.enumlabel ORDER BY e.enumsortorder))::text
+				 FROM pg_catalog.pg_enum e WHERE e.enumtypid = bt.oid)
+			END as enum_type,
+			col_description(a.attrelid, a.attnum) as doc
+		FROM pg_catalog.pg_attribute a
+		JOIN pg_catalog.pg_type t ON t.oid = a.atttypid
+		JOIN pg_catalog.pg_type bt ON bt.oid = CASE WHEN t.typcategory = 'A' THEN t.typelem ELSE t.oid END
+		WHERE a.attrelid::bigint = $1
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, relid)
+	if err != nil {
+		return fmt.Errorf("failed to query composite type of column %s: %w", field.Name, err)
+	}
+	defer rows.Close()
+
+	var nested Schema
+	var composites []*int64
+	for rows.Next() {
+		var attribute FieldSchema
+		var compositeType *int64
+		var enumType *string
+		var precision, scale, length *int64
+		var doc *string
+		if err := rows.Scan(&attribute.Name, &attribute.Repeated, &attribute.Type, &precision, &scale, &length,
+			&compositeType, &enumType, &doc); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		if doc != nil {
+			attribute.Doc = *doc
+		}
+		setTypeModifiers(&attribute, precision, scale, length)
+		if err := setEnumType(&attribute, enumType); err != nil {
+			return err
+		}
+		nested = append(nested, attribute)
+		composites = append(composites, compositeType)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	if err := p.setCompositeTypes(ctx, nested, composites, depth+1); err != nil {
+		return err
+	}
+	field.Type = "composite"
+	field.Schema = nested
+	return nil
+}
+
+// setCompositeTypes loads the nested schemas of the fields whose composite type is set
+func (p *typeProvider) setCompositeTypes(ctx context.Context, schema Schema, composites []*int64, depth int) error {
+	for i, relid := range composites {
+		if relid == nil {
+			continue
+		}
+		if err := p.setCompositeType(ctx, &schema[i], *relid, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
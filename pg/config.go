@@ -0,0 +1,213 @@
+package pg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaConfig is the document read by NewTypeProviderFromConfig
+type schemaConfig struct {
+	Tables map[string][]columnConfig `yaml:"tables" json:"tables"`
+}
+
+// columnConfig declares a column, or a field of a composite or JSON column
+type columnConfig struct {
+	Name     string         `yaml:"name" json:"name"`
+	Type     string         `yaml:"type" json:"type,omitempty"`
+	Repeated bool           `yaml:"repeated" json:"repeated,omitempty"`
+	Fields   []columnConfig `yaml:"fields" json:"fields,omitempty"`
+	Enum     []string       `yaml:"enum" json:"enum,omitempty"`
+	NotNull  bool           `yaml:"notNull" json:"notNull,omitempty"`
+	Doc      string         `yaml:"doc" json:"doc,omitempty"`
+
+	Generated bool `yaml:"generated" json:"generated,omitempty"`
+	Identity  bool `yaml:"identity" json:"identity,omitempty"`
+
+	Precision int `yaml:"precision" json:"precision,omitempty"`
+	Scale     int `yaml:"scale" json:"scale,omitempty"`
+	Length    int `yaml:"length" json:"length,omitempty"`
+}
+
+// NewTypeProviderFromConfig creates a new PostgreSQL type provider from a YAML or JSON document
+// declaring the columns of each table, so that the schemas filters can touch are kept and
+// reviewed as configuration:
+//
+//	tables:
+//	  users:
+//	    - name: id
+//	      type: integer
+//	      notNull: true
+//	      doc: Unique user identifier
+//	    - name: tags
+//	      type: text[]
+//	    - name: status
+//	      type: user_status
+//	      enum: [active, suspended]
+//	    - name: address
+//	      type: composite
+//	      fields:
+//	        - name: city
+//	          type: text
+//
+// A type ending in [] is shorthand for repeated: true. NotNull marks columns declared NOT NULL,
+// and doc documents a column. Generated and identity mark generated and identity columns.
+// Precision and scale constrain numeric columns, and length character columns. Enum lists the
+// labels of an enum type in sort order. Fields declare the structure of composite columns, and
+// the keys of the documents of JSON columns, which are then typed objects in CEL rather than
+// dynamic values.
+func NewTypeProviderFromConfig(r io.Reader) (TypeProvider, error) {
+	schemas, err := SchemasFromConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewTypeProvider(schemas), nil
+}
+
+// SchemasFromConfig reads the schemas of the tables declared by a YAML or JSON document, as
+// described by NewTypeProviderFromConfig, for use with cel2sql.WithSchemas.
+func SchemasFromConfig(r io.Reader) (map[string]Schema, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	var config schemaConfig
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode schema configuration: %w", err)
+	}
+
+	schemas := make(map[string]Schema, len(config.Tables))
+	for tableName, columns := range config.Tables {
+		schema, err := configSchema(columns)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", tableName, err)
+		}
+		schemas[tableName] = schema
+	}
+	return schemas, nil
+}
+
+// configSchema converts declared columns to field schemas
+func configSchema(columns []columnConfig) (Schema, error) {
+	schema := make(Schema, 0, len(columns))
+	seen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if column.Name == "" {
+			return nil, errors.New("column without a name")
+		}
+		if seen[column.Name] {
+			return nil, fmt.Errorf("duplicate column %s", column.Name)
+		}
+		seen[column.Name] = true
+
+		typeName := strings.TrimSpace(column.Type)
+		if !strings.Contains(typeName, `"`) {
+			// Quoted names of user-defined types are case sensitive
+			typeName = strings.ToLower(typeName)
+		}
+		repeated := column.Repeated
+		if strings.HasSuffix(typeName, "[]") {
+			typeName = strings.TrimSpace(strings.TrimSuffix(typeName, "[]"))
+			repeated = true
+		}
+		if typeName == "" {
+			if len(column.Fields) == 0 {
+				return nil, fmt.Errorf("column %s has no type", column.Name)
+			}
+			typeName = "composite"
+		}
+
+		field := FieldSchema{
+			Name:      column.Name,
+			Type:      typeName,
+			Repeated:  repeated,
+			Enum:      column.Enum,
+			NotNull:   column.NotNull,
+			Doc:       column.Doc,
+			Generated: column.Generated,
+			Identity:  column.Identity,
+			Precision: column.Precision,
+			Scale:     column.Scale,
+			Length:    column.Length,
+		}
+		if len(column.Fields) > 0 {
+			nested, err := configSchema(column.Fields)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", column.Name, err)
+			}
+			field.Schema = nested
+		}
+		schema = append(schema, field)
+	}
+	return schema, nil
+}
+
+// ExportSchemas serializes the loaded schemas to JSON, in the document format read by
+// ImportSchemas and NewTypeProviderFromConfig, so that schemas introspected once can be
+// snapshotted, versioned and loaded where there is no database access. Tables that were looked
+// up but do not exist are not exported.
+func (p *typeProvider) ExportSchemas() ([]byte, error) {
+	p.mu.RLock()
+	config := schemaConfig{Tables: make(map[string][]columnConfig, len(p.schemas))}
+	for tableName, schema := range p.schemas {
+		if schema != nil {
+			config.Tables[tableName] = schemaColumns(schema)
+		}
+	}
+	p.mu.RUnlock()
+	return json.MarshalIndent(config, "", "  ")
+}
+
+// ImportSchemas loads schemas exported by ExportSchemas, replacing loaded tables of the same name
+func (p *typeProvider) ImportSchemas(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var config schemaConfig
+	if err := decoder.Decode(&config); err != nil {
+		return fmt.Errorf("failed to decode schemas: %w", err)
+	}
+
+	schemas := make(map[string]Schema, len(config.Tables))
+	for tableName, columns := range config.Tables {
+		schema, err := configSchema(columns)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", tableName, err)
+		}
+		schemas[tableName] = schema
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for tableName, schema := range schemas {
+		p.schemas[tableName] = schema
+	}
+	return nil
+}
+
+// schemaColumns converts field schemas to declared columns
+func schemaColumns(schema Schema) []columnConfig {
+	columns := make([]columnConfig, 0, len(schema))
+	for _, field := range schema {
+		column := columnConfig{
+			Name:      field.Name,
+			Type:      field.Type,
+			Repeated:  field.Repeated,
+			Enum:      field.Enum,
+			NotNull:   field.NotNull,
+			Doc:       field.Doc,
+			Generated: field.Generated,
+			Identity:  field.Identity,
+			Precision: field.Precision,
+			Scale:     field.Scale,
+			Length:    field.Length,
+		}
+		if len(field.Schema) > 0 {
+			column.Fields = schemaColumns(field.Schema)
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
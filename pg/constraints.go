@@ -0,0 +1,182 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ForeignKey describes a foreign key constraint of a table
+type ForeignKey struct {
+	Name       string   // constraint name, empty if not declared
+	Columns    []string // referencing columns, in constraint order
+	RefTable   string   // referenced table, schema-qualified when its schema differs from the table's
+	RefColumns []string // referenced columns, empty when the key references the primary key
+}
+
+// addKey adds a unique key, the primary key being kept first
+func (m *tableMetadata) addKey(key []string, primary bool) {
+	if !primary {
+		m.uniqueKeys = append(m.uniqueKeys, key)
+		return
+	}
+	m.primaryKey = key
+	m.uniqueKeys = append([][]string{key}, m.uniqueKeys...)
+}
+
+// ForeignKeys returns the foreign key constraints of a table loaded with LoadTableSchema,
+// LoadSchema or NewTypeProviderFromDDL, under the same name the table was loaded with
+func (p *typeProvider) ForeignKeys(tableName string) []ForeignKey {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.metadata[tableName].foreignKeys
+}
+
+// PrimaryKey returns the primary key columns of a loaded table, or nil if it has none
+func (p *typeProvider) PrimaryKey(tableName string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.metadata[tableName].primaryKey
+}
+
+// UniqueKeys returns the column sets of a loaded table that are known to be unique: its primary
+// key first, then its unique constraints and, when introspected, unique indexes that are neither
+// partial nor on expressions. Columns of unique keys that are not NOT NULL may still hold
+// several NULLs.
+func (p *typeProvider) UniqueKeys(tableName string) [][]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.metadata[tableName].uniqueKeys
+}
+
+// uniqueKeysQuery selects the columns of the unique indexes of the tables of schema $1, or only
+// of table $2 when it is not NULL, which include the indexes backing primary keys and unique
+// constraints. Partial indexes, indexes on expressions and included columns are left out.
+const uniqueKeysQuery = `
+		SELECT
+			c.relname,
+			i.indisprimary,
+			array_to_json(ARRAY(
+				SELECT a.attname FROM unnest(i.indkey::int2[]) WITH ORDINALITY k(attnum, n)
+				JOIN pg_catalog.pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+				WHERE k.n <= i.indnkeyatts
+				ORDER BY k.n))::text as columns
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_class c ON c.oid = i.indrelid
+		JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE i.indisunique
+		AND i.indpred IS NULL
+		AND i.indexprs IS NULL
+		AND n.nspname = $1
+		AND ($2::text IS NULL OR c.relname = $2::text)
+		ORDER BY c.relname, i.indisprimary DESC, ic.relname
+	`
+
+// foreignKeysQuery selects the foreign keys of the tables of schema $1, or only of table $2 when
+// it is not NULL
+const foreignKeysQuery = `
+		SELECT
+			c.relname,
+			con.conname,
+			array_to_json(ARRAY(
+				SELECT a.attname FROM unnest(con.conkey) WITH ORDINALITY k(attnum, n)
+				JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.n))::text as columns,
+			rn.nspname,
+			r.relname,
+			array_to_json(ARRAY(
+				SELECT a.attname FROM unnest(con.confkey) WITH ORDINALITY k(attnum, n)
+				JOIN pg_catalog.pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.n))::text as ref_columns
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_class r ON r.oid = con.confrelid
+		JOIN pg_catalog.pg_namespace rn ON rn.oid = r.relnamespace
+		WHERE con.contype = 'f'
+		AND n.nspname = $1
+		AND ($2::text IS NULL OR c.relname = $2::text)
+		ORDER BY c.relname, con.conname
+	`
+
+// queryForeignKeys queries the foreign keys of the tables of a database schema, or only of the
+// given table if not empty, by table name
+func (p *typeProvider) queryForeignKeys(ctx context.Context, schemaName, table string) (map[string][]ForeignKey, error) {
+	var tableParam any
+	if table != "" {
+		tableParam = table
+	}
+	rows, err := p.db.Query(ctx, foreignKeysQuery, schemaName, tableParam)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	foreignKeys := make(map[string][]ForeignKey)
+	for rows.Next() {
+		var tableName, columns, refSchema, refColumns string
+		var fk ForeignKey
+		if err := rows.Scan(&tableName, &fk.Name, &columns, &refSchema, &fk.RefTable, &refColumns); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(columns), &fk.Columns); err != nil {
+			return nil, fmt.Errorf("failed to decode columns of foreign key %s: %w", fk.Name, err)
+		}
+		if err := json.Unmarshal([]byte(refColumns), &fk.RefColumns); err != nil {
+			return nil, fmt.Errorf("failed to decode columns of foreign key %s: %w", fk.Name, err)
+		}
+		if refSchema != schemaName {
+			fk.RefTable = refSchema + "." + fk.RefTable
+		}
+		foreignKeys[tableName] = append(foreignKeys[tableName], fk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return foreignKeys, nil
+}
+
+// queryKeys adds the unique and foreign keys of the tables of a database schema, or only of the
+// given table if not empty, to their metadata
+func (p *typeProvider) queryKeys(ctx context.Context, schemaName, table string, metadata map[string]tableMetadata) error {
+	var tableParam any
+	if table != "" {
+		tableParam = table
+	}
+	rows, err := p.db.Query(ctx, uniqueKeysQuery, schemaName, tableParam)
+	if err != nil {
+		return fmt.Errorf("failed to query unique keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, columns string
+		var primary bool
+		if err := rows.Scan(&tableName, &primary, &columns); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		var key []string
+		if err := json.Unmarshal([]byte(columns), &key); err != nil {
+			return fmt.Errorf("failed to decode columns of unique key of %s: %w", tableName, err)
+		}
+		m := metadata[tableName]
+		m.addKey(key, primary)
+		metadata[tableName] = m
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	foreignKeys, err := p.queryForeignKeys(ctx, schemaName, table)
+	if err != nil {
+		return err
+	}
+	for tableName, fks := range foreignKeys {
+		m := metadata[tableName]
+		m.foreignKeys = fks
+		metadata[tableName] = m
+	}
+	return nil
+}
@@ -0,0 +1,746 @@
+package pg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NewTypeProviderFromDDL creates a new PostgreSQL type provider from the CREATE TABLE and
+// CREATE TYPE statements of a DDL script, such as a schema dump or migrations, so that accurate
+// providers can be built without a database. Array columns, JSON/JSONB columns and columns of
+// composite and enum types are supported, as are primary, unique and foreign keys declared in
+// CREATE TABLE or added with ALTER TABLE, CREATE UNIQUE INDEX on plain columns, COMMENT ON
+// COLUMN and partitions created with PARTITION OF or attached with ATTACH PARTITION; other
+// statements are ignored. Tables are registered under their names as written, e.g. "users" or
+// "analytics.events", and partitions are not registered as tables of their own.
+func NewTypeProviderFromDDL(ddl string) (TypeProvider, error) {
+	schemas, metadata, err := parseDDL(ddl)
+	if err != nil {
+		return nil, err
+	}
+	p := NewTypeProvider(schemas).(*typeProvider)
+	for tableName, m := range metadata {
+		p.setMetadata(tableName, m)
+	}
+	return p, nil
+}
+
+// ddlToken is a lexical token of a DDL script
+type ddlToken struct {
+	text   string // identifiers are folded to lower case unless quoted
+	quoted bool   // quoted identifier or string literal, never a keyword
+	str    bool   // string literal
+	pos    int
+}
+
+// is checks if the token is the given keyword or punctuation
+func (t ddlToken) is(word string) bool {
+	return !t.quoted && t.text == word
+}
+
+// ddlType is a user-defined type, either a composite type or an enum
+type ddlType struct {
+	columns []ddlColumn
+	labels  []string
+}
+
+// ddlColumn is a parsed column whose type is resolved once all composite types are known
+type ddlColumn struct {
+	name      string
+	typeName  string
+	repeated  bool
+	notNull   bool
+	generated bool
+	identity  bool
+	typmods   []int  // type modifiers such as the precision and scale of numeric(10, 2)
+	doc       string // set with COMMENT ON COLUMN
+}
+
+type ddlParser struct {
+	tokens []ddlToken
+	pos    int
+}
+
+// parseDDL parses the tables of a DDL script into schemas and table metadata
+func parseDDL(ddl string) (map[string]Schema, map[string]tableMetadata, error) {
+	tokens, err := tokenizeDDL(ddl)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &ddlParser{tokens: tokens}
+
+	tables := make(map[string][]ddlColumn)
+	metadata := make(map[string]tableMetadata)
+	var tableOrder []string
+	userTypes := make(map[string]ddlType) // by unqualified name
+	comments := make(map[string]string)   // by qualified column name
+	for !p.done() {
+		start := p.pos
+		switch {
+		case p.acceptWords("create"):
+			p.acceptWords("or", "replace")
+			p.acceptWords("global")
+			p.acceptWords("local")
+			p.acceptWords("temporary")
+			p.acceptWords("temp")
+			p.acceptWords("unlogged")
+			unique := p.acceptWords("unique")
+			switch {
+			case p.acceptWords("table"):
+				p.acceptWords("if", "not", "exists")
+				name, columns, c, err := p.parseColumns()
+				if err != nil {
+					return nil, nil, err
+				}
+				if columns != nil {
+					if _, found := tables[name]; !found {
+						tableOrder = append(tableOrder, name)
+					}
+					tables[name] = columns
+					metadata[name] = c
+				} else if p.acceptWords("partition", "of") {
+					parent, err := p.parseName()
+					if err != nil {
+						return nil, nil, err
+					}
+					m := metadata[parent]
+					m.partitions = append(m.partitions, name)
+					metadata[parent] = m
+				}
+			case unique && p.acceptWords("index"):
+				if name, key := p.parseUniqueIndex(); key != nil {
+					c := metadata[name]
+					c.addKey(key, false)
+					metadata[name] = c
+				}
+			case p.acceptWords("type"):
+				name, err := p.parseName()
+				if err != nil {
+					return nil, nil, err
+				}
+				name = name[strings.LastIndex(name, ".")+1:]
+				switch {
+				case p.acceptWords("as", "enum"):
+					userTypes[name] = ddlType{labels: p.parseLabels()}
+				case p.acceptWords("as") && p.peek().is("("):
+					p.pos-- // parseColumns expects the name before the parenthesis
+					_, columns, _, err := p.parseColumns()
+					if err != nil {
+						return nil, nil, err
+					}
+					userTypes[name] = ddlType{columns: columns}
+				}
+			}
+		case p.acceptWords("comment", "on", "column"):
+			name, err := p.parseName()
+			if err != nil {
+				return nil, nil, err
+			}
+			if p.acceptWords("is") {
+				// IS NULL drops the comment
+				comments[name] = ""
+				if p.peek().str {
+					comments[name] = p.next().text
+				}
+			}
+		case p.acceptWords("alter", "table"):
+			p.acceptWords("if", "exists")
+			p.acceptWords("only")
+			name, err := p.parseName()
+			if err != nil {
+				return nil, nil, err
+			}
+			c := metadata[name]
+			switch {
+			case p.acceptWords("add"):
+				p.parseTableConstraint(&c)
+			case p.acceptWords("attach", "partition"):
+				partition, err := p.parseName()
+				if err != nil {
+					return nil, nil, err
+				}
+				c.partitions = append(c.partitions, partition)
+			}
+			metadata[name] = c
+		}
+		if p.pos == start {
+			p.pos++
+		}
+		p.skipStatement()
+	}
+
+	for name, doc := range comments {
+		dot := strings.LastIndex(name, ".")
+		if dot < 0 {
+			continue
+		}
+		relation, columnName := name[:dot], name[dot+1:]
+		columns, found := tables[relation]
+		if !found {
+			columns = userTypes[relation[strings.LastIndex(relation, ".")+1:]].columns
+		}
+		for i := range columns {
+			if columns[i].name == columnName {
+				columns[i].doc = doc
+			}
+		}
+	}
+
+	schemas := make(map[string]Schema, len(tables))
+	for _, name := range tableOrder {
+		schema, err := resolveDDLColumns(tables[name], userTypes, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("table %s: %w", name, err)
+		}
+		schemas[name] = schema
+	}
+	for name, c := range metadata {
+		if c.isEmpty() {
+			delete(metadata, name)
+		}
+	}
+	return schemas, metadata, nil
+}
+
+// resolveDDLColumns builds the field schemas of columns, expanding composite types and enums
+func resolveDDLColumns(columns []ddlColumn, userTypes map[string]ddlType, resolving []string) (Schema, error) {
+	schema := make(Schema, 0, len(columns))
+	for _, column := range columns {
+		field := FieldSchema{
+			Name:      column.name,
+			Type:      column.typeName,
+			Repeated:  column.repeated,
+			NotNull:   column.notNull,
+			Doc:       column.doc,
+			Generated: column.generated,
+			Identity:  column.identity,
+		}
+		if len(column.typmods) > 0 {
+			switch column.typeName {
+			case "numeric", "decimal":
+				field.Precision = column.typmods[0]
+				if len(column.typmods) > 1 {
+					field.Scale = column.typmods[1]
+				}
+			case "character varying", "varchar", "character", "char":
+				field.Length = column.typmods[0]
+			}
+		}
+		typeName := column.typeName[strings.LastIndex(column.typeName, ".")+1:]
+		userType, found := userTypes[typeName]
+		if found && userType.labels != nil {
+			field.Enum = userType.labels
+		} else if found {
+			for _, r := range resolving {
+				if r == typeName {
+					return nil, fmt.Errorf("composite type %s is recursive", typeName)
+				}
+			}
+			nested, err := resolveDDLColumns(userType.columns, userTypes, append(resolving, typeName))
+			if err != nil {
+				return nil, err
+			}
+			field.Type = "composite"
+			field.Schema = nested
+		}
+		schema = append(schema, field)
+	}
+	return schema, nil
+}
+
+// ddlConstraintKeywords end the type of a column definition
+var ddlConstraintKeywords = map[string]bool{
+	"not": true, "null": true, "default": true, "primary": true, "references": true, "unique": true,
+	"check": true, "constraint": true, "collate": true, "generated": true, "deferrable": true,
+	"initially": true, "compression": true, "storage": true,
+}
+
+// ddlTableConstraintKeywords start a table constraint rather than a column definition
+var ddlTableConstraintKeywords = map[string]bool{
+	"constraint": true, "primary": true, "unique": true, "check": true, "foreign": true,
+	"exclude": true, "like": true,
+}
+
+// ddlTypeAliases maps type names that the provider does not know to equivalent ones
+var ddlTypeAliases = map[string]string{
+	"serial": "integer", "serial4": "integer", "bigserial": "bigint", "serial8": "bigint",
+	"smallserial": "smallint", "serial2": "smallint", "float": "double precision",
+	"timestamp with time zone": "timestamptz", "timestamp without time zone": "timestamp",
+	"time with time zone": "timetz", "time without time zone": "time",
+}
+
+// parseColumns parses "name ( column, ... )" of a CREATE TABLE or CREATE TYPE statement, with
+// the keys declared by column and table constraints. It returns nil columns for statements
+// without a column list, such as CREATE TABLE ... AS.
+func (p *ddlParser) parseColumns() (string, []ddlColumn, tableMetadata, error) {
+	var constraints tableMetadata
+	name, err := p.parseName()
+	if err != nil {
+		return "", nil, constraints, err
+	}
+	if !p.peek().is("(") {
+		return name, nil, constraints, nil
+	}
+	p.pos++
+
+	columns := []ddlColumn{}
+	for {
+		if p.done() {
+			return "", nil, constraints, fmt.Errorf("unterminated column list of %s", name)
+		}
+		tok := p.peek()
+		if tok.is(")") {
+			p.pos++
+			return name, columns, constraints, nil
+		}
+		if tok.is(",") {
+			p.pos++
+			continue
+		}
+		if !tok.quoted && ddlTableConstraintKeywords[tok.text] {
+			for _, key := range p.parseTableConstraint(&constraints) {
+				for i := range columns {
+					if columns[i].name == key {
+						columns[i].notNull = true
+					}
+				}
+			}
+			p.skipElement()
+			continue
+		}
+		column, err := p.parseColumn()
+		if err != nil {
+			return "", nil, constraints, fmt.Errorf("%s: %w", name, err)
+		}
+		p.parseColumnConstraints(&column, &constraints)
+		columns = append(columns, column)
+	}
+}
+
+// parseColumn parses a column name and its type, stopping at the first constraint
+func (p *ddlParser) parseColumn() (ddlColumn, error) {
+	column := ddlColumn{name: p.next().text}
+	var words []string
+	for !p.done() {
+		tok := p.peek()
+		switch {
+		case tok.is(",") || tok.is(")"):
+		case tok.is("("):
+			// Type modifiers such as varchar(255) or numeric(10, 2)
+			column.typmods = p.parseTypeModifiers()
+			continue
+		case tok.is("["):
+			column.repeated = true
+			for !p.done() && !p.next().is("]") {
+			}
+			continue
+		case tok.is("array"):
+			column.repeated = true
+			p.pos++
+			continue
+		case tok.is("."):
+			p.pos++
+			if len(words) > 0 {
+				words[len(words)-1] += "." + p.next().text
+			}
+			continue
+		case !tok.quoted && ddlConstraintKeywords[tok.text]:
+		default:
+			words = append(words, tok.text)
+			p.pos++
+			continue
+		}
+		break
+	}
+	if len(words) == 0 {
+		return ddlColumn{}, fmt.Errorf("column %s has no type", column.name)
+	}
+	column.typeName = strings.Join(words, " ")
+	if alias, found := ddlTypeAliases[column.typeName]; found {
+		column.typeName = alias
+	}
+	return column, nil
+}
+
+// parseTypeModifiers parses the parenthesized integers following a type name
+func (p *ddlParser) parseTypeModifiers() []int {
+	var typmods []int
+	depth := 0
+	for !p.done() {
+		tok := p.next()
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+			if depth == 0 {
+				return typmods
+			}
+		default:
+			if n, err := strconv.Atoi(tok.text); err == nil && !tok.quoted {
+				typmods = append(typmods, n)
+			}
+		}
+	}
+	return typmods
+}
+
+// parseColumnConstraints parses the constraints of a column definition, marking the column NOT
+// NULL for NOT NULL and PRIMARY KEY, marking generated and identity columns, and adding the keys
+// declared with PRIMARY KEY, UNIQUE and REFERENCES to the table constraints
+func (p *ddlParser) parseColumnConstraints(column *ddlColumn, constraints *tableMetadata) {
+	constraintName := ""
+	for !p.done() {
+		tok := p.peek()
+		switch {
+		case tok.is(",") || tok.is(")"):
+			return
+		case tok.is("("):
+			p.skipParenthesized()
+		case p.acceptWords("not", "null"):
+			column.notNull = true
+		case p.acceptWords("primary", "key"):
+			column.notNull = true
+			constraints.addKey([]string{column.name}, true)
+		case p.acceptWords("unique"):
+			constraints.addKey([]string{column.name}, false)
+		case p.acceptWords("generated"):
+			p.acceptWords("always")
+			p.acceptWords("by", "default")
+			if p.acceptWords("as", "identity") {
+				column.identity = true
+				column.notNull = true
+			} else if p.acceptWords("as") {
+				p.skipParenthesized()
+				column.generated = true
+			}
+		case p.acceptWords("constraint"):
+			constraintName = p.next().text
+		case p.acceptWords("references"):
+			if fk := p.parseReferences(constraintName, []string{column.name}); fk != nil {
+				constraints.foreignKeys = append(constraints.foreignKeys, *fk)
+			}
+		default:
+			p.pos++
+		}
+	}
+}
+
+// parseTableConstraint adds the key of a PRIMARY KEY, UNIQUE or FOREIGN KEY table constraint to
+// the table constraints, leaving the parser at the start of the constraint. It returns the
+// columns of a primary key.
+func (p *ddlParser) parseTableConstraint(constraints *tableMetadata) (primaryKey []string) {
+	start := p.pos
+	defer func() { p.pos = start }()
+	constraintName := ""
+	if p.acceptWords("constraint") {
+		constraintName = p.next().text
+	}
+	switch {
+	case p.acceptWords("primary", "key"):
+		primaryKey = p.parseNameList()
+		if primaryKey != nil {
+			constraints.addKey(primaryKey, true)
+		}
+		return primaryKey
+	case p.acceptWords("unique"):
+		p.acceptWords("nulls", "not", "distinct")
+		p.acceptWords("nulls", "distinct")
+		if key := p.parseNameList(); key != nil {
+			constraints.addKey(key, false)
+		}
+	case p.acceptWords("foreign", "key"):
+		columns := p.parseNameList()
+		if columns != nil && p.acceptWords("references") {
+			if fk := p.parseReferences(constraintName, columns); fk != nil {
+				constraints.foreignKeys = append(constraints.foreignKeys, *fk)
+			}
+		}
+	}
+	return nil
+}
+
+// parseUniqueIndex parses "[CONCURRENTLY] [IF NOT EXISTS] [name] ON [ONLY] table [USING method]
+// (column, ...)" of a CREATE UNIQUE INDEX statement, returning nil columns for partial indexes
+// and indexes on expressions
+func (p *ddlParser) parseUniqueIndex() (string, []string) {
+	p.acceptWords("concurrently")
+	p.acceptWords("if", "not", "exists")
+	if !p.peek().is("on") {
+		if _, err := p.parseName(); err != nil {
+			return "", nil
+		}
+	}
+	if !p.acceptWords("on") {
+		return "", nil
+	}
+	p.acceptWords("only")
+	table, err := p.parseName()
+	if err != nil {
+		return "", nil
+	}
+	if p.acceptWords("using") {
+		p.pos++
+	}
+	if !p.peek().is("(") {
+		return "", nil
+	}
+	var key []string
+	depth := 0
+	for !p.done() {
+		tok := p.next()
+		switch {
+		case tok.is("("):
+			depth++
+			if depth > 1 {
+				return "", nil // expression
+			}
+		case tok.is(")"):
+			for p.acceptWords("include") {
+				p.skipParenthesized()
+			}
+			if p.peek().is("where") {
+				return "", nil
+			}
+			return table, key
+		case tok.is(","):
+		case !tok.quoted && (tok.text == "asc" || tok.text == "desc" || tok.text == "nulls" ||
+			tok.text == "first" || tok.text == "last"):
+		case tok.quoted || isDDLIdentifier(tok.text):
+			key = append(key, tok.text)
+		default:
+			return "", nil // expression
+		}
+	}
+	return "", nil
+}
+
+// parseReferences parses the referenced table and columns following REFERENCES
+func (p *ddlParser) parseReferences(constraintName string, columns []string) *ForeignKey {
+	refTable, err := p.parseName()
+	if err != nil {
+		return nil
+	}
+	return &ForeignKey{Name: constraintName, Columns: columns, RefTable: refTable, RefColumns: p.parseNameList()}
+}
+
+// parseNameList parses a parenthesized list of column names, returning nil if there is none
+func (p *ddlParser) parseNameList() []string {
+	if !p.peek().is("(") {
+		return nil
+	}
+	names := []string{}
+	for p.pos++; !p.done() && !p.peek().is(")"); p.pos++ {
+		if tok := p.peek(); !tok.is(",") {
+			names = append(names, tok.text)
+		}
+	}
+	p.pos++
+	return names
+}
+
+// parseLabels parses the parenthesized labels of an enum
+func (p *ddlParser) parseLabels() []string {
+	labels := []string{}
+	if !p.peek().is("(") {
+		return labels
+	}
+	for p.pos++; !p.done() && !p.peek().is(")"); p.pos++ {
+		if tok := p.peek(); tok.str {
+			labels = append(labels, tok.text)
+		}
+	}
+	return labels
+}
+
+// parseName parses a possibly schema-qualified name
+func (p *ddlParser) parseName() (string, error) {
+	if p.done() {
+		return "", fmt.Errorf("expected a name at end of input")
+	}
+	name := p.next()
+	if !name.quoted && !isDDLIdentifier(name.text) {
+		return "", fmt.Errorf("expected a name at offset %d, found %q", name.pos, name.text)
+	}
+	qualified := name.text
+	for p.peek().is(".") {
+		p.pos++
+		qualified += "." + p.next().text
+	}
+	return qualified, nil
+}
+
+// skipElement skips to the end of a column definition or table constraint
+func (p *ddlParser) skipElement() {
+	for !p.done() {
+		tok := p.peek()
+		if tok.is(",") || tok.is(")") {
+			return
+		}
+		if tok.is("(") {
+			p.skipParenthesized()
+			continue
+		}
+		p.pos++
+	}
+}
+
+// skipParenthesized skips a balanced parenthesized group
+func (p *ddlParser) skipParenthesized() {
+	depth := 0
+	for !p.done() {
+		tok := p.next()
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// skipStatement skips to the token after the next top-level semicolon
+func (p *ddlParser) skipStatement() {
+	for !p.done() {
+		tok := p.peek()
+		if tok.is("(") {
+			p.skipParenthesized()
+			continue
+		}
+		p.pos++
+		if tok.is(";") {
+			return
+		}
+	}
+}
+
+// acceptWords consumes the given sequence of keywords if the next tokens match it
+func (p *ddlParser) acceptWords(words ...string) bool {
+	if p.pos+len(words) > len(p.tokens) {
+		return false
+	}
+	for i, word := range words {
+		if !p.tokens[p.pos+i].is(word) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *ddlParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *ddlParser) peek() ddlToken {
+	if p.done() {
+		return ddlToken{pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *ddlParser) next() ddlToken {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func isDDLIdentifier(s string) bool {
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r) && r != '$') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// tokenizeDDL splits a DDL script into identifiers, keywords, string literals and punctuation.
+// Dollar-quoted bodies and comments are dropped, as the parser only needs the table structure.
+func tokenizeDDL(ddl string) ([]ddlToken, error) {
+	var tokens []ddlToken
+	for i := 0; i < len(ddl); {
+		c := rune(ddl[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.HasPrefix(ddl[i:], "--"):
+			end := strings.IndexByte(ddl[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case strings.HasPrefix(ddl[i:], "/*"):
+			end := strings.Index(ddl[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += end + 4
+		case c == '\'':
+			var b strings.Builder
+			end := i + 1
+			for ; end < len(ddl); end++ {
+				if ddl[end] == '\'' {
+					if end+1 < len(ddl) && ddl[end+1] == '\'' {
+						b.WriteByte('\'')
+						end++
+						continue
+					}
+					break
+				}
+				b.WriteByte(ddl[end])
+			}
+			if end >= len(ddl) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, ddlToken{text: b.String(), quoted: true, str: true, pos: i})
+			i = end + 1
+		case c == '"':
+			var b strings.Builder
+			end := i + 1
+			for ; end < len(ddl); end++ {
+				if ddl[end] == '"' {
+					if end+1 < len(ddl) && ddl[end+1] == '"' {
+						b.WriteByte('"')
+						end++
+						continue
+					}
+					break
+				}
+				b.WriteByte(ddl[end])
+			}
+			if end >= len(ddl) {
+				return nil, fmt.Errorf("unterminated quoted identifier at offset %d", i)
+			}
+			tokens = append(tokens, ddlToken{text: b.String(), quoted: true, pos: i})
+			i = end + 1
+		case c == '$':
+			// Dollar-quoted string such as $$ ... $$ or $body$ ... $body$
+			tagEnd := strings.IndexByte(ddl[i+1:], '$')
+			if tagEnd < 0 {
+				return nil, fmt.Errorf("unexpected $ at offset %d", i)
+			}
+			tag := ddl[i : i+tagEnd+2]
+			end := strings.Index(ddl[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string at offset %d", i)
+			}
+			tokens = append(tokens, ddlToken{text: "$", quoted: true, pos: i})
+			i += len(tag) + end + len(tag)
+		case c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			start := i
+			for i < len(ddl) && (ddl[i] == '_' || ddl[i] == '$' || unicode.IsLetter(rune(ddl[i])) || unicode.IsDigit(rune(ddl[i]))) {
+				i++
+			}
+			tokens = append(tokens, ddlToken{text: strings.ToLower(ddl[start:i]), pos: start})
+		default:
+			tokens = append(tokens, ddlToken{text: string(c), pos: i})
+			i++
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// enumTypeColumn selects the name and labels of the enum type of enum and enum array columns,
+// as a JSON object, or NULL for other columns. The name is qualified when the type is not
+// visible on the search path.
+const enumTypeColumn = `(SELECT json_build_object(
+					'name', format_type(t.oid, NULL),
+					'labels', (SELECT json_agg(e.enumlabel ORDER BY e.enumsortorder)
+					           FROM pg_catalog.pg_enum e WHERE e.enumtypid = t.oid))::text
+				FROM ` + columnBaseType + `
+				AND t.typtype = 'e'
+			) as enum_type`
+
+// setEnumType records the enum type selected by enumTypeColumn in the field
+func setEnumType(field *FieldSchema, enumType *string) error {
+	if enumType == nil {
+		return nil
+	}
+	var enum struct {
+		Name   string   `json:"name"`
+		Labels []string `json:"labels"`
+	}
+	if err := json.Unmarshal([]byte(*enumType), &enum); err != nil {
+		return fmt.Errorf("failed to decode enum type of column %s: %w", field.Name, err)
+	}
+	field.Type = enum.Name
+	field.Enum = enum.Labels
+	return nil
+}
+
+// findEnumLabel finds a label qualified by its enum type name, e.g. "order_status.shipped", among
+// the enum columns of the loaded schemas, returning its position in sort order
+func (p *typeProvider) findEnumLabel(name string) (int, bool) {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return 0, false
+	}
+	typeName, label := name[:i], name[i+1:]
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, schema := range p.schemas {
+		if position, found := findEnumLabel(schema, typeName, label); found {
+			return position, true
+		}
+	}
+	return 0, false
+}
+
+func findEnumLabel(schema Schema, typeName, label string) (int, bool) {
+	for _, field := range schema {
+		if len(field.Enum) > 0 && field.Type == typeName {
+			for i, l := range field.Enum {
+				if l == label {
+					return i, true
+				}
+			}
+		}
+		if position, found := findEnumLabel(field.Schema, typeName, label); found {
+			return position, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,386 @@
+package pg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SchemaFromJSONSchema derives a table schema from a JSON Schema document describing its rows,
+// an object whose properties are the columns. Strings are text, with the date-time, date and
+// time formats mapped to timestamptz, date and time, integers are bigint, numbers are double
+// precision, arrays are arrays of their items, and objects are jsonb columns whose keys are
+// described as by JSONColumnFromJSONSchema. Required properties that do not allow null are
+// NOT NULL, and descriptions are recorded as the documentation of the columns.
+//
+// Local references, e.g. "#/$defs/address", are resolved against the document. Recursive
+// references, unions of several types and schemas without a type are untyped JSON.
+func SchemaFromJSONSchema(data []byte) (Schema, error) {
+	p := &jsonSchemaParser{root: data}
+	schema, _, err := p.object(data, "")
+	return schema, err
+}
+
+// JSONColumnFromJSONSchema derives a json or jsonb column from a JSON Schema document
+// describing the objects it holds. The keys of the objects are recorded as the schema of the
+// column, so that CEL expressions select them as typed fields and the converter renders them as
+// JSON path access: strings are text, integers bigint, numbers double precision, nested objects
+// have the type of the column, and arrays are JSON arrays of their items.
+func JSONColumnFromJSONSchema(name, columnType string, data []byte) (FieldSchema, error) {
+	if columnType != "json" && columnType != "jsonb" {
+		return FieldSchema{}, fmt.Errorf("column type %s is not json or jsonb", columnType)
+	}
+	p := &jsonSchemaParser{root: data}
+	schema, doc, err := p.object(data, columnType)
+	if err != nil {
+		return FieldSchema{}, err
+	}
+	return FieldSchema{Name: name, Type: columnType, Schema: schema, Doc: doc}, nil
+}
+
+// errNotObject is returned for JSON Schemas that do not describe objects with properties
+var errNotObject = errors.New("JSON Schema does not describe an object with properties")
+
+// jsonSchemaNode holds the keywords of a JSON Schema used to derive columns. Nullable is the
+// OpenAPI 3.0 keyword.
+type jsonSchemaNode struct {
+	Ref         string            `json:"$ref"`
+	Type        json.RawMessage   `json:"type"`
+	Format      string            `json:"format"`
+	Description string            `json:"description"`
+	Nullable    bool              `json:"nullable"`
+	Properties  json.RawMessage   `json:"properties"`
+	Required    []string          `json:"required"`
+	Items       json.RawMessage   `json:"items"`
+	AllOf       []json.RawMessage `json:"allOf"`
+	AnyOf       []json.RawMessage `json:"anyOf"`
+	OneOf       []json.RawMessage `json:"oneOf"`
+	Column      string            `json:"x-column"`
+}
+
+// jsonSchemaParser derives fields from the schemas of a JSON Schema document, resolving
+// references against its root
+type jsonSchemaParser struct {
+	root      json.RawMessage
+	resolving []string          // references being resolved
+	columns   map[string]string // columns named by x-column, if collected
+}
+
+// parse derives a field from a schema. jsonType is the type of the JSON column the value is
+// stored in, or empty for the columns of a row.
+func (p *jsonSchemaParser) parse(name string, data json.RawMessage, required bool, jsonType string) (FieldSchema, error) {
+	var node jsonSchemaNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return FieldSchema{}, fmt.Errorf("invalid JSON Schema for %q: %w", name, err)
+	}
+	untyped := FieldSchema{Name: name, Type: jsonType, Doc: node.Description}
+	if jsonType == "" {
+		untyped.Type = "jsonb"
+	}
+
+	if node.Ref != "" {
+		for _, ref := range p.resolving {
+			if ref == node.Ref {
+				// Recursive structures cannot be typed
+				return untyped, nil
+			}
+		}
+		resolved, err := p.resolve(node.Ref)
+		if err != nil {
+			return FieldSchema{}, err
+		}
+		p.resolving = append(p.resolving, node.Ref)
+		defer func() { p.resolving = p.resolving[:len(p.resolving)-1] }()
+		field, err := p.parse(name, resolved, required, jsonType)
+		if err != nil {
+			return FieldSchema{}, err
+		}
+		if node.Description != "" {
+			field.Doc = node.Description
+		}
+		return field, nil
+	}
+
+	if len(node.AllOf) == 1 && len(node.Properties) == 0 {
+		// A single member is used to annotate a reference
+		field, err := p.parse(name, node.AllOf[0], required && !node.Nullable, jsonType)
+		if err != nil {
+			return FieldSchema{}, err
+		}
+		if node.Description != "" {
+			field.Doc = node.Description
+		}
+		return field, nil
+	}
+	if len(node.AllOf) > 0 {
+		field := FieldSchema{Name: name, Type: jsonType, NotNull: required && !node.Nullable, Doc: node.Description}
+		if jsonType == "" {
+			field.Type = "jsonb"
+		}
+		var err error
+		if field.Schema, _, err = p.object(data, field.Type); err != nil {
+			return FieldSchema{}, fmt.Errorf("%q: %w", name, err)
+		}
+		return field, nil
+	}
+
+	nullable := node.Nullable
+	if variants := append(node.AnyOf, node.OneOf...); len(variants) > 0 {
+		var typed []json.RawMessage
+		for _, variant := range variants {
+			if isNullSchema(variant) {
+				nullable = true
+			} else {
+				typed = append(typed, variant)
+			}
+		}
+		if len(typed) != 1 {
+			return untyped, nil
+		}
+		field, err := p.parse(name, typed[0], required && !nullable, jsonType)
+		if err != nil {
+			return FieldSchema{}, err
+		}
+		if node.Description != "" {
+			field.Doc = node.Description
+		}
+		return field, nil
+	}
+
+	typeNames, err := jsonSchemaTypes(node.Type)
+	if err != nil {
+		return FieldSchema{}, fmt.Errorf("invalid type of %q: %w", name, err)
+	}
+	var types []string
+	for _, typeName := range typeNames {
+		if typeName == "null" {
+			nullable = true
+		} else {
+			types = append(types, typeName)
+		}
+	}
+	if len(types) == 0 && len(node.Properties) > 0 {
+		types = []string{"object"}
+	}
+	if len(types) != 1 {
+		return untyped, nil
+	}
+
+	field := FieldSchema{Name: name, NotNull: required && !nullable, Doc: node.Description}
+	switch types[0] {
+	case "string":
+		field.Type = "text"
+		if jsonType == "" {
+			switch node.Format {
+			case "date-time":
+				field.Type = "timestamptz"
+			case "date":
+				field.Type = "date"
+			case "time":
+				field.Type = "time"
+			}
+		}
+	case "integer":
+		field.Type = "bigint"
+	case "number":
+		field.Type = "double precision"
+	case "boolean":
+		field.Type = "boolean"
+	case "object":
+		if len(node.Properties) == 0 {
+			return untyped, nil
+		}
+		field.Type = jsonType
+		if jsonType == "" {
+			field.Type, jsonType = "jsonb", "jsonb"
+		}
+		if field.Schema, err = p.properties(node.Properties, node.Required, jsonType); err != nil {
+			return FieldSchema{}, err
+		}
+	case "array":
+		if len(node.Items) == 0 || node.Items[0] != '{' {
+			return untyped, nil
+		}
+		items, err := p.parse(name, node.Items, false, jsonType)
+		if err != nil {
+			return FieldSchema{}, err
+		}
+		field.Type, field.Schema, field.Repeated = items.Type, items.Schema, true
+	default:
+		return FieldSchema{}, fmt.Errorf("unsupported type %s of %q", types[0], name)
+	}
+	return field, nil
+}
+
+// object derives the fields of the properties of the object described by a document, and
+// returns its description
+func (p *jsonSchemaParser) object(data json.RawMessage, jsonType string) (Schema, string, error) {
+	var node jsonSchemaNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, "", fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	if node.Ref != "" {
+		for _, ref := range p.resolving {
+			if ref == node.Ref {
+				return nil, "", fmt.Errorf("recursive reference %s", ref)
+			}
+		}
+		resolved, err := p.resolve(node.Ref)
+		if err != nil {
+			return nil, "", err
+		}
+		p.resolving = append(p.resolving, node.Ref)
+		defer func() { p.resolving = p.resolving[:len(p.resolving)-1] }()
+		schema, doc, err := p.object(resolved, jsonType)
+		if node.Description != "" {
+			doc = node.Description
+		}
+		return schema, doc, err
+	}
+
+	// The members of allOf contribute their properties, which may be redeclared
+	var schema Schema
+	for _, member := range node.AllOf {
+		nested, _, err := p.object(member, jsonType)
+		if err != nil {
+			return nil, "", err
+		}
+		schema = mergeFields(schema, nested)
+	}
+	if len(node.Properties) > 0 {
+		nested, err := p.properties(node.Properties, node.Required, jsonType)
+		if err != nil {
+			return nil, "", err
+		}
+		schema = mergeFields(schema, nested)
+	}
+	if len(schema) == 0 {
+		return nil, "", errNotObject
+	}
+	return schema, node.Description, nil
+}
+
+// mergeFields appends fields to a schema, replacing fields of the same name
+func mergeFields(schema, fields Schema) Schema {
+	for _, field := range fields {
+		replaced := false
+		for i := range schema {
+			if schema[i].Name == field.Name {
+				schema[i], replaced = field, true
+				break
+			}
+		}
+		if !replaced {
+			schema = append(schema, field)
+		}
+	}
+	return schema
+}
+
+// properties derives the fields of the properties of an object schema, in document order
+func (p *jsonSchemaParser) properties(data json.RawMessage, required []string, jsonType string) (Schema, error) {
+	names, values, err := orderedObject(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid properties: %w", err)
+	}
+	schema := make(Schema, 0, len(names))
+	for i, name := range names {
+		isRequired := false
+		for _, r := range required {
+			if r == name {
+				isRequired = true
+				break
+			}
+		}
+		field, err := p.parse(name, values[i], isRequired, jsonType)
+		if err != nil {
+			return nil, err
+		}
+		schema = append(schema, field)
+
+		if p.columns != nil && jsonType == "" {
+			var node jsonSchemaNode
+			if err := json.Unmarshal(values[i], &node); err == nil && node.Column != "" {
+				p.columns[name] = node.Column
+			}
+		}
+	}
+	return schema, nil
+}
+
+// resolve resolves a local reference, a JSON pointer into the root document such as
+// "#/$defs/address"
+func (p *jsonSchemaParser) resolve(ref string) (json.RawMessage, error) {
+	pointer, found := strings.CutPrefix(ref, "#")
+	if !found {
+		return nil, fmt.Errorf("unsupported reference %s: only local references are resolved", ref)
+	}
+	current := p.root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(current, &object); err != nil {
+			return nil, fmt.Errorf("unresolved reference %s", ref)
+		}
+		next, found := object[token]
+		if !found {
+			return nil, fmt.Errorf("unresolved reference %s", ref)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// jsonSchemaTypes reads the type keyword, a type name or an array of type names
+func jsonSchemaTypes(data json.RawMessage) ([]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var typeName string
+	if err := json.Unmarshal(data, &typeName); err == nil {
+		return []string{typeName}, nil
+	}
+	var typeNames []string
+	if err := json.Unmarshal(data, &typeNames); err != nil {
+		return nil, err
+	}
+	return typeNames, nil
+}
+
+// isNullSchema checks if a schema only allows null
+func isNullSchema(data json.RawMessage) bool {
+	var node jsonSchemaNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return false
+	}
+	typeNames, err := jsonSchemaTypes(node.Type)
+	return err == nil && len(typeNames) == 1 && typeNames[0] == "null"
+}
+
+// orderedObject decodes the members of a JSON object in document order
+func orderedObject(data json.RawMessage) ([]string, []json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, nil, errors.New("not an object")
+	}
+	var names []string
+	var values []json.RawMessage
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		names = append(names, token.(string))
+		values = append(values, value)
+	}
+	return names, values, nil
+}
@@ -0,0 +1,58 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// WithLazyLoading makes the provider load the schema of an unknown table the first time the
+// CEL type checker references it, so that environments do not need to preload every table.
+// Each lookup is bounded by timeout. Since the type provider interface cannot report errors,
+// a failed lookup makes the type unknown and is retried on the next reference, while tables
+// that do not exist are only looked up once.
+func WithLazyLoading(timeout time.Duration) ProviderOption {
+	return func(p *typeProvider) {
+		p.lazyTimeout = timeout
+		p.lazyLoaded = make(map[string]bool)
+	}
+}
+
+// loadOnDemand loads the table that a CEL type name may refer to, either its first segment
+// ("users" for users.address) or its first two segments for schema-qualified tables
+// ("analytics.events" for analytics.events.location). It reports whether a table was loaded.
+func (p *typeProvider) loadOnDemand(typeName string) bool {
+	typeNames := strings.Split(typeName, ".")
+	candidates := []string{typeNames[0]}
+	if len(typeNames) > 1 {
+		candidates = append(candidates, typeNames[0]+"."+typeNames[1])
+	}
+
+	loaded := false
+	for _, tableName := range candidates {
+		p.mu.RLock()
+		done := p.lazyLoaded[tableName]
+		p.mu.RUnlock()
+		if done {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.lazyTimeout)
+		schema, exists, err := p.queryTableSchema(ctx, tableName)
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		p.mu.Lock()
+		p.lazyLoaded[tableName] = true
+		if exists {
+			if _, found := p.schemas[tableName]; !found {
+				p.schemas[tableName] = schema
+			}
+			loaded = true
+		}
+		p.mu.Unlock()
+	}
+	return loaded
+}
@@ -0,0 +1,58 @@
+package pg
+
+import "context"
+
+// tableMetadata is what is known of a table besides its columns
+type tableMetadata struct {
+	primaryKey  []string
+	uniqueKeys  [][]string // the primary key first, then unique constraints and indexes
+	foreignKeys []ForeignKey
+	partitions  []string
+}
+
+// isEmpty checks if nothing is known of the table
+func (m tableMetadata) isEmpty() bool {
+	return m.primaryKey == nil && m.uniqueKeys == nil && m.foreignKeys == nil && m.partitions == nil
+}
+
+// setMetadata records the metadata of a table. The caller must hold p.mu.
+func (p *typeProvider) setMetadata(tableName string, metadata tableMetadata) {
+	if metadata.isEmpty() {
+		delete(p.metadata, tableName)
+		return
+	}
+	if p.metadata == nil {
+		p.metadata = make(map[string]tableMetadata)
+	}
+	p.metadata[tableName] = metadata
+}
+
+// queryMetadata queries the metadata of the tables of a database schema, or only of the given
+// table if not empty, by table name
+func (p *typeProvider) queryMetadata(ctx context.Context, schemaName, table string) (map[string]tableMetadata, error) {
+	metadata := make(map[string]tableMetadata)
+	if err := p.queryKeys(ctx, schemaName, table, metadata); err != nil {
+		return nil, err
+	}
+	if err := p.queryPartitions(ctx, schemaName, table, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// queryTableMetadata queries the metadata of a possibly schema-qualified table
+func (p *typeProvider) queryTableMetadata(ctx context.Context, tableName string) (tableMetadata, error) {
+	schemaName, table := SplitTableName(tableName)
+	if schemaName == "" {
+		resolved, err := p.resolveSchema(ctx, table)
+		if err != nil || resolved == "" {
+			return tableMetadata{}, err
+		}
+		schemaName = resolved
+	}
+	metadata, err := p.queryMetadata(ctx, schemaName, table)
+	if err != nil {
+		return tableMetadata{}, err
+	}
+	return metadata[table], nil
+}
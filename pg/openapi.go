@@ -0,0 +1,143 @@
+package pg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPISchemas holds the schemas of tables derived from the component schemas of an OpenAPI
+// document, along with the columns their properties are stored in.
+type OpenAPISchemas struct {
+	Schemas map[string]Schema            // schemas by component name
+	Columns map[string]map[string]string // columns named by x-column, by component and property
+}
+
+// SchemasFromOpenAPI derives the schemas of tables from the component schemas of an OpenAPI 3
+// document in YAML or JSON, so that the specification defining the filter parameters of an API
+// also drives the conversion of filters. Each component is a table named after it, whose
+// columns are the properties of the component as described by SchemaFromJSONSchema: object
+// properties are jsonb columns with their keys described, and references to other components
+// are resolved. The nullable keyword of OpenAPI 3.0 is supported, as well as allOf.
+//
+// Only the given components are derived, or every object component if none are given. A
+// property stored in a column of another name declares it with the x-column extension; pass
+// ColumnName to cel2sql.WithFieldNameMapper to apply it.
+func SchemasFromOpenAPI(spec []byte, components ...string) (OpenAPISchemas, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(spec, &document); err != nil {
+		return OpenAPISchemas{}, fmt.Errorf("failed to decode OpenAPI document: %w", err)
+	}
+	var root bytes.Buffer
+	if err := writeYAMLAsJSON(&root, &document); err != nil {
+		return OpenAPISchemas{}, fmt.Errorf("failed to decode OpenAPI document: %w", err)
+	}
+
+	var openAPI struct {
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(root.Bytes(), &openAPI); err != nil {
+		return OpenAPISchemas{}, fmt.Errorf("failed to decode OpenAPI document: %w", err)
+	}
+
+	explicit := len(components) > 0
+	if !explicit {
+		for name := range openAPI.Components.Schemas {
+			components = append(components, name)
+		}
+		sort.Strings(components)
+	}
+
+	result := OpenAPISchemas{
+		Schemas: make(map[string]Schema, len(components)),
+		Columns: make(map[string]map[string]string),
+	}
+	for _, name := range components {
+		data, found := openAPI.Components.Schemas[name]
+		if !found {
+			return OpenAPISchemas{}, fmt.Errorf("component %s not found", name)
+		}
+		p := &jsonSchemaParser{root: root.Bytes(), columns: make(map[string]string)}
+		schema, _, err := p.object(data, "")
+		if err != nil {
+			if !explicit && errors.Is(err, errNotObject) {
+				// Enums and other scalar components are not tables
+				continue
+			}
+			return OpenAPISchemas{}, fmt.Errorf("component %s: %w", name, err)
+		}
+		result.Schemas[name] = schema
+		if len(p.columns) > 0 {
+			result.Columns[name] = p.columns
+		}
+	}
+	return result, nil
+}
+
+// ColumnName returns the column storing a property of a component, as declared with the
+// x-column extension, or the name of the property. It is a cel2sql.FieldNameMapper.
+func (s OpenAPISchemas) ColumnName(table, field string) string {
+	if column, found := s.Columns[table][field]; found {
+		return column
+	}
+	return field
+}
+
+// writeYAMLAsJSON writes a YAML node as JSON, keeping the order of mapping keys
+func writeYAMLAsJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeYAMLAsJSON(buf, node.Content[0])
+	case yaml.AliasNode:
+		return writeYAMLAsJSON(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeYAMLAsJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeYAMLAsJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		buf.Write(data)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+)
+
+// Partitions returns the partitions of a loaded partitioned table, schema-qualified when their
+// schema differs from the table's, or nil if it is not partitioned. Partitions are not loaded as
+// tables of their own by LoadSchema: filters should query the partitioned table, which lets
+// PostgreSQL prune the partitions that cannot match.
+func (p *typeProvider) Partitions(tableName string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.metadata[tableName].partitions
+}
+
+// partitionsQuery selects the partitions of the partitioned tables of schema $1, or only of
+// table $2 when it is not NULL
+const partitionsQuery = `
+		SELECT
+			parent.relname,
+			cn.nspname,
+			child.relname
+		FROM pg_catalog.pg_inherits i
+		JOIN pg_catalog.pg_class parent ON parent.oid = i.inhparent
+		JOIN pg_catalog.pg_namespace n ON n.oid = parent.relnamespace
+		JOIN pg_catalog.pg_class child ON child.oid = i.inhrelid
+		JOIN pg_catalog.pg_namespace cn ON cn.oid = child.relnamespace
+		WHERE child.relispartition
+		AND n.nspname = $1
+		AND ($2::text IS NULL OR parent.relname = $2::text)
+		ORDER BY parent.relname, child.relname
+	`
+
+// isPartition is a condition on the information_schema.columns row c that holds for partitions
+const isPartition = `EXISTS (SELECT 1 FROM pg_catalog.pg_class pc
+			JOIN pg_catalog.pg_namespace pn ON pn.oid = pc.relnamespace
+			WHERE pn.nspname = c.table_schema
+			AND pc.relname = c.table_name
+			AND pc.relispartition)`
+
+// queryPartitions adds the partitions of the partitioned tables of a database schema, or only of
+// the given table if not empty, to their metadata
+func (p *typeProvider) queryPartitions(ctx context.Context, schemaName, table string, metadata map[string]tableMetadata) error {
+	var tableParam any
+	if table != "" {
+		tableParam = table
+	}
+	rows, err := p.db.Query(ctx, partitionsQuery, schemaName, tableParam)
+	if err != nil {
+		return fmt.Errorf("failed to query partitions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, partitionSchema, partition string
+		if err := rows.Scan(&tableName, &partitionSchema, &partition); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		if partitionSchema != schemaName {
+			partition = partitionSchema + "." + partition
+		}
+		m := metadata[tableName]
+		m.partitions = append(m.partitions, partition)
+		metadata[tableName] = m
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	return nil
+}
@@ -3,9 +3,14 @@ package pg
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/cel-go/checker/decls"
 	"github.com/google/cel-go/common/types"
@@ -22,46 +27,204 @@ type FieldSchema struct {
 	Type     string        // PostgreSQL type name (text, integer, boolean, etc.)
 	Repeated bool          // true for arrays
 	Schema   []FieldSchema // for composite types
+	Enum     []string      // labels of enum types in sort order, Type being the enum type name
+	NotNull  bool          // true for columns declared NOT NULL
+	Doc      string        // column comment, set with COMMENT ON COLUMN
+
+	// Generated and identity columns can be filtered on like any other column, but statements
+	// built alongside converted filters should not assign them: generated columns are computed
+	// from other columns, and identity columns draw their values from a sequence.
+	Generated bool // true for GENERATED ALWAYS AS (...) STORED columns
+	Identity  bool // true for GENERATED ... AS IDENTITY columns
+
+	Precision int // declared precision of numeric columns, zero if unconstrained
+	Scale     int // declared scale of numeric columns
+	Length    int // maximum length of character columns, zero if unconstrained
 }
 
 // Schema represents a PostgreSQL table schema as a slice of field schemas.
 type Schema []FieldSchema
 
-// TypeProvider interface for PostgreSQL type providers
+// TypeProvider interface for PostgreSQL type providers. A TypeProvider is safe for concurrent use:
+// schemas may be loaded, imported or reloaded while conversions look up types, and each lookup
+// sees either the previous or the new schema of a table, never a partially updated one.
+//
+// The providers created by this package also implement the optional interfaces below, which
+// callers check with a type assertion, e.g. provider.(pg.SchemaLoader).
 type TypeProvider interface {
 	types.Provider
 	LoadTableSchema(ctx context.Context, tableName string) error
 	Close()
 }
 
+// TableLister is implemented by type providers that list the names of their tables.
+type TableLister interface {
+	Tables() []string
+}
+
+// SchemaLoader is implemented by type providers that load every table and view of a database
+// schema at once.
+type SchemaLoader interface {
+	LoadSchema(ctx context.Context, schemaName string) error
+}
+
+// SchemaWatcher is implemented by type providers that reload schemas when notified of DDL
+// changes.
+type SchemaWatcher interface {
+	WatchSchemaChanges(ctx context.Context, channel string) error
+}
+
+// SchemaExporter is implemented by type providers that serialize their schemas as JSON
+// snapshots and load them back.
+type SchemaExporter interface {
+	ExportSchemas() ([]byte, error)
+	ImportSchemas(data []byte) error
+}
+
+// ConstraintProvider is implemented by type providers that know the keys of their tables.
+type ConstraintProvider interface {
+	PrimaryKey(tableName string) []string
+	UniqueKeys(tableName string) [][]string
+	ForeignKeys(tableName string) []ForeignKey
+}
+
+// PartitionProvider is implemented by type providers that know the partitions of their tables.
+type PartitionProvider interface {
+	Partitions(tableName string) []string
+}
+
+// FieldDocProvider is implemented by type providers that know the documentation of fields.
+type FieldDocProvider interface {
+	FieldDoc(structType, fieldName string) (string, bool)
+}
+
+var (
+	_ TableLister        = (*typeProvider)(nil)
+	_ SchemaLoader       = (*typeProvider)(nil)
+	_ SchemaWatcher      = (*typeProvider)(nil)
+	_ SchemaExporter     = (*typeProvider)(nil)
+	_ ConstraintProvider = (*typeProvider)(nil)
+	_ PartitionProvider  = (*typeProvider)(nil)
+	_ FieldDocProvider   = (*typeProvider)(nil)
+)
+
 type typeProvider struct {
-	schemas map[string]Schema
-	pool    *pgxpool.Pool
+	mu       sync.RWMutex // guards schemas, metadata and lazyLoaded
+	schemas  map[string]Schema
+	metadata map[string]tableMetadata // by table name as loaded
+
+	db         querier       // runs introspection queries, nil without a connection
+	pool       *pgxpool.Pool // set when db is backed by a pgx pool
+	ownsPool   bool          // the pool was created by the provider and is closed with it
+	searchPath []string
+
+	lazyTimeout time.Duration   // set with WithLazyLoading
+	lazyLoaded  map[string]bool // table names already looked up on demand
 }
 
-// NewTypeProvider creates a new PostgreSQL type provider with pre-defined schemas
+// NewTypeProvider creates a new PostgreSQL type provider with pre-defined schemas. The map is
+// copied, so the caller may keep modifying it without affecting the provider.
 func NewTypeProvider(schemas map[string]Schema) TypeProvider {
-	return &typeProvider{schemas: schemas}
+	p := &typeProvider{schemas: make(map[string]Schema, len(schemas))}
+	for tableName, schema := range schemas {
+		p.schemas[tableName] = schema
+	}
+	return p
 }
 
 // NewTypeProviderWithConnection creates a new PostgreSQL type provider that can introspect database schemas
-func NewTypeProviderWithConnection(ctx context.Context, connectionString string) (TypeProvider, error) {
+func NewTypeProviderWithConnection(ctx context.Context, connectionString string, opts ...ProviderOption) (TypeProvider, error) {
 	pool, err := pgxpool.New(ctx, connectionString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
-	return &typeProvider{
+	p := NewTypeProviderWithPool(pool, opts...).(*typeProvider)
+	p.ownsPool = true
+	return p, nil
+}
+
+// NewTypeProviderWithPool creates a new PostgreSQL type provider that introspects database
+// schemas through an existing connection pool. The pool remains owned by the caller: Close does
+// not close it.
+func NewTypeProviderWithPool(pool *pgxpool.Pool, opts ...ProviderOption) TypeProvider {
+	p := &typeProvider{
 		schemas: make(map[string]Schema),
 		pool:    pool,
-	}, nil
+	}
+	if pool != nil {
+		p.db = poolQuerier{pool}
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// NewTypeProviderWithDB creates a new PostgreSQL type provider that introspects database
+// schemas through database/sql, with any PostgreSQL driver such as lib/pq or pgx's stdlib. The
+// database remains owned by the caller: Close does not close it. WatchSchemaChanges is not
+// supported, as it needs a pgx connection.
+func NewTypeProviderWithDB(db *sql.DB, opts ...ProviderOption) TypeProvider {
+	p := &typeProvider{
+		schemas: make(map[string]Schema),
+	}
+	if db != nil {
+		p.db = dbQuerier{db}
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
-// LoadTableSchema loads schema information for a table from the database
+// Tables returns the names of the known tables, sorted
+func (p *typeProvider) Tables() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Sorted(maps.Keys(p.schemas))
+}
+
+// LoadTableSchema loads schema information for a table from the database. The table name may
+// be schema-qualified, e.g. "analytics.events", in which case the schema is registered under
+// the qualified name and CEL types must use it too. Unqualified names are looked up in the
+// search path, see WithSearchPath.
 func (p *typeProvider) LoadTableSchema(ctx context.Context, tableName string) error {
-	if p.pool == nil {
+	if p.db == nil {
 		return errors.New("no database connection available")
 	}
+	// Unknown tables are registered without columns
+	schema, exists, err := p.queryTableSchema(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	var metadata tableMetadata
+	if exists {
+		if metadata, err = p.queryTableMetadata(ctx, tableName); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.schemas[tableName] = schema
+	p.setMetadata(tableName, metadata)
+	return nil
+}
+
+// queryTableSchema queries the columns of a possibly schema-qualified table, reporting whether
+// the table exists
+func (p *typeProvider) queryTableSchema(ctx context.Context, tableName string) (Schema, bool, error) {
+	schemaName, table := SplitTableName(tableName)
+	if schemaName == "" {
+		resolved, err := p.resolveSchema(ctx, table)
+		if err != nil {
+			return nil, false, err
+		}
+		if resolved == "" {
+			return nil, false, nil
+		}
+		schemaName = resolved
+	}
 
 	query := `
 		SELECT 
@@ -69,84 +232,278 @@ func (p *typeProvider) LoadTableSchema(ctx context.Context, tableName string) er
 			data_type, 
 			is_nullable, 
 			column_default,
-			CASE 
-				WHEN data_type = 'ARRAY' THEN 
-					(SELECT data_type FROM information_schema.element_types 
-					 WHERE object_name = $1 
-					 AND collection_type_identifier = (
-						SELECT dtd_identifier FROM information_schema.columns 
-						WHERE table_name = $1 AND column_name = c.column_name
-					))
-				ELSE data_type
-			END as element_type
+			is_generated = 'ALWAYS' as generated,
+			is_identity = 'YES' as identity,
+			numeric_precision,
+			numeric_scale,
+			character_maximum_length,
+			` + elementTypeColumn + `,
+			` + enumTypeColumn + `,
+			` + compositeTypeColumn + `,
+			` + columnDocColumn + `
 		FROM information_schema.columns c
 		WHERE table_name = $1 
+		AND table_schema = $2
 		ORDER BY ordinal_position
 	`
 
-	rows, err := p.pool.Query(ctx, query, tableName)
+	rows, err := p.db.Query(ctx, query, table, schemaName)
 	if err != nil {
-		return fmt.Errorf("failed to query table schema: %w", err)
+		return nil, false, fmt.Errorf("failed to query table schema: %w", err)
 	}
 	defer rows.Close()
 
 	var schema Schema
+	var composites []*int64
 	for rows.Next() {
 		var columnName, dataType, isNullable string
 		var columnDefault *string
 		var elementType string
-
-		err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault, &elementType)
+		var enumType *string
+		var compositeType *int64
+		var precision, scale, length *int64
+		var doc *string
+		var generated, identity bool
+
+		err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault, &generated, &identity,
+			&precision, &scale, &length, &elementType, &enumType, &compositeType, &doc)
 		if err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return nil, false, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		field := FieldSchema{
-			Name:     columnName,
-			Type:     elementType,         // Use element type for arrays, or data_type for non-arrays
-			Repeated: dataType == "ARRAY", // PostgreSQL returns "ARRAY" for array columns
+			Name:      columnName,
+			Type:      elementType,         // Use element type for arrays, or data_type for non-arrays
+			Repeated:  dataType == "ARRAY", // PostgreSQL returns "ARRAY" for array columns
+			NotNull:   isNullable == "NO",
+			Generated: generated,
+			Identity:  identity,
+		}
+		if doc != nil {
+			field.Doc = *doc
+		}
+		setTypeModifiers(&field, precision, scale, length)
+		if err := setEnumType(&field, enumType); err != nil {
+			return nil, false, err
 		}
 
 		schema = append(schema, field)
+		composites = append(composites, compositeType)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+	if err := p.setCompositeTypes(ctx, schema, composites, 1); err != nil {
+		return nil, false, err
+	}
+	return schema, len(schema) > 0, nil
+}
+
+// columnBaseType is the FROM and WHERE clause of a subquery on the information_schema.columns
+// row c that binds t to the pg_type of the column, or of its elements for array columns.
+// Resolving the element through typelem works for arrays of any type and dimension.
+const columnBaseType = `pg_catalog.pg_type ct
+				JOIN pg_catalog.pg_namespace cn ON cn.oid = ct.typnamespace
+				JOIN pg_catalog.pg_type t ON t.oid = CASE WHEN ct.typcategory = 'A' THEN ct.typelem ELSE ct.oid END
+				WHERE cn.nspname = c.udt_schema
+				AND ct.typname = c.udt_name`
+
+// elementTypeColumn selects the element type of array columns, or the data type otherwise
+const elementTypeColumn = `CASE 
+				WHEN c.data_type = 'ARRAY' THEN 
+					(SELECT format_type(t.oid, NULL) FROM ` + columnBaseType + `)
+				ELSE c.data_type
+			END as element_type`
+
+// columnDocColumn selects the comment of the information_schema.columns row c, whose ordinal
+// position is its attribute number
+const columnDocColumn = `col_description(
+				(quote_ident(c.table_schema) || '.' || quote_ident(c.table_name))::regclass,
+				c.ordinal_position::int) as doc`
+
+// LoadSchema loads every table and view of a database schema in a single query. Tables are
+// registered under their schema-qualified names, e.g. "analytics.events", and also under their
+// unqualified names when the schema is on the search path (see WithSearchPath). Partitions are
+// left out, as they share the columns of their partitioned table, see Partitions.
+func (p *typeProvider) LoadSchema(ctx context.Context, schemaName string) error {
+	if p.db == nil {
+		return errors.New("no database connection available")
+	}
+
+	query := `
+		SELECT 
+			c.table_name,
+			c.column_name, 
+			c.data_type, 
+			c.is_nullable,
+			c.is_generated = 'ALWAYS' as generated,
+			c.is_identity = 'YES' as identity,
+			c.numeric_precision,
+			c.numeric_scale,
+			c.character_maximum_length,
+			` + elementTypeColumn + `,
+			` + enumTypeColumn + `,
+			` + compositeTypeColumn + `,
+			` + columnDocColumn + `,
+			c.table_schema = ANY(` + searchPathArray + `) as on_search_path
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+			ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = $1
+		AND t.table_type IN ('BASE TABLE', 'VIEW')
+		AND NOT ` + isPartition + `
+		ORDER BY c.table_name, c.ordinal_position
+	`
+
+	rows, err := p.db.Query(ctx, query, schemaName, p.searchPathParam())
+	if err != nil {
+		return fmt.Errorf("failed to query schema: %w", err)
 	}
+	defer rows.Close()
 
+	schemas := make(map[string]Schema)
+	composites := make(map[string][]*int64)
+	onSearchPath := false
+	for rows.Next() {
+		var tableName, columnName, dataType, isNullable, elementType string
+		var enumType *string
+		var compositeType *int64
+		var precision, scale, length *int64
+		var doc *string
+		var generated, identity bool
+		if err := rows.Scan(&tableName, &columnName, &dataType, &isNullable, &generated, &identity,
+			&precision, &scale, &length, &elementType, &enumType, &compositeType, &doc, &onSearchPath); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		field := FieldSchema{
+			Name:      columnName,
+			Type:      elementType,
+			Repeated:  dataType == "ARRAY",
+			NotNull:   isNullable == "NO",
+			Generated: generated,
+			Identity:  identity,
+		}
+		if doc != nil {
+			field.Doc = *doc
+		}
+		setTypeModifiers(&field, precision, scale, length)
+		if err := setEnumType(&field, enumType); err != nil {
+			return err
+		}
+		schemas[tableName] = append(schemas[tableName], field)
+		composites[tableName] = append(composites[tableName], compositeType)
+	}
 	if err := rows.Err(); err != nil {
 		return fmt.Errorf("error iterating rows: %w", err)
 	}
+	rows.Close()
+	for tableName, schema := range schemas {
+		if err := p.setCompositeTypes(ctx, schema, composites[tableName], 1); err != nil {
+			return err
+		}
+	}
 
-	p.schemas[tableName] = schema
+	metadata, err := p.queryMetadata(ctx, schemaName, "")
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for tableName, schema := range schemas {
+		p.schemas[schemaName+"."+tableName] = schema
+		p.setMetadata(schemaName+"."+tableName, metadata[tableName])
+		if onSearchPath {
+			p.schemas[tableName] = schema
+			p.setMetadata(tableName, metadata[tableName])
+		}
+	}
 	return nil
 }
 
-// Close closes the database connection pool
+// Close closes the database connection pool, unless it was supplied by the caller
 func (p *typeProvider) Close() {
-	if p.pool != nil {
+	if p.pool != nil && p.ownsPool {
 		p.pool.Close()
 	}
 }
 
+// EnumValue returns the position in sort order of an enum label, e.g. "order_status.shipped",
+// among the enum types of the loaded columns
 func (p *typeProvider) EnumValue(enumName string) ref.Val {
+	if i, found := p.findEnumLabel(enumName); found {
+		return types.Int(i)
+	}
 	return types.NewErr("unknown enum name '%s'", enumName)
 }
 
-func (p *typeProvider) FindIdent(_ string) (ref.Val, bool) {
+// FindIdent returns an enum label, e.g. "order_status.shipped", as a string value
+func (p *typeProvider) FindIdent(identName string) (ref.Val, bool) {
+	if _, found := p.findEnumLabel(identName); found {
+		return types.String(identName[strings.LastIndex(identName, ".")+1:]), true
+	}
 	return nil, false
 }
 
+// SplitTableName splits a possibly schema-qualified table name such as "analytics.events" into
+// its schema and table, the schema being empty for unqualified names.
+func SplitTableName(name string) (schemaName, tableName string) {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// FindTable returns the key in schemas of the table that a CEL type name belongs to, and the
+// path of composite fields below it. Since table names may be schema-qualified, the longest
+// matching prefix of the type name is used: "analytics.events.location" resolves to the
+// "analytics.events" table and the "location" composite field if that table is known.
+func FindTable(schemas map[string]Schema, typeName string) (table string, path []string, found bool) {
+	// Try the longest prefix first, splitting the path only once a table matched
+	for table = typeName; table != ""; {
+		if _, found = schemas[table]; found {
+			if len(table) < len(typeName) {
+				path = strings.Split(typeName[len(table)+1:], ".")
+			}
+			return table, path, true
+		}
+		i := strings.LastIndexByte(table, '.')
+		if i < 0 {
+			break
+		}
+		table = table[:i]
+	}
+	return "", nil, false
+}
+
 func (p *typeProvider) findSchema(typeName string) (Schema, bool) {
-	typeNames := strings.Split(typeName, ".")
-	schema, found := p.schemas[typeNames[0]]
-	if !found {
+	p.mu.RLock()
+	schema, found := p.lookupSchema(typeName)
+	p.mu.RUnlock()
+	if found || p.lazyTimeout == 0 || p.db == nil {
+		return schema, found
+	}
+	if !p.loadOnDemand(typeName) {
 		return nil, false
 	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lookupSchema(typeName)
+}
 
-	// For single-level types, return the schema directly
-	if len(typeNames) == 1 {
-		return schema, true
+// lookupSchema finds the schema of a table or composite type among the loaded schemas. The
+// caller must hold p.mu.
+func (p *typeProvider) lookupSchema(typeName string) (Schema, bool) {
+	table, path, found := FindTable(p.schemas, typeName)
+	if !found {
+		return nil, false
 	}
+	schema := p.schemas[table]
 
 	// For nested types, traverse the schema hierarchy
-	for _, tn := range typeNames[1:] {
+	for _, tn := range path {
 		var s Schema
 		for _, fieldSchema := range schema {
 			if fieldSchema.Name == tn {
@@ -183,6 +540,21 @@ func (p *typeProvider) FindStructFieldNames(structType string) ([]string, bool)
 	return fieldNames, true
 }
 
+// FieldDoc returns the documentation of a field of a table or composite type, from its column
+// comment, reporting whether the field exists
+func (p *typeProvider) FieldDoc(structType, fieldName string) (string, bool) {
+	schema, found := p.findSchema(structType)
+	if !found {
+		return "", false
+	}
+	for _, field := range schema {
+		if field.Name == fieldName {
+			return field.Doc, true
+		}
+	}
+	return "", false
+}
+
 func (p *typeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
 	schema, found := p.findSchema(structType)
 	if !found {
@@ -217,10 +589,22 @@ func (p *typeProvider) FindStructFieldType(structType, fieldName string) (*types
 		exprType = sqltypes.Date
 	case "time", "timetz", "time with time zone", "time without time zone":
 		exprType = sqltypes.Time
+	case "interval":
+		exprType = decls.Duration
 	case "json", "jsonb":
-		// JSON and JSONB types are treated as dynamic objects in CEL
-		exprType = decls.Dyn
+		// JSON and JSONB types are treated as dynamic objects in CEL, unless the keys of their
+		// objects are described
+		if len(field.Schema) > 0 {
+			exprType = decls.NewObjectType(strings.Join([]string{structType, fieldName}, "."))
+		} else {
+			exprType = decls.Dyn
+		}
 	default:
+		if len(field.Enum) > 0 {
+			// Enum labels are strings in CEL
+			exprType = decls.String
+			break
+		}
 		// Handle composite types
 		if strings.Contains(field.Type, "composite") || len(field.Schema) > 0 {
 			exprType = decls.NewObjectType(strings.Join([]string{structType, fieldName}, "."))
@@ -250,3 +634,21 @@ func (p *typeProvider) NewValue(structType string, _ map[string]ref.Val) ref.Val
 }
 
 var _ types.Provider = new(typeProvider)
+
+// setTypeModifiers records the declared precision and scale of numeric columns and the maximum
+// length of character columns
+func setTypeModifiers(field *FieldSchema, precision, scale, length *int64) {
+	switch field.Type {
+	case "numeric", "decimal":
+		if precision != nil {
+			field.Precision = int(*precision)
+		}
+		if scale != nil {
+			field.Scale = int(*scale)
+		}
+	case "character varying", "varchar", "character", "char", "bpchar":
+		if length != nil {
+			field.Length = int(*length)
+		}
+	}
+}
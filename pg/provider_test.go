@@ -2,10 +2,17 @@
 package pg_test
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/cel-go/common/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/spandigital/cel2sql/v2/pg"
 	"github.com/spandigital/cel2sql/v2/test"
@@ -236,3 +243,928 @@ func Test_typeProvider_FindStructFieldType(t *testing.T) {
 		})
 	}
 }
+
+func Test_typeProvider_SchemaQualifiedTables(t *testing.T) {
+	typeProvider := pg.NewTypeProvider(map[string]pg.Schema{
+		"analytics.events": {
+			{Name: "name", Type: "text"},
+			{Name: "location", Type: "composite", Schema: []pg.FieldSchema{{Name: "city", Type: "text"}}},
+		},
+		"events": {
+			{Name: "id", Type: "integer"},
+		},
+	})
+
+	_, found := typeProvider.FindStructType("analytics.events")
+	assert.True(t, found)
+	_, found = typeProvider.FindStructType("analytics")
+	assert.False(t, found)
+
+	fieldNames, found := typeProvider.FindStructFieldNames("analytics.events")
+	assert.True(t, found)
+	assert.Equal(t, []string{"name", "location"}, fieldNames)
+	fieldNames, found = typeProvider.FindStructFieldNames("events")
+	assert.True(t, found)
+	assert.Equal(t, []string{"id"}, fieldNames)
+
+	got, found := typeProvider.FindStructFieldType("analytics.events", "location")
+	assert.True(t, found)
+	assert.Equal(t, types.NewObjectType("analytics.events.location"), got.Type)
+	got, found = typeProvider.FindStructFieldType("analytics.events.location", "city")
+	assert.True(t, found)
+	assert.Equal(t, types.StringType, got.Type)
+}
+
+func TestFindTable(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"analytics.events": {{Name: "name", Type: "text"}},
+		"users":            {{Name: "name", Type: "text"}},
+	}
+	tests := []struct {
+		typeName  string
+		wantTable string
+		wantPath  []string
+		wantFound bool
+	}{
+		{typeName: "users", wantTable: "users", wantFound: true},
+		{typeName: "users.address", wantTable: "users", wantPath: []string{"address"}, wantFound: true},
+		{typeName: "analytics.events", wantTable: "analytics.events", wantFound: true},
+		{typeName: "analytics.events.location", wantTable: "analytics.events", wantPath: []string{"location"}, wantFound: true},
+		{typeName: "analytics", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			table, path, found := pg.FindTable(schemas, tt.typeName)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantTable, table)
+			assert.ElementsMatch(t, tt.wantPath, path)
+		})
+	}
+}
+
+func TestSplitTableName(t *testing.T) {
+	schemaName, table := pg.SplitTableName("analytics.events")
+	assert.Equal(t, "analytics", schemaName)
+	assert.Equal(t, "events", table)
+
+	schemaName, table = pg.SplitTableName("events")
+	assert.Empty(t, schemaName)
+	assert.Equal(t, "events", table)
+}
+
+func TestSchemaChangeTriggerSQL(t *testing.T) {
+	sql := pg.SchemaChangeTriggerSQL("schema_changes")
+	assert.Contains(t, sql, "PERFORM pg_notify('schema_changes', r.object_identity);")
+	assert.Contains(t, sql, "ON ddl_command_end")
+	assert.Contains(t, sql, "ON sql_drop")
+
+	assert.Contains(t, pg.SchemaChangeTriggerSQL("it's"), "pg_notify('it''s',")
+}
+
+func TestNewTypeProviderWithDB_WithoutConnection(t *testing.T) {
+	provider := pg.NewTypeProviderWithDB(nil)
+	err := provider.LoadTableSchema(context.Background(), "users")
+	assert.EqualError(t, err, "no database connection available")
+}
+
+func TestNewTypeProviderFromDDL(t *testing.T) {
+	typeProvider, err := pg.NewTypeProviderFromDDL(`
+		-- Address of a customer
+		CREATE TYPE address AS (street text, city varchar(100), geo point);
+		CREATE TYPE status AS ENUM ('active', 'inactive');
+		CREATE TABLE IF NOT EXISTS "Customers" (
+			id bigserial PRIMARY KEY,
+			name varchar(255) NOT NULL DEFAULT 'unknown, yet',
+			score NUMERIC(10, 2) CHECK (score >= 0),
+			tags text[],
+			matrix integer[][] ,
+			aliases varchar ARRAY,
+			settings jsonb DEFAULT '{}'::jsonb,
+			home address,
+			created_at timestamp with time zone DEFAULT now(),
+			retry_after interval,
+			CONSTRAINT name_unique UNIQUE (name)
+		);
+		CREATE TABLE analytics.events (id int, payload json);
+		CREATE FUNCTION touch() RETURNS trigger AS $$ BEGIN RETURN NEW; END; $$ LANGUAGE plpgsql;
+		CREATE INDEX customers_name ON "Customers" (name);
+	`)
+	require.NoError(t, err)
+
+	fieldNames, found := typeProvider.FindStructFieldNames("Customers")
+	require.True(t, found)
+	assert.Equal(t, []string{"id", "name", "score", "tags", "matrix", "aliases", "settings", "home", "created_at", "retry_after"}, fieldNames)
+
+	tests := []struct {
+		structType string
+		fieldName  string
+		wantType   *types.Type
+	}{
+		{structType: "Customers", fieldName: "id", wantType: types.IntType},
+		{structType: "Customers", fieldName: "name", wantType: types.StringType},
+		{structType: "Customers", fieldName: "score", wantType: types.DoubleType},
+		{structType: "Customers", fieldName: "tags", wantType: types.NewListType(types.StringType)},
+		{structType: "Customers", fieldName: "matrix", wantType: types.NewListType(types.IntType)},
+		{structType: "Customers", fieldName: "aliases", wantType: types.NewListType(types.StringType)},
+		{structType: "Customers", fieldName: "home", wantType: types.NewObjectType("Customers.home")},
+		{structType: "Customers.home", fieldName: "city", wantType: types.StringType},
+		{structType: "Customers", fieldName: "created_at", wantType: types.TimestampType},
+		{structType: "Customers", fieldName: "retry_after", wantType: types.DurationType},
+		{structType: "analytics.events", fieldName: "id", wantType: types.IntType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.structType+"."+tt.fieldName, func(t *testing.T) {
+			got, found := typeProvider.FindStructFieldType(tt.structType, tt.fieldName)
+			require.True(t, found)
+			assert.Equal(t, tt.wantType, got.Type)
+		})
+	}
+
+	_, found = typeProvider.FindStructType("customers_name")
+	assert.False(t, found)
+}
+
+func TestNewTypeProviderFromDDL_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ddl     string
+		wantErr string
+	}{
+		{name: "unterminated column list", ddl: "CREATE TABLE users (id int", wantErr: "unterminated column list of users"},
+		{name: "unterminated string", ddl: "CREATE TABLE users (name text DEFAULT 'x)", wantErr: "unterminated string at offset 38"},
+		{name: "recursive type", ddl: "CREATE TYPE node AS (next node); CREATE TABLE t (n node);", wantErr: "table t: composite type node is recursive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pg.NewTypeProviderFromDDL(tt.ddl)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewTypeProviderFromConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name: "yaml",
+			config: `
+tables:
+  users:
+    - name: id
+      type: integer
+    - name: tags
+      type: text[]
+    - name: scores
+      type: double precision
+      repeated: true
+    - name: settings
+      type: jsonb
+      fields:
+        - name: theme
+          type: text
+    - name: address
+      type: composite
+      fields:
+        - name: city
+          type: text
+`,
+		},
+		{
+			name: "json",
+			config: `{"tables": {"users": [
+				{"name": "id", "type": "integer"},
+				{"name": "tags", "type": "text[]"},
+				{"name": "scores", "type": "double precision", "repeated": true},
+				{"name": "settings", "type": "jsonb", "fields": [{"name": "theme", "type": "text"}]},
+				{"name": "address", "fields": [{"name": "city", "type": "text"}]}
+			]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typeProvider, err := pg.NewTypeProviderFromConfig(strings.NewReader(tt.config))
+			require.NoError(t, err)
+
+			fieldNames, found := typeProvider.FindStructFieldNames("users")
+			require.True(t, found)
+			assert.Equal(t, []string{"id", "tags", "scores", "settings", "address"}, fieldNames)
+
+			wantTypes := map[string]*types.Type{
+				"id":       types.IntType,
+				"tags":     types.NewListType(types.StringType),
+				"scores":   types.NewListType(types.DoubleType),
+				"settings": types.NewObjectType("users.settings"),
+				"address":  types.NewObjectType("users.address"),
+			}
+			for fieldName, wantType := range wantTypes {
+				got, found := typeProvider.FindStructFieldType("users", fieldName)
+				require.True(t, found, fieldName)
+				assert.Equal(t, wantType, got.Type, fieldName)
+			}
+			got, found := typeProvider.FindStructFieldType("users.address", "city")
+			require.True(t, found)
+			assert.Equal(t, types.StringType, got.Type)
+		})
+	}
+}
+
+func TestNewTypeProviderFromConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{name: "unknown key", config: "tables:\n  users:\n    - name: id\n      typ: integer\n", wantErr: "failed to decode schema configuration"},
+		{name: "missing name", config: "tables:\n  users:\n    - type: integer\n", wantErr: "table users: column without a name"},
+		{name: "missing type", config: "tables:\n  users:\n    - name: id\n", wantErr: "table users: column id has no type"},
+		{name: "duplicate column", config: "tables:\n  users:\n    - {name: id, type: int}\n    - {name: id, type: text}\n", wantErr: "table users: duplicate column id"},
+		{name: "nested error", config: "tables:\n  users:\n    - name: address\n      fields: [{name: city}]\n", wantErr: "table users: column address: column city has no type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pg.NewTypeProviderFromConfig(strings.NewReader(tt.config))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSchemasFromConfig(t *testing.T) {
+	schemas, err := pg.SchemasFromConfig(strings.NewReader(`{"tables": {"users": [
+		{"name": "id", "type": "integer", "notNull": true},
+		{"name": "tags", "type": "text[]"},
+		{"name": "address", "fields": [{"name": "city", "type": "text"}]}
+	]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]pg.Schema{
+		"users": {
+			{Name: "id", Type: "integer", NotNull: true},
+			{Name: "tags", Type: "text", Repeated: true},
+			{Name: "address", Type: "composite", Schema: []pg.FieldSchema{{Name: "city", Type: "text"}}},
+		},
+	}, schemas)
+
+	_, err = pg.SchemasFromConfig(strings.NewReader("tables:\n  users:\n    - name: id\n"))
+	require.EqualError(t, err, "table users: column id has no type")
+}
+
+func Test_typeProvider_ExportImportSchemas(t *testing.T) {
+	source := pg.NewTypeProvider(map[string]pg.Schema{
+		"analytics.events": {
+			{Name: "id", Type: "bigint"},
+			{Name: "tags", Type: "text", Repeated: true, Doc: "Labels set by the sender"},
+			{Name: "location", Type: "composite", Schema: []pg.FieldSchema{{Name: "city", Type: "text"}}},
+		},
+		"missing": nil,
+	})
+	data, err := source.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "missing")
+
+	target := pg.NewTypeProvider(nil)
+	require.NoError(t, target.(pg.SchemaExporter).ImportSchemas(data))
+
+	fieldNames, found := target.FindStructFieldNames("analytics.events")
+	require.True(t, found)
+	assert.Equal(t, []string{"id", "tags", "location"}, fieldNames)
+	got, found := target.FindStructFieldType("analytics.events", "tags")
+	require.True(t, found)
+	assert.Equal(t, types.NewListType(types.StringType), got.Type)
+	got, found = target.FindStructFieldType("analytics.events.location", "city")
+	require.True(t, found)
+	assert.Equal(t, types.StringType, got.Type)
+
+	fromConfig, err := pg.NewTypeProviderFromConfig(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	_, found = fromConfig.FindStructType("analytics.events")
+	assert.True(t, found)
+
+	doc, found := target.(pg.FieldDocProvider).FieldDoc("analytics.events", "tags")
+	require.True(t, found)
+	assert.Equal(t, "Labels set by the sender", doc)
+
+	exported, err := target.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(exported))
+
+	err = target.(pg.SchemaExporter).ImportSchemas([]byte(`{"tables": {"users": [{"name": "id", "typ": "int"}]}}`))
+	assert.ErrorContains(t, err, "failed to decode schemas")
+}
+
+func Test_typeProvider_ConcurrentAccess(t *testing.T) {
+	schemas := map[string]pg.Schema{"users": {{Name: "id", Type: "integer"}}}
+	typeProvider := pg.NewTypeProvider(schemas)
+	schemas["users"] = nil // the provider keeps its own copy
+
+	data := []byte(`{"tables": {"users": [{"name": "id", "type": "integer"}, {"name": "name", "type": "text"}]}}`)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.NoError(t, typeProvider.(pg.SchemaExporter).ImportSchemas(data))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				got, found := typeProvider.FindStructFieldType("users", "id")
+				if assert.True(t, found) {
+					assert.Equal(t, types.IntType, got.Type)
+				}
+				_, err := typeProvider.(pg.SchemaExporter).ExportSchemas()
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_typeProvider_Enums(t *testing.T) {
+	typeProvider, err := pg.NewTypeProviderFromDDL(`
+		CREATE TYPE order_status AS ENUM ('pending', 'shipped', 'it''s late');
+		CREATE TABLE orders (id serial, status order_status NOT NULL DEFAULT 'pending', history order_status[]);
+	`)
+	require.NoError(t, err)
+
+	got, found := typeProvider.FindStructFieldType("orders", "status")
+	require.True(t, found)
+	assert.Equal(t, types.StringType, got.Type)
+	got, found = typeProvider.FindStructFieldType("orders", "history")
+	require.True(t, found)
+	assert.Equal(t, types.NewListType(types.StringType), got.Type)
+
+	assert.Equal(t, types.Int(1), typeProvider.EnumValue("order_status.shipped"))
+	assert.Equal(t, types.ErrType, typeProvider.EnumValue("order_status.lost").Type())
+	assert.Equal(t, types.ErrType, typeProvider.EnumValue("shipped").Type())
+
+	label, found := typeProvider.FindIdent("order_status.pending")
+	assert.True(t, found)
+	assert.Equal(t, types.String("pending"), label)
+	_, found = typeProvider.FindIdent("order_status.lost")
+	assert.False(t, found)
+
+	data, err := typeProvider.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"enum": [`)
+	imported := pg.NewTypeProvider(nil)
+	require.NoError(t, imported.(pg.SchemaExporter).ImportSchemas(data))
+	assert.Equal(t, types.Int(2), imported.EnumValue("order_status.it's late"))
+}
+
+func TestNewTypeProviderFromDDL_NotNull(t *testing.T) {
+	typeProvider, err := pg.NewTypeProviderFromDDL(`
+		CREATE TABLE memberships (
+			user_id integer,
+			group_id integer CONSTRAINT group_fk REFERENCES groups (id),
+			role text NOT NULL CHECK (role <> ''),
+			note text NULL,
+			id serial PRIMARY KEY,
+			CONSTRAINT memberships_pk PRIMARY KEY (user_id, group_id)
+		);
+	`)
+	require.NoError(t, err)
+
+	data, err := typeProvider.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"tables": {"memberships": [
+		{"name": "user_id", "type": "integer", "notNull": true},
+		{"name": "group_id", "type": "integer", "notNull": true},
+		{"name": "role", "type": "text", "notNull": true},
+		{"name": "note", "type": "text"},
+		{"name": "id", "type": "integer", "notNull": true}
+	]}}`, string(data))
+}
+
+func TestNewTypeProviderFromDDL_TypeModifiers(t *testing.T) {
+	typeProvider, err := pg.NewTypeProviderFromDDL(`
+		CREATE TABLE products (
+			price numeric(10, 2),
+			stock decimal(5),
+			weight numeric,
+			sku varchar(8),
+			code character(3),
+			ratio float(24)
+		);
+	`)
+	require.NoError(t, err)
+
+	data, err := typeProvider.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"tables": {"products": [
+		{"name": "price", "type": "numeric", "precision": 10, "scale": 2},
+		{"name": "stock", "type": "decimal", "precision": 5},
+		{"name": "weight", "type": "numeric"},
+		{"name": "sku", "type": "varchar", "length": 8},
+		{"name": "code", "type": "character", "length": 3},
+		{"name": "ratio", "type": "double precision"}
+	]}}`, string(data))
+
+	imported := pg.NewTypeProvider(nil)
+	require.NoError(t, imported.(pg.SchemaExporter).ImportSchemas(data))
+	exported, err := imported.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(exported))
+}
+
+func TestNewTypeProviderFromDDL_ForeignKeys(t *testing.T) {
+	typeProvider, err := pg.NewTypeProviderFromDDL(`
+		CREATE TABLE users (id serial PRIMARY KEY, org_id integer REFERENCES orgs);
+		CREATE TABLE memberships (
+			user_id integer CONSTRAINT member_user REFERENCES users (id) ON DELETE CASCADE,
+			group_id integer,
+			group_kind text,
+			CONSTRAINT member_group FOREIGN KEY (group_id, group_kind) REFERENCES acl.groups (id, kind)
+		);
+		ALTER TABLE ONLY public.audit ADD CONSTRAINT audit_user FOREIGN KEY (actor_id) REFERENCES public.users(id);
+		ALTER TABLE users ADD COLUMN nickname text;
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		table string
+		want  []pg.ForeignKey
+	}{
+		{table: "users", want: []pg.ForeignKey{{Columns: []string{"org_id"}, RefTable: "orgs"}}},
+		{table: "memberships", want: []pg.ForeignKey{
+			{Name: "member_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+			{Name: "member_group", Columns: []string{"group_id", "group_kind"}, RefTable: "acl.groups", RefColumns: []string{"id", "kind"}},
+		}},
+		{table: "public.audit", want: []pg.ForeignKey{
+			{Name: "audit_user", Columns: []string{"actor_id"}, RefTable: "public.users", RefColumns: []string{"id"}},
+		}},
+		{table: "orgs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			assert.Equal(t, tt.want, typeProvider.(pg.ConstraintProvider).ForeignKeys(tt.table))
+		})
+	}
+}
+
+func TestNewTypeProviderFromDDL_UniqueKeys(t *testing.T) {
+	typeProvider, err := pg.NewTypeProviderFromDDL(`
+		CREATE TABLE users (id serial PRIMARY KEY, email text UNIQUE, org_id integer, handle text);
+		CREATE UNIQUE INDEX users_handle ON users USING btree (org_id, handle DESC) INCLUDE (email);
+		CREATE UNIQUE INDEX users_lower_email ON users (lower(email));
+		CREATE UNIQUE INDEX users_active_handle ON users (handle) WHERE active;
+		CREATE TABLE memberships (
+			user_id integer,
+			group_id integer,
+			CONSTRAINT membership_once UNIQUE NULLS NOT DISTINCT (user_id, group_id)
+		);
+		ALTER TABLE ONLY memberships ADD CONSTRAINT memberships_pkey PRIMARY KEY (user_id, group_id);
+		CREATE TABLE events (payload jsonb);
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		table          string
+		wantPrimaryKey []string
+		wantUniqueKeys [][]string
+	}{
+		{
+			table:          "users",
+			wantPrimaryKey: []string{"id"},
+			wantUniqueKeys: [][]string{{"id"}, {"email"}, {"org_id", "handle"}},
+		},
+		{
+			table:          "memberships",
+			wantPrimaryKey: []string{"user_id", "group_id"},
+			wantUniqueKeys: [][]string{{"user_id", "group_id"}, {"user_id", "group_id"}},
+		},
+		{table: "events"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			assert.Equal(t, tt.wantPrimaryKey, typeProvider.(pg.ConstraintProvider).PrimaryKey(tt.table))
+			assert.Equal(t, tt.wantUniqueKeys, typeProvider.(pg.ConstraintProvider).UniqueKeys(tt.table))
+		})
+	}
+}
+
+func Test_typeProvider_FieldDoc(t *testing.T) {
+	fromDDL, err := pg.NewTypeProviderFromDDL(`
+		CREATE TYPE address AS (city text, zip text);
+		CREATE TABLE users (id integer, email text, home address, nickname text);
+		COMMENT ON COLUMN users.email IS 'Primary contact address, verified on sign-up';
+		COMMENT ON COLUMN users.nickname IS 'Display name';
+		COMMENT ON COLUMN users.nickname IS NULL;
+		COMMENT ON COLUMN address.zip IS 'Postal code';
+		COMMENT ON TABLE users IS 'Registered users';
+	`)
+	require.NoError(t, err)
+	fromConfig, err := pg.NewTypeProviderFromConfig(strings.NewReader(`
+tables:
+  users:
+    - name: id
+      type: integer
+    - name: email
+      type: text
+      doc: Primary contact address, verified on sign-up
+    - name: home
+      fields:
+        - name: city
+          type: text
+        - name: zip
+          type: text
+          doc: Postal code
+    - name: nickname
+      type: text
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		structType string
+		fieldName  string
+		wantDoc    string
+		wantFound  bool
+	}{
+		{structType: "users", fieldName: "email", wantDoc: "Primary contact address, verified on sign-up", wantFound: true},
+		{structType: "users", fieldName: "id", wantFound: true},
+		{structType: "users", fieldName: "nickname", wantFound: true},
+		{structType: "users.home", fieldName: "zip", wantDoc: "Postal code", wantFound: true},
+		{structType: "users", fieldName: "missing"},
+		{structType: "missing", fieldName: "email"},
+	}
+	for name, typeProvider := range map[string]pg.TypeProvider{"ddl": fromDDL, "config": fromConfig} {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.structType+"."+tt.fieldName, func(t *testing.T) {
+				doc, found := typeProvider.(pg.FieldDocProvider).FieldDoc(tt.structType, tt.fieldName)
+				assert.Equal(t, tt.wantFound, found)
+				assert.Equal(t, tt.wantDoc, doc)
+			})
+		}
+	}
+}
+
+func TestNewTypeProviderFromDDL_GeneratedColumns(t *testing.T) {
+	typeProvider, err := pg.NewTypeProviderFromDDL(`
+		CREATE TABLE orders (
+			id bigint GENERATED ALWAYS AS IDENTITY (START WITH 1000),
+			line integer GENERATED BY DEFAULT AS IDENTITY,
+			price numeric(10, 2) NOT NULL,
+			quantity integer,
+			total numeric GENERATED ALWAYS AS (price * quantity) STORED,
+			note text DEFAULT 'none'
+		);
+	`)
+	require.NoError(t, err)
+
+	data, err := typeProvider.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"tables": {"orders": [
+		{"name": "id", "type": "bigint", "notNull": true, "identity": true},
+		{"name": "line", "type": "integer", "notNull": true, "identity": true},
+		{"name": "price", "type": "numeric", "notNull": true, "precision": 10, "scale": 2},
+		{"name": "quantity", "type": "integer"},
+		{"name": "total", "type": "numeric", "generated": true},
+		{"name": "note", "type": "text"}
+	]}}`, string(data))
+}
+
+func TestNewTypeProviderFromDDL_Partitions(t *testing.T) {
+	typeProvider, err := pg.NewTypeProviderFromDDL(`
+		CREATE TABLE measurements (id bigint, taken_at timestamptz NOT NULL, value double precision)
+			PARTITION BY RANGE (taken_at);
+		CREATE TABLE measurements_2025 PARTITION OF measurements
+			FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
+		CREATE TABLE archive.measurements_2024 (LIKE measurements);
+		ALTER TABLE ONLY measurements ATTACH PARTITION archive.measurements_2024
+			FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+	`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"measurements_2025", "archive.measurements_2024"}, typeProvider.(pg.PartitionProvider).Partitions("measurements"))
+	assert.Nil(t, typeProvider.(pg.PartitionProvider).Partitions("measurements_2025"))
+
+	_, found := typeProvider.FindStructType("measurements")
+	assert.True(t, found)
+	_, found = typeProvider.FindStructType("measurements_2025")
+	assert.False(t, found)
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+		Zip  *string
+	}
+	type Audit struct {
+		CreatedAt time.Time  `db:"created_at"`
+		DeletedAt *time.Time `db:"deleted_at"`
+	}
+	type User struct {
+		Audit
+		ID       int64             `db:"id" json:"userId"`
+		Email    string            `json:"email,omitempty"`
+		Nickname sql.NullString    `db:"nickname"`
+		Score    float64           `db:"score"`
+		Active   bool              `db:"active"`
+		Tags     []string          `db:"tags"`
+		Grid     [][]int32         `db:"grid"`
+		Home     Address           `db:"home"`
+		Avatar   []byte            `db:"avatar"`
+		Prefs    map[string]string `db:"prefs"`
+		Payload  json.RawMessage   `db:"payload"`
+		Password string            `db:"-"`
+		internal string
+	}
+
+	schema, err := pg.SchemaFromStruct(&User{})
+	require.NoError(t, err)
+	assert.Equal(t, pg.Schema{
+		{Name: "created_at", Type: "timestamptz", NotNull: true},
+		{Name: "deleted_at", Type: "timestamptz"},
+		{Name: "id", Type: "bigint", NotNull: true},
+		{Name: "email", Type: "text", NotNull: true},
+		{Name: "nickname", Type: "text"},
+		{Name: "score", Type: "double precision", NotNull: true},
+		{Name: "active", Type: "boolean", NotNull: true},
+		{Name: "tags", Type: "text", Repeated: true},
+		{Name: "grid", Type: "integer", Repeated: true},
+		{Name: "home", Type: "composite", NotNull: true, Schema: pg.Schema{
+			{Name: "city", Type: "text", NotNull: true},
+			{Name: "zip", Type: "text"},
+		}},
+		{Name: "avatar", Type: "bytea"},
+		{Name: "prefs", Type: "jsonb"},
+		{Name: "payload", Type: "jsonb"},
+	}, schema)
+
+	type Node struct {
+		Children []Node `db:"children"`
+	}
+	tests := []struct {
+		name    string
+		model   any
+		wantErr string
+	}{
+		{name: "not a struct", model: 42, wantErr: "not a struct"},
+		{name: "nil", model: nil, wantErr: "not a struct"},
+		{name: "recursive", model: Node{}, wantErr: "recursive"},
+		{name: "unsupported", model: struct{ Fn func() }{}, wantErr: "unsupported type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pg.SchemaFromStruct(tt.model)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewTypeProviderFromStructs(t *testing.T) {
+	type Order struct {
+		ID       int64     `db:"id"`
+		PlacedAt time.Time `db:"placed_at"`
+		Items    []string  `db:"items"`
+	}
+	typeProvider, err := pg.NewTypeProviderFromStructs(map[string]any{"orders": Order{}})
+	require.NoError(t, err)
+
+	fieldType, found := typeProvider.FindStructFieldType("orders", "placed_at")
+	require.True(t, found)
+	assert.Equal(t, types.TimestampType, fieldType.Type)
+	fieldType, found = typeProvider.FindStructFieldType("orders", "items")
+	require.True(t, found)
+	assert.Equal(t, types.NewListType(types.StringType), fieldType.Type)
+
+	_, err = pg.NewTypeProviderFromStructs(map[string]any{"orders": "not a struct"})
+	assert.ErrorContains(t, err, "table orders")
+}
+
+func TestSchemaFromJSONSchema(t *testing.T) {
+	schema, err := pg.SchemaFromJSONSchema([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["id", "created_at", "nickname"],
+		"properties": {
+			"id": {"type": "integer"},
+			"created_at": {"type": "string", "format": "date-time"},
+			"birthday": {"type": "string", "format": "date"},
+			"nickname": {"type": ["string", "null"], "description": "Display name"},
+			"score": {"type": "number"},
+			"active": {"type": "boolean"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"address": {"$ref": "#/$defs/address"},
+			"manager": {"anyOf": [{"$ref": "#/$defs/user"}, {"type": "null"}]},
+			"extra": {}
+		},
+		"$defs": {
+			"address": {
+				"type": "object",
+				"required": ["city"],
+				"properties": {"city": {"type": "string"}, "since": {"type": "string", "format": "date-time"}}
+			},
+			"user": {"type": "object", "properties": {"id": {"type": "integer"}, "manager": {"$ref": "#/$defs/user"}}}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, pg.Schema{
+		{Name: "id", Type: "bigint", NotNull: true},
+		{Name: "created_at", Type: "timestamptz", NotNull: true},
+		{Name: "birthday", Type: "date"},
+		{Name: "nickname", Type: "text", Doc: "Display name"},
+		{Name: "score", Type: "double precision"},
+		{Name: "active", Type: "boolean"},
+		{Name: "tags", Type: "text", Repeated: true},
+		{Name: "address", Type: "jsonb", Schema: pg.Schema{
+			{Name: "city", Type: "text", NotNull: true},
+			{Name: "since", Type: "text"},
+		}},
+		{Name: "manager", Type: "jsonb", Schema: pg.Schema{
+			{Name: "id", Type: "bigint"},
+			{Name: "manager", Type: "jsonb"},
+		}},
+		{Name: "extra", Type: "jsonb"},
+	}, schema)
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "not an object", data: `{"type": "string"}`, wantErr: "does not describe an object"},
+		{name: "invalid", data: `{"properties": `, wantErr: "invalid JSON Schema"},
+		{name: "unresolved reference", data: `{"properties": {"a": {"$ref": "#/$defs/missing"}}}`, wantErr: "unresolved reference #/$defs/missing"},
+		{name: "remote reference", data: `{"properties": {"a": {"$ref": "other.json#/a"}}}`, wantErr: "only local references"},
+		{name: "unsupported type", data: `{"properties": {"a": {"type": "decimal"}}}`, wantErr: `unsupported type decimal of "a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pg.SchemaFromJSONSchema([]byte(tt.data))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestJSONColumnFromJSONSchema(t *testing.T) {
+	column, err := pg.JSONColumnFromJSONSchema("payload", "json", []byte(`{
+		"description": "Event payload",
+		"properties": {
+			"at": {"type": "string", "format": "date-time"},
+			"items": {"type": "array", "items": {"type": "object", "properties": {"sku": {"type": "string"}}}}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, pg.FieldSchema{Name: "payload", Type: "json", Doc: "Event payload", Schema: pg.Schema{
+		{Name: "at", Type: "text"},
+		{Name: "items", Type: "json", Repeated: true, Schema: pg.Schema{{Name: "sku", Type: "text"}}},
+	}}, column)
+
+	typeProvider := pg.NewTypeProvider(map[string]pg.Schema{"events": {column}})
+	fieldType, found := typeProvider.FindStructFieldType("events", "payload")
+	require.True(t, found)
+	assert.Equal(t, types.NewObjectType("events.payload"), fieldType.Type)
+	fieldType, found = typeProvider.FindStructFieldType("events.payload", "items")
+	require.True(t, found)
+	assert.Equal(t, types.NewListType(types.NewObjectType("events.payload.items")), fieldType.Type)
+
+	_, err = pg.JSONColumnFromJSONSchema("payload", "text", []byte(`{"properties": {}}`))
+	assert.ErrorContains(t, err, "not json or jsonb")
+}
+
+func TestSchemasFromOpenAPI(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info: {title: Shop, version: "1.0"}
+paths: {}
+components:
+  schemas:
+    Status:
+      type: string
+      enum: [pending, shipped]
+    Audited:
+      type: object
+      required: [created_at]
+      properties:
+        created_at: {type: string, format: date-time}
+    Order:
+      description: A customer order
+      allOf:
+        - $ref: '#/components/schemas/Audited'
+        - type: object
+          required: [id]
+          properties:
+            id: {type: integer, format: int64}
+            customerId: {type: integer, x-column: customer_id}
+            status: {$ref: '#/components/schemas/Status'}
+            note: {type: string, nullable: true}
+            shipping:
+              type: object
+              properties:
+                city: {type: string}
+                express: {type: boolean}
+`)
+	result, err := pg.SchemasFromOpenAPI(spec)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]pg.Schema{
+		"Audited": {{Name: "created_at", Type: "timestamptz", NotNull: true}},
+		"Order": {
+			{Name: "created_at", Type: "timestamptz", NotNull: true},
+			{Name: "id", Type: "bigint", NotNull: true},
+			{Name: "customerId", Type: "bigint"},
+			{Name: "status", Type: "text"},
+			{Name: "note", Type: "text"},
+			{Name: "shipping", Type: "jsonb", Schema: pg.Schema{
+				{Name: "city", Type: "text"},
+				{Name: "express", Type: "boolean"},
+			}},
+		},
+	}, result.Schemas)
+	assert.Equal(t, "customer_id", result.ColumnName("Order", "customerId"))
+	assert.Equal(t, "status", result.ColumnName("Order", "status"))
+	assert.Equal(t, "id", result.ColumnName("Audited", "id"))
+
+	result, err = pg.SchemasFromOpenAPI([]byte(`{"components": {"schemas": {"Order": {"properties": {"id": {"type": "integer"}}}}}}`), "Order")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]pg.Schema{"Order": {{Name: "id", Type: "bigint"}}}, result.Schemas)
+
+	tests := []struct {
+		name       string
+		spec       string
+		components []string
+		wantErr    string
+	}{
+		{name: "invalid", spec: "components: [", wantErr: "failed to decode OpenAPI document"},
+		{name: "missing component", spec: "components: {schemas: {}}", components: []string{"Order"}, wantErr: "component Order not found"},
+		{name: "scalar component", spec: "components: {schemas: {Status: {type: string}}}", components: []string{"Status"}, wantErr: "component Status: JSON Schema does not describe an object"},
+		{name: "recursive allOf", spec: "components: {schemas: {A: {allOf: [{$ref: '#/components/schemas/A'}]}}}", wantErr: "component A: recursive reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pg.SchemasFromOpenAPI([]byte(tt.spec), tt.components...)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSchemaFromAvro(t *testing.T) {
+	schema, err := pg.SchemaFromAvro([]byte(`{
+		"type": "record",
+		"name": "Order",
+		"namespace": "com.acme.shop",
+		"fields": [
+			{"name": "id", "type": "long"},
+			{"name": "placed_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+			{"name": "ship_by", "type": ["null", {"type": "int", "logicalType": "date"}], "doc": "Latest shipping date"},
+			{"name": "total", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["PENDING", "SHIPPED"]}},
+			{"name": "previous_status", "type": ["null", "Status"]},
+			{"name": "tags", "type": {"type": "array", "items": "string"}},
+			{"name": "attributes", "type": {"type": "map", "values": "string"}},
+			{"name": "address", "type": ["null", {
+				"type": "record",
+				"name": "Address",
+				"fields": [
+					{"name": "city", "type": "string"},
+					{"name": "updated_at", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+				]
+			}]},
+			{"name": "billing", "type": "com.acme.shop.Address"},
+			{"name": "parent", "type": ["null", "Order"]},
+			{"name": "score", "type": ["int", "double"]}
+		]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, pg.Schema{
+		{Name: "id", Type: "bigint", NotNull: true},
+		{Name: "placed_at", Type: "timestamptz", NotNull: true},
+		{Name: "ship_by", Type: "date", Doc: "Latest shipping date"},
+		{Name: "total", Type: "numeric", NotNull: true, Precision: 10, Scale: 2},
+		{Name: "status", Type: "text", NotNull: true},
+		{Name: "previous_status", Type: "text"},
+		{Name: "tags", Type: "text", NotNull: true, Repeated: true},
+		{Name: "attributes", Type: "jsonb", NotNull: true},
+		{Name: "address", Type: "jsonb", Schema: pg.Schema{
+			{Name: "city", Type: "text", NotNull: true},
+			{Name: "updated_at", Type: "bigint", NotNull: true},
+		}},
+		{Name: "billing", Type: "jsonb", NotNull: true, Schema: pg.Schema{
+			{Name: "city", Type: "text", NotNull: true},
+			{Name: "updated_at", Type: "bigint", NotNull: true},
+		}},
+		{Name: "parent", Type: "jsonb"},
+		{Name: "score", Type: "jsonb"},
+	}, schema)
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "invalid", data: `{"type": `, wantErr: "invalid Avro schema"},
+		{name: "not a record", data: `"string"`, wantErr: "invalid Avro schema"},
+		{name: "enum", data: `{"type": "enum", "name": "Status", "symbols": ["A"]}`, wantErr: "not a record"},
+		{name: "unknown type", data: `{"type": "record", "name": "R", "fields": [{"name": "a", "type": "Missing"}]}`, wantErr: "field a: unknown Avro type Missing"},
+		{name: "null", data: `{"type": "record", "name": "R", "fields": [{"name": "a", "type": "null"}]}`, wantErr: "null is only supported in unions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pg.SchemaFromAvro([]byte(tt.data))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -2,13 +2,16 @@ package pg_test
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
 	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -483,6 +486,650 @@ func TestLoadTableSchema_NonExistentTable(t *testing.T) {
 	assert.NotNil(t, foundType, "non_existent_table type should not be nil")
 }
 
+func TestLoadTableSchema_SchemaQualified(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// Tables with the same name in two schemas must not be merged
+	_, err = pool.Exec(ctx, `
+		CREATE SCHEMA analytics;
+		CREATE TABLE analytics.events (name text, tags text[]);
+		CREATE TABLE public.events (id integer);
+	`)
+	require.NoError(t, err)
+
+	provider, err := pg.NewTypeProviderWithConnection(ctx, connStr)
+	require.NoError(t, err)
+	defer provider.Close()
+
+	require.NoError(t, provider.LoadTableSchema(ctx, "analytics.events"))
+
+	fieldNames, found := provider.FindStructFieldNames("analytics.events")
+	require.True(t, found)
+	assert.Equal(t, []string{"name", "tags"}, fieldNames)
+
+	fieldType, found := provider.FindStructFieldType("analytics.events", "tags")
+	require.True(t, found)
+	assert.Equal(t, types.NewListType(types.StringType), fieldType.Type)
+
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("event", cel.ObjectType("analytics.events")),
+	)
+	require.NoError(t, err)
+	ast, issues := env.Compile(`event.name == "signup" && "beta" in event.tags`)
+	require.NoError(t, issues.Err())
+	sql, err := cel2sql.Convert(ast)
+	require.NoError(t, err)
+
+	var count int
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM analytics.events event WHERE "+sql).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestLoadTableSchema_SearchPath(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE SCHEMA tenant_a;
+		CREATE SCHEMA tenant_b;
+		CREATE TABLE tenant_a.orders (total numeric);
+		CREATE TABLE tenant_b.orders (amount numeric, currency text);
+		CREATE TABLE tenant_b.invoices (number text);
+		CREATE TABLE public.users (name text);
+	`)
+	require.NoError(t, err)
+
+	t.Run("default_search_path", func(t *testing.T) {
+		provider, err := pg.NewTypeProviderWithConnection(ctx, connStr)
+		require.NoError(t, err)
+		defer provider.Close()
+
+		require.NoError(t, provider.LoadTableSchema(ctx, "users"))
+		fieldNames, found := provider.FindStructFieldNames("users")
+		require.True(t, found)
+		assert.Equal(t, []string{"name"}, fieldNames)
+
+		// tenant_b is not on the search_path of the connection
+		require.NoError(t, provider.LoadTableSchema(ctx, "invoices"))
+		fieldNames, found = provider.FindStructFieldNames("invoices")
+		require.True(t, found)
+		assert.Empty(t, fieldNames)
+	})
+
+	t.Run("configured_search_path", func(t *testing.T) {
+		provider, err := pg.NewTypeProviderWithConnection(ctx, connStr, pg.WithSearchPath("tenant_b", "public"))
+		require.NoError(t, err)
+		defer provider.Close()
+
+		require.NoError(t, provider.LoadTableSchema(ctx, "orders"))
+		fieldNames, found := provider.FindStructFieldNames("orders")
+		require.True(t, found)
+		assert.Equal(t, []string{"amount", "currency"}, fieldNames)
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		provider, err := pg.NewTypeProviderWithConnection(ctx, connStr, pg.WithSearchPath("tenant_a", "tenant_b"))
+		require.NoError(t, err)
+		defer provider.Close()
+
+		err = provider.LoadTableSchema(ctx, "orders")
+		require.ErrorIs(t, err, pg.ErrAmbiguousTable)
+		assert.Contains(t, err.Error(), "tenant_a, tenant_b")
+
+		require.NoError(t, provider.LoadTableSchema(ctx, "tenant_a.orders"))
+		fieldNames, found := provider.FindStructFieldNames("tenant_a.orders")
+		require.True(t, found)
+		assert.Equal(t, []string{"total"}, fieldNames)
+	})
+}
+
+func TestLoadSchema_WholeSchema(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE SCHEMA analytics;
+		CREATE TABLE analytics.events (name text, tags text[]);
+		CREATE VIEW analytics.signups AS SELECT name FROM analytics.events WHERE name = 'signup';
+		CREATE TABLE public.users (name text, age integer);
+	`)
+	require.NoError(t, err)
+
+	provider, err := pg.NewTypeProviderWithConnection(ctx, connStr)
+	require.NoError(t, err)
+	defer provider.Close()
+
+	require.NoError(t, provider.(pg.SchemaLoader).LoadSchema(ctx, "analytics"))
+	require.NoError(t, provider.(pg.SchemaLoader).LoadSchema(ctx, "public"))
+
+	tests := []struct {
+		typeName   string
+		wantFields []string
+		wantFound  bool
+	}{
+		{typeName: "analytics.events", wantFields: []string{"name", "tags"}, wantFound: true},
+		{typeName: "analytics.signups", wantFields: []string{"name"}, wantFound: true},
+		{typeName: "public.users", wantFields: []string{"name", "age"}, wantFound: true},
+		{typeName: "users", wantFields: []string{"name", "age"}, wantFound: true},
+		{typeName: "events", wantFound: false}, // analytics is not on the search_path
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			fieldNames, found := provider.FindStructFieldNames(tt.typeName)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantFields, fieldNames)
+		})
+	}
+
+	fieldType, found := provider.FindStructFieldType("analytics.events", "tags")
+	require.True(t, found)
+	assert.Equal(t, types.NewListType(types.StringType), fieldType.Type)
+}
+
+func TestTypeProvider_LazyLoading(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		postgres.WithInitScripts("create_test_table.sql"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	provider, err := pg.NewTypeProviderWithConnection(ctx, connStr, pg.WithLazyLoading(5*time.Second))
+	require.NoError(t, err)
+	defer provider.Close()
+
+	// No table is preloaded: the checker triggers loading of users
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("user", cel.ObjectType("users")),
+	)
+	require.NoError(t, err)
+	ast, issues := env.Compile(`user.name == "John" && user.age > 30`)
+	require.NoError(t, issues.Err())
+	sql, err := cel2sql.Convert(ast)
+	require.NoError(t, err)
+	assert.Equal(t, "user.name = 'John' AND user.age > 30", sql)
+
+	fieldNames, found := provider.FindStructFieldNames("users")
+	require.True(t, found)
+	assert.Contains(t, fieldNames, "email")
+
+	_, found = provider.FindStructType("missing_table")
+	assert.False(t, found)
+}
+
+func TestTypeProvider_WatchSchemaChanges(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, pg.SchemaChangeTriggerSQL("schema_changes"))
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `CREATE TABLE orders (total numeric)`)
+	require.NoError(t, err)
+
+	provider, err := pg.NewTypeProviderWithConnection(ctx, connStr)
+	require.NoError(t, err)
+	defer provider.Close()
+	require.NoError(t, provider.LoadTableSchema(ctx, "orders"))
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- provider.(pg.SchemaWatcher).WatchSchemaChanges(watchCtx, "schema_changes")
+	}()
+
+	fieldNames := func() []string {
+		names, _ := provider.FindStructFieldNames("orders")
+		return names
+	}
+
+	// Give the watcher time to LISTEN before the migration runs
+	time.Sleep(500 * time.Millisecond)
+	_, err = pool.Exec(ctx, `ALTER TABLE orders ADD COLUMN currency text`)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(fieldNames()) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, []string{"total", "currency"}, fieldNames())
+
+	_, err = pool.Exec(ctx, `DROP TABLE orders`)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, found := provider.FindStructType("orders")
+		return !found
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-watchErr, context.Canceled)
+}
+
+func TestNewTypeProviderWithPool_ExistingPool(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		postgres.WithInitScripts("create_test_table.sql"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	provider := pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.LoadTableSchema(ctx, "users"))
+	fieldNames, found := provider.FindStructFieldNames("users")
+	require.True(t, found)
+	assert.Contains(t, fieldNames, "name")
+
+	// Closing the provider leaves the caller's pool open
+	provider.Close()
+	require.NoError(t, pool.Ping(ctx))
+}
+
+func TestNewTypeProviderWithDB_DatabaseSQL(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		postgres.WithInitScripts("create_test_table.sql"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	db, err := sql.Open("pgx", connStr)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	provider := pg.NewTypeProviderWithDB(db, pg.WithSearchPath("public"))
+	defer provider.Close()
+
+	require.NoError(t, provider.LoadTableSchema(ctx, "users"))
+	fieldNames, found := provider.FindStructFieldNames("users")
+	require.True(t, found)
+	assert.Contains(t, fieldNames, "email")
+
+	require.NoError(t, provider.(pg.SchemaLoader).LoadSchema(ctx, "public"))
+	_, found = provider.FindStructType("public.users")
+	assert.True(t, found)
+
+	err = provider.(pg.SchemaWatcher).WatchSchemaChanges(ctx, "schema_changes")
+	assert.EqualError(t, err, "schema change notifications need a pgx connection pool")
+	require.NoError(t, db.PingContext(ctx))
+}
+
+func TestLoadTableSchema_CompositeTypes(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE TYPE geo AS (lat double precision, lng double precision);
+		CREATE TYPE address AS (street varchar(100), city text, zips text[], location geo);
+		CREATE TABLE customers (name text, address address, previous address[]);
+		INSERT INTO customers VALUES
+			('alice', ROW('1 Main St', 'Cape Town', '{8001}', ROW(-33.9, 18.4)), '{}'),
+			('bob', ROW('2 High St', 'London', '{}', ROW(51.5, -0.1)), '{}');
+	`)
+	require.NoError(t, err)
+
+	provider := pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.LoadTableSchema(ctx, "customers"))
+	require.NoError(t, provider.(pg.SchemaLoader).LoadSchema(ctx, "public"))
+
+	for _, typeName := range []string{"customers", "public.customers"} {
+		got, found := provider.FindStructFieldType(typeName, "address")
+		require.True(t, found)
+		assert.Equal(t, types.NewObjectType(typeName+".address"), got.Type)
+		got, found = provider.FindStructFieldType(typeName, "previous")
+		require.True(t, found)
+		assert.Equal(t, types.NewListType(types.NewObjectType(typeName+".previous")), got.Type)
+	}
+	fieldNames, found := provider.FindStructFieldNames("customers.address")
+	require.True(t, found)
+	assert.Equal(t, []string{"street", "city", "zips", "location"}, fieldNames)
+	got, found := provider.FindStructFieldType("customers.address", "zips")
+	require.True(t, found)
+	assert.Equal(t, types.NewListType(types.StringType), got.Type)
+	got, found = provider.FindStructFieldType("customers.address.location", "lat")
+	require.True(t, found)
+	assert.Equal(t, types.DoubleType, got.Type)
+
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("customers", cel.ObjectType("customers")),
+	)
+	require.NoError(t, err)
+	ast, issues := env.Compile(`customers.address.city == "Cape Town" && customers.address.location.lat < 0.0`)
+	require.NoError(t, issues.Err())
+	sqlCondition, err := cel2sql.Convert(ast)
+	require.NoError(t, err)
+
+	var name string
+	err = pool.QueryRow(ctx, "SELECT name FROM customers WHERE "+sqlCondition).Scan(&name)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", name)
+}
+
+func TestLoadTableSchema_ArrayElementTypes(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE TYPE mood AS ENUM ('happy', 'sad');
+		CREATE TYPE point2d AS (x integer, y integer);
+		CREATE TABLE samples (
+			matrix integer[][],
+			labels varchar(10)[],
+			stamps timestamp with time zone[],
+			moods mood[],
+			points point2d[]
+		);
+		CREATE VIEW sample_view AS SELECT matrix, moods FROM samples;
+	`)
+	require.NoError(t, err)
+
+	provider := pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.LoadTableSchema(ctx, "samples"))
+	require.NoError(t, provider.LoadTableSchema(ctx, "sample_view"))
+
+	tests := []struct {
+		typeName  string
+		fieldName string
+		wantType  *types.Type
+	}{
+		{typeName: "samples", fieldName: "matrix", wantType: types.NewListType(types.IntType)},
+		{typeName: "samples", fieldName: "labels", wantType: types.NewListType(types.StringType)},
+		{typeName: "samples", fieldName: "stamps", wantType: types.NewListType(types.TimestampType)},
+		{typeName: "samples", fieldName: "moods", wantType: types.NewListType(types.StringType)},
+		{typeName: "samples", fieldName: "points", wantType: types.NewListType(types.NewObjectType("samples.points"))},
+		{typeName: "samples.points", fieldName: "x", wantType: types.IntType},
+		{typeName: "sample_view", fieldName: "matrix", wantType: types.NewListType(types.IntType)},
+		{typeName: "sample_view", fieldName: "moods", wantType: types.NewListType(types.StringType)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName+"."+tt.fieldName, func(t *testing.T) {
+			got, found := provider.FindStructFieldType(tt.typeName, tt.fieldName)
+			require.True(t, found)
+			assert.Equal(t, tt.wantType, got.Type)
+		})
+	}
+	assert.Equal(t, types.Int(1), provider.EnumValue("mood.sad"))
+}
+
+func TestLoadTableSchema_EnumTypes(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE TYPE order_status AS ENUM ('pending', 'shipped', 'delivered');
+		CREATE TABLE orders (id serial, status order_status, history order_status[]);
+		INSERT INTO orders (status, history) VALUES ('pending', '{pending}'), ('delivered', '{pending,shipped,delivered}');
+	`)
+	require.NoError(t, err)
+
+	provider := pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.LoadTableSchema(ctx, "orders"))
+
+	data, err := provider.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"type": "order_status"`)
+	assert.Equal(t, types.Int(2), provider.EnumValue("order_status.delivered"))
+
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("orders", cel.ObjectType("orders")),
+	)
+	require.NoError(t, err)
+
+	labels := []string{"pending", "shipped", "delivered"}
+	schemas := map[string]pg.Schema{"orders": {
+		{Name: "id", Type: "integer"},
+		{Name: "status", Type: "order_status", Enum: labels},
+		{Name: "history", Type: "order_status", Repeated: true, Enum: labels},
+	}}
+
+	// Labels compare in enum sort order, not alphabetically
+	ast, issues := env.Compile(`orders.status > "pending" && "shipped" in orders.history`)
+	require.NoError(t, issues.Err())
+	sqlCondition, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+	require.NoError(t, err)
+	assert.Equal(t, "orders.status > 'pending'::order_status AND 'shipped'::order_status = ANY(orders.history)", sqlCondition)
+
+	var count int
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM orders WHERE "+sqlCondition).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
 func TestLoadTableSchema_WithoutConnection(t *testing.T) {
 	// Create type provider without database connection
 	provider := pg.NewTypeProvider(make(map[string]pg.Schema))
@@ -1433,59 +2080,59 @@ func TestRegexPatternMatching(t *testing.T) {
 	require.NoError(t, err)
 
 	tests := []struct {
-		name        string
-		celExpr     string
-		expectedSQL string
-		description string
+		name          string
+		celExpr       string
+		expectedSQL   string
+		description   string
 		expectedCount int
 	}{
 		{
-			name:        "email_domain_pattern",
-			celExpr:     `test_regex.email.matches(".*@example\\.com")`,
-			expectedSQL: "test_regex.email ~ '.*@example\\.com'",
-			description: "Match emails with example.com domain",
+			name:          "email_domain_pattern",
+			celExpr:       `test_regex.email.matches(".*@example\\.com")`,
+			expectedSQL:   "test_regex.email ~ '[^\\n]*@example\\.com'",
+			description:   "Match emails with example.com domain",
 			expectedCount: 1, // john.doe@example.com
 		},
 		{
-			name:        "code_pattern_alpha_numeric",
-			celExpr:     `test_regex.code.matches("^[A-Z]{3}\\d{3}$")`,
-			expectedSQL: "test_regex.code ~ '^[A-Z]{3}[[:digit:]]{3}$'",
-			description: "Match 3 uppercase letters followed by 3 digits",
+			name:          "code_pattern_alpha_numeric",
+			celExpr:       `test_regex.code.matches("^[A-Z]{3}\\d{3}$")`,
+			expectedSQL:   "test_regex.code ~ '^[A-Z]{3}[0-9]{3}$'",
+			description:   "Match 3 uppercase letters followed by 3 digits",
 			expectedCount: 5, // ABC123, XYZ789, DEF456, GHI999, JKL111
 		},
 		{
-			name:        "phone_basic_format",
-			celExpr:     `test_regex.phone.matches("^\\d{3}-\\d{4}$")`,
-			expectedSQL: "test_regex.phone ~ '^[[:digit:]]{3}-[[:digit:]]{4}$'",
-			description: "Match basic phone format XXX-XXXX",
+			name:          "phone_basic_format",
+			celExpr:       `test_regex.phone.matches("^\\d{3}-\\d{4}$")`,
+			expectedSQL:   "test_regex.phone ~ '^[0-9]{3}-[0-9]{4}$'",
+			description:   "Match basic phone format XXX-XXXX",
 			expectedCount: 2, // 555-1234, 555-5678
 		},
 		{
-			name:        "description_word_boundary",
-			celExpr:     `test_regex.description.matches("\\btest\\b")`,
-			expectedSQL: "test_regex.description ~ '\\ytest\\y'",
-			description: "Match whole word 'test' using word boundaries",
+			name:          "description_word_boundary",
+			celExpr:       `test_regex.description.matches("\\btest\\b")`,
+			expectedSQL:   "test_regex.description ~ '\\ytest\\y'",
+			description:   "Match whole word 'test' using word boundaries",
 			expectedCount: 2, // Contains 'test' as whole word
 		},
 		{
-			name:        "email_function_style",
-			celExpr:     `matches(test_regex.email, ".*\\.org$")`,
-			expectedSQL: "test_regex.email ~ '.*\\.org$'",
-			description: "Function-style matches for .org domains",
+			name:          "email_function_style",
+			celExpr:       `matches(test_regex.email, ".*\\.org$")`,
+			expectedSQL:   "test_regex.email ~ '[^\\n]*\\.org$'",
+			description:   "Function-style matches for .org domains",
 			expectedCount: 1, // jane.smith@company.org
 		},
 		{
-			name:        "complex_pattern_whitespace",
-			celExpr:     `test_regex.description.matches("\\w+\\s+\\w+")`,
-			expectedSQL: "test_regex.description ~ '[[:alnum:]_]+[[:space:]]+[[:alnum:]_]+'",
-			description: "Match two words separated by whitespace",
+			name:          "complex_pattern_whitespace",
+			celExpr:       `test_regex.description.matches("\\w+\\s+\\w+")`,
+			expectedSQL:   "test_regex.description ~ '[0-9A-Z_a-z]+[\\t\\n\\f\\r ]+[0-9A-Z_a-z]+'",
+			description:   "Match two words separated by whitespace",
 			expectedCount: 5, // All descriptions have at least two words
 		},
 		{
-			name:        "negated_pattern_no_digits",
-			celExpr:     `!test_regex.name.matches("\\d")`,
-			expectedSQL: "NOT test_regex.name ~ '[[:digit:]]'",
-			description: "Names that don't contain any digits",
+			name:          "negated_pattern_no_digits",
+			celExpr:       `!test_regex.name.matches("\\d")`,
+			expectedSQL:   "NOT test_regex.name ~ '[0-9]'",
+			description:   "Names that don't contain any digits",
 			expectedCount: 5, // All names in test data contain no digits
 		},
 	}
@@ -1537,3 +2184,305 @@ func TestRegexPatternMatching(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadTableSchema_ForeignKeys(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE SCHEMA acl;
+		CREATE TABLE acl.groups (id integer, kind text, PRIMARY KEY (id, kind));
+		CREATE TABLE users (id integer PRIMARY KEY);
+		CREATE TABLE memberships (
+			user_id integer CONSTRAINT member_user REFERENCES users (id),
+			group_id integer,
+			group_kind text,
+			CONSTRAINT member_group FOREIGN KEY (group_id, group_kind) REFERENCES acl.groups (id, kind)
+		);
+	`)
+	require.NoError(t, err)
+
+	want := []pg.ForeignKey{
+		{Name: "member_group", Columns: []string{"group_id", "group_kind"}, RefTable: "acl.groups", RefColumns: []string{"id", "kind"}},
+		{Name: "member_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}},
+	}
+
+	provider := pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.LoadTableSchema(ctx, "memberships"))
+	assert.Equal(t, want, provider.(pg.ConstraintProvider).ForeignKeys("memberships"))
+
+	provider = pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.(pg.SchemaLoader).LoadSchema(ctx, "public"))
+	assert.Equal(t, want, provider.(pg.ConstraintProvider).ForeignKeys("memberships"))
+	assert.Nil(t, provider.(pg.ConstraintProvider).ForeignKeys("users"))
+}
+
+func TestLoadTableSchema_UniqueKeys(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE users (
+			id integer PRIMARY KEY,
+			email text CONSTRAINT users_email UNIQUE,
+			org_id integer,
+			handle text
+		);
+		CREATE UNIQUE INDEX users_handle ON users (org_id, handle) INCLUDE (email);
+		CREATE UNIQUE INDEX users_lower_email ON users (lower(email));
+		CREATE UNIQUE INDEX users_active_handle ON users (handle) WHERE id > 0;
+		CREATE TABLE events (payload jsonb);
+	`)
+	require.NoError(t, err)
+
+	provider := pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.LoadTableSchema(ctx, "users"))
+	require.NoError(t, provider.LoadTableSchema(ctx, "events"))
+
+	assert.Equal(t, []string{"id"}, provider.(pg.ConstraintProvider).PrimaryKey("users"))
+	assert.Equal(t, [][]string{{"id"}, {"email"}, {"org_id", "handle"}}, provider.(pg.ConstraintProvider).UniqueKeys("users"))
+	assert.Nil(t, provider.(pg.ConstraintProvider).PrimaryKey("events"))
+	assert.Nil(t, provider.(pg.ConstraintProvider).UniqueKeys("events"))
+}
+
+func TestLoadTableSchema_ColumnComments(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE TYPE address AS (city text, zip text);
+		CREATE TABLE "User Accounts" (id integer, email text, home address);
+		COMMENT ON COLUMN "User Accounts".email IS 'Primary contact address';
+		COMMENT ON COLUMN address.zip IS 'Postal code';
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		load func(pg.TypeProvider) error
+	}{
+		{name: "LoadTableSchema", load: func(p pg.TypeProvider) error { return p.LoadTableSchema(ctx, "User Accounts") }},
+		{name: "LoadSchema", load: func(p pg.TypeProvider) error { return p.(pg.SchemaLoader).LoadSchema(ctx, "public") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := pg.NewTypeProviderWithPool(pool)
+			require.NoError(t, tt.load(provider))
+
+			doc, found := provider.(pg.FieldDocProvider).FieldDoc("User Accounts", "email")
+			require.True(t, found)
+			assert.Equal(t, "Primary contact address", doc)
+			doc, found = provider.(pg.FieldDocProvider).FieldDoc("User Accounts", "id")
+			require.True(t, found)
+			assert.Empty(t, doc)
+			doc, found = provider.(pg.FieldDocProvider).FieldDoc("User Accounts.home", "zip")
+			require.True(t, found)
+			assert.Equal(t, "Postal code", doc)
+		})
+	}
+}
+
+func TestLoadTableSchema_GeneratedColumns(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE orders (
+			id bigint GENERATED ALWAYS AS IDENTITY,
+			price numeric(10, 2) NOT NULL,
+			quantity integer,
+			total numeric GENERATED ALWAYS AS (price * quantity) STORED
+		);
+	`)
+	require.NoError(t, err)
+
+	columns := `[
+		{"name": "id", "type": "bigint", "notNull": true, "identity": true},
+		{"name": "price", "type": "numeric", "notNull": true, "precision": 10, "scale": 2},
+		{"name": "quantity", "type": "integer"},
+		{"name": "total", "type": "numeric", "generated": true}
+	]`
+
+	provider := pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.LoadTableSchema(ctx, "orders"))
+	data, err := provider.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"tables": {"orders": `+columns+`}}`, string(data))
+
+	provider = pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.(pg.SchemaLoader).LoadSchema(ctx, "public"))
+	data, err = provider.(pg.SchemaExporter).ExportSchemas()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"tables": {"orders": `+columns+`, "public.orders": `+columns+`}}`, string(data))
+}
+
+func TestLoadTableSchema_PartitionedTables(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Second*60),
+		),
+	)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate container: %v", err)
+		}
+	}()
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE SCHEMA archive;
+		CREATE TABLE measurements (id bigint, taken_at timestamptz NOT NULL, value double precision)
+			PARTITION BY RANGE (taken_at);
+		CREATE TABLE measurements_2025 PARTITION OF measurements
+			FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
+		CREATE TABLE archive.measurements_2024 PARTITION OF measurements
+			FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+		INSERT INTO measurements VALUES (1, '2024-06-01', 1.5), (2, '2025-06-01', 2.5);
+	`)
+	require.NoError(t, err)
+
+	provider := pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.(pg.SchemaLoader).LoadSchema(ctx, "public"))
+
+	fieldNames, found := provider.FindStructFieldNames("measurements")
+	require.True(t, found)
+	assert.Equal(t, []string{"id", "taken_at", "value"}, fieldNames)
+	_, found = provider.FindStructType("measurements_2025")
+	assert.False(t, found, "partitions are not loaded as tables")
+	assert.Equal(t, []string{"archive.measurements_2024", "measurements_2025"}, provider.(pg.PartitionProvider).Partitions("measurements"))
+
+	provider = pg.NewTypeProviderWithPool(pool)
+	require.NoError(t, provider.LoadTableSchema(ctx, "measurements"))
+	assert.Equal(t, []string{"archive.measurements_2024", "measurements_2025"}, provider.(pg.PartitionProvider).Partitions("measurements"))
+
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("measurements", cel.ObjectType("measurements")),
+	)
+	require.NoError(t, err)
+	ast, issues := env.Compile(`measurements.taken_at >= timestamp("2025-01-01T00:00:00Z")`)
+	require.NoError(t, issues.Err())
+	sqlCondition, err := cel2sql.Convert(ast)
+	require.NoError(t, err)
+
+	var count int
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM measurements WHERE "+sqlCondition).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
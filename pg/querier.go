@@ -0,0 +1,55 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// querier runs the introspection queries, through either pgx or database/sql
+type querier interface {
+	Query(ctx context.Context, query string, args ...any) (rows, error)
+}
+
+// rows is the subset of pgx.Rows and sql.Rows used to read query results
+type rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+	Close()
+}
+
+type poolQuerier struct {
+	pool *pgxpool.Pool
+}
+
+func (q poolQuerier) Query(ctx context.Context, query string, args ...any) (rows, error) {
+	r, err := q.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type dbQuerier struct {
+	db *sql.DB
+}
+
+func (q dbQuerier) Query(ctx context.Context, query string, args ...any) (rows, error) {
+	r, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return sqlRows{r}, nil
+}
+
+// sqlRows adapts sql.Rows, whose Close returns an error that carries no information once the
+// rows have been iterated and Err checked
+type sqlRows struct {
+	*sql.Rows
+}
+
+func (r sqlRows) Close() {
+	_ = r.Rows.Close()
+}
@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrAmbiguousTable is returned when an unqualified table name exists in more than one schema
+// of the search path.
+var ErrAmbiguousTable = errors.New("ambiguous table name")
+
+// ProviderOption configures a type provider created with NewTypeProviderWithConnection.
+type ProviderOption func(*typeProvider)
+
+// WithSearchPath sets the schemas searched, in order, for unqualified table names passed to
+// LoadTableSchema. By default the search_path of the connection is used, as PostgreSQL does.
+func WithSearchPath(schemas ...string) ProviderOption {
+	return func(p *typeProvider) {
+		p.searchPath = append(p.searchPath, schemas...)
+	}
+}
+
+// searchPathArray is the SQL text[] of the search path given as query parameter $2 by
+// searchPathParam. The search path is passed as a JSON array rather than a text[], which not
+// every database/sql driver can encode.
+const searchPathArray = `CASE WHEN $2::jsonb IS NULL THEN current_schemas(false)
+			ELSE ARRAY(SELECT jsonb_array_elements_text($2::jsonb)) END`
+
+// searchPathParam returns the configured search path as a JSON array, or nil to use the
+// search_path of the connection
+func (p *typeProvider) searchPathParam() any {
+	if len(p.searchPath) == 0 {
+		return nil
+	}
+	encoded, _ := json.Marshal(p.searchPath)
+	return string(encoded)
+}
+
+// resolveSchema returns the schema of the search path that holds an unqualified table, or ""
+// if there is none. Unlike PostgreSQL, which silently uses the first match, a table found in
+// several schemas is rejected, since the CEL type name cannot tell them apart.
+func (p *typeProvider) resolveSchema(ctx context.Context, tableName string) (string, error) {
+	query := `
+		SELECT s.name
+		FROM unnest(` + searchPathArray + `) WITH ORDINALITY AS s(name, position)
+		JOIN information_schema.tables t ON t.table_schema = s.name AND t.table_name = $1
+		ORDER BY s.position
+	`
+	rows, err := p.db.Query(ctx, query, tableName, p.searchPathParam())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve table schema: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return "", fmt.Errorf("failed to scan row: %w", err)
+		}
+		schemas = append(schemas, name)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	switch len(schemas) {
+	case 0:
+		return "", nil
+	case 1:
+		return schemas[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s exists in schemas %s, qualify it with the schema name",
+			ErrAmbiguousTable, tableName, strings.Join(schemas, ", "))
+	}
+}
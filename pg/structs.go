@@ -0,0 +1,196 @@
+package pg
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	durationType     = reflect.TypeOf(time.Duration(0))
+	rawMessageType   = reflect.TypeOf(json.RawMessage(nil))
+	driverValuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// NewTypeProviderFromStructs creates a new PostgreSQL type provider from Go models, by table
+// name, so that CEL types can be kept in sync with the structs rows are scanned into without
+// database access. See SchemaFromStruct.
+func NewTypeProviderFromStructs(models map[string]any) (TypeProvider, error) {
+	schemas := make(map[string]Schema, len(models))
+	for tableName, model := range models {
+		schema, err := SchemaFromStruct(model)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", tableName, err)
+		}
+		schemas[tableName] = schema
+	}
+	return NewTypeProvider(schemas), nil
+}
+
+// SchemaFromStruct derives a schema from the exported fields of a struct, or pointer to struct.
+// Columns are named by the db tag, then the json tag, then the lower-cased field name, and
+// fields tagged "-" are skipped; embedded structs contribute their fields. Nested structs are
+// composite columns, slices and arrays are arrays, maps and json.RawMessage are jsonb, and the
+// sql.Null types have the type of their value. Fields that cannot hold NULL, as opposed to
+// pointers, slices, maps and the sql.Null types, are NOT NULL.
+func SchemaFromStruct(model any) (Schema, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model of type %T is not a struct", model)
+	}
+	return structSchema(t, nil)
+}
+
+// structSchema derives the schema of a struct type, resolving being the struct types enclosing it
+func structSchema(t reflect.Type, resolving []reflect.Type) (Schema, error) {
+	for _, r := range resolving {
+		if r == t {
+			return nil, fmt.Errorf("struct %s is recursive", t)
+		}
+	}
+	resolving = append(resolving, t)
+
+	var schema Schema
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, skip := structColumnName(sf)
+		if skip {
+			continue
+		}
+		if sf.Anonymous && name == "" {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && !isScalarStruct(embedded) {
+				fields, err := structSchema(embedded, resolving)
+				if err != nil {
+					return nil, err
+				}
+				schema = append(schema, fields...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		field := FieldSchema{Name: name, NotNull: true}
+		if err := setStructFieldType(&field, sf.Type, resolving); err != nil {
+			return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		schema = append(schema, field)
+	}
+	return schema, nil
+}
+
+// structColumnName returns the column named by the db or json tag of a struct field, or
+// reports that the field is skipped
+func structColumnName(sf reflect.StructField) (string, bool) {
+	for _, key := range []string{"db", "json"} {
+		tag, found := sf.Tag.Lookup(key)
+		if !found {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			return "", true
+		}
+		if name != "" {
+			return name, false
+		}
+	}
+	return "", false
+}
+
+// setStructFieldType sets the type of a field from the Go type of a struct field
+func setStructFieldType(field *FieldSchema, t reflect.Type, resolving []reflect.Type) error {
+	if t.Kind() == reflect.Pointer {
+		field.NotNull = false
+		t = t.Elem()
+	}
+	if t.PkgPath() == "database/sql" && strings.HasPrefix(t.Name(), "Null") && t.Kind() == reflect.Struct {
+		// sql.NullString{String, Valid}, sql.Null[T]{V, Valid}, ...
+		field.NotNull = false
+		t = t.Field(0).Type
+	}
+
+	switch {
+	case t == timeType:
+		field.Type = "timestamptz"
+		return nil
+	case t == durationType:
+		field.Type = "bigint"
+		return nil
+	case t == rawMessageType:
+		field.Type = "jsonb"
+		field.NotNull = false
+		return nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		field.Type = "bytea"
+		field.NotNull = false
+		return nil
+	case t.Implements(driverValuerType) || reflect.PointerTo(t).Implements(driverValuerType):
+		// Custom types such as UUIDs are written as text by their driver.Valuer
+		field.Type = "text"
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		field.Type = "text"
+	case reflect.Bool:
+		field.Type = "boolean"
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		field.Type = "smallint"
+	case reflect.Int32, reflect.Uint16:
+		field.Type = "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		field.Type = "bigint"
+	case reflect.Float32:
+		field.Type = "real"
+	case reflect.Float64:
+		field.Type = "double precision"
+	case reflect.Map, reflect.Interface:
+		field.Type = "jsonb"
+		field.NotNull = false
+	case reflect.Slice, reflect.Array:
+		if field.Repeated {
+			// Nested arrays are multidimensional arrays of the innermost element type
+			return setStructFieldType(field, t.Elem(), resolving)
+		}
+		field.Repeated = true
+		field.NotNull = t.Kind() == reflect.Array
+		notNull := field.NotNull
+		if err := setStructFieldType(field, t.Elem(), resolving); err != nil {
+			return err
+		}
+		field.NotNull = notNull
+	case reflect.Struct:
+		nested, err := structSchema(t, resolving)
+		if err != nil {
+			return err
+		}
+		field.Type = "composite"
+		field.Schema = nested
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+// isScalarStruct checks if a struct type is stored as a single value rather than as fields
+func isScalarStruct(t reflect.Type) bool {
+	return t == timeType || t.Implements(driverValuerType) || reflect.PointerTo(t).Implements(driverValuerType) ||
+		(t.PkgPath() == "database/sql" && strings.HasPrefix(t.Name(), "Null"))
+}
@@ -0,0 +1,110 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SchemaChangeTriggerSQL returns the DDL of event triggers that publish the schema-qualified
+// name of every created, altered or dropped table and view on a NOTIFY channel, for use with
+// WatchSchemaChanges. It needs to be run once per database by a superuser, typically in a
+// migration.
+func SchemaChangeTriggerSQL(channel string) string {
+	channel = strings.ReplaceAll(channel, "'", "''")
+	return `CREATE OR REPLACE FUNCTION cel2sql_notify_ddl_command() RETURNS event_trigger
+LANGUAGE plpgsql AS $$
+DECLARE r record;
+BEGIN
+	FOR r IN SELECT object_identity FROM pg_event_trigger_ddl_commands()
+		WHERE object_type IN ('table', 'view')
+	LOOP
+		PERFORM pg_notify('` + channel + `', r.object_identity);
+	END LOOP;
+END $$;
+
+CREATE OR REPLACE FUNCTION cel2sql_notify_sql_drop() RETURNS event_trigger
+LANGUAGE plpgsql AS $$
+DECLARE r record;
+BEGIN
+	FOR r IN SELECT object_identity FROM pg_event_trigger_dropped_objects()
+		WHERE object_type IN ('table', 'view')
+	LOOP
+		PERFORM pg_notify('` + channel + `', r.object_identity);
+	END LOOP;
+END $$;
+
+DROP EVENT TRIGGER IF EXISTS cel2sql_ddl_command;
+CREATE EVENT TRIGGER cel2sql_ddl_command ON ddl_command_end
+	EXECUTE FUNCTION cel2sql_notify_ddl_command();
+
+DROP EVENT TRIGGER IF EXISTS cel2sql_sql_drop;
+CREATE EVENT TRIGGER cel2sql_sql_drop ON sql_drop
+	EXECUTE FUNCTION cel2sql_notify_sql_drop();
+`
+}
+
+// WatchSchemaChanges listens on a NOTIFY channel carrying table names, as published by the
+// triggers of SchemaChangeTriggerSQL, and reloads the schemas of the affected tables that were
+// already loaded, removing dropped ones. It blocks until ctx is done, so it is usually run in
+// its own goroutine; it holds one connection of the pool meanwhile.
+func (p *typeProvider) WatchSchemaChanges(ctx context.Context, channel string) error {
+	if p.pool == nil {
+		return errors.New("schema change notifications need a pgx connection pool")
+	}
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+		p.reloadTable(ctx, notification.Payload)
+	}
+}
+
+// reloadTable reloads the loaded schemas registered for a schema-qualified table name, either
+// under that name or unqualified. Tables that no longer exist, or whose schema cannot be
+// queried, are removed rather than left stale.
+func (p *typeProvider) reloadTable(ctx context.Context, qualifiedName string) {
+	_, table := SplitTableName(qualifiedName)
+
+	var affected []string
+	p.mu.RLock()
+	for tableName := range p.schemas {
+		if tableName == qualifiedName || tableName == table {
+			affected = append(affected, tableName)
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, tableName := range affected {
+		schema, exists, err := p.queryTableSchema(ctx, tableName)
+		var metadata tableMetadata
+		if err == nil && exists {
+			metadata, err = p.queryTableMetadata(ctx, tableName)
+		}
+		p.mu.Lock()
+		if err == nil && exists {
+			p.schemas[tableName] = schema
+		} else {
+			delete(p.schemas, tableName)
+		}
+		p.setMetadata(tableName, metadata)
+		delete(p.lazyLoaded, tableName)
+		p.mu.Unlock()
+	}
+}
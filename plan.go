@@ -0,0 +1,85 @@
+package cel2sql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// Plan is an expression converted once, for filters that are saved and rendered on every
+// request. The analysis of comprehensions, JSON access and referenced fields is done by
+// Compile; rendering a Plan only copies out its result. A Plan is safe for concurrent use.
+type Plan struct {
+	result   *Result
+	literals map[int]any
+	features []string // reported again when a Cache serves the plan, see WithMetrics
+}
+
+// Compile converts a CEL AST with the given options into a Plan. Variables named with
+// WithParameters and literals with WithLiteralPlaceholders become placeholders, whose values
+// are supplied when the plan is rendered with SQLWithParams.
+func Compile(ast *cel.Ast, opts ...ConvertOption) (*Plan, error) {
+	con, expr, err := convert(ast, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer con.release()
+	return con.plan(expr), nil
+}
+
+// compileCheckedExpr compiles a checked expression in its protobuf form like Compile
+func compileCheckedExpr(checked *exprpb.CheckedExpr, opts []ConvertOption) (*Plan, error) {
+	if checked.GetExpr() == nil || len(checked.GetTypeMap()) == 0 {
+		return nil, errors.New("cannot convert unchecked expression")
+	}
+	con := newConverter(checked.GetTypeMap())
+	con.checkedSourceInfo = checked.GetSourceInfo()
+	if err := con.convert(checked.GetExpr(), opts); err != nil {
+		return nil, err
+	}
+	defer con.release()
+	return con.plan(checked.GetExpr()), nil
+}
+
+// plan collects the SQL written for expr into a Plan
+func (con *converter) plan(expr *exprpb.Expr) *Plan {
+	return &Plan{
+		result:   con.result(expr),
+		literals: con.literalValues,
+		features: con.features,
+	}
+}
+
+// SQL returns the SQL of the plan.
+func (p *Plan) SQL() string {
+	return p.result.SQL
+}
+
+// Result returns the SQL of the plan with metadata about it, see ConvertWithResult. It is
+// shared by all callers and must not be modified.
+func (p *Plan) Result() *Result {
+	return p.result
+}
+
+// SQLWithParams returns the SQL of the plan with the arguments for its placeholders, in
+// placeholder order: the value of $1 is at index 0, and so on, or the order of Parameters for
+// other placeholder styles. Parameters are looked up by name in vals, and literals bound to
+// placeholders take their value from the expression. An error wrapping ErrMissingParameter is
+// returned if vals has no value for a parameter.
+func (p *Plan) SQLWithParams(vals map[string]any) (string, []any, error) {
+	args := make([]any, len(p.result.Parameters))
+	for i, name := range p.result.Parameters {
+		if value, ok := p.literals[i]; ok {
+			args[i] = value
+			continue
+		}
+		value, ok := vals[name]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s", ErrMissingParameter, name)
+		}
+		args[i] = value
+	}
+	return p.result.SQL, args, nil
+}
@@ -0,0 +1,134 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestCompile(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("min_age", cel.IntType),
+		cel.Variable("max_age", cel.IntType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		source   string
+		opts     []cel2sql.ConvertOption
+		vals     map[string]any
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "no_parameters",
+			source:   `age >= 18`,
+			wantSQL:  "age >= 18",
+			wantArgs: []any{},
+		},
+		{
+			name:     "parameters",
+			source:   `age <= max_age && age >= min_age && age != max_age`,
+			opts:     []cel2sql.ConvertOption{cel2sql.WithParameters("min_age", "max_age")},
+			vals:     map[string]any{"min_age": 18, "max_age": 65},
+			wantSQL:  "age <= $1 AND age >= $2 AND age != $1",
+			wantArgs: []any{65, 18},
+		},
+		{
+			name:     "literal_placeholders",
+			source:   `name == "a" && age >= min_age`,
+			opts:     []cel2sql.ConvertOption{cel2sql.WithParameters("min_age"), cel2sql.WithLiteralPlaceholders()},
+			vals:     map[string]any{"min_age": 18},
+			wantSQL:  "name = $1 AND age >= $2",
+			wantArgs: []any{"a", 18},
+		},
+		{
+			name:   "question_style",
+			source: `age <= max_age && age >= min_age && age != max_age`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithParameters("min_age", "max_age"),
+				cel2sql.WithPlaceholderStyle(cel2sql.PlaceholderQuestion),
+			},
+			vals:     map[string]any{"min_age": 18, "max_age": 65},
+			wantSQL:  "age <= ? AND age >= ? AND age != ?",
+			wantArgs: []any{65, 18, 65},
+		},
+		{
+			name:   "at_style_with_literal_placeholders",
+			source: `name == "a" && age >= min_age && age < 65`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithParameters("min_age"),
+				cel2sql.WithLiteralPlaceholders(),
+				cel2sql.WithPlaceholderStyle(cel2sql.PlaceholderAt),
+			},
+			vals:     map[string]any{"min_age": 18},
+			wantSQL:  "name = @p1 AND age >= @min_age AND age < @p3",
+			wantArgs: []any{"a", 18, int64(65)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			plan, err := cel2sql.Compile(ast, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSQL, plan.SQL())
+
+			// rendering twice gives the same result
+			for range 2 {
+				sql, args, err := plan.SQLWithParams(tt.vals)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantSQL, sql)
+				assert.Equal(t, tt.wantArgs, args)
+			}
+		})
+	}
+}
+
+func TestCompileMissingParameter(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("age", cel.IntType),
+		cel.Variable("min_age", cel.IntType),
+	)
+	require.NoError(t, err)
+	ast, issues := env.Compile(`age >= min_age`)
+	require.NoError(t, issues.Err())
+
+	plan, err := cel2sql.Compile(ast, cel2sql.WithParameters("min_age"))
+	require.NoError(t, err)
+
+	_, _, err = plan.SQLWithParams(map[string]any{"max_age": 65})
+	require.ErrorIs(t, err, cel2sql.ErrMissingParameter)
+	assert.Contains(t, err.Error(), "min_age")
+}
+
+func TestCompileResult(t *testing.T) {
+	env, schemas := newColumnsTestEnv(t)
+	ast, issues := env.Compile(`users.name == region`)
+	require.NoError(t, issues.Err())
+
+	plan, err := cel2sql.Compile(ast, cel2sql.WithSchemas(schemas), cel2sql.WithParameters("region"))
+	require.NoError(t, err)
+	want, err := cel2sql.ConvertWithResult(ast, cel2sql.WithSchemas(schemas), cel2sql.WithParameters("region"))
+	require.NoError(t, err)
+	assert.Equal(t, want, plan.Result())
+}
+
+func TestCompileInvalid(t *testing.T) {
+	env, err := cel.NewEnv(cel.Variable("name", cel.StringType))
+	require.NoError(t, err)
+	ast, issues := env.Compile(`name.matches("(?=a)")`)
+	require.NoError(t, issues.Err())
+
+	_, err = cel2sql.Compile(ast, cel2sql.WithStrictRegex())
+	require.ErrorIs(t, err, cel2sql.ErrUnsupportedRegex)
+}
@@ -0,0 +1,90 @@
+package cel2sql
+
+import (
+	"sync"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// maxPooledSQL bounds the buffer kept by a pooled converter, so that one huge expression does
+// not pin its memory for the lifetime of the pool
+const maxPooledSQL = 64 << 10
+
+// converterPool reuses converters and their buffers across conversions
+var converterPool = sync.Pool{
+	New: func() any { return new(converter) },
+}
+
+// newConverter takes a converter from the pool for the given type map
+func newConverter(typeMap map[int64]*exprpb.Type) *converter {
+	con := converterPool.Get().(*converter)
+	con.typeMap = typeMap
+	return con
+}
+
+// release resets con and returns it to the pool. Nothing it returned may be referenced through
+// con afterwards; the generated SQL is copied out by bytes.Buffer.String.
+func (con *converter) release() {
+	if con.str.Cap() > maxPooledSQL {
+		return
+	}
+	con.str.Reset()
+	clear(con.literalCasts)
+	clear(con.debugFragments)
+	*con = converter{
+		str:            con.str,
+		warnings:       con.warnings[:0],
+		literalCasts:   con.literalCasts,
+		debugFragments: con.debugFragments,
+	}
+	converterPool.Put(con)
+}
+
+// estimateSQLLength guesses the length of the SQL generated for expr from its nodes and
+// literals, so that the buffer is grown once up front rather than repeatedly while writing
+func estimateSQLLength(expr *exprpb.Expr) int {
+	n := 0
+	stack := make([]*exprpb.Expr, 1, 16)
+	stack[0] = expr
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch kind := node.GetExprKind().(type) {
+		case *exprpb.Expr_ConstExpr:
+			switch c := kind.ConstExpr.GetConstantKind().(type) {
+			case *exprpb.Constant_StringValue:
+				n += len(c.StringValue) + 2
+			case *exprpb.Constant_BytesValue:
+				n += 2*len(c.BytesValue) + 8
+			default:
+				n += 8
+			}
+		case *exprpb.Expr_IdentExpr:
+			n += len(kind.IdentExpr.GetName())
+		case *exprpb.Expr_SelectExpr:
+			n += len(kind.SelectExpr.GetField()) + 4
+		case *exprpb.Expr_CallExpr:
+			n += len(kind.CallExpr.GetFunction()) + 4
+		case *exprpb.Expr_ListExpr:
+			n += 8 + 2*len(kind.ListExpr.GetElements())
+		case *exprpb.Expr_ComprehensionExpr:
+			// EXISTS (SELECT 1 FROM UNNEST(...) AS x WHERE ...) and the like, written from the
+			// range and the loop step; the rest is accumulator plumbing
+			comp := kind.ComprehensionExpr
+			n += 48 + len(comp.GetIterVar())
+			stack = append(stack, comp.GetIterRange(), comp.GetLoopStep())
+			continue
+		}
+		stack = appendChildExprs(stack, node)
+	}
+	return n
+}
+
+// growBuffer grows the buffer for the SQL estimated for expr, at most up to MaxSQLLength
+func (con *converter) growBuffer(expr *exprpb.Expr) {
+	n := estimateSQLLength(expr)
+	if limit := con.opts.limits.MaxSQLLength; limit > 0 && n > limit {
+		n = limit
+	}
+	con.str.Grow(n)
+}
@@ -0,0 +1,111 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertReusesNoState(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("age", cel.IntType),
+		cel.Variable("min_age", cel.IntType),
+	)
+	require.NoError(t, err)
+	compile := func(source string) *cel.Ast {
+		ast, issues := env.Compile(source)
+		require.NoError(t, issues.Err())
+		return ast
+	}
+
+	// SQL and parameters returned earlier must survive later conversions
+	first, firstParams, err := cel2sql.ConvertWithParameters(compile(`age >= min_age && name == "a"`),
+		cel2sql.WithParameters("min_age"), cel2sql.WithLiteralPlaceholders())
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		_, err := cel2sql.Convert(compile(`name.startsWith("b") || age < 3`))
+		require.NoError(t, err)
+		_, err = cel2sql.Convert(compile(`name.matches("(")`))
+		require.Error(t, err)
+	}
+	assert.Equal(t, "age >= $1 AND name = $2", first)
+	assert.Equal(t, []string{"min_age", ""}, firstParams)
+
+	// Options, parameters and warnings of earlier conversions must not leak into later ones
+	sql, params, err := cel2sql.ConvertWithParameters(compile(`age >= min_age`))
+	require.NoError(t, err)
+	assert.Equal(t, "age >= min_age", sql)
+	assert.Empty(t, params)
+	_, warnings, err := cel2sql.ConvertWithWarnings(compile(`name.matches("(?m)^ab")`))
+	require.NoError(t, err)
+	require.NotEmpty(t, warnings)
+	_, warnings, err = cel2sql.ConvertWithWarnings(compile(`age > 1`))
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func BenchmarkConvert(b *testing.B) {
+	schemas := map[string]pg.Schema{
+		"users": {
+			{Name: "name", Type: "text", NotNull: true},
+			{Name: "age", Type: "integer"},
+			{Name: "email", Type: "text"},
+			{Name: "created_at", Type: "timestamptz"},
+			{Name: "tags", Type: "text", Repeated: true},
+			{Name: "preferences", Type: "jsonb"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("user", cel.ObjectType("users")),
+	)
+	require.NoError(b, err)
+
+	benchmarks := []struct {
+		name   string
+		source string
+		opts   []cel2sql.ConvertOption
+	}{
+		{
+			name:   "comparisons",
+			source: `user.name == "Alice" && user.age >= 18 && user.age < 65 || user.email.endsWith("@example.com")`,
+		},
+		{
+			name:   "functions",
+			source: `user.name.startsWith("A") && user.created_at > timestamp("2024-01-01T00:00:00Z") && size(user.tags) > 2`,
+		},
+		{
+			name:   "comprehension",
+			source: `user.tags.exists(t, t == "admin") && user.preferences.theme == "dark"`,
+		},
+		{
+			name: "nested_comprehensions",
+			source: `user.tags.exists(t, t.startsWith("admin") && t != "admin-readonly") &&
+				user.tags.all(t, t.size() < 64 && !t.contains(" ")) &&
+				user.tags.filter(t, t.endsWith("-team")).map(t, t + "-member").exists(m, m in ["core-team-member", "infra-team-member"])`,
+		},
+		{
+			name:   "schemas",
+			source: `user.name == "Alice" && user.age != 30 && user.email != "x"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithSchemas(schemas)},
+		},
+	}
+	for _, bm := range benchmarks {
+		ast, issues := env.Compile(bm.source)
+		require.NoError(b, issues.Err())
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := cel2sql.Convert(ast, bm.opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
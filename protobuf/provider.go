@@ -0,0 +1,253 @@
+// Package protobuf provides a CEL type provider for tables whose rows are described by protobuf
+// messages, so that services whose API types are protos can compile CEL written against proto
+// field names into SQL against the underlying tables. Fields map to columns of the same name,
+// unless renamed with WithColumnNames or a custom field option, see WithColumnNameOption; pass
+// the provider's ColumnName to cel2sql.WithFieldNameMapper to apply the mapping in SQL.
+package protobuf
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TypeProvider interface for protobuf type providers. A TypeProvider is immutable and safe for
+// concurrent use.
+type TypeProvider interface {
+	types.Provider
+	// ColumnName returns the column of a field of a table or nested message type, and has the
+	// signature of cel2sql.FieldNameMapper.
+	ColumnName(table, field string) string
+	// Tables returns the CEL type names of the tables, sorted.
+	Tables() []string
+}
+
+// Option configures a type provider created with NewTypeProvider.
+type Option func(*typeProvider)
+
+// WithColumnNames maps fields of a table, or of a nested message type such as
+// "orders.shipping", to columns, by proto field name. Fields that are not mapped keep their
+// name.
+func WithColumnNames(table string, columns map[string]string) Option {
+	return func(p *typeProvider) {
+		if p.columns[table] == nil {
+			p.columns[table] = make(map[string]string, len(columns))
+		}
+		for field, column := range columns {
+			p.columns[table][field] = column
+		}
+	}
+}
+
+// WithColumnNameOption reads the columns of fields from a string field option, such as
+//
+//	extend google.protobuf.FieldOptions {
+//	  string column = 50000;
+//	}
+//
+//	message Order {
+//	  string customer_id = 1 [(column) = "cust_id"];
+//	}
+//
+// Columns mapped with WithColumnNames take precedence.
+func WithColumnNameOption(option protoreflect.ExtensionType) Option {
+	return func(p *typeProvider) {
+		p.columnOption = option
+	}
+}
+
+type typeProvider struct {
+	tables       map[string]protoreflect.MessageDescriptor
+	columns      map[string]map[string]string // by table and field name
+	columnOption protoreflect.ExtensionType
+}
+
+// NewTypeProvider creates a new type provider whose tables, by CEL type name, have the fields of
+// the given messages. Singular message fields are nested object types, e.g. "orders.shipping",
+// repeated fields lists and map fields maps. Well-known types map to their CEL equivalents.
+func NewTypeProvider(tables map[string]protoreflect.MessageDescriptor, opts ...Option) TypeProvider {
+	p := &typeProvider{
+		tables:  make(map[string]protoreflect.MessageDescriptor, len(tables)),
+		columns: make(map[string]map[string]string),
+	}
+	for tableName, message := range tables {
+		p.tables[tableName] = message
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *typeProvider) Tables() []string {
+	return slices.Sorted(maps.Keys(p.tables))
+}
+
+func (p *typeProvider) ColumnName(table, field string) string {
+	if column, found := p.columns[table][field]; found {
+		return column
+	}
+	if p.columnOption == nil {
+		return field
+	}
+	message, found := p.findMessage(table)
+	if !found {
+		return field
+	}
+	fd := message.Fields().ByName(protoreflect.Name(field))
+	if fd == nil || fd.Options() == nil || !proto.HasExtension(fd.Options(), p.columnOption) {
+		return field
+	}
+	if column, ok := proto.GetExtension(fd.Options(), p.columnOption).(string); ok && column != "" {
+		return column
+	}
+	return field
+}
+
+// findMessage finds the message of a table or nested message type. The longest table name
+// prefixing the type name is used, and the rest of it is the path of message fields below the
+// table.
+func (p *typeProvider) findMessage(typeName string) (protoreflect.MessageDescriptor, bool) {
+	typeNames := strings.Split(typeName, ".")
+	for i := len(typeNames); i > 0; i-- {
+		message, found := p.tables[strings.Join(typeNames[:i], ".")]
+		if !found {
+			continue
+		}
+		for _, name := range typeNames[i:] {
+			fd := message.Fields().ByName(protoreflect.Name(name))
+			if fd == nil || fd.IsMap() || fd.Message() == nil || wellKnownType(fd.Message()) != nil {
+				return nil, false
+			}
+			message = fd.Message()
+		}
+		return message, true
+	}
+	return nil, false
+}
+
+func (p *typeProvider) EnumValue(enumName string) ref.Val {
+	return types.NewErr("unknown enum name '%s'", enumName)
+}
+
+func (p *typeProvider) FindIdent(_ string) (ref.Val, bool) {
+	return nil, false
+}
+
+func (p *typeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, found := p.findMessage(structType); !found {
+		return nil, false
+	}
+	return types.NewObjectType(structType), true
+}
+
+func (p *typeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	message, found := p.findMessage(structType)
+	if !found {
+		return nil, false
+	}
+	fields := message.Fields()
+	fieldNames := make([]string, fields.Len())
+	for i := range fieldNames {
+		fieldNames[i] = string(fields.Get(i).Name())
+	}
+	return fieldNames, true
+}
+
+func (p *typeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	message, found := p.findMessage(structType)
+	if !found {
+		return nil, false
+	}
+	fd := message.Fields().ByName(protoreflect.Name(fieldName))
+	if fd == nil {
+		return nil, false
+	}
+
+	var exprType *exprpb.Type
+	switch {
+	case fd.IsMap():
+		exprType = decls.NewMapType(kindType(fd.MapKey()), kindType(fd.MapValue()))
+	case fd.Message() != nil && wellKnownType(fd.Message()) == nil:
+		exprType = decls.NewObjectType(structType + "." + fieldName)
+	default:
+		exprType = kindType(fd)
+	}
+	if fd.IsList() {
+		exprType = decls.NewListType(exprType)
+	}
+	celType, err := types.ExprTypeToType(exprType)
+	if err != nil {
+		return nil, false
+	}
+	return &types.FieldType{Type: celType}, true
+}
+
+func (p *typeProvider) NewValue(structType string, _ map[string]ref.Val) ref.Val {
+	return types.NewErr("unknown type '%s'", structType)
+}
+
+var _ types.Provider = new(typeProvider)
+
+// kindType maps a scalar or well-known field type to a CEL type. Enums are ints, as in CEL, and
+// other messages, such as map values, which cannot be nested object types, are dynamic.
+func kindType(fd protoreflect.FieldDescriptor) *exprpb.Type {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return decls.Bool
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind, protoreflect.EnumKind:
+		return decls.Int
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return decls.Uint
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return decls.Double
+	case protoreflect.BytesKind:
+		return decls.Bytes
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if t := wellKnownType(fd.Message()); t != nil {
+			return t
+		}
+		return decls.Dyn
+	default:
+		return decls.String
+	}
+}
+
+// wellKnownType maps a well-known message type to its CEL type, or returns nil for other
+// messages
+func wellKnownType(message protoreflect.MessageDescriptor) *exprpb.Type {
+	switch message.FullName() {
+	case "google.protobuf.Timestamp":
+		return decls.Timestamp
+	case "google.protobuf.Duration":
+		return decls.Duration
+	case "google.protobuf.Struct":
+		return decls.NewMapType(decls.String, decls.Dyn)
+	case "google.protobuf.Value", "google.protobuf.Any":
+		return decls.Dyn
+	case "google.protobuf.ListValue":
+		return decls.NewListType(decls.Dyn)
+	case "google.protobuf.BoolValue":
+		return decls.Bool
+	case "google.protobuf.Int32Value", "google.protobuf.Int64Value":
+		return decls.Int
+	case "google.protobuf.UInt32Value", "google.protobuf.UInt64Value":
+		return decls.Uint
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return decls.Double
+	case "google.protobuf.StringValue":
+		return decls.String
+	case "google.protobuf.BytesValue":
+		return decls.Bytes
+	default:
+		return nil
+	}
+}
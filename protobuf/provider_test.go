@@ -0,0 +1,222 @@
+package protobuf_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/protobuf"
+)
+
+// newOrderDescriptor builds the descriptor of
+//
+//	extend google.protobuf.FieldOptions { string column = 50000; }
+//	message Order {
+//	  string id = 1;
+//	  string customer_id = 2 [(column) = "cust_id"];
+//	  google.protobuf.Timestamp created_at = 3;
+//	  repeated string tags = 4;
+//	  Address shipping = 5;
+//	  map<string, string> labels = 6;
+//	  google.protobuf.Int64Value priority = 7;
+//	  uint32 quantity = 8;
+//	  Status status = 9;
+//	  message Address { string city = 1; string postal_code = 2; }
+//	  enum Status { STATUS_UNSPECIFIED = 0; SHIPPED = 1; }
+//	}
+func newOrderDescriptor(t *testing.T) (protoreflect.MessageDescriptor, protoreflect.ExtensionType) {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   typ.Enum(),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	const (
+		stringType  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		messageType = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+
+	column := field("column", 50000, stringType, "")
+	column.Extendee = proto.String(".google.protobuf.FieldOptions")
+	customerID := field("customer_id", 2, stringType, "")
+	tags := field("tags", 4, stringType, "")
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	labels := field("labels", 6, messageType, ".shop.Order.LabelsEntry")
+	labels.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("shop/order.proto"),
+		Package:    proto.String("shop"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/descriptor.proto", "google/protobuf/timestamp.proto", "google/protobuf/wrappers.proto"},
+		Extension:  []*descriptorpb.FieldDescriptorProto{column},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Order"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, stringType, ""),
+				customerID,
+				field("created_at", 3, messageType, ".google.protobuf.Timestamp"),
+				tags,
+				field("shipping", 5, messageType, ".shop.Order.Address"),
+				labels,
+				field("priority", 7, messageType, ".google.protobuf.Int64Value"),
+				field("quantity", 8, descriptorpb.FieldDescriptorProto_TYPE_UINT32, ""),
+				field("status", 9, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".shop.Order.Status"),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name:  proto.String("Address"),
+					Field: []*descriptorpb.FieldDescriptorProto{field("city", 1, stringType, ""), field("postal_code", 2, stringType, "")},
+				},
+				{
+					Name:    proto.String("LabelsEntry"),
+					Field:   []*descriptorpb.FieldDescriptorProto{field("key", 1, stringType, ""), field("value", 2, stringType, "")},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				},
+			},
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Status"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("STATUS_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("SHIPPED"), Number: proto.Int32(1)},
+				},
+			}},
+		}},
+	}
+
+	// The option is set once its extension type exists
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	columnOption := dynamicpb.NewExtensionType(fd.Extensions().Get(0))
+	customerID.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(customerID.Options, columnOption, "cust_id")
+
+	fd, err = protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return fd.Messages().ByName("Order"), dynamicpb.NewExtensionType(fd.Extensions().Get(0))
+}
+
+func TestTypeProvider_FindStructFieldType(t *testing.T) {
+	order, _ := newOrderDescriptor(t)
+	provider := protobuf.NewTypeProvider(map[string]protoreflect.MessageDescriptor{"orders": order})
+
+	fieldNames, found := provider.FindStructFieldNames("orders")
+	require.True(t, found)
+	assert.Equal(t, []string{"id", "customer_id", "created_at", "tags", "shipping", "labels", "priority", "quantity", "status"}, fieldNames)
+
+	tests := []struct {
+		structType string
+		fieldName  string
+		wantType   *types.Type
+	}{
+		{structType: "orders", fieldName: "id", wantType: types.StringType},
+		{structType: "orders", fieldName: "created_at", wantType: types.TimestampType},
+		{structType: "orders", fieldName: "tags", wantType: types.NewListType(types.StringType)},
+		{structType: "orders", fieldName: "shipping", wantType: types.NewObjectType("orders.shipping")},
+		{structType: "orders", fieldName: "labels", wantType: types.NewMapType(types.StringType, types.StringType)},
+		{structType: "orders", fieldName: "priority", wantType: types.IntType},
+		{structType: "orders", fieldName: "quantity", wantType: types.UintType},
+		{structType: "orders", fieldName: "status", wantType: types.IntType},
+		{structType: "orders.shipping", fieldName: "postal_code", wantType: types.StringType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.structType+"."+tt.fieldName, func(t *testing.T) {
+			got, found := provider.FindStructFieldType(tt.structType, tt.fieldName)
+			require.True(t, found)
+			assert.Equal(t, tt.wantType.String(), got.Type.String())
+		})
+	}
+
+	_, found = provider.FindStructType("orders.created_at")
+	assert.False(t, found, "well-known types are not nested object types")
+	_, found = provider.FindStructFieldType("orders", "missing")
+	assert.False(t, found)
+}
+
+func TestTypeProvider_ColumnName(t *testing.T) {
+	order, columnOption := newOrderDescriptor(t)
+
+	tests := []struct {
+		name       string
+		opts       []protobuf.Option
+		table      string
+		field      string
+		wantColumn string
+	}{
+		{name: "unmapped", table: "orders", field: "customer_id", wantColumn: "customer_id"},
+		{
+			name:       "option",
+			opts:       []protobuf.Option{protobuf.WithColumnNameOption(columnOption)},
+			table:      "orders",
+			field:      "customer_id",
+			wantColumn: "cust_id",
+		},
+		{
+			name: "mapping over option",
+			opts: []protobuf.Option{
+				protobuf.WithColumnNameOption(columnOption),
+				protobuf.WithColumnNames("orders", map[string]string{"customer_id": "buyer_id"}),
+			},
+			table:      "orders",
+			field:      "customer_id",
+			wantColumn: "buyer_id",
+		},
+		{
+			name:       "nested mapping",
+			opts:       []protobuf.Option{protobuf.WithColumnNames("orders.shipping", map[string]string{"postal_code": "zip"})},
+			table:      "orders.shipping",
+			field:      "postal_code",
+			wantColumn: "zip",
+		},
+		{
+			name:       "option without value",
+			opts:       []protobuf.Option{protobuf.WithColumnNameOption(columnOption)},
+			table:      "orders",
+			field:      "id",
+			wantColumn: "id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := protobuf.NewTypeProvider(map[string]protoreflect.MessageDescriptor{"orders": order}, tt.opts...)
+			assert.Equal(t, tt.wantColumn, provider.ColumnName(tt.table, tt.field))
+		})
+	}
+}
+
+func TestTypeProvider_Convert(t *testing.T) {
+	order, columnOption := newOrderDescriptor(t)
+	provider := protobuf.NewTypeProvider(map[string]protoreflect.MessageDescriptor{"orders": order},
+		protobuf.WithColumnNameOption(columnOption),
+		protobuf.WithColumnNames("orders", map[string]string{"created_at": "created"}))
+
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("order", cel.ObjectType("orders")),
+	)
+	require.NoError(t, err)
+	ast, issues := env.Compile(`order.customer_id == "c-1" && order.created_at > timestamp("2025-01-01T00:00:00Z") && "gift" in order.tags`)
+	require.NoError(t, issues.Err())
+
+	got, err := cel2sql.Convert(ast, cel2sql.WithFieldNameMapper(provider.ColumnName))
+	require.NoError(t, err)
+	assert.Equal(t, "order.cust_id = 'c-1' AND order.created > CAST('2025-01-01T00:00:00Z' AS TIMESTAMP WITH TIME ZONE) AND 'gift' = ANY(order.tags)", got)
+}
@@ -0,0 +1,346 @@
+package cel2sql
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// checkRegexConvertible parses an RE2 pattern and reports constructs that cannot be expressed
+// as a PostgreSQL POSIX regular expression. Patterns that RE2 itself rejects, such as
+// lookarounds and backreferences, fail to parse.
+func checkRegexConvertible(pattern string) error {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnsupportedRegex, err)
+	}
+	for i := 0; i < len(pattern)-1; i++ {
+		if pattern[i] != '\\' {
+			continue
+		}
+		if next := pattern[i+1]; next == 'p' || next == 'P' {
+			return fmt.Errorf("%w: %q: Unicode character classes are not supported", ErrUnsupportedRegex, pattern)
+		}
+		i++ // skip the escaped character
+	}
+
+	stack := []*syntax.Regexp{re}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node.Op == syntax.OpCapture && node.Name != "" {
+			return fmt.Errorf("%w: %q: named group %s is not supported", ErrUnsupportedRegex, pattern, node.Name)
+		}
+		stack = append(stack, node.Sub...)
+	}
+	return nil
+}
+
+// posixRegex is an RE2 pattern converted for PostgreSQL
+type posixRegex struct {
+	pattern         string
+	caseInsensitive bool     // match with ~* instead of ~
+	approximations  []string // constructs PostgreSQL can only approximate
+}
+
+// leadingCaseInsensitiveFlag matches a flag group at the start of a pattern that enables (?i)
+var leadingCaseInsensitiveFlag = regexp.MustCompile(`^\(\?([a-zA-Z]*)i([a-zA-Z]*)\)`)
+
+// convertRE2ToPOSIX translates an RE2 pattern into a PostgreSQL regular expression by parsing
+// it into a syntax tree and rendering each node. A leading (?i) flag is dropped in favour of the
+// case-insensitive ~* operator when the whole pattern is case-insensitive; otherwise
+// case-insensitive literals are expanded into bracket expressions. Constructs that PostgreSQL
+// can only approximate, such as multi-line anchors, are reported as approximations.
+func convertRE2ToPOSIX(re2Pattern string) (posixRegex, error) {
+	re, err := syntax.Parse(re2Pattern, syntax.Perl)
+	if err != nil {
+		return posixRegex{}, fmt.Errorf("%w: %w", ErrUnsupportedRegex, err)
+	}
+	t := &regexTranslator{
+		caseInsensitive: leadingCaseInsensitiveFlag.MatchString(re2Pattern) && isCaseInsensitive(re),
+	}
+	if err := t.write(re); err != nil {
+		return posixRegex{}, fmt.Errorf("%w: %q: %w", ErrUnsupportedRegex, re2Pattern, err)
+	}
+	return posixRegex{
+		pattern:         t.str.String(),
+		caseInsensitive: t.caseInsensitive,
+		approximations:  t.approximations,
+	}, nil
+}
+
+// isCaseInsensitive checks if a parsed pattern matches regardless of case: every literal that
+// has case variants folds case, and every character class holds all the case variants of its
+// characters. Flags such as (?-i) make parts of a pattern case-sensitive again.
+func isCaseInsensitive(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return true
+		}
+		for _, r := range re.Rune {
+			if unicode.SimpleFold(r) != r {
+				return false
+			}
+		}
+	case syntax.OpCharClass:
+		return isFoldClosed(re.Rune)
+	}
+	for _, sub := range re.Sub {
+		if !isCaseInsensitive(sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFoldClosed checks if a character class holds all the case variants of its characters
+func isFoldClosed(ranges []rune) bool {
+	for i := 0; i < len(ranges); i += 2 {
+		for _, cr := range unicode.CaseRanges {
+			lo, hi := max(ranges[i], rune(cr.Lo)), min(ranges[i+1], rune(cr.Hi))
+			for r := lo; r <= hi; r++ {
+				for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+					if !classContains(ranges, f) {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// classContains checks if a character class contains r
+func classContains(ranges []rune, r rune) bool {
+	for i := 0; i < len(ranges); i += 2 {
+		if ranges[i] <= r && r <= ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// regexTranslator renders an RE2 syntax tree as a PostgreSQL regular expression
+type regexTranslator struct {
+	str             strings.Builder
+	approximations  []string
+	caseInsensitive bool // matched with ~*, so literals need not be expanded into case variants
+}
+
+func (t *regexTranslator) write(re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return nil
+	case syntax.OpLiteral:
+		foldCase := re.Flags&syntax.FoldCase != 0
+		for _, r := range re.Rune {
+			if foldCase && t.caseInsensitive {
+				// Folded literals hold any one of their case variants
+				t.writeLiteralRune(unicode.ToLower(r), false)
+				continue
+			}
+			t.writeLiteralRune(r, foldCase)
+		}
+		return nil
+	case syntax.OpCharClass:
+		t.writeCharClass(re.Rune)
+		return nil
+	case syntax.OpAnyCharNotNL:
+		// PostgreSQL's . matches newlines, RE2's only with the s flag
+		t.str.WriteString(`[^\n]`)
+		return nil
+	case syntax.OpAnyChar:
+		t.str.WriteString(".")
+		return nil
+	case syntax.OpBeginLine:
+		t.approximations = append(t.approximations, "multi-line ^ is matched at the start of the value only")
+		t.str.WriteString("^")
+		return nil
+	case syntax.OpEndLine:
+		t.approximations = append(t.approximations, "multi-line $ is matched at the end of the value only")
+		t.str.WriteString("$")
+		return nil
+	case syntax.OpBeginText:
+		t.str.WriteString("^")
+		return nil
+	case syntax.OpEndText:
+		t.str.WriteString("$")
+		return nil
+	case syntax.OpWordBoundary:
+		t.str.WriteString(`\y`)
+		return nil
+	case syntax.OpNoWordBoundary:
+		t.str.WriteString(`\Y`)
+		return nil
+	case syntax.OpCapture:
+		// Group names have no meaning in a boolean match and are dropped
+		t.str.WriteString("(")
+		if err := t.write(re.Sub[0]); err != nil {
+			return err
+		}
+		t.str.WriteString(")")
+		return nil
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		if err := t.writeAtom(re.Sub[0]); err != nil {
+			return err
+		}
+		switch re.Op {
+		case syntax.OpStar:
+			t.str.WriteString("*")
+		case syntax.OpPlus:
+			t.str.WriteString("+")
+		case syntax.OpQuest:
+			t.str.WriteString("?")
+		default:
+			t.str.WriteString("{")
+			t.str.WriteString(strconv.Itoa(re.Min))
+			if re.Max != re.Min {
+				t.str.WriteString(",")
+				if re.Max >= 0 {
+					t.str.WriteString(strconv.Itoa(re.Max))
+				}
+			}
+			t.str.WriteString("}")
+		}
+		if re.Flags&syntax.NonGreedy != 0 {
+			t.str.WriteString("?")
+		}
+		return nil
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpAlternate {
+				if err := t.writeGroup(sub); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := t.write(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	case syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			if i > 0 {
+				t.str.WriteString("|")
+			}
+			if err := t.write(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported regular expression construct %s", re)
+}
+
+// writeAtom writes re so that a following quantifier applies to all of it
+func (t *regexTranslator) writeAtom(re *syntax.Regexp) error {
+	switch {
+	case re.Op == syntax.OpLiteral && len(re.Rune) == 1,
+		re.Op == syntax.OpCharClass, re.Op == syntax.OpAnyChar, re.Op == syntax.OpAnyCharNotNL,
+		re.Op == syntax.OpCapture:
+		return t.write(re)
+	}
+	return t.writeGroup(re)
+}
+
+// writeGroup writes re inside a non-capturing group
+func (t *regexTranslator) writeGroup(re *syntax.Regexp) error {
+	t.str.WriteString("(?:")
+	if err := t.write(re); err != nil {
+		return err
+	}
+	t.str.WriteString(")")
+	return nil
+}
+
+// writeLiteralRune writes a literal character, escaping regex metacharacters. Case-insensitive
+// literals are written as a bracket expression of all their case variants.
+func (t *regexTranslator) writeLiteralRune(r rune, foldCase bool) {
+	if foldCase {
+		variants := []rune{r}
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			variants = append(variants, f)
+		}
+		if len(variants) > 1 {
+			t.str.WriteString("[")
+			for _, v := range variants {
+				t.writeClassRune(v)
+			}
+			t.str.WriteString("]")
+			return
+		}
+	}
+	if strings.ContainsRune(`\.+*?()|[]{}^$`, r) {
+		t.str.WriteString(`\`)
+		t.str.WriteRune(r)
+		return
+	}
+	t.writeRune(r)
+}
+
+// writeCharClass writes a bracket expression for the given rune ranges, negated when the
+// ranges cover both ends of the Unicode range. A class of every character is written as ".".
+func (t *regexTranslator) writeCharClass(ranges []rune) {
+	if len(ranges) == 2 && ranges[0] == 0 && ranges[1] == unicode.MaxRune {
+		t.str.WriteString(".")
+		return
+	}
+	t.str.WriteString("[")
+	if len(ranges) > 0 && ranges[0] == 0 && ranges[len(ranges)-1] == unicode.MaxRune {
+		t.str.WriteString("^")
+		negated := make([]rune, 0, len(ranges))
+		for i := 1; i < len(ranges)-1; i += 2 {
+			negated = append(negated, ranges[i]+1, ranges[i+1]-1)
+		}
+		ranges = negated
+	}
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		t.writeClassRune(lo)
+		if hi > lo+1 {
+			t.str.WriteString("-")
+		}
+		if hi > lo {
+			t.writeClassRune(hi)
+		}
+	}
+	t.str.WriteString("]")
+}
+
+// writeClassRune writes a character inside a bracket expression, escaping the characters
+// that are special there.
+func (t *regexTranslator) writeClassRune(r rune) {
+	if strings.ContainsRune(`\]^-[`, r) {
+		t.str.WriteString(`\`)
+		t.str.WriteRune(r)
+		return
+	}
+	t.writeRune(r)
+}
+
+// writeRune writes a character, using escapes for non-printable characters
+func (t *regexTranslator) writeRune(r rune) {
+	switch {
+	case r == '\t':
+		t.str.WriteString(`\t`)
+	case r == '\n':
+		t.str.WriteString(`\n`)
+	case r == '\f':
+		t.str.WriteString(`\f`)
+	case r == '\r':
+		t.str.WriteString(`\r`)
+	case r == '\v':
+		t.str.WriteString(`\v`)
+	case unicode.IsPrint(r):
+		t.str.WriteRune(r)
+	case r <= 0xFFFF:
+		fmt.Fprintf(&t.str, `\u%04X`, r)
+	default:
+		fmt.Fprintf(&t.str, `\U%08X`, r)
+	}
+}
@@ -0,0 +1,213 @@
+package cel2sql_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertStrictRegex(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("pattern", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "convertible",
+			source: `name.matches("^\\d{3}-[a-z]+$")`,
+			want:   "name ~ '^[0-9]{3}-[a-z]+$'",
+		},
+		{
+			name:   "escaped_backslash_before_p",
+			source: `name.matches("a\\\\p")`,
+			want:   `name ~ 'a\\p'`,
+		},
+		{
+			name:    "lookahead",
+			source:  `name.matches("a(?=b)")`,
+			wantErr: "invalid or unsupported Perl syntax",
+		},
+		{
+			name:    "named_group",
+			source:  `name.matches("(?P<year>\\d{4})")`,
+			wantErr: "named group year is not supported",
+		},
+		{
+			name:   "case_insensitive_flag",
+			source: `name.matches("(?i)abc")`,
+			want:   "name ~* 'abc'",
+		},
+		{
+			name:    "multiline_flag",
+			source:  `name.matches("(?m)^abc$")`,
+			wantErr: "multi-line ^ is matched at the start of the value only",
+		},
+		{
+			name:    "unicode_class",
+			source:  `name.matches("\\pL+")`,
+			wantErr: "Unicode character classes are not supported",
+		},
+		{
+			name:    "non_literal",
+			source:  `name.matches(pattern)`,
+			wantErr: "pattern must be a string literal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithStrictRegex())
+			validateErr := cel2sql.Validate(ast, cel2sql.WithStrictRegex())
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, cel2sql.ErrUnsupportedRegex)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.ErrorIs(t, validateErr, cel2sql.ErrUnsupportedRegex)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, validateErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertRegexTranslation(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    string
+	}{
+		{name: "literal_metacharacters", pattern: `a\.b\*c`, want: `a\.b\*c`},
+		{name: "quoted_literal", pattern: `\Qa.b*c\E`, want: `a\.b\*c`},
+		{name: "class_escapes", pattern: `[\]\-a\\]`, want: `[\-\\\]a]`},
+		{name: "negated_class", pattern: `[^a-z]`, want: `[^a-z]`},
+		{name: "negated_digit", pattern: `\D+`, want: `[^0-9]+`},
+		{name: "whitespace", pattern: `\s`, want: `[\t\n\f\r ]`},
+		{name: "non_capturing_group", pattern: `(?:ab)+c`, want: `(?:ab)+c`},
+		{name: "capturing_group", pattern: `(ab)+`, want: `(ab)+`},
+		{name: "named_group", pattern: `(?P<year>\d{4})`, want: `([0-9]{4})`},
+		{name: "alternation", pattern: `^(cat|dog)$`, want: `^(cat|dog)$`},
+		{name: "alternation_in_concat", pattern: `x(?:a|bc)y`, want: `x(?:a|bc)y`},
+		{name: "repeat_range", pattern: `a{2,5}b{3,}`, want: `a{2,5}b{3,}`},
+		{name: "non_greedy", pattern: `a+?b*?`, want: `a+?b*?`},
+		{name: "no_word_boundary", pattern: `\Bx`, want: `\Yx`},
+		{name: "case_insensitive_group", pattern: `(?i:ab)c`, want: `[Aa][Bb]c`},
+		{name: "control_characters", pattern: "a\tb", want: `a\tb`},
+		{name: "end_of_text", pattern: `abc\z`, want: `abc$`},
+		{name: "any_char_but_newline", pattern: `a.b`, want: `a[^\n]b`},
+		{name: "any_char", pattern: `(?s)a.b`, want: `a.b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(`name.matches(` + strconv.Quote(tt.pattern) + `)`)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast)
+			require.NoError(t, err)
+			assert.Equal(t, "name ~ '"+tt.want+"'", got)
+		})
+	}
+}
+
+func TestConvertInvalidRegex(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	ast, issues := env.Compile(`name.matches("a(?=b)")`)
+	require.Empty(t, issues)
+
+	_, err = cel2sql.Convert(ast)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, cel2sql.ErrUnsupportedRegex)
+}
+
+func TestConvertCaseInsensitiveRegex(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "leading_flag",
+			source: `name.matches("(?i)urgent|priority")`,
+			want:   "name ~* 'urgent|priority'",
+		},
+		{
+			name:   "function_style",
+			source: `matches(name, "(?i)^abc$")`,
+			want:   "name ~* '^abc$'",
+		},
+		{
+			name:   "combined_flags",
+			source: `name.matches("(?im)^abc")`,
+			want:   "name ~* '^abc'",
+		},
+		{
+			name:   "negated",
+			source: `!name.matches("(?i)abc")`,
+			want:   "NOT name ~* 'abc'",
+		},
+		{
+			name:   "flag_only",
+			source: `name.matches("(?i)")`,
+			want:   "name ~* ''",
+		},
+		{
+			name:   "scoped_group",
+			source: `name.matches("a(?i)b")`,
+			want:   "name ~ 'a[Bb]'",
+		},
+		{
+			name:   "flag_turned_off",
+			source: `name.matches("(?i)a(?-i)b")`,
+			want:   "name ~ '[Aa]b'",
+		},
+		{
+			name:   "flag_turned_off_for_class",
+			source: `name.matches("(?i)a(?-i:[b-d])")`,
+			want:   "name ~ '[Aa][b-d]'",
+		},
+		{
+			name:   "flag_with_class",
+			source: `name.matches("(?i)A[b-d]1")`,
+			want:   "name ~* 'a[B-Db-d]1'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.Convert(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package cel2sql
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// Result is the SQL generated for an expression together with metadata about it, for
+// authorization checks and observability.
+type Result struct {
+	SQL        string
+	Tables     []string  // CEL type names of the referenced tables, sorted
+	Columns    []string  // referenced columns as "table.column", or bare variable names, sorted
+	JSONPaths  []string  // JSON paths read from JSON columns, e.g. "users.preferences.theme", sorted
+	Subqueries int       // number of subqueries emitted for comprehensions and JSON array membership
+	Cost       Cost      // static cost estimate, see Estimate
+	Warnings   []Warning // lossy conversions, see ConvertWithWarnings
+	Parameters []string  // variable bound to each placeholder, see ConvertWithParameters
+}
+
+// ConvertWithResult converts a CEL AST like Convert and returns the SQL with metadata about the
+// tables, columns and JSON paths it references.
+func ConvertWithResult(ast *cel.Ast, opts ...ConvertOption) (*Result, error) {
+	con, expr, err := convert(ast, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer con.release()
+	return con.result(expr), nil
+}
+
+// result collects the SQL written for expr and its metadata
+func (con *converter) result(expr *exprpb.Expr) *Result {
+	result := &Result{
+		SQL:        con.str.String(),
+		Subqueries: con.subqueries,
+		Cost:       con.estimate(expr),
+		Warnings:   con.resolveWarnings(),
+		Parameters: con.parameters,
+	}
+	result.Tables, result.Columns, result.JSONPaths = con.collectReferences(expr)
+	return result
+}
+
+// ReferencedFields returns the columns, as "table.column" or bare variable names, and the JSON
+// paths referenced by an expression without converting it, so that index coverage and access
+// permissions can be checked up front. The result is sorted.
+func ReferencedFields(ast *cel.Ast) ([]string, error) {
+	checkedExpr, err := cel.AstToCheckedExpr(ast)
+	if err != nil {
+		return nil, err
+	}
+	con := &converter{
+		typeMap: checkedExpr.TypeMap,
+	}
+	if err := con.checkDepth(checkedExpr.Expr); err != nil {
+		return nil, err
+	}
+	_, columns, paths := con.collectReferences(checkedExpr.Expr)
+	fields := append(columns, paths...)
+	slices.Sort(fields)
+	return fields, nil
+}
+
+// collectReferences returns the sorted tables, columns and JSON paths referenced by expr
+func (con *converter) collectReferences(expr *exprpb.Expr) (tables, columns, paths []string) {
+	tableSet := make(map[string]bool)
+	columnSet := make(map[string]bool)
+	_ = con.walkColumnRefs(expr, nil, func(ref columnRef) error {
+		if ref.Table != "" {
+			tableSet[ref.Table] = true
+		}
+		columnSet[ref.String()] = true
+		return nil
+	})
+	pathSet := make(map[string]bool)
+	stack := []*exprpb.Expr{expr}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if path, ok := con.jsonPath(node); ok {
+			pathSet[path] = true
+			continue
+		}
+		stack = append(stack, childExprs(node)...)
+	}
+	return sortedKeys(tableSet), sortedKeys(columnSet), sortedKeys(pathSet)
+}
+
+// jsonPath returns the JSON path read by a field selection below a column that is not itself
+// a table, e.g. "users.preferences.theme" for users.preferences.theme.
+func (con *converter) jsonPath(expr *exprpb.Expr) (string, bool) {
+	var keys []string
+	for current := expr; ; {
+		sel := current.GetSelectExpr()
+		if sel == nil {
+			return "", false
+		}
+		if typ := con.getType(sel.GetOperand()); isMessageType(typ) {
+			if len(keys) == 0 {
+				return "", false
+			}
+			slices.Reverse(keys)
+			column := columnRef{Table: typ.GetMessageType(), Column: sel.GetField()}
+			return column.String() + "." + strings.Join(keys, "."), true
+		}
+		keys = append(keys, sel.GetField())
+		current = sel.GetOperand()
+	}
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,68 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestConvertWithResult(t *testing.T) {
+	env, _ := newColumnsTestEnv(t)
+
+	ast, issues := env.Compile(`users.name == region && users.preferences.theme == "dark" && employees.exists(e, e.age > 30)`)
+	require.Empty(t, issues)
+
+	got, err := cel2sql.ConvertWithResult(ast)
+	require.NoError(t, err)
+	assert.Equal(t, &cel2sql.Result{
+		SQL:        "users.name = region AND users.preferences->>'theme' = 'dark' AND EXISTS (SELECT 1 FROM UNNEST(employees) AS e WHERE (e).age > 30)",
+		Tables:     []string{"users"},
+		Columns:    []string{"employees", "region", "users.age", "users.name", "users.preferences"},
+		JSONPaths:  []string{"users.preferences.theme"},
+		Subqueries: 1,
+		Cost:       cel2sql.Cost{Subqueries: 1, Total: 10},
+	}, got)
+
+	ast, issues = env.Compile(`users.ssn == "x"`)
+	require.Empty(t, issues)
+	_, err = cel2sql.ConvertWithResult(ast, cel2sql.WithDeniedColumns("users.ssn"))
+	assert.EqualError(t, err, "column users.ssn is denied")
+}
+
+func TestReferencedFields(t *testing.T) {
+	env, _ := newColumnsTestEnv(t)
+
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name:   "columns_and_json_paths",
+			source: `users.name == region && users.preferences.theme == "dark" && users.preferences.layout.columns > 2`,
+			want:   []string{"region", "users.name", "users.preferences", "users.preferences.layout.columns", "users.preferences.theme"},
+		},
+		{
+			name:   "comprehension",
+			source: `employees.exists(e, e.ssn == "x" && e.tags.exists(t, t == region))`,
+			want:   []string{"employees", "region", "users.ssn", "users.tags"},
+		},
+		{
+			name:   "literals_only",
+			source: `1 + 1 == 2`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			got, err := cel2sql.ReferencedFields(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
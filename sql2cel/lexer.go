@@ -0,0 +1,127 @@
+package sql2cel
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind classifies a lexical token of a SQL expression.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+// token is a lexical token with its byte offset in the input, for error messages.
+type token struct {
+	kind  tokenKind
+	text  string
+	value string // unescaped value of string literals and quoted identifiers
+	pos   int
+}
+
+// keyword checks if the token is the given unquoted keyword, case-insensitively
+func (t token) keyword(kw string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.text, kw)
+}
+
+// operators lists the multi-character operators before their single-character prefixes
+var operators = []string{"<>", "!=", "<=", ">=", "||", "!~*", "!~", "~*", "=", "<", ">", "+", "-", "*", "/", "%", "~", "(", ")", ",", "."}
+
+// tokenize splits a SQL expression into tokens
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'':
+			value, end, err := scanQuoted(input, i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: input[i:end], value: value, pos: i})
+			i = end
+		case c == '"':
+			value, end, err := scanQuoted(input, i, '"')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenQuotedIdent, text: input[i:end], value: value, pos: i})
+			i = end
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(input) && (input[i] >= '0' && input[i] <= '9' || input[i] == '.') {
+				i++
+			}
+			i = scanExponent(input, i)
+			tokens = append(tokens, token{kind: tokenNumber, text: input[start:i], pos: start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(input) && (input[i] == '_' || unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[start:i], pos: start})
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// scanExponent scans the exponent part of a number at input[start], e.g. e3 or E-2, returning
+// its end offset, or start if there is none
+func scanExponent(input string, start int) int {
+	i := start
+	if i >= len(input) || input[i] != 'e' && input[i] != 'E' {
+		return start
+	}
+	i++
+	if i < len(input) && (input[i] == '+' || input[i] == '-') {
+		i++
+	}
+	if i >= len(input) || input[i] < '0' || input[i] > '9' {
+		return start
+	}
+	for i < len(input) && input[i] >= '0' && input[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// scanQuoted scans a quoted string or identifier starting at input[start], where a doubled
+// quote character stands for itself. It returns the unescaped value and the end offset.
+func scanQuoted(input string, start int, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		if input[i] != quote {
+			b.WriteByte(input[i])
+			continue
+		}
+		if i+1 < len(input) && input[i+1] == quote {
+			b.WriteByte(quote)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("unterminated quoted text at offset %d", start)
+}
@@ -0,0 +1,515 @@
+// Package sql2cel converts a restricted subset of PostgreSQL boolean expressions, such as the
+// WHERE clauses of legacy saved filters, into equivalent CEL expressions.
+//
+// The supported subset covers column references, string, numeric, boolean and NULL literals,
+// arithmetic, comparisons, AND, OR, NOT, IS [NOT] NULL, IS [NOT] TRUE/FALSE, [NOT] IN lists,
+// [NOT] BETWEEN, [NOT] LIKE and ILIKE, the ~, ~*, !~ and !~* regular expression operators and
+// the LENGTH function. Anything else is rejected with an error.
+package sql2cel
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CEL operator precedences, from loosest to tightest
+const (
+	precOr = iota + 1
+	precAnd
+	precRelation
+	precAdditive
+	precMultiplicative
+	precUnary
+	precPrimary
+)
+
+// celExpr is a converted CEL expression with the precedence of its outermost operator.
+type celExpr struct {
+	text string
+	prec int
+}
+
+// wrap returns the expression text, parenthesized if it binds looser than prec
+func (e celExpr) wrap(prec int) string {
+	if e.prec < prec {
+		return "(" + e.text + ")"
+	}
+	return e.text
+}
+
+// Convert converts a PostgreSQL boolean expression into a CEL expression.
+func Convert(sql string) (string, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return "", err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return "", err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return "", p.unexpected(tok)
+	}
+	return expr.text, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptKeyword consumes the next token if it is the given keyword
+func (p *parser) acceptKeyword(kw string) bool {
+	if p.peek().keyword(kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptOperator consumes the next token if it is one of the given operators
+func (p *parser) acceptOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expectOperator(op string) error {
+	if _, ok := p.acceptOperator(op); !ok {
+		return fmt.Errorf("expected %q at offset %d", op, p.peek().pos)
+	}
+	return nil
+}
+
+func (p *parser) unexpected(tok token) error {
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+}
+
+func (p *parser) parseOr() (celExpr, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return celExpr{}, err
+	}
+	for p.acceptKeyword("OR") {
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return celExpr{}, err
+		}
+		lhs = celExpr{text: lhs.wrap(precOr) + " || " + rhs.wrap(precOr+1), prec: precOr}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseAnd() (celExpr, error) {
+	lhs, err := p.parseNot()
+	if err != nil {
+		return celExpr{}, err
+	}
+	for p.acceptKeyword("AND") {
+		rhs, err := p.parseNot()
+		if err != nil {
+			return celExpr{}, err
+		}
+		lhs = celExpr{text: lhs.wrap(precAnd) + " && " + rhs.wrap(precAnd+1), prec: precAnd}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseNot() (celExpr, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return celExpr{}, err
+		}
+		return negate(operand), nil
+	}
+	return p.parsePredicate()
+}
+
+// comparisonOperators maps SQL comparison operators to CEL
+var comparisonOperators = map[string]string{
+	"=": "==", "<>": "!=", "!=": "!=", "<": "<", "<=": "<=", ">": ">", ">=": ">=",
+}
+
+func (p *parser) parsePredicate() (celExpr, error) {
+	lhs, err := p.parseAdditive()
+	if err != nil {
+		return celExpr{}, err
+	}
+	if op, ok := p.acceptOperator("=", "<>", "!=", "<", "<=", ">", ">="); ok {
+		rhs, err := p.parseAdditive()
+		if err != nil {
+			return celExpr{}, err
+		}
+		return relation(lhs, comparisonOperators[op], rhs), nil
+	}
+	if op, ok := p.acceptOperator("~", "~*", "!~", "!~*"); ok {
+		pattern, err := p.parseAdditive()
+		if err != nil {
+			return celExpr{}, err
+		}
+		if strings.HasSuffix(op, "*") {
+			lit, ok := unquote(pattern)
+			if !ok {
+				return celExpr{}, fmt.Errorf("%s requires a string literal pattern", op)
+			}
+			pattern = stringLiteral("(?i)" + lit)
+		}
+		match := call(lhs, "matches", pattern)
+		if strings.HasPrefix(op, "!") {
+			return negate(match), nil
+		}
+		return match, nil
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		tok := p.next()
+		var value string
+		switch {
+		case tok.keyword("NULL"):
+			value = "null"
+		case tok.keyword("TRUE"):
+			value = "true"
+		case tok.keyword("FALSE"):
+			value = "false"
+		default:
+			return celExpr{}, p.unexpected(tok)
+		}
+		op := "=="
+		if not {
+			op = "!="
+		}
+		return relation(lhs, op, celExpr{text: value, prec: precPrimary}), nil
+	}
+
+	not := p.acceptKeyword("NOT")
+	var result celExpr
+	switch {
+	case p.acceptKeyword("IN"):
+		if err := p.expectOperator("("); err != nil {
+			return celExpr{}, err
+		}
+		var elems []string
+		for {
+			elem, err := p.parseAdditive()
+			if err != nil {
+				return celExpr{}, err
+			}
+			elems = append(elems, elem.text)
+			if _, ok := p.acceptOperator(","); !ok {
+				break
+			}
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return celExpr{}, err
+		}
+		result = relation(lhs, "in", celExpr{text: "[" + strings.Join(elems, ", ") + "]", prec: precPrimary})
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseAdditive()
+		if err != nil {
+			return celExpr{}, err
+		}
+		if !p.acceptKeyword("AND") {
+			return celExpr{}, fmt.Errorf("expected AND in BETWEEN at offset %d", p.peek().pos)
+		}
+		high, err := p.parseAdditive()
+		if err != nil {
+			return celExpr{}, err
+		}
+		if not {
+			// NOT BETWEEN is rewritten directly rather than negating the conjunction
+			lower, upper := relation(lhs, "<", low), relation(lhs, ">", high)
+			return celExpr{text: lower.text + " || " + upper.text, prec: precOr}, nil
+		}
+		lower, upper := relation(lhs, ">=", low), relation(lhs, "<=", high)
+		result = celExpr{text: lower.text + " && " + upper.text, prec: precAnd}
+	case p.peek().keyword("LIKE"), p.peek().keyword("ILIKE"):
+		caseInsensitive := p.next().keyword("ILIKE")
+		tok := p.next()
+		if tok.kind != tokenString {
+			return celExpr{}, fmt.Errorf("LIKE requires a string literal pattern at offset %d", tok.pos)
+		}
+		result = convertLike(lhs, tok.value, caseInsensitive)
+	default:
+		if not {
+			return celExpr{}, p.unexpected(p.peek())
+		}
+		return lhs, nil
+	}
+	if not {
+		return negate(result), nil
+	}
+	return result, nil
+}
+
+func (p *parser) parseAdditive() (celExpr, error) {
+	lhs, err := p.parseMultiplicative()
+	if err != nil {
+		return celExpr{}, err
+	}
+	for {
+		op, ok := p.acceptOperator("+", "-", "||")
+		if !ok {
+			return lhs, nil
+		}
+		rhs, err := p.parseMultiplicative()
+		if err != nil {
+			return celExpr{}, err
+		}
+		if op == "||" {
+			// String concatenation
+			op = "+"
+		}
+		lhs = celExpr{text: lhs.wrap(precAdditive) + " " + op + " " + rhs.wrap(precAdditive+1), prec: precAdditive}
+	}
+}
+
+func (p *parser) parseMultiplicative() (celExpr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return celExpr{}, err
+	}
+	for {
+		op, ok := p.acceptOperator("*", "/", "%")
+		if !ok {
+			return lhs, nil
+		}
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return celExpr{}, err
+		}
+		lhs = celExpr{text: lhs.wrap(precMultiplicative) + " " + op + " " + rhs.wrap(precMultiplicative+1), prec: precMultiplicative}
+	}
+}
+
+func (p *parser) parseUnary() (celExpr, error) {
+	if _, ok := p.acceptOperator("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return celExpr{}, err
+		}
+		return celExpr{text: "-" + operand.wrap(precUnary), prec: precUnary}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (celExpr, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokenNumber:
+		if _, err := strconv.ParseFloat(tok.text, 64); err != nil {
+			return celExpr{}, fmt.Errorf("invalid number %q at offset %d", tok.text, tok.pos)
+		}
+		return celExpr{text: tok.text, prec: precPrimary}, nil
+	case tok.kind == tokenString:
+		return stringLiteral(tok.value), nil
+	case tok.keyword("TRUE"), tok.keyword("FALSE"), tok.keyword("NULL"):
+		return celExpr{text: strings.ToLower(tok.text), prec: precPrimary}, nil
+	case tok.kind == tokenOperator && tok.text == "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return celExpr{}, err
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return celExpr{}, err
+		}
+		return inner, nil
+	case tok.kind == tokenIdent || tok.kind == tokenQuotedIdent:
+		name, err := identifier(tok)
+		if err != nil {
+			return celExpr{}, err
+		}
+		if _, ok := p.acceptOperator("("); ok {
+			return p.parseFunction(tok, name)
+		}
+		path := []string{name}
+		for {
+			if _, ok := p.acceptOperator("."); !ok {
+				break
+			}
+			field, err := identifier(p.next())
+			if err != nil {
+				return celExpr{}, err
+			}
+			path = append(path, field)
+		}
+		return celExpr{text: strings.Join(path, "."), prec: precPrimary}, nil
+	}
+	return celExpr{}, p.unexpected(tok)
+}
+
+// parseFunction converts a function call whose opening parenthesis has been consumed
+func (p *parser) parseFunction(tok token, name string) (celExpr, error) {
+	switch strings.ToUpper(name) {
+	case "LENGTH", "CHAR_LENGTH":
+		arg, err := p.parseAdditive()
+		if err != nil {
+			return celExpr{}, err
+		}
+		if err := p.expectOperator(")"); err != nil {
+			return celExpr{}, err
+		}
+		return celExpr{text: "size(" + arg.text + ")", prec: precPrimary}, nil
+	}
+	return celExpr{}, fmt.Errorf("unsupported function %s at offset %d", name, tok.pos)
+}
+
+// unquote returns the value of a converted string literal
+func unquote(e celExpr) (string, bool) {
+	value, err := strconv.Unquote(e.text)
+	return value, err == nil && strings.HasPrefix(e.text, `"`)
+}
+
+// sqlKeywords cannot be used as unquoted identifiers
+var sqlKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IS": true, "NULL": true, "TRUE": true, "FALSE": true,
+	"IN": true, "LIKE": true, "ILIKE": true, "BETWEEN": true,
+}
+
+// celReservedWords cannot be used as CEL identifiers
+var celReservedWords = map[string]bool{
+	"as": true, "break": true, "const": true, "continue": true, "else": true, "false": true,
+	"for": true, "function": true, "if": true, "import": true, "in": true, "let": true,
+	"loop": true, "package": true, "namespace": true, "null": true, "return": true,
+	"true": true, "var": true, "void": true, "while": true,
+}
+
+var celIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// identifier returns the CEL identifier for an identifier token. Unquoted identifiers are
+// folded to lower case, like PostgreSQL does.
+func identifier(tok token) (string, error) {
+	var name string
+	switch tok.kind {
+	case tokenIdent:
+		if sqlKeywords[strings.ToUpper(tok.text)] {
+			return "", fmt.Errorf("unexpected keyword %s at offset %d", tok.text, tok.pos)
+		}
+		name = strings.ToLower(tok.text)
+	case tokenQuotedIdent:
+		name = tok.value
+	default:
+		return "", fmt.Errorf("expected identifier at offset %d", tok.pos)
+	}
+	if !celIdentifier.MatchString(name) || celReservedWords[name] {
+		return "", fmt.Errorf("identifier %q at offset %d is not a valid CEL identifier", name, tok.pos)
+	}
+	return name, nil
+}
+
+// stringLiteral returns a CEL string literal
+func stringLiteral(value string) celExpr {
+	return celExpr{text: strconv.Quote(value), prec: precPrimary}
+}
+
+// relation returns a CEL relational expression
+func relation(lhs celExpr, op string, rhs celExpr) celExpr {
+	return celExpr{text: lhs.wrap(precRelation+1) + " " + op + " " + rhs.wrap(precRelation+1), prec: precRelation}
+}
+
+// call returns a CEL receiver-style function call
+func call(target celExpr, function string, args ...celExpr) celExpr {
+	texts := make([]string, len(args))
+	for i, arg := range args {
+		texts[i] = arg.text
+	}
+	return celExpr{text: target.wrap(precPrimary) + "." + function + "(" + strings.Join(texts, ", ") + ")", prec: precPrimary}
+}
+
+// negate returns the logical negation of a CEL expression
+func negate(e celExpr) celExpr {
+	return celExpr{text: "!" + e.wrap(precUnary), prec: precUnary}
+}
+
+// convertLike converts a LIKE pattern to startsWith, endsWith, contains or equality where
+// possible, and to an anchored regular expression otherwise. Backslash escapes the next
+// character, as in PostgreSQL's default LIKE escape.
+func convertLike(lhs celExpr, pattern string, caseInsensitive bool) celExpr {
+	type segment struct {
+		literal  string
+		wildcard byte // '%', '_' or 0 for a literal
+	}
+	var segments []segment
+	var lit strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			lit.WriteByte(pattern[i])
+		case c == '%' || c == '_':
+			if lit.Len() > 0 {
+				segments = append(segments, segment{literal: lit.String()})
+				lit.Reset()
+			}
+			segments = append(segments, segment{wildcard: c})
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	if lit.Len() > 0 {
+		segments = append(segments, segment{literal: lit.String()})
+	}
+
+	if !caseInsensitive {
+		isAny := func(s segment) bool { return s.wildcard == '%' }
+		switch {
+		case len(segments) == 0:
+			return relation(lhs, "==", stringLiteral(""))
+		case len(segments) == 1 && segments[0].wildcard == 0:
+			return relation(lhs, "==", stringLiteral(segments[0].literal))
+		case len(segments) == 2 && segments[0].wildcard == 0 && isAny(segments[1]):
+			return call(lhs, "startsWith", stringLiteral(segments[0].literal))
+		case len(segments) == 2 && isAny(segments[0]) && segments[1].wildcard == 0:
+			return call(lhs, "endsWith", stringLiteral(segments[1].literal))
+		case len(segments) == 3 && isAny(segments[0]) && segments[1].wildcard == 0 && isAny(segments[2]):
+			return call(lhs, "contains", stringLiteral(segments[1].literal))
+		}
+	}
+
+	// LIKE wildcards match newlines, hence the s flag
+	var re strings.Builder
+	re.WriteString("(?s")
+	if caseInsensitive {
+		re.WriteString("i")
+	}
+	re.WriteString(")^")
+	for _, s := range segments {
+		switch s.wildcard {
+		case '%':
+			re.WriteString(".*")
+		case '_':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(s.literal))
+		}
+	}
+	re.WriteString("$")
+	return call(lhs, "matches", stringLiteral(re.String()))
+}
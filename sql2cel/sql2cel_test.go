@@ -0,0 +1,70 @@
+package sql2cel_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2/sql2cel"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		want    string
+		wantErr string
+	}{
+		{name: "comparison", sql: `age >= 18`, want: `age >= 18`},
+		{name: "qualified_column", sql: `users.name = 'O''Brien'`, want: `users.name == "O'Brien"`},
+		{name: "not_equal", sql: `status <> 'closed'`, want: `status != "closed"`},
+		{name: "and_or_precedence", sql: `a = 1 OR b = 2 AND c = 3`, want: `a == 1 || b == 2 && c == 3`},
+		{name: "parentheses", sql: `(a = 1 OR b = 2) AND c = 3`, want: `(a == 1 || b == 2) && c == 3`},
+		{name: "not", sql: `NOT (active = TRUE)`, want: `!(active == true)`},
+		{name: "is_null", sql: `deleted_at IS NULL AND manager IS NOT NULL`, want: `deleted_at == null && manager != null`},
+		{name: "is_true", sql: `active IS TRUE`, want: `active == true`},
+		{name: "in_list", sql: `status IN ('a', 'b')`, want: `status in ["a", "b"]`},
+		{name: "not_in_list", sql: `id NOT IN (1, 2, 3)`, want: `!(id in [1, 2, 3])`},
+		{name: "between", sql: `age BETWEEN 18 AND 65 AND active`, want: `age >= 18 && age <= 65 && active`},
+		{name: "not_between", sql: `age NOT BETWEEN 18 AND 65`, want: `age < 18 || age > 65`},
+		{name: "like_prefix", sql: `name LIKE 'Jo%'`, want: `name.startsWith("Jo")`},
+		{name: "like_suffix", sql: `email LIKE '%@example.com'`, want: `email.endsWith("@example.com")`},
+		{name: "like_contains", sql: `name NOT LIKE '%admin%'`, want: `!name.contains("admin")`},
+		{name: "like_exact_escaped", sql: `code LIKE '100\%'`, want: `code == "100%"`},
+		{name: "like_general", sql: `code LIKE 'A_1%.x'`, want: `code.matches("(?s)^A.1.*\\.x$")`},
+		{name: "ilike", sql: `name ILIKE 'jo%'`, want: `name.matches("(?si)^jo.*$")`},
+		{name: "regex", sql: `name ~ '^[a-z]+$'`, want: `name.matches("^[a-z]+$")`},
+		{name: "regex_case_insensitive_negated", sql: `name !~* 'admin'`, want: `!name.matches("(?i)admin")`},
+		{name: "arithmetic", sql: `price * (1 - discount) > 100.5`, want: `price * (1 - discount) > 100.5`},
+		{name: "exponent", sql: `x = 1.5e3 OR x < 2E-2`, want: `x == 1.5e3 || x < 2E-2`},
+		{name: "subtraction_associativity", sql: `a - (b - c) = 0`, want: `a - (b - c) == 0`},
+		{name: "concatenation", sql: `first || ' ' || last = 'Jo Do'`, want: `first + " " + last == "Jo Do"`},
+		{name: "length", sql: `LENGTH(name) > 3`, want: `size(name) > 3`},
+		{name: "unary_minus", sql: `balance > -10`, want: `balance > -10`},
+		{name: "quoted_identifier", sql: `"Status" = 'x'`, want: `Status == "x"`},
+		{name: "case_folding", sql: `Users.Name = 'x'`, want: `users.name == "x"`},
+		{name: "unsupported_function", sql: `lower(name) = 'x'`, wantErr: "unsupported function lower at offset 0"},
+		{name: "unsupported_syntax", sql: `name::text = 'x'`, wantErr: "unexpected character ':' at offset 4"},
+		{name: "reserved_identifier", sql: `"in" = 1`, wantErr: `identifier "in" at offset 0 is not a valid CEL identifier`},
+		{name: "trailing_tokens", sql: `a = 1 b`, wantErr: `unexpected "b" at offset 6`},
+		{name: "unterminated_string", sql: `a = 'x`, wantErr: "unterminated quoted text at offset 4"},
+	}
+	env, err := cel.NewEnv()
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sql2cel.Convert(tt.sql)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			_, issues := env.Parse(got)
+			assert.Nil(t, issues, "generated CEL must parse")
+		})
+	}
+}
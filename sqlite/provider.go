@@ -0,0 +1,258 @@
+// Package sqlite provides a CEL type provider for SQLite tables, mirroring the pg package:
+// schemas are declared directly or discovered with PRAGMA table_xinfo through database/sql,
+// with any SQLite driver.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2/sqltypes"
+)
+
+// FieldSchema represents a SQLite column with its name and declared type.
+type FieldSchema struct {
+	Name      string
+	Type      string // declared type, upper case, e.g. INTEGER, TEXT, JSON or VARCHAR(20)
+	NotNull   bool   // true for columns declared NOT NULL
+	Generated bool   // true for GENERATED ALWAYS AS (...) columns
+}
+
+// Schema represents a SQLite table schema as a slice of field schemas.
+type Schema []FieldSchema
+
+// TypeProvider interface for SQLite type providers. A TypeProvider is safe for concurrent use.
+type TypeProvider interface {
+	types.Provider
+	LoadTableSchema(ctx context.Context, tableName string) error
+	LoadSchema(ctx context.Context) error
+	Tables() []string
+}
+
+type typeProvider struct {
+	mu      sync.RWMutex // guards schemas
+	schemas map[string]Schema
+	db      *sql.DB
+}
+
+// NewTypeProvider creates a new SQLite type provider with pre-defined schemas. The map is
+// copied, so the caller may keep modifying it without affecting the provider.
+func NewTypeProvider(schemas map[string]Schema) TypeProvider {
+	p := &typeProvider{schemas: make(map[string]Schema, len(schemas))}
+	for tableName, schema := range schemas {
+		p.schemas[tableName] = schema
+	}
+	return p
+}
+
+// NewTypeProviderWithDB creates a new SQLite type provider that discovers table schemas through
+// database/sql. The database remains owned by the caller.
+func NewTypeProviderWithDB(db *sql.DB) TypeProvider {
+	return &typeProvider{
+		schemas: make(map[string]Schema),
+		db:      db,
+	}
+}
+
+// Queries of the columns of a table, and of every table and view of the main database. Hidden
+// columns of virtual tables have hidden = 1, generated columns hidden = 2 (virtual) or 3
+// (stored).
+const (
+	tableColumnsQuery  = `SELECT name, type, "notnull", hidden FROM pragma_table_xinfo(?, ?) WHERE hidden <> 1 ORDER BY cid`
+	schemaColumnsQuery = `
+		SELECT m.name, c.name, c.type, c."notnull", c.hidden
+		FROM sqlite_master m
+		JOIN pragma_table_xinfo(m.name) c
+		WHERE m.type IN ('table', 'view')
+		AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		AND c.hidden <> 1
+		ORDER BY m.name, c.cid
+	`
+)
+
+// Tables returns the names of the known tables, sorted
+func (p *typeProvider) Tables() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Sorted(maps.Keys(p.schemas))
+}
+
+// LoadTableSchema loads schema information for a table from the database. The table name may
+// be qualified with an attached database, e.g. "archive.events", in which case the schema is
+// registered under the qualified name and CEL types must use it too.
+func (p *typeProvider) LoadTableSchema(ctx context.Context, tableName string) error {
+	if p.db == nil {
+		return errors.New("no database connection available")
+	}
+	databaseName, table := "main", tableName
+	if i := strings.Index(tableName, "."); i >= 0 {
+		databaseName, table = tableName[:i], tableName[i+1:]
+	}
+	rows, err := p.db.QueryContext(ctx, tableColumnsQuery, table, databaseName)
+	if err != nil {
+		return fmt.Errorf("failed to query table schema: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	// Unknown tables are registered without columns
+	var schema Schema
+	for rows.Next() {
+		var field FieldSchema
+		var hidden int
+		if err := rows.Scan(&field.Name, &field.Type, &field.NotNull, &hidden); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		field.Type = strings.ToUpper(field.Type)
+		field.Generated = hidden > 1
+		schema = append(schema, field)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.schemas[tableName] = schema
+	return nil
+}
+
+// LoadSchema loads every table and view of the main database in a single query
+func (p *typeProvider) LoadSchema(ctx context.Context) error {
+	if p.db == nil {
+		return errors.New("no database connection available")
+	}
+	rows, err := p.db.QueryContext(ctx, schemaColumnsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to query schema: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	schemas := make(map[string]Schema)
+	for rows.Next() {
+		var tableName string
+		var field FieldSchema
+		var hidden int
+		if err := rows.Scan(&tableName, &field.Name, &field.Type, &field.NotNull, &hidden); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		field.Type = strings.ToUpper(field.Type)
+		field.Generated = hidden > 1
+		schemas[tableName] = append(schemas[tableName], field)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for tableName, schema := range schemas {
+		p.schemas[tableName] = schema
+	}
+	return nil
+}
+
+func (p *typeProvider) findSchema(typeName string) (Schema, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	schema, found := p.schemas[typeName]
+	return schema, found && len(schema) > 0
+}
+
+func (p *typeProvider) EnumValue(enumName string) ref.Val {
+	return types.NewErr("unknown enum name '%s'", enumName)
+}
+
+func (p *typeProvider) FindIdent(_ string) (ref.Val, bool) {
+	return nil, false
+}
+
+func (p *typeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, found := p.findSchema(structType); !found {
+		return nil, false
+	}
+	return types.NewObjectType(structType), true
+}
+
+func (p *typeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	schema, found := p.findSchema(structType)
+	if !found {
+		return nil, false
+	}
+	fieldNames := make([]string, len(schema))
+	for i, field := range schema {
+		fieldNames[i] = field.Name
+	}
+	return fieldNames, true
+}
+
+func (p *typeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	schema, found := p.findSchema(structType)
+	if !found {
+		return nil, false
+	}
+	for _, field := range schema {
+		if field.Name != fieldName {
+			continue
+		}
+		celType, err := types.ExprTypeToType(fieldType(field.Type))
+		if err != nil {
+			return nil, false
+		}
+		return &types.FieldType{Type: celType}, true
+	}
+	return nil, false
+}
+
+func (p *typeProvider) NewValue(structType string, _ map[string]ref.Val) ref.Val {
+	return types.NewErr("unknown type '%s'", structType)
+}
+
+var _ types.Provider = new(typeProvider)
+
+// fieldType maps a declared column type to a CEL type. SQLite stores values by type affinity,
+// so conventional names such as BOOLEAN, DATETIME and JSON are recognized first, then the
+// affinity rules of SQLite apply.
+func fieldType(declaredType string) *exprpb.Type {
+	baseType := declaredType
+	if i := strings.IndexByte(baseType, '('); i >= 0 {
+		baseType = strings.TrimSpace(baseType[:i])
+	}
+	switch baseType {
+	case "BOOLEAN", "BOOL":
+		return decls.Bool
+	case "DATETIME", "TIMESTAMP":
+		return decls.Timestamp
+	case "DATE":
+		return sqltypes.Date
+	case "TIME":
+		return sqltypes.Time
+	case "JSON", "JSONB":
+		// JSON is stored as TEXT or JSONB blobs by convention, and is dynamic in CEL
+		return decls.Dyn
+	case "", "ANY":
+		// Columns without a declared type, and ANY columns of STRICT tables, hold any value
+		return decls.Dyn
+	}
+	switch {
+	case strings.Contains(baseType, "INT"):
+		return decls.Int
+	case strings.Contains(baseType, "CHAR"), strings.Contains(baseType, "CLOB"), strings.Contains(baseType, "TEXT"):
+		return decls.String
+	case strings.Contains(baseType, "BLOB"):
+		return decls.Bytes
+	default:
+		// REAL and NUMERIC affinity
+		return decls.Double
+	}
+}
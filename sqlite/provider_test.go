@@ -0,0 +1,171 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/sqlite"
+)
+
+// column is a row of pragma_table_xinfo served by the fake driver
+type column struct {
+	database, table, name, declaredType string
+	notNull, hidden                     int64
+}
+
+// pragmaConnector is a database/sql connector answering the schema queries from a fixed set of
+// columns
+type pragmaConnector struct {
+	columns []column
+}
+
+func (c pragmaConnector) Connect(context.Context) (driver.Conn, error) { return pragmaConn(c), nil }
+func (c pragmaConnector) Driver() driver.Driver                        { return nil }
+
+type pragmaConn pragmaConnector
+
+func (c pragmaConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c pragmaConn) Close() error                        { return nil }
+func (c pragmaConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c pragmaConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	rows := &pragmaRows{}
+	for _, col := range c.columns {
+		if col.hidden == 1 {
+			continue
+		}
+		switch {
+		case len(args) == 0 && col.database == "main":
+			rows.columns = []string{"table", "name", "type", "notnull", "hidden"}
+			rows.values = append(rows.values, []driver.Value{col.table, col.name, col.declaredType, col.notNull, col.hidden})
+		case len(args) == 2 && col.table == args[0].Value && col.database == args[1].Value:
+			rows.columns = []string{"name", "type", "notnull", "hidden"}
+			rows.values = append(rows.values, []driver.Value{col.name, col.declaredType, col.notNull, col.hidden})
+		}
+	}
+	return rows, nil
+}
+
+type pragmaRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *pragmaRows) Columns() []string { return r.columns }
+func (r *pragmaRows) Close() error      { return nil }
+
+func (r *pragmaRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	db := sql.OpenDB(pragmaConnector{columns: []column{
+		{"main", "notes", "id", "INTEGER", 1, 0},
+		{"main", "notes", "title", "varchar(80)", 1, 0},
+		{"main", "notes", "body", "TEXT", 0, 0},
+		{"main", "notes", "pinned", "BOOLEAN", 0, 0},
+		{"main", "notes", "rating", "REAL", 0, 0},
+		{"main", "notes", "created_at", "DATETIME", 0, 0},
+		{"main", "notes", "due", "DATE", 0, 0},
+		{"main", "notes", "meta", "JSON", 0, 0},
+		{"main", "notes", "attachment", "BLOB", 0, 0},
+		{"main", "notes", "extra", "", 0, 0},
+		{"main", "notes", "title_length", "INT", 0, 3},
+		{"main", "strict_items", "value", "ANY", 0, 0},
+		{"main", "strict_items", "price", "NUMERIC", 0, 0},
+		{"main", "search", "rank", "", 0, 1},
+		{"archive", "notes", "id", "INTEGER", 1, 0},
+	}})
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestTypeProvider_LoadTableSchema(t *testing.T) {
+	provider := sqlite.NewTypeProviderWithDB(newTestDB(t))
+	require.NoError(t, provider.LoadTableSchema(context.Background(), "notes"))
+	require.NoError(t, provider.LoadTableSchema(context.Background(), "strict_items"))
+	require.NoError(t, provider.LoadTableSchema(context.Background(), "archive.notes"))
+	require.NoError(t, provider.LoadTableSchema(context.Background(), "missing"))
+
+	tests := []struct {
+		structType string
+		fieldName  string
+		wantType   *types.Type
+	}{
+		{structType: "notes", fieldName: "id", wantType: types.IntType},
+		{structType: "notes", fieldName: "title", wantType: types.StringType},
+		{structType: "notes", fieldName: "body", wantType: types.StringType},
+		{structType: "notes", fieldName: "pinned", wantType: types.BoolType},
+		{structType: "notes", fieldName: "rating", wantType: types.DoubleType},
+		{structType: "notes", fieldName: "created_at", wantType: types.TimestampType},
+		{structType: "notes", fieldName: "due", wantType: types.NewOpaqueType("DATE")},
+		{structType: "notes", fieldName: "meta", wantType: types.DynType},
+		{structType: "notes", fieldName: "attachment", wantType: types.BytesType},
+		{structType: "notes", fieldName: "extra", wantType: types.DynType},
+		{structType: "notes", fieldName: "title_length", wantType: types.IntType},
+		{structType: "strict_items", fieldName: "value", wantType: types.DynType},
+		{structType: "strict_items", fieldName: "price", wantType: types.DoubleType},
+		{structType: "archive.notes", fieldName: "id", wantType: types.IntType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.structType+"."+tt.fieldName, func(t *testing.T) {
+			got, found := provider.FindStructFieldType(tt.structType, tt.fieldName)
+			require.True(t, found)
+			assert.Equal(t, tt.wantType.String(), got.Type.String())
+		})
+	}
+
+	_, found := provider.FindStructType("missing")
+	assert.False(t, found)
+}
+
+func TestTypeProvider_LoadSchema(t *testing.T) {
+	provider := sqlite.NewTypeProviderWithDB(newTestDB(t))
+	require.NoError(t, provider.LoadSchema(context.Background()))
+
+	fieldNames, found := provider.FindStructFieldNames("notes")
+	require.True(t, found)
+	assert.Len(t, fieldNames, 11)
+	_, found = provider.FindStructType("strict_items")
+	assert.True(t, found)
+	_, found = provider.FindStructType("search")
+	assert.False(t, found, "hidden columns of virtual tables are left out")
+
+	err := sqlite.NewTypeProvider(nil).LoadSchema(context.Background())
+	assert.EqualError(t, err, "no database connection available")
+}
+
+func TestTypeProvider_Convert(t *testing.T) {
+	provider := sqlite.NewTypeProvider(map[string]sqlite.Schema{
+		"notes": {
+			{Name: "pinned", Type: "BOOLEAN"},
+			{Name: "title", Type: "TEXT", NotNull: true},
+			{Name: "rating", Type: "REAL"},
+		},
+	})
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(provider),
+		cel.Variable("notes", cel.ObjectType("notes")),
+	)
+	require.NoError(t, err)
+
+	ast, issues := env.Compile(`notes.pinned && notes.title != "" && notes.rating >= 4.0`)
+	require.NoError(t, issues.Err())
+	got, err := cel2sql.Convert(ast)
+	require.NoError(t, err)
+	assert.Equal(t, "notes.pinned AND notes.title != '' AND notes.rating >= 4", got)
+}
@@ -2,6 +2,8 @@
 package sqltypes
 
 import (
+	"strings"
+
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/checker/decls"
 )
@@ -24,3 +26,123 @@ var SQLTypeDeclarations = cel.Types(
 	// Custom abstract types
 	Date, Time, DateTime, Interval, DatePart,
 )
+
+// datePartNames are the date parts declared as constants for interval(), e.g. interval(1, DAY)
+var datePartNames = []string{"YEAR", "MONTH", "DAY", "HOUR", "MINUTE", "SECOND"}
+
+// SQLFunctionDeclarations provides CEL declarations for the SQL date and time functions and
+// operators that cel2sql converts: date(), time(), datetime(), timestamp(datetime, zone),
+// interval(n, part), current_date(), current_datetime(zone), the date parts YEAR to SECOND,
+// arithmetic with intervals, ordering comparisons and field accessors. The declarations do not
+// register the types with the type provider, so they can be combined with any provider.
+var SQLFunctionDeclarations = cel.Lib(sqlFunctionsLib{})
+
+type sqlFunctionsLib struct{}
+
+func (sqlFunctionsLib) LibraryName() string {
+	return "cel2sql.sqltypes"
+}
+
+func (sqlFunctionsLib) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+func (sqlFunctionsLib) CompileOptions() []cel.EnvOption {
+	date, tm, datetime := cel.OpaqueType("DATE"), cel.OpaqueType("TIME"), cel.OpaqueType("DATETIME")
+	interval, datePart := cel.OpaqueType("INTERVAL"), cel.OpaqueType("date_part")
+
+	var opts []cel.EnvOption
+	for _, part := range datePartNames {
+		opts = append(opts, cel.Variable(part, datePart))
+	}
+	opts = append(opts,
+		cel.Function("date",
+			cel.Overload("date_string", []*cel.Type{cel.StringType}, date),
+			cel.Overload("date_int_int_int", []*cel.Type{cel.IntType, cel.IntType, cel.IntType}, date)),
+		cel.Function("time", cel.Overload("time_string", []*cel.Type{cel.StringType}, tm)),
+		cel.Function("datetime",
+			cel.Overload("datetime_string", []*cel.Type{cel.StringType}, datetime),
+			cel.Overload("datetime_date_time", []*cel.Type{date, tm}, datetime)),
+		cel.Function("timestamp",
+			cel.Overload("timestamp_datetime_string", []*cel.Type{datetime, cel.StringType}, cel.TimestampType)),
+		cel.Function("interval", cel.Overload("interval_int_datepart", []*cel.Type{cel.IntType, datePart}, interval)),
+		cel.Function("current_date", cel.Overload("current_date", []*cel.Type{}, date)),
+		cel.Function("current_datetime", cel.Overload("current_datetime_string", []*cel.Type{cel.StringType}, datetime)),
+		cel.Function("_+_",
+			cel.Overload("date_add_interval", []*cel.Type{date, interval}, date),
+			cel.Overload("date_add_int", []*cel.Type{date, cel.IntType}, date),
+			cel.Overload("time_add_interval", []*cel.Type{tm, interval}, tm),
+			cel.Overload("datetime_add_interval", []*cel.Type{datetime, interval}, datetime),
+			cel.Overload("timestamp_add_interval", []*cel.Type{cel.TimestampType, interval}, cel.TimestampType)),
+		cel.Function("_-_",
+			cel.Overload("date_sub_interval", []*cel.Type{date, interval}, date),
+			cel.Overload("time_sub_interval", []*cel.Type{tm, interval}, tm),
+			cel.Overload("datetime_sub_interval", []*cel.Type{datetime, interval}, datetime),
+			cel.Overload("timestamp_sub_interval", []*cel.Type{cel.TimestampType, interval}, cel.TimestampType)),
+	)
+	for _, op := range []struct{ function, name string }{
+		{"_<_", "lt"}, {"_<=_", "le"}, {"_>_", "gt"}, {"_>=_", "ge"},
+	} {
+		opts = append(opts, cel.Function(op.function,
+			cel.Overload("date_"+op.name+"_date", []*cel.Type{date, date}, cel.BoolType),
+			cel.Overload("time_"+op.name+"_time", []*cel.Type{tm, tm}, cel.BoolType),
+			cel.Overload("datetime_"+op.name+"_datetime", []*cel.Type{datetime, datetime}, cel.BoolType),
+			cel.Overload("interval_"+op.name+"_interval", []*cel.Type{interval, interval}, cel.BoolType),
+			cel.Overload("interval_"+op.name+"_duration", []*cel.Type{interval, cel.DurationType}, cel.BoolType),
+			cel.Overload("duration_"+op.name+"_interval", []*cel.Type{cel.DurationType, interval}, cel.BoolType),
+			// Dates and timestamps compared with strings, which are converted to their type
+			cel.Overload("date_"+op.name+"_string", []*cel.Type{date, cel.StringType}, cel.BoolType),
+			cel.Overload("string_"+op.name+"_date", []*cel.Type{cel.StringType, date}, cel.BoolType),
+			cel.Overload("timestamp_"+op.name+"_string", []*cel.Type{cel.TimestampType, cel.StringType}, cel.BoolType),
+			cel.Overload("string_"+op.name+"_timestamp", []*cel.Type{cel.StringType, cel.TimestampType}, cel.BoolType)))
+	}
+	for _, accessor := range []struct {
+		function string
+		types    []*cel.Type
+	}{
+		{"getFullYear", []*cel.Type{date, datetime}},
+		{"getMonth", []*cel.Type{date, datetime}},
+		{"getDayOfMonth", []*cel.Type{date, datetime}},
+		{"getHours", []*cel.Type{tm, datetime}},
+		{"getMinutes", []*cel.Type{tm, datetime}},
+		{"getSeconds", []*cel.Type{tm, datetime}},
+	} {
+		var overloads []cel.FunctionOpt
+		for _, t := range accessor.types {
+			overloads = append(overloads,
+				cel.MemberOverload(strings.ToLower(t.TypeName())+"_"+accessor.function, []*cel.Type{t}, cel.IntType))
+		}
+		opts = append(opts, cel.Function(accessor.function, overloads...))
+	}
+	return opts
+}
+
+// ArrayFunctionDeclarations provides CEL declarations for the array functions that cel2sql
+// converts to PostgreSQL array operators: containsAll(list, list), true if the first list holds
+// every element of the second, and containsAny(list, list), true if the lists share an element.
+// Both can also be called on the first list, e.g. tags.containsAny(["a", "b"]). list.indexOf(x)
+// returns the index of the first element equal to x, or -1.
+var ArrayFunctionDeclarations = cel.Lib(arrayFunctionsLib{})
+
+type arrayFunctionsLib struct{}
+
+func (arrayFunctionsLib) LibraryName() string {
+	return "cel2sql.sqltypes.arrays"
+}
+
+func (arrayFunctionsLib) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+func (arrayFunctionsLib) CompileOptions() []cel.EnvOption {
+	list := cel.ListType(cel.TypeParamType("T"))
+	var opts []cel.EnvOption
+	for _, function := range []string{"containsAll", "containsAny"} {
+		opts = append(opts, cel.Function(function,
+			cel.Overload(function+"_list_list", []*cel.Type{list, list}, cel.BoolType),
+			cel.MemberOverload("list_"+function+"_list", []*cel.Type{list, list}, cel.BoolType)))
+	}
+	opts = append(opts, cel.Function("indexOf",
+		cel.MemberOverload("list_indexOf", []*cel.Type{list, cel.TypeParamType("T")}, cel.IntType)))
+	return opts
+}
@@ -26,6 +26,11 @@ func isTimestampType(typ *exprpb.Type) bool {
 	return typ.GetWellKnown() == exprpb.Type_TIMESTAMP
 }
 
+// isDateType checks if a type is specifically a DATE
+func isDateType(typ *exprpb.Type) bool {
+	return typ.GetAbstractType().GetName() == "DATE"
+}
+
 // isDurationRelatedType checks if a type is duration-related (INTERVAL, DURATION)
 func isDurationRelatedType(typ *exprpb.Type) bool {
 	abstractType := typ.GetAbstractType()
@@ -136,6 +141,9 @@ func (con *converter) callInterval(_ *exprpb.Expr, args []*exprpb.Expr) error {
 
 // callExtractFromTimestamp handles timestamp field extraction (YEAR, MONTH, DAY, etc.)
 func (con *converter) callExtractFromTimestamp(function string, target *exprpb.Expr, args []*exprpb.Expr) error {
+	if isDurationRelatedType(con.getType(target)) {
+		return con.callDurationGetter(function, target)
+	}
 	con.str.WriteString("EXTRACT(")
 	switch function {
 	case overloads.TimeGetFullYear:
@@ -172,21 +180,40 @@ func (con *converter) callExtractFromTimestamp(function string, target *exprpb.E
 	con.str.WriteString(")")
 	if function == overloads.TimeGetMonth || function == overloads.TimeGetDayOfYear || function == overloads.TimeGetDayOfMonth || function == overloads.TimeGetDayOfWeek {
 		con.str.WriteString(" - 1")
+		con.warn(target, WarningTimestampField, "%s is zero-based in CEL; 1 is subtracted from the value PostgreSQL extracts", function)
 	}
 	return nil
 }
 
+// durationUnits maps the duration accessors to the operation that converts seconds to their unit
+var durationUnits = map[string]string{
+	overloads.TimeGetHours:        " / 3600",
+	overloads.TimeGetMinutes:      " / 60",
+	overloads.TimeGetSeconds:      "",
+	overloads.TimeGetMilliseconds: " * 1000",
+}
+
+// callDurationGetter converts a duration accessor, which returns the whole duration in its unit
+// truncated toward zero, unlike the fields EXTRACT returns from an interval
+func (con *converter) callDurationGetter(function string, target *exprpb.Expr) error {
+	unit, ok := durationUnits[function]
+	if !ok {
+		return fmt.Errorf("%w: %s is not defined on durations", ErrUnsupportedFunction, function)
+	}
+	con.str.WriteString("TRUNC(EXTRACT(EPOCH FROM ")
+	if err := con.visit(target); err != nil {
+		return err
+	}
+	con.str.WriteString(")")
+	con.str.WriteString(unit)
+	con.str.WriteString(")::bigint")
+	return nil
+}
+
 // callTimestampFromString converts string literals to PostgreSQL timestamps
 func (con *converter) callTimestampFromString(_ *exprpb.Expr, args []*exprpb.Expr) error {
 	if len(args) == 1 {
-		// For PostgreSQL, we need to cast the string to a timestamp
-		con.str.WriteString("CAST(")
-		err := con.visit(args[0])
-		if err != nil {
-			return err
-		}
-		con.str.WriteString(" AS TIMESTAMP WITH TIME ZONE)")
-		return nil
+		return con.writeTimestamp(args[0])
 	} else if len(args) == 2 {
 		// Handle timestamp(datetime, timezone) format
 		con.str.WriteString("TIMESTAMP(")
@@ -205,3 +232,81 @@ func (con *converter) callTimestampFromString(_ *exprpb.Expr, args []*exprpb.Exp
 
 	return fmt.Errorf("timestamp function expects 1 or 2 arguments, got %d", len(args))
 }
+
+// parseTimestampLiteral parses a string literal converted with timestamp() like CEL does, as an
+// RFC 3339 timestamp within the years 1 to 9999, and returns it in UTC
+func parseTimestampLiteral(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err == nil && (t.UTC().Year() < 1 || t.UTC().Year() > 9999) {
+		err = errors.New("year out of range")
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: timestamp(%q): %v", ErrInvalidLiteral, s, err)
+	}
+	return t.UTC(), nil
+}
+
+// timestampStringLayouts are the forms of strings compared with timestamps: RFC 3339, a date
+// and time without offset, which PostgreSQL reads in the session time zone, or a date
+var timestampStringLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.DateOnly,
+}
+
+// checkTemporalString checks that a string compared with a date or timestamptz can be cast to it
+func checkTemporalString(cast, s string) error {
+	layouts := timestampStringLayouts
+	if cast == "date" {
+		layouts = []string{time.DateOnly}
+	}
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q cannot be compared with a %s", ErrInvalidLiteral, s, cast)
+}
+
+// writeTimestamp writes the conversion of a string to a timestamp in the style selected with
+// WithTimestampLiteralStyle. String literals are validated and normalized to UTC.
+func (con *converter) writeTimestamp(arg *exprpb.Expr) error {
+	var literal string
+	if isStringLiteral(arg) {
+		t, err := parseTimestampLiteral(arg.GetConstExpr().GetStringValue())
+		if err != nil {
+			return err
+		}
+		if !con.opts.literalPlaceholders {
+			literal = "'" + t.Format(time.RFC3339Nano) + "'"
+		}
+	}
+	writeArg := func(nested bool) error {
+		if literal != "" {
+			con.str.WriteString(literal)
+			return nil
+		}
+		return con.visitMaybeNested(arg, nested)
+	}
+
+	switch {
+	case con.opts.timestampStyle == TimestampTypedLiteral && literal != "":
+		con.str.WriteString("TIMESTAMPTZ ")
+		con.str.WriteString(literal)
+	case con.opts.timestampStyle == TimestampCastOperator:
+		if err := writeArg(isBinaryOrTernaryOperator(arg)); err != nil {
+			return err
+		}
+		con.str.WriteString("::timestamptz")
+	default:
+		// Typed literals fall back to CAST for values only known when the query runs
+		con.str.WriteString("CAST(")
+		if err := writeArg(false); err != nil {
+			return err
+		}
+		con.str.WriteString(" AS TIMESTAMP WITH TIME ZONE)")
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertTimestampLiteralStyle(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("created_at", cel.TimestampType),
+		cel.Variable("since", cel.StringType),
+	)
+	require.NoError(t, err)
+
+	typed := cel2sql.WithTimestampLiteralStyle(cel2sql.TimestampTypedLiteral)
+	operator := cel2sql.WithTimestampLiteralStyle(cel2sql.TimestampCastOperator)
+	tests := []struct {
+		name    string
+		source  string
+		opts    []cel2sql.ConvertOption
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "cast",
+			source: `created_at > timestamp("2023-01-01T00:00:00Z")`,
+			want:   "created_at > CAST('2023-01-01T00:00:00Z' AS TIMESTAMP WITH TIME ZONE)",
+		},
+		{
+			name:   "typed_literal",
+			source: `created_at > timestamp("2023-01-01T00:00:00Z")`,
+			opts:   []cel2sql.ConvertOption{typed},
+			want:   "created_at > TIMESTAMPTZ '2023-01-01T00:00:00Z'",
+		},
+		{
+			name:   "cast_operator",
+			source: `created_at > timestamp("2023-01-01T00:00:00Z")`,
+			opts:   []cel2sql.ConvertOption{operator},
+			want:   "created_at > '2023-01-01T00:00:00Z'::timestamptz",
+		},
+		{
+			name:   "normalized_to_utc",
+			source: `created_at < timestamp("2023-06-30T23:30:00.5-02:00")`,
+			opts:   []cel2sql.ConvertOption{typed},
+			want:   "created_at < TIMESTAMPTZ '2023-07-01T01:30:00.5Z'",
+		},
+		{
+			name:   "typed_literal_of_variable",
+			source: `created_at > timestamp(since)`,
+			opts:   []cel2sql.ConvertOption{typed},
+			want:   "created_at > CAST(since AS TIMESTAMP WITH TIME ZONE)",
+		},
+		{
+			name:   "cast_operator_of_expression",
+			source: `created_at > timestamp(since + "T00:00:00Z")`,
+			opts:   []cel2sql.ConvertOption{operator},
+			want:   "created_at > (since || 'T00:00:00Z')::timestamptz",
+		},
+		{
+			name:   "literal_placeholder",
+			source: `created_at > timestamp("2023-01-01T00:00:00Z")`,
+			opts:   []cel2sql.ConvertOption{operator, cel2sql.WithLiteralPlaceholders()},
+			want:   "created_at > $1::timestamptz",
+		},
+		{
+			name:    "malformed",
+			source:  `created_at > timestamp("2023-01-01")`,
+			wantErr: cel2sql.ErrInvalidLiteral,
+		},
+		{
+			name:    "out_of_range",
+			source:  `created_at > timestamp("0001-01-01T00:00:00+01:00")`,
+			opts:    []cel2sql.ConvertOption{typed},
+			wantErr: cel2sql.ErrInvalidLiteral,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorIs(t, cel2sql.Validate(ast), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertDurationComparisons(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"jobs": {
+			{Name: "timeout", Type: "interval"},
+			{Name: "started_at", Type: "timestamptz"},
+			{Name: "finished_at", Type: "timestamptz"},
+			{Name: "duration", Type: "interval"},
+			{Name: "backoffs", Type: "interval", Repeated: true},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas),
+		cel.Variable("limit", cel.DurationType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "literals",
+			source: `duration("1h") > duration("30m")`,
+			want:   "INTERVAL 1 HOUR > INTERVAL 30 MINUTE",
+		},
+		{
+			name:   "interval_column",
+			source: `jobs.timeout >= duration("90s")`,
+			want:   "jobs.timeout >= INTERVAL 90 SECOND",
+		},
+		{
+			name:   "interval_column_on_right",
+			source: `duration("5m") < jobs.timeout && jobs.timeout != limit`,
+			want:   "INTERVAL 5 MINUTE < jobs.timeout AND jobs.timeout != limit",
+		},
+		{
+			name:   "column_named_duration",
+			source: `jobs.duration > duration("2h")`,
+			want:   "jobs.duration > INTERVAL 2 HOUR",
+		},
+		{
+			name:   "interval_array",
+			source: `jobs.backoffs.exists(b, b > duration("1m"))`,
+			want:   "EXISTS (SELECT 1 FROM UNNEST(jobs.backoffs) AS b WHERE b > INTERVAL 1 MINUTE)",
+		},
+		{
+			name:   "duration_arithmetic",
+			source: `jobs.timeout + duration("1m") <= limit`,
+			want:   "jobs.timeout + INTERVAL 1 MINUTE <= limit",
+		},
+		{
+			name:   "elapsed_time",
+			source: `jobs.finished_at - jobs.started_at > jobs.timeout`,
+			want:   "jobs.finished_at - jobs.started_at > jobs.timeout",
+		},
+		{
+			name:   "interval_column_in_timestamp_arithmetic",
+			source: `jobs.started_at + jobs.timeout < jobs.finished_at`,
+			want:   "jobs.started_at + jobs.timeout < jobs.finished_at",
+		},
+		{
+			name:   "interval_function",
+			source: `interval(2, HOUR) > jobs.timeout`,
+			want:   "INTERVAL 2 HOUR > jobs.timeout",
+		},
+		{
+			name:   "membership",
+			source: `jobs.timeout in [duration("1m"), duration("2m")]`,
+			want:   "jobs.timeout = ANY(ARRAY[INTERVAL 1 MINUTE, INTERVAL 2 MINUTE])",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertDurationGetters(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("timeout", cel.DurationType),
+		cel.Variable("started_at", cel.TimestampType),
+		cel.Variable("finished_at", cel.TimestampType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "hours",
+			source: `timeout.getHours() > 2`,
+			want:   "TRUNC(EXTRACT(EPOCH FROM timeout) / 3600)::bigint > 2",
+		},
+		{
+			name:   "minutes_of_difference",
+			source: `(finished_at - started_at).getMinutes() >= 90`,
+			want:   "TRUNC(EXTRACT(EPOCH FROM finished_at - started_at) / 60)::bigint >= 90",
+		},
+		{
+			name:   "seconds_of_literal",
+			source: `duration("90m").getSeconds() == 5400`,
+			want:   "TRUNC(EXTRACT(EPOCH FROM INTERVAL 90 MINUTE))::bigint = 5400",
+		},
+		{
+			name:   "milliseconds",
+			source: `timeout.getMilliseconds() < 500`,
+			want:   "TRUNC(EXTRACT(EPOCH FROM timeout) * 1000)::bigint < 500",
+		},
+		{
+			name:   "timestamp_field",
+			source: `started_at.getHours() == 3`,
+			want:   "EXTRACT(HOUR FROM started_at) = 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertTemporalStringComparisons(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"employees": {
+			{Name: "name", Type: "text"},
+			{Name: "hired_at", Type: "timestamptz"},
+			{Name: "birth_date", Type: "date"},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		opts    []cel2sql.ConvertOption
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "timestamp_column",
+			source: `employees.hired_at > "2023-01-01"`,
+			want:   "employees.hired_at > '2023-01-01'::timestamptz",
+		},
+		{
+			name:   "timestamp_with_offset",
+			source: `employees.hired_at <= "2023-06-30T23:59:59+02:00"`,
+			want:   "employees.hired_at <= '2023-06-30T23:59:59+02:00'::timestamptz",
+		},
+		{
+			name:   "date_column_on_right",
+			source: `"1990-01-01" <= employees.birth_date`,
+			want:   "'1990-01-01'::date <= employees.birth_date",
+		},
+		{
+			name:   "date_equality",
+			source: `dyn(employees.birth_date) == "1990-01-01"`,
+			want:   "employees.birth_date = '1990-01-01'::date",
+		},
+		{
+			name:   "text_column",
+			source: `employees.name > "M"`,
+			want:   "employees.name > 'M'",
+		},
+		{
+			name:   "placeholder",
+			source: `employees.hired_at > "2023-01-01 09:00:00"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithLiteralPlaceholders()},
+			want:   "employees.hired_at > $1::timestamptz",
+		},
+		{
+			name:    "invalid_date",
+			source:  `employees.birth_date < "2023-01-01T00:00:00Z"`,
+			wantErr: cel2sql.ErrInvalidLiteral,
+		},
+		{
+			name:    "invalid_timestamp",
+			source:  `employees.hired_at > "yesterday"`,
+			wantErr: cel2sql.ErrInvalidLiteral,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, tt.opts...)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package cel2sql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// typeModifierCast validates a literal compared for equality with a column of limited precision
+// or length. Numeric literals compared with numeric(p,s) columns are cast to the exact column
+// type; string literals longer than a character column can hold, and uint literals beyond the
+// range of an integer column, are rejected.
+func typeModifierCast(field pg.FieldSchema, value *exprpb.Constant) (string, error) {
+	switch v := value.GetConstantKind().(type) {
+	case *exprpb.Constant_StringValue:
+		if field.Length > 0 && utf8.RuneCountInString(v.StringValue) > field.Length {
+			return "", fmt.Errorf("%w: %q is longer than the %d characters of column %s",
+				ErrLiteralOutOfRange, v.StringValue, field.Length, field.Name)
+		}
+	case *exprpb.Constant_Int64Value:
+		return numericCast(field, strconv.FormatInt(v.Int64Value, 10))
+	case *exprpb.Constant_Uint64Value:
+		if maxValue, ok := integerColumnMax(field.Type); ok && v.Uint64Value > maxValue {
+			return "", fmt.Errorf("%w: %d exceeds the range of %s column %s",
+				ErrLiteralOutOfRange, v.Uint64Value, field.Type, field.Name)
+		}
+		return numericCast(field, strconv.FormatUint(v.Uint64Value, 10))
+	case *exprpb.Constant_DoubleValue:
+		if math.IsInf(v.DoubleValue, 0) || math.IsNaN(v.DoubleValue) {
+			return "", nil
+		}
+		return numericCast(field, strconv.FormatFloat(v.DoubleValue, 'f', -1, 64))
+	}
+	return "", nil
+}
+
+// numericCast checks that a decimal number fits a numeric(p,s) column and returns the column type
+func numericCast(field pg.FieldSchema, number string) (string, error) {
+	if !isNumericColumn(field) || field.Precision == 0 {
+		return "", nil
+	}
+	integer, fraction, _ := strings.Cut(strings.TrimPrefix(number, "-"), ".")
+	integer = strings.TrimLeft(integer, "0")
+	if len(integer) > field.Precision-field.Scale || len(fraction) > field.Scale {
+		return "", fmt.Errorf("%w: %s does not fit numeric(%d,%d) column %s",
+			ErrLiteralOutOfRange, number, field.Precision, field.Scale, field.Name)
+	}
+	return fmt.Sprintf("numeric(%d,%d)", field.Precision, field.Scale), nil
+}
@@ -0,0 +1,97 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertTypeModifiers(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"products": {
+			{Name: "price", Type: "numeric", Precision: 6, Scale: 2},
+			{Name: "weight", Type: "numeric"},
+			{Name: "stock", Type: "numeric", Precision: 5},
+			{Name: "sku", Type: "character varying", Length: 8},
+			{Name: "name", Type: "text"},
+		},
+	}
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(schemas)),
+		cel.Variable("product", cel.ObjectType("products")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "numeric_equals",
+			source: `product.price == 19.99`,
+			want:   "product.price = 19.99::numeric(6,2)",
+		},
+		{
+			name:   "numeric_in",
+			source: `product.price in [-9999.5, 0.25]`,
+			want:   "product.price = ANY(ARRAY[-9999.5::numeric(6,2), 0.25::numeric(6,2)])",
+		},
+		{
+			name:   "integer_scale",
+			source: `product.stock != 99999.0`,
+			want:   "product.stock != 99999::numeric(5,0)",
+		},
+		{
+			name:   "ordering_not_validated",
+			source: `product.price < 1000000.0`,
+			want:   "product.price < 1e+06",
+		},
+		{
+			name:   "unconstrained",
+			source: `product.weight == 1234567.125 && product.name == "a very long name"`,
+			want:   "product.weight = 1.234567125e+06 AND product.name = 'a very long name'",
+		},
+		{
+			name:   "string_within_length",
+			source: `product.sku == "ABC-1234"`,
+			want:   "product.sku = 'ABC-1234'",
+		},
+		{
+			name:    "too_many_digits",
+			source:  `product.price == 10000.0`,
+			wantErr: cel2sql.ErrLiteralOutOfRange,
+		},
+		{
+			name:    "too_many_decimals",
+			source:  `product.price == 1.005`,
+			wantErr: cel2sql.ErrLiteralOutOfRange,
+		},
+		{
+			name:    "string_too_long",
+			source:  `product.sku in ["ABC-1234", "ABC-12345"]`,
+			wantErr: cel2sql.ErrLiteralOutOfRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cel2sql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// PostgreSQL has no unsigned integer types, so uint values are compared as the integers they
+// are: uint literals are written as numbers, with a numeric cast beyond the range of bigint, and
+// uint() casts to numeric(20), which holds every uint64.
+const uintSQLType = "NUMERIC(20)"
+
+// integerColumnMax returns the largest value held by an integer column type
+func integerColumnMax(typ string) (uint64, bool) {
+	switch typ {
+	case "smallint", "int2":
+		return math.MaxInt16, true
+	case "integer", "int", "int4":
+		return math.MaxInt32, true
+	case "bigint", "int8":
+		return math.MaxInt64, true
+	}
+	return 0, false
+}
+
+// exceedsBigint checks if a uint literal is beyond the range of bigint, the largest PostgreSQL
+// integer type
+func exceedsBigint(value *exprpb.Constant) bool {
+	v, ok := value.GetConstantKind().(*exprpb.Constant_Uint64Value)
+	return ok && v.Uint64Value > math.MaxInt64
+}
+
+// checkUintConversion checks that a literal converted with uint() is in the range of uint64,
+// since CEL fails to convert other values where SQL would return them
+func checkUintConversion(arg *exprpb.Expr) error {
+	value := arg.GetConstExpr()
+	var inRange bool
+	switch v := value.GetConstantKind().(type) {
+	case *exprpb.Constant_Int64Value:
+		inRange = v.Int64Value >= 0
+	case *exprpb.Constant_DoubleValue:
+		// 2^64 is the first double beyond the range of uint64
+		inRange = v.DoubleValue > -1 && v.DoubleValue < math.MaxUint64
+	case *exprpb.Constant_StringValue:
+		_, err := strconv.ParseUint(v.StringValue, 10, 64)
+		inRange = err == nil
+	default:
+		return nil
+	}
+	if !inRange {
+		return fmt.Errorf("%w: uint(%s) is not an unsigned integer", ErrLiteralOutOfRange, constantString(value))
+	}
+	return nil
+}
+
+// constantString formats a literal for error messages
+func constantString(value *exprpb.Constant) string {
+	switch v := value.GetConstantKind().(type) {
+	case *exprpb.Constant_Int64Value:
+		return strconv.FormatInt(v.Int64Value, 10)
+	case *exprpb.Constant_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *exprpb.Constant_StringValue:
+		return strconv.Quote(v.StringValue)
+	}
+	return value.String()
+}
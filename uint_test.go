@@ -0,0 +1,108 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertUint(t *testing.T) {
+	schemas := map[string]pg.Schema{
+		"counters": {
+			{Name: "hits", Type: "bigint"},
+			{Name: "shards", Type: "smallint"},
+			{Name: "total", Type: "numeric", Precision: 20},
+		},
+	}
+	env, err := cel2sql.NewEnv(pg.NewTypeProvider(schemas),
+		cel.Variable("n", cel.UintType),
+		cel.Variable("ratio", cel.DoubleType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "literal",
+			source: `n >= 42u`,
+			want:   "n >= 42",
+		},
+		{
+			name:   "literal_beyond_bigint",
+			source: `n in [1u, 18446744073709551615u]`,
+			want:   "n = ANY(ARRAY[1, 18446744073709551615::numeric])",
+		},
+		{
+			name:   "numeric_column",
+			source: `dyn(counters.total) == 18446744073709551615u`,
+			want:   "counters.total = 18446744073709551615::numeric(20,0)",
+		},
+		{
+			name:   "conversion",
+			source: `uint(counters.hits) > n`,
+			want:   "CAST(counters.hits AS NUMERIC(20)) > n",
+		},
+		{
+			name:   "conversion_of_double",
+			source: `uint(ratio * 100.0) == n`,
+			want:   "CAST(TRUNC(ratio * 100) AS NUMERIC(20)) = n",
+		},
+		{
+			name:   "conversion_of_string",
+			source: `uint("18446744073709551615") == n`,
+			want:   "CAST('18446744073709551615' AS NUMERIC(20)) = n",
+		},
+		{
+			name:    "negative_conversion",
+			source:  `uint(-1) == n`,
+			wantErr: cel2sql.ErrLiteralOutOfRange,
+		},
+		{
+			name:    "invalid_string_conversion",
+			source:  `uint("-1") == n`,
+			wantErr: cel2sql.ErrLiteralOutOfRange,
+		},
+		{
+			name:    "beyond_bigint_column",
+			source:  `dyn(counters.hits) == 18446744073709551615u`,
+			wantErr: cel2sql.ErrLiteralOutOfRange,
+		},
+		{
+			name:    "beyond_smallint_column",
+			source:  `dyn(counters.shards) != 40000u`,
+			wantErr: cel2sql.ErrLiteralOutOfRange,
+		},
+		{
+			name:   "ordering_not_validated",
+			source: `dyn(counters.shards) < 40000u`,
+			want:   "counters.shards < 40000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.NoError(t, issues.Err())
+
+			got, err := cel2sql.Convert(ast, cel2sql.WithSchemas(schemas))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	ast, issues := env.Compile(`uint(-1) == n`)
+	require.NoError(t, issues.Err())
+	assert.ErrorIs(t, cel2sql.Validate(ast), cel2sql.ErrLiteralOutOfRange)
+}
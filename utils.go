@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/overloads"
 	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
 )
 
@@ -23,8 +24,20 @@ func isListType(typ *exprpb.Type) bool {
 	return ok
 }
 
+// isMessageType checks if a type is a message (struct/composite) type
+func isMessageType(typ *exprpb.Type) bool {
+	_, ok := typ.GetTypeKind().(*exprpb.Type_MessageType)
+	return ok
+}
+
 // Expression type checking utilities
 
+// isMapLiteral checks if an expression is a map literal such as {"key": value}
+func isMapLiteral(node *exprpb.Expr) bool {
+	s := node.GetStructExpr()
+	return s != nil && s.GetMessageName() == ""
+}
+
 // isNullLiteral checks if an expression is a NULL literal
 func isNullLiteral(node *exprpb.Expr) bool {
 	_, isConst := node.ExprKind.(*exprpb.Expr_ConstExpr)
@@ -55,6 +68,14 @@ func isStringLiteral(node *exprpb.Expr) bool {
 	return isString
 }
 
+// unwrapDyn returns the argument of a dyn() call, or the expression itself otherwise
+func unwrapDyn(node *exprpb.Expr) *exprpb.Expr {
+	if call := node.GetCallExpr(); call != nil && call.GetFunction() == overloads.TypeConvertDyn && len(call.GetArgs()) == 1 {
+		return call.GetArgs()[0]
+	}
+	return node
+}
+
 // isFieldAccessExpression checks if an expression is a field access (like trigram.cell[0].value)
 func isFieldAccessExpression(expr *exprpb.Expr) bool {
 	switch expr.GetExprKind().(type) {
@@ -70,6 +91,39 @@ func isFieldAccessExpression(expr *exprpb.Expr) bool {
 	return false
 }
 
+// childExprs returns the direct sub-expressions of an expression
+func childExprs(expr *exprpb.Expr) []*exprpb.Expr {
+	return appendChildExprs(nil, expr)
+}
+
+// appendChildExprs appends the direct children of expr to children, for walks that reuse a
+// slice across nodes
+func appendChildExprs(children []*exprpb.Expr, expr *exprpb.Expr) []*exprpb.Expr {
+	switch expr.GetExprKind().(type) {
+	case *exprpb.Expr_SelectExpr:
+		children = append(children, expr.GetSelectExpr().GetOperand())
+	case *exprpb.Expr_CallExpr:
+		call := expr.GetCallExpr()
+		if call.GetTarget() != nil {
+			children = append(children, call.GetTarget())
+		}
+		children = append(children, call.GetArgs()...)
+	case *exprpb.Expr_ListExpr:
+		children = append(children, expr.GetListExpr().GetElements()...)
+	case *exprpb.Expr_StructExpr:
+		for _, entry := range expr.GetStructExpr().GetEntries() {
+			if entry.GetMapKey() != nil {
+				children = append(children, entry.GetMapKey())
+			}
+			children = append(children, entry.GetValue())
+		}
+	case *exprpb.Expr_ComprehensionExpr:
+		comp := expr.GetComprehensionExpr()
+		children = append(children, comp.GetIterRange(), comp.GetAccuInit(), comp.GetLoopCondition(), comp.GetLoopStep(), comp.GetResult())
+	}
+	return children
+}
+
 // Field name validation and extraction
 
 var fieldNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,127}$`)
@@ -77,15 +131,25 @@ var fieldNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,127}$`)
 // validateFieldName validates that a field name follows PostgreSQL naming conventions
 func validateFieldName(name string) error {
 	if !fieldNameRegexp.MatchString(name) {
-		return fmt.Errorf("invalid field name \"%s\"", name)
+		return fmt.Errorf("%w: invalid field name \"%s\"", ErrUnsafeIdentifier, name)
 	}
 	return nil
 }
 
+var plainIdentifierRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// quoteIdentifier quotes a PostgreSQL identifier unless it can be written as is
+func quoteIdentifier(name string) string {
+	if plainIdentifierRegexp.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
 // extractFieldName extracts a field name from a string literal expression
 func extractFieldName(node *exprpb.Expr) (string, error) {
 	if !isStringLiteral(node) {
-		return "", fmt.Errorf("unsupported type: %v", node)
+		return "", fmt.Errorf("%w: %v", ErrUnknownType, node)
 	}
 	fieldName := node.GetConstExpr().GetStringValue()
 	if err := validateFieldName(fieldName); err != nil {
@@ -110,9 +174,9 @@ func bytesToOctets(byteVal []byte) string {
 
 // isNumericComparison checks if an operator is a numeric comparison
 func isNumericComparison(op string) bool {
-	return op == operators.Greater || op == operators.GreaterEquals || 
-		   op == operators.Less || op == operators.LessEquals ||
-		   op == operators.Equals || op == operators.NotEquals
+	return op == operators.Greater || op == operators.GreaterEquals ||
+		op == operators.Less || op == operators.LessEquals ||
+		op == operators.Equals || op == operators.NotEquals
 }
 
 // isNumericType checks if a type represents a numeric value
@@ -121,7 +185,7 @@ func isNumericType(typ *exprpb.Type) bool {
 		return false
 	}
 	primitive := typ.GetPrimitive()
-	return primitive == exprpb.Type_INT64 || 
-		   primitive == exprpb.Type_UINT64 || 
-		   primitive == exprpb.Type_DOUBLE
+	return primitive == exprpb.Type_INT64 ||
+		primitive == exprpb.Type_UINT64 ||
+		primitive == exprpb.Type_DOUBLE
 }
@@ -0,0 +1,117 @@
+package cel2sql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// Validate checks that an expression can be converted to SQL without generating it, so that
+// filters can be validated when they are saved rather than when they are queried. Like
+// Convert with WithCollectErrors, it reports every unsupported construct, joined into a single
+// error. Unlike Convert, it also reports calls of functions that are neither built in nor
+// registered with WithFunction, RegisterFunction or an interceptor, rather than passing them
+// through to SQL.
+func Validate(ast *cel.Ast, opts ...ConvertOption) error {
+	checkedExpr, err := cel.AstToCheckedExpr(ast)
+	if err != nil {
+		return err
+	}
+	con := &converter{
+		typeMap:                checkedExpr.TypeMap,
+		sourceInfo:             ast.NativeRep().SourceInfo(),
+		checkedSourceInfo:      checkedExpr.SourceInfo,
+		root:                   checkedExpr.Expr,
+		rejectUnknownFunctions: true,
+	}
+	for _, opt := range opts {
+		opt(&con.opts)
+	}
+	if err := con.checkDepth(checkedExpr.Expr); err != nil {
+		return err
+	}
+	return con.validate(checkedExpr.Expr)
+}
+
+// validate reports every problem in expr that would make its conversion fail, prefixed
+// with its source location where known. The expression is converted with a converter that
+// collects the errors of all nodes instead of stopping at the first, see visit.
+func (con *converter) validate(expr *exprpb.Expr) error {
+	var errs []error
+	if err := con.checkFeatureNames(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := con.checkParameterNames(); err != nil {
+		errs = append(errs, err)
+	}
+	disallowed := make(map[string]bool)
+	for _, feature := range con.opts.disallowedFeatures {
+		disallowed[feature] = true
+	}
+	if len(disallowed) > 0 {
+		stack := []*exprpb.Expr{expr}
+		for len(stack) > 0 {
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			// Each disallowed feature is reported where it is first used
+			if feature := con.nodeFeature(node); disallowed[feature] {
+				delete(disallowed, feature)
+				errs = append(errs, con.locate(node.GetId(), &FeatureError{Feature: feature}))
+			}
+			children := con.convertedChildren(node)
+			// Push in reverse so that features are reported in source order
+			for i := len(children) - 1; i >= 0; i-- {
+				stack = append(stack, children[i])
+			}
+		}
+	}
+
+	sub := newConverter(con.typeMap)
+	sub.opts = con.opts
+	sub.sourceInfo, sub.checkedSourceInfo, sub.root = con.source(), con.checkedSourceInfo, con.root
+	sub.rejectUnknownFunctions = con.rejectUnknownFunctions
+	sub.collecting = true
+	_ = sub.visit(expr)
+	errs = append(errs, sub.errs...)
+	sub.release()
+
+	if len(con.opts.deniedColumns) > 0 || con.opts.strictColumns {
+		seen := make(map[columnRef]bool)
+		_ = con.walkColumnRefs(expr, nil, func(ref columnRef) error {
+			if err := con.checkColumn(ref); err != nil && !seen[ref] {
+				seen[ref] = true
+				errs = append(errs, err)
+			}
+			return nil
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// locate prefixes err with the source location of the node with the given ID, where known
+func (con *converter) locate(id int64, err error) error {
+	if loc := con.source().GetStartLocation(id); loc.Line() > 0 {
+		return fmt.Errorf("%d:%d: %w", loc.Line(), loc.Column()+1, err)
+	}
+	return err
+}
+
+// convertedChildren returns the sub-expressions that the converter renders. For comprehensions
+// these are the range and the macro arguments rather than the accumulator plumbing.
+func (con *converter) convertedChildren(expr *exprpb.Expr) []*exprpb.Expr {
+	comp := expr.GetComprehensionExpr()
+	if comp == nil {
+		return childExprs(expr)
+	}
+	children := []*exprpb.Expr{comp.GetIterRange()}
+	if info, err := con.identifyComprehension(expr); err == nil {
+		for _, e := range []*exprpb.Expr{info.Predicate, info.Filter, info.Transform} {
+			if e != nil {
+				children = append(children, e)
+			}
+		}
+	}
+	return children
+}
@@ -0,0 +1,132 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+)
+
+func TestValidate(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("tags", cel.ListType(cel.StringType)),
+		cel.Variable("attrs", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Function("ns.custom", cel.Overload("ns_custom_string", []*cel.Type{cel.StringType}, cel.BoolType)),
+		cel.Function("soundex", cel.Overload("soundex_string", []*cel.Type{cel.StringType}, cel.StringType)),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		source   string
+		opts     []cel2sql.ConvertOption
+		wantErrs []string
+		// passedThrough is set for functions that Convert passes through to SQL
+		passedThrough bool
+	}{
+		{
+			name:   "supported",
+			source: `name.startsWith("a") && tags.exists(t, t == name) && size(name) > 1`,
+		},
+		{
+			name:   "registered_function",
+			source: `soundex(name) == "A000"`,
+			opts:   []cel2sql.ConvertOption{cel2sql.WithFunction("soundex", cel2sql.Template("SOUNDEX({0})"))},
+		},
+		{
+			name:          "unregistered_function",
+			source:        `name == "a" || soundex(name) == "A000"`,
+			wantErrs:      []string{"1:23: unsupported function: soundex"},
+			passedThrough: true,
+		},
+		{
+			name:     "unknown_function",
+			source:   `ns.custom(name)`,
+			wantErrs: []string{"1:10: unsupported function: ns.custom"},
+		},
+		{
+			name:   "every_problem_reported",
+			source: `ns.custom(name) || attrs["bad key"] == "x" || tags.exists(t, ns.custom(t))`,
+			wantErrs: []string{
+				"1:10: unsupported function: ns.custom",
+				"1:25: unsafe identifier",
+				"1:71: unsupported function: ns.custom",
+			},
+		},
+		{
+			name:     "columns",
+			source:   `name == "a" && tags.size() > 1`,
+			opts:     []cel2sql.ConvertOption{cel2sql.WithDeniedColumns("name", "tags")},
+			wantErrs: []string{"column name is denied", "column tags is denied"},
+		},
+		{
+			name:   "disallowed_features",
+			source: `name.matches("^a") && tags.exists(t, t == name) && tags.all(t, t.matches("b"))`,
+			opts: []cel2sql.ConvertOption{
+				cel2sql.WithDisallowedFeatures(cel2sql.FeatureComprehension, cel2sql.FeatureRegex),
+			},
+			wantErrs: []string{
+				"1:13: expression uses disallowed feature regex",
+				"1:34: expression uses disallowed feature comprehension",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			err := cel2sql.Validate(ast, tt.opts...)
+			if len(tt.wantErrs) == 0 {
+				require.NoError(t, err)
+				_, err = cel2sql.Convert(ast, tt.opts...)
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tt.wantErrs {
+				assert.Contains(t, err.Error(), want)
+			}
+			_, err = cel2sql.Convert(ast, tt.opts...)
+			if tt.passedThrough {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err, "Convert should reject what Validate rejects")
+		})
+	}
+}
+
+func TestConvertCollectErrors(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("attrs", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Function("ns.custom", cel.Overload("ns_custom_string", []*cel.Type{cel.StringType}, cel.BoolType)),
+	)
+	require.NoError(t, err)
+
+	ast, issues := env.Compile(`ns.custom(name) || attrs["bad key"] == "x" || name == "a"`)
+	require.Empty(t, issues)
+
+	_, err = cel2sql.Convert(ast)
+	require.Error(t, err)
+	assert.Equal(t, "unsupported function: ns.custom", err.Error())
+
+	_, err = cel2sql.Convert(ast, cel2sql.WithCollectErrors())
+	require.Error(t, err)
+	joined, ok := err.(interface{ Unwrap() []error })
+	require.True(t, ok)
+	assert.Len(t, joined.Unwrap(), 2)
+	assert.ErrorIs(t, err, cel2sql.ErrUnsupportedFunction)
+	assert.ErrorIs(t, err, cel2sql.ErrUnsafeIdentifier)
+
+	ast, issues = env.Compile(`name == "a"`)
+	require.Empty(t, issues)
+	got, err := cel2sql.Convert(ast, cel2sql.WithCollectErrors())
+	require.NoError(t, err)
+	assert.Equal(t, "name = 'a'", got)
+}
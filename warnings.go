@@ -0,0 +1,82 @@
+package cel2sql
+
+import (
+	"fmt"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// WarningKind classifies a Warning.
+type WarningKind int
+
+// Kinds of lossy conversion reported by ConvertWithWarnings
+const (
+	WarningRegex          WarningKind = iota // RE2 pattern that POSIX regular expressions may interpret differently
+	WarningTimestampField                    // timestamp accessor whose SQL result is adjusted to CEL's zero-based numbering
+	WarningJSONComparison                    // JSON value compared with a type its text may not match
+	WarningNullComparison                    // comparison with a nullable column that is false rather than true for NULL
+	WarningNaNComparison                     // NaN literal, which PostgreSQL orders and compares equal to itself
+)
+
+// String returns a string representation of the warning kind
+func (k WarningKind) String() string {
+	switch k {
+	case WarningRegex:
+		return "regex"
+	case WarningTimestampField:
+		return "timestamp_field"
+	case WarningJSONComparison:
+		return "json_comparison"
+	case WarningNullComparison:
+		return "null_comparison"
+	case WarningNaNComparison:
+		return "nan_comparison"
+	default:
+		return "unknown"
+	}
+}
+
+// Warning describes a semantic difference between a CEL expression and the SQL generated for it.
+type Warning struct {
+	Kind    WarningKind
+	Message string
+	Line    int // 1-based source line, zero if unknown
+	Column  int // 1-based source column, zero if unknown
+}
+
+// String returns the warning prefixed with its source location, if known
+func (w Warning) String() string {
+	if w.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s", w.Line, w.Column, w.Message)
+	}
+	return w.Message
+}
+
+// pendingWarning is a warning recorded during conversion, before its location is resolved
+type pendingWarning struct {
+	id      int64
+	kind    WarningKind
+	message string
+}
+
+// warn records a lossy conversion of expr
+func (con *converter) warn(expr *exprpb.Expr, kind WarningKind, format string, args ...any) {
+	con.warnings = append(con.warnings, pendingWarning{id: expr.GetId(), kind: kind, message: fmt.Sprintf(format, args...)})
+}
+
+// resolveWarnings attaches source locations to the warnings recorded during conversion
+func (con *converter) resolveWarnings() []Warning {
+	if len(con.warnings) == 0 {
+		return nil
+	}
+	sourceInfo := con.source()
+	warnings := make([]Warning, 0, len(con.warnings))
+	for _, w := range con.warnings {
+		warning := Warning{Kind: w.kind, Message: w.message}
+		if loc := sourceInfo.GetStartLocation(w.id); loc.Line() > 0 {
+			warning.Line, warning.Column = loc.Line(), loc.Column()+1
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
@@ -0,0 +1,102 @@
+package cel2sql_test
+
+import (
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+func TestConvertWithWarnings(t *testing.T) {
+	env, err := cel.NewEnv(
+		cel.CustomTypeProvider(pg.NewTypeProvider(map[string]pg.Schema{
+			"information_assets": {
+				{Name: "metadata", Type: "jsonb"},
+			},
+		})),
+		cel.Variable("information_assets", cel.ObjectType("information_assets")),
+		cel.Variable("name", cel.StringType),
+		cel.Variable("pattern", cel.StringType),
+		cel.Variable("created_at", cel.TimestampType),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source string
+		want   []cel2sql.Warning
+	}{
+		{
+			name:   "lossless",
+			source: `name.matches("^a[0-9]+$") && created_at.getFullYear() == 2024`,
+		},
+		{
+			name:   "regex_multiline",
+			source: `name.matches("(?m)^ab")`,
+			want: []cel2sql.Warning{{
+				Kind:    cel2sql.WarningRegex,
+				Message: `pattern "(?m)^ab": multi-line ^ is matched at the start of the value only`,
+				Line:    1, Column: 14,
+			}},
+		},
+		{
+			name:   "regex_not_literal",
+			source: `name.matches(pattern)`,
+			want: []cel2sql.Warning{{
+				Kind:    cel2sql.WarningRegex,
+				Message: "pattern is not a literal and is passed to PostgreSQL without RE2 to POSIX conversion",
+				Line:    1, Column: 14,
+			}},
+		},
+		{
+			name:   "timestamp_month",
+			source: `created_at.getMonth() == 0`,
+			want: []cel2sql.Warning{{
+				Kind:    cel2sql.WarningTimestampField,
+				Message: "getMonth is zero-based in CEL; 1 is subtracted from the value PostgreSQL extracts",
+				Line:    1, Column: 1,
+			}},
+		},
+		{
+			name:   "json_numeric",
+			source: `information_assets.metadata.version > 1`,
+			want: []cel2sql.Warning{{
+				Kind:    cel2sql.WarningJSONComparison,
+				Message: "JSON value is cast to numeric for comparison; rows where it is not a number fail the query",
+				Line:    1, Column: 37,
+			}},
+		},
+		{
+			name:   "json_boolean",
+			source: `true == information_assets.metadata.archived`,
+			want: []cel2sql.Warning{{
+				Kind:    cel2sql.WarningJSONComparison,
+				Message: "JSON value is cast to boolean for comparison; rows where it is not a boolean fail the query",
+				Line:    1, Column: 6,
+			}},
+		},
+		{
+			name:   "json_text_ordering",
+			source: `information_assets.metadata.version > "10"`,
+			want: []cel2sql.Warning{{
+				Kind:    cel2sql.WarningJSONComparison,
+				Message: "JSON value is compared as text; numbers stored in JSON are ordered lexically",
+				Line:    1, Column: 37,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, issues := env.Compile(tt.source)
+			require.Empty(t, issues)
+
+			_, got, err := cel2sql.ConvertWithWarnings(ast)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Loads the cel2sql WebAssembly module built with `make wasm`. wasm_exec.js, copied next to the
+// module by the build, must be loaded first, as it defines the Go runtime:
+//
+//   <script src="wasm_exec.js"></script>
+//   <script type="module">
+//     import { load } from './cel2sql.js';
+//     const cel2sql = await load('cel2sql.wasm');
+//     const { sql, parameters, warnings } = cel2sql.convert({
+//       expression: 'user.age >= min_age',
+//       schema: { tables: { users: [{ name: 'age', type: 'integer' }] } },
+//       tables: { user: 'users' },
+//       parameters: { min_age: 'int' },
+//     });
+//   </script>
+
+/**
+ * Instantiates the module and returns the converter.
+ * @param {string|URL} url location of cel2sql.wasm
+ */
+export async function load(url = 'cel2sql.wasm') {
+  const go = new globalThis.Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(instance);
+  return {
+    /**
+     * Converts a CEL expression to SQL.
+     * @param {object} request expression, schema, tables, parameters and placeholderStyle
+     * @returns {{sql: string, parameters?: string[], warnings?: object[], columns?: string[]}}
+     * @throws {Error} if the expression is invalid or cannot be converted
+     */
+    convert(request) {
+      const response = JSON.parse(globalThis.cel2sql.convert(JSON.stringify(request)));
+      if (response.error) {
+        throw new Error(response.error);
+      }
+      return response;
+    },
+  };
+}
@@ -0,0 +1,99 @@
+// Command wasm builds the converter as a WebAssembly module for browsers, so that filter
+// builders can preview the SQL of filters without a server round trip. Schemas are passed with
+// each request in the JSON form read by pg.NewTypeProviderFromConfig, as no database is
+// reachable. Build it with make wasm, and load it with cel2sql.js.
+//
+// Outside of WebAssembly it converts a request read from stdin, to try out requests.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spandigital/cel2sql/v2"
+	"github.com/spandigital/cel2sql/v2/internal/service"
+	"github.com/spandigital/cel2sql/v2/pg"
+)
+
+// request is a conversion request, as sent by cel2sql.js
+type request struct {
+	Expression       string            `json:"expression"`
+	Schema           json.RawMessage   `json:"schema"`           // schema configuration, see pg.NewTypeProviderFromConfig
+	Tables           map[string]string `json:"tables"`           // tables by CEL variable
+	Parameters       map[string]string `json:"parameters"`       // CEL types of parameters by name, e.g. "int" or "list(string)"
+	PlaceholderStyle string            `json:"placeholderStyle"` // dollar (default), question, colon or at
+}
+
+// response is the result of a conversion, with Error set if it failed
+type response struct {
+	SQL        string    `json:"sql,omitempty"`
+	Parameters []string  `json:"parameters,omitempty"`
+	Warnings   []warning `json:"warnings,omitempty"`
+	Columns    []string  `json:"columns,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// warning is a lossy conversion, see cel2sql.Warning
+type warning struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// convert converts a JSON request and returns the JSON response
+func convert(data []byte) []byte {
+	resp, err := convertRequest(data)
+	if err != nil {
+		resp = response{Error: err.Error()}
+	}
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(resp); err != nil {
+		return []byte(fmt.Sprintf(`{"error": %q}`, err.Error()))
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
+
+// convertRequest converts the expression of a JSON request against the schemas it declares
+func convertRequest(data []byte) (response, error) {
+	var req request
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return response{}, fmt.Errorf("invalid request: %w", err)
+	}
+	schemas := map[string]pg.Schema{}
+	if len(req.Schema) > 0 {
+		var err error
+		if schemas, err = pg.SchemasFromConfig(bytes.NewReader(req.Schema)); err != nil {
+			return response{}, err
+		}
+	}
+	style, err := service.ParsePlaceholderStyle(req.PlaceholderStyle)
+	if err != nil {
+		return response{}, err
+	}
+
+	catalog := service.NewCatalog(map[string]map[string]pg.Schema{"": schemas})
+	ast, opts, err := catalog.Compile(service.Request{
+		Expression: req.Expression,
+		Tables:     req.Tables,
+		Parameters: req.Parameters,
+	})
+	if err != nil {
+		return response{}, err
+	}
+	result, err := cel2sql.ConvertWithResult(ast, append(opts, cel2sql.WithPlaceholderStyle(style))...)
+	if err != nil {
+		return response{}, err
+	}
+
+	resp := response{SQL: result.SQL, Parameters: result.Parameters, Columns: result.Columns}
+	for _, w := range result.Warnings {
+		resp.Warnings = append(resp.Warnings, warning{Kind: w.Kind.String(), Message: w.Message, Line: w.Line, Column: w.Column})
+	}
+	return resp, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	schema := `{"tables": {"users": [
+		{"name": "age", "type": "integer", "notNull": true},
+		{"name": "name", "type": "text"}
+	]}}`
+
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{
+			name:    "convert",
+			request: `{"expression": "user.age >= min_age && user.name != \"x\"", "schema": ` + schema + `, "tables": {"user": "users"}, "parameters": {"min_age": "int"}}`,
+			want: `{"sql": "user.age >= $1 AND user.name != 'x'", "parameters": ["min_age"], "columns": ["users.age", "users.name"],
+				"warnings": [{"kind": "null_comparison", "message": "rows where the column is NULL do not match !=; use WithNullSafeEquality to compare NULL like CEL", "line": 1, "column": 34}]}`,
+		},
+		{
+			name:    "placeholder_style",
+			request: `{"expression": "user.age >= min_age", "schema": ` + schema + `, "tables": {"user": "users"}, "parameters": {"min_age": "int"}, "placeholderStyle": "at"}`,
+			want:    `{"sql": "user.age >= @min_age", "parameters": ["min_age"], "columns": ["users.age"]}`,
+		},
+		{
+			name:    "without_schema",
+			request: `{"expression": "age >= 18", "parameters": {}}`,
+			want:    `{"error": "ERROR: <input>:1:1: undeclared reference to 'age' (in container '')\n | age >= 18\n | ^"}`,
+		},
+		{
+			name:    "invalid_schema",
+			request: `{"expression": "true", "schema": {"tables": {"users": [{"name": "age"}]}}}`,
+			want:    `{"error": "table users: column age has no type"}`,
+		},
+		{
+			name:    "unknown_table",
+			request: `{"expression": "true", "schema": ` + schema + `, "tables": {"order": "orders"}}`,
+			want:    `{"error": "unknown table \"orders\" in schema \"\""}`,
+		},
+		{
+			name:    "invalid_request",
+			request: `{"expr": "true"}`,
+			want:    `{"error": "invalid request: json: unknown field \"expr\""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.want, string(convert([]byte(tt.request))))
+		})
+	}
+}
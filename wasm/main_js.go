@@ -0,0 +1,17 @@
+package main
+
+import "syscall/js"
+
+// main exposes cel2sql.convert(request) to JavaScript, taking and returning JSON strings, and
+// keeps the module running
+func main() {
+	js.Global().Set("cel2sql", js.ValueOf(map[string]any{
+		"convert": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			if len(args) != 1 || args[0].Type() != js.TypeString {
+				return `{"error": "convert expects a JSON request string"}`
+			}
+			return string(convert([]byte(args[0].String())))
+		}),
+	}))
+	select {}
+}